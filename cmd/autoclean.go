@@ -20,7 +20,7 @@ func newCleanCommand(opts *dobiOptions) *cobra.Command {
 }
 
 func runClean(opts *dobiOptions) error {
-	conf, err := config.Load(opts.filename)
+	conf, err := loadConfig(opts.filename)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dnephin/dobi/tasks"
+	"github.com/spf13/cobra"
+)
+
+type benchOptions struct {
+	runs         int
+	clean        bool
+	baseline     string
+	saveBaseline bool
+	threshold    float64
+}
+
+func newBenchCommand(opts *dobiOptions) *cobra.Command {
+	benchOpts := benchOptions{}
+	cmd := &cobra.Command{
+		Use:   "bench [flags] RESOURCE[:ACTION] [RESOURCE[:ACTION]...]",
+		Short: "Run tasks multiple times and compare the durations to a baseline",
+		Long: "Run tasks multiple times and record the distribution of the " +
+			"duration of each task. The median duration of each task is compared " +
+			"to the baseline, and the exit code is non-zero when a task is slower " +
+			"than the baseline by more than the threshold.",
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBench(opts, benchOpts, args)
+		},
+	}
+	flags := cmd.Flags()
+	flags.IntVar(&benchOpts.runs, "runs", 5, "Number of times to run the tasks")
+	flags.BoolVar(
+		&benchOpts.clean,
+		"clean",
+		false,
+		"Run the remove action of the resources before each run, so no task is up-to-date")
+	flags.StringVar(
+		&benchOpts.baseline,
+		"baseline",
+		"",
+		"Path to the baseline file (default "+tasks.DefaultBenchBaseline+")")
+	flags.BoolVar(
+		&benchOpts.saveBaseline,
+		"save-baseline",
+		false,
+		"Write the results to the baseline file instead of comparing them")
+	flags.Float64Var(
+		&benchOpts.threshold,
+		"threshold",
+		10,
+		"Percent increase in the median duration of a task which fails the benchmark")
+	return cmd
+}
+
+func runBench(opts *dobiOptions, benchOpts benchOptions, args []string) error {
+	conf, err := loadConfig(opts.filename)
+	if err != nil {
+		return err
+	}
+
+	client, err := buildClient()
+	if err != nil {
+		return fmt.Errorf("failed to create client: %s", err)
+	}
+
+	baseline := benchOpts.baseline
+	if baseline == "" {
+		baseline = filepath.Join(conf.WorkingDir, tasks.DefaultBenchBaseline)
+	}
+	taskNames, params := splitParams(args)
+	return tasks.Bench(tasks.BenchOptions{
+		RunOptions: tasks.RunOptions{
+			Client:    client,
+			NewClient: buildClientForHost,
+			Config:    conf,
+			Tasks:     taskNames,
+			Params:    params,
+			Variables: opts.variables,
+			Quiet:     opts.quiet,
+			BindMount: !opts.noBindMount,
+			Skip:      opts.skip,
+			Strict:    opts.strict,
+		},
+		Runs:         benchOpts.runs,
+		Clean:        benchOpts.clean,
+		Baseline:     baseline,
+		SaveBaseline: benchOpts.saveBaseline,
+		Threshold:    benchOpts.threshold / 100,
+		Out:          os.Stdout,
+	})
+}
@@ -0,0 +1,398 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/spf13/cobra"
+)
+
+// defaultDaemonSocket is the socket the daemon listens on when --listen is
+// not set, relative to the directory of the config file
+const defaultDaemonSocket = ".dobi/daemon.sock"
+
+// daemonTokenEnvVar is the environment variable which sets the token when
+// --token is not set
+const daemonTokenEnvVar = "DOBI_DAEMON_TOKEN"
+
+type daemonOptions struct {
+	listen string
+	token  string
+}
+
+func newDaemonCommand(opts *dobiOptions) *cobra.Command {
+	daemonOpts := daemonOptions{}
+	cmd := &cobra.Command{
+		Use:   "daemon [flags]",
+		Short: "Run a daemon which runs tasks requested with an HTTP API",
+		Long: "Run a daemon which keeps the config loaded and the connection to " +
+			"Docker open, so that editors and CI agents can run tasks without the " +
+			"startup time of dobi. The config is loaded again when the file changes.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDaemon(opts, daemonOpts)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(
+		&daemonOpts.listen,
+		"listen",
+		"",
+		"Path of a unix socket, or tcp://HOST:PORT, to listen on (default "+
+			defaultDaemonSocket+"). Any client which can connect can run tasks "+
+			"with the Docker credentials of the daemon, so tcp requires --token")
+	flags.StringVar(
+		&daemonOpts.token,
+		"token",
+		os.Getenv(daemonTokenEnvVar),
+		"Token which clients must send as \"Authorization: Bearer TOKEN\" "+
+			"(default $"+daemonTokenEnvVar+")")
+	return cmd
+}
+
+func runDaemon(opts *dobiOptions, daemonOpts daemonOptions) error {
+	if strings.HasPrefix(daemonOpts.listen, "tcp://") && daemonOpts.token == "" {
+		return fmt.Errorf("--token is required to listen on %s", daemonOpts.listen)
+	}
+	conf, err := loadConfig(opts.filename)
+	if err != nil {
+		return err
+	}
+	dockerClient, err := buildClient()
+	if err != nil {
+		return fmt.Errorf("failed to create client: %s", err)
+	}
+
+	listener, err := listenDaemon(daemonOpts.listen, conf.WorkingDir)
+	if err != nil {
+		return err
+	}
+	d := newDaemon(opts, dockerClient, conf)
+	d.token = daemonOpts.token
+	server := &http.Server{Handler: d.handler()}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		logging.Log.Info("Stopping the daemon")
+		server.Close() // nolint: errcheck
+	}()
+
+	logging.Log.Infof("Listening on %s", listener.Addr())
+	if err := server.Serve(listener); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// listenDaemon listens on a tcp address, when the value starts with tcp://, or
+// otherwise on a unix socket. A socket left by a previous daemon is removed.
+func listenDaemon(value, workingDir string) (net.Listener, error) {
+	if strings.HasPrefix(value, "tcp://") {
+		return net.Listen("tcp", strings.TrimPrefix(value, "tcp://"))
+	}
+	if value == "" {
+		value = filepath.Join(workingDir, defaultDaemonSocket)
+	}
+	if err := os.MkdirAll(filepath.Dir(value), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(value); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove the old socket: %s", err)
+	}
+	return net.Listen("unix", value)
+}
+
+// daemonRunRequest is the body of a request to run tasks
+type daemonRunRequest struct {
+	Tasks     []string          `json:"tasks"`
+	Params    map[string]string `json:"params"`
+	Variables map[string]string `json:"variables"`
+	Skip      []string          `json:"skip"`
+	Force     []string          `json:"force"`
+	KeepGoing bool              `json:"keep-going"`
+}
+
+// daemonEvent is one line of the response to a request to run tasks. Every
+// line has the output of the run, except for the last line, which has the
+// result.
+type daemonEvent struct {
+	Output string        `json:"output,omitempty"`
+	Result *daemonResult `json:"result,omitempty"`
+}
+
+// daemonResult is the result of a run
+type daemonResult struct {
+	Status   string             `json:"status"`
+	Error    string             `json:"error,omitempty"`
+	Tasks    []tasks.TaskResult `json:"tasks"`
+	Finished time.Time          `json:"finished"`
+}
+
+// daemonStatus is the response to a status request
+type daemonStatus struct {
+	Config  string        `json:"config"`
+	Started time.Time     `json:"started"`
+	Running []string      `json:"running"`
+	LastRun *daemonResult `json:"last-run"`
+	// ConfigChanges are the resources which changed when the config was last
+	// loaded again
+	ConfigChanges *config.Changes `json:"config-changes,omitempty"`
+}
+
+type daemon struct {
+	opts   *dobiOptions
+	client client.DockerClient
+	// token is required in the Authorization header of every request, when
+	// it is not empty
+	token string
+	// runTasks runs the tasks, it is replaced by tests
+	runTasks func(tasks.RunOptions) error
+	started  time.Time
+
+	// mu guards the fields below. Only one run is allowed at a time because
+	// the log output is shared by all the tasks.
+	mu          sync.Mutex
+	conf        *config.Config
+	confLoaded  time.Time
+	confChanges *config.Changes
+	running     []string
+	lastRun     *daemonResult
+}
+
+func newDaemon(
+	opts *dobiOptions,
+	dockerClient client.DockerClient,
+	conf *config.Config,
+) *daemon {
+	return &daemon{
+		opts:       opts,
+		client:     dockerClient,
+		runTasks:   tasks.Run,
+		started:    time.Now(),
+		conf:       conf,
+		confLoaded: time.Now(),
+	}
+}
+
+func (d *daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/run", d.handleRun)
+	mux.HandleFunc("/status", d.handleStatus)
+	return d.authorize(mux)
+}
+
+// authorize rejects requests which do not have the token of the daemon
+func (d *daemon) authorize(next http.Handler) http.Handler {
+	if d.token == "" {
+		return next
+	}
+	expected := []byte("Bearer " + d.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		actual := []byte(req.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(actual, expected) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (d *daemon) handleStatus(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d.mu.Lock()
+	status := daemonStatus{
+		Config:        d.opts.filename,
+		Started:       d.started,
+		Running:       d.running,
+		LastRun:       d.lastRun,
+		ConfigChanges: d.confChanges,
+	}
+	d.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status) // nolint: errcheck
+}
+
+func (d *daemon) handleRun(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	runReq := daemonRunRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&runReq); err != nil && err != io.EOF {
+		http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+		return
+	}
+	conf, err := d.startRun(runReq.Tasks)
+	switch {
+	case err == errRunInProgress:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	out := newEventWriter(w)
+	report := &tasks.Report{}
+	logOut := logging.Log.Out
+	logging.Log.Out = io.MultiWriter(logOut, out)
+	err = d.runTasks(tasks.RunOptions{
+		Client:       d.client,
+		NewClient:    buildClientForHost,
+		Config:       conf,
+		Tasks:        runReq.Tasks,
+		Params:       runReq.Params,
+		Variables:    mergeVariables(d.opts.variables, runReq.Variables),
+		Quiet:        d.opts.quiet,
+		BindMount:    !d.opts.noBindMount,
+		PrefixOutput: usePrefix(*d.opts, out),
+		JobOutput:    out,
+		LogDir:       d.opts.logDir,
+		Skip:         runReq.Skip,
+		Force:        runReq.Force,
+		Strict:       d.opts.strict,
+		KeepGoing:    runReq.KeepGoing,
+		SaveRunState: true,
+		Report:       report,
+	})
+	logging.Log.Out = logOut
+
+	result := d.finishRun(report, err)
+	out.write(daemonEvent{Result: result}) // nolint: errcheck
+}
+
+var errRunInProgress = fmt.Errorf("a run is already in progress")
+
+// startRun marks the tasks as running, and returns the config. The config is
+// loaded again if the file was modified since it was last loaded.
+func (d *daemon) startRun(taskNames []string) (*config.Config, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running != nil {
+		return nil, errRunInProgress
+	}
+	if err := d.reloadConfig(); err != nil {
+		return nil, err
+	}
+	d.running = append([]string{}, taskNames...)
+	return d.conf, nil
+}
+
+func (d *daemon) reloadConfig() error {
+	info, err := os.Stat(d.opts.filename)
+	if err != nil || !info.ModTime().After(d.confLoaded) {
+		return nil
+	}
+	logging.Log.Infof("Loading %s again, because it was modified", d.opts.filename)
+	conf, err := loadConfig(d.opts.filename)
+	if err != nil {
+		return err
+	}
+	changes := config.Diff(d.conf, conf)
+	logChanges(changes)
+	d.conf = conf
+	d.confLoaded = time.Now()
+	d.confChanges = &changes
+	return nil
+}
+
+// logChanges logs the resources which changed when the config was loaded
+// again. The tasks of the next run are collected from the new config.
+func logChanges(changes config.Changes) {
+	if changes.IsZero() {
+		logging.Log.Info("No resources changed")
+		return
+	}
+	if len(changes.Added) > 0 {
+		logging.Log.Infof("Added resources: %s", strings.Join(changes.Added, ", "))
+	}
+	if len(changes.Changed) > 0 {
+		logging.Log.Infof("Changed resources: %s", strings.Join(changes.Changed, ", "))
+	}
+	if len(changes.Removed) > 0 {
+		logging.Log.Infof("Removed resources: %s", strings.Join(changes.Removed, ", "))
+	}
+}
+
+func (d *daemon) finishRun(report *tasks.Report, err error) *daemonResult {
+	result := &daemonResult{
+		Status:   "success",
+		Tasks:    report.Tasks,
+		Finished: time.Now(),
+	}
+	if err != nil {
+		result.Status = "failure"
+		result.Error = err.Error()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.running = nil
+	d.lastRun = result
+	return result
+}
+
+// mergeVariables returns the variables from --var of the daemon, with the
+// variables of the request replacing them
+func mergeVariables(base, override map[string]string) map[string]string {
+	variables := make(map[string]string)
+	for name, value := range base {
+		variables[name] = value
+	}
+	for name, value := range override {
+		variables[name] = value
+	}
+	return variables
+}
+
+// eventWriter writes each write as a line of JSON to the response, and
+// flushes it so that the client receives the output as the tasks run
+type eventWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+	enc *json.Encoder
+}
+
+func newEventWriter(out io.Writer) *eventWriter {
+	return &eventWriter{out: out, enc: json.NewEncoder(out)}
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	if err := w.write(daemonEvent{Output: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *eventWriter) write(event daemonEvent) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.enc.Encode(event); err != nil {
+		return err
+	}
+	if flusher, ok := w.out.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestDaemonRun(t *testing.T) {
+	opts := &dobiOptions{
+		filename:  "missing.yaml",
+		variables: map[string]string{"tag": "dev", "os": "linux"},
+	}
+	d := newDaemon(opts, nil, &config.Config{})
+	var runOptions tasks.RunOptions
+	d.runTasks = func(options tasks.RunOptions) error {
+		runOptions = options
+		fmt.Fprint(options.JobOutput, "hello\n")
+		options.Report.Tasks = append(options.Report.Tasks,
+			tasks.TaskResult{Name: "one:run", Status: "done"})
+		return fmt.Errorf("one:run failed")
+	}
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	body := `{"tasks": ["one"], "variables": {"tag": "v1"}}`
+	resp, err := http.Post(server.URL+"/run", "application/json", strings.NewReader(body))
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusOK))
+
+	events := []daemonEvent{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		event := daemonEvent{}
+		assert.NilError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	assert.NilError(t, scanner.Err())
+	assert.Assert(t, is.Len(events, 2))
+	assert.Check(t, is.Equal(events[0].Output, "hello\n"))
+	assert.Check(t, is.Nil(events[0].Result))
+	result := events[1].Result
+	assert.Assert(t, result != nil)
+	assert.Check(t, is.Equal(result.Status, "failure"))
+	assert.Check(t, is.Equal(result.Error, "one:run failed"))
+	assert.Check(t, is.Len(result.Tasks, 1))
+
+	assert.Check(t, is.DeepEqual(runOptions.Tasks, []string{"one"}))
+	expected := map[string]string{"tag": "v1", "os": "linux"}
+	assert.Check(t, is.DeepEqual(runOptions.Variables, expected))
+
+	resp, err = http.Get(server.URL + "/status")
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	status := daemonStatus{}
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&status))
+	assert.Check(t, is.Len(status.Running, 0))
+	assert.Assert(t, status.LastRun != nil)
+	assert.Check(t, is.Equal(status.LastRun.Status, "failure"))
+}
+
+func TestDaemonRunInProgress(t *testing.T) {
+	d := newDaemon(&dobiOptions{filename: "missing.yaml"}, nil, &config.Config{})
+	d.running = []string{"one"}
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/run", "application/json", strings.NewReader(`{}`))
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusConflict))
+}
+
+func TestDaemonReloadsModifiedConfig(t *testing.T) {
+	file := fs.NewFile(t, "dobi.yaml", fs.WithContent(`
+mount=src:
+    bind: .
+    path: /src
+
+mount=dist:
+    bind: dist/
+    path: /dist
+`))
+	defer file.Remove()
+	prev := &config.Config{Resources: map[string]config.Resource{
+		"src":  &config.MountConfig{Bind: "./other", Path: "/src"},
+		"docs": &config.MountConfig{Bind: "docs/", Path: "/docs"},
+	}}
+	d := newDaemon(&dobiOptions{filename: file.Path()}, nil, prev)
+	d.confLoaded = time.Now().Add(-time.Hour)
+
+	conf, err := d.startRun([]string{"dist"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(conf.Resources, 2))
+	expected := &config.Changes{
+		Added:   []string{"dist"},
+		Changed: []string{"src"},
+		Removed: []string{"docs"},
+	}
+	assert.Check(t, is.DeepEqual(d.confChanges, expected))
+}
+
+func TestDaemonRequiresToken(t *testing.T) {
+	d := newDaemon(&dobiOptions{filename: "missing.yaml"}, nil, &config.Config{})
+	d.token = "secret"
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/status")
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusUnauthorized))
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/status", nil)
+	assert.NilError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusOK))
+}
+
+func TestRunDaemonRequiresTokenForTCP(t *testing.T) {
+	err := runDaemon(&dobiOptions{filename: "missing.yaml"}, daemonOptions{listen: "tcp://:8080"})
+	assert.Check(t, is.ErrorContains(err, "--token is required"))
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dnephin/dobi/tasks"
+	"github.com/spf13/cobra"
+)
+
+func newDepsCommand(opts *dobiOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deps TASK [TASK...] [PARAM=VALUE...]",
+		Short: "Print the tasks run by a task, and if each task is stale",
+		Long: "Print every task which is run by the tasks, in the order they are " +
+			"run, with the action and the state of each task. A task is stale " +
+			"when it would run. The tasks are not run.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDeps(opts, args)
+		},
+	}
+	return cmd
+}
+
+func runDeps(opts *dobiOptions, args []string) error {
+	conf, err := loadConfig(opts.filename)
+	if err != nil {
+		return err
+	}
+	dockerClient, err := buildClient()
+	if err != nil {
+		return fmt.Errorf("failed to create client: %s", err)
+	}
+	taskNames, params := splitParams(args)
+	return tasks.Deps(tasks.DepsOptions{
+		Client:    dockerClient,
+		NewClient: buildClientForHost,
+		Config:    conf,
+		Tasks:     taskNames,
+		Params:    params,
+		Variables: opts.variables,
+		Out:       os.Stdout,
+	})
+}
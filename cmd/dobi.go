@@ -2,12 +2,17 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
 	"github.com/dnephin/dobi/logging"
 	"github.com/dnephin/dobi/tasks"
 	"github.com/dnephin/dobi/tasks/client"
+	"github.com/docker/docker/pkg/term"
 	docker "github.com/fsouza/go-dockerclient"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -26,9 +31,31 @@ var (
 
 type dobiOptions struct {
 	filename    string
+	envFile     string
 	verbose     bool
 	quiet       bool
+	logLevel    string
+	quietSkip   bool
+	matrix      bool
+	keepGoing   bool
+	resume      bool
+	strict      bool
+	interactive bool
+	onlyPaths   []string
+	skip        []string
+	force       []string
+	hostProfile string
+	defaultMem  string
+	reportFile  string
+	traceFile   string
 	noBindMount bool
+	noColor     bool
+	prefix      string
+	logOutput   string
+	jobOutput   string
+	logDir      string
+	vars        []string
+	variables   map[string]string
 	tasks       []string
 	version     bool
 }
@@ -38,7 +65,7 @@ func NewRootCommand() *cobra.Command {
 	var opts dobiOptions
 
 	cmd := &cobra.Command{
-		Use:              "dobi [flags] RESOURCE[:ACTION] [RESOURCE[:ACTION]...]",
+		Use:              "dobi [flags] RESOURCE[:ACTION] [RESOURCE[:ACTION]...] [PARAM=VALUE...]",
 		Short:            "A build automation tool for Docker applications",
 		SilenceUsage:     true,
 		SilenceErrors:    true,
@@ -49,26 +76,149 @@ func NewRootCommand() *cobra.Command {
 			return runDobi(opts)
 		},
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			initLogging(opts.verbose, opts.quiet)
-			return nil
+			if err := initLogging(opts); err != nil {
+				return err
+			}
+			opts.filename = findConfigFile(opts.filename)
+			var err error
+			if opts.variables, err = parseVariables(opts.vars); err != nil {
+				return err
+			}
+			return loadEnvFile(opts)
 		},
 	}
 
 	flags := cmd.Flags()
-	flags.StringVarP(&opts.filename, "filename", "f", "dobi.yaml", "Path to config file")
+	flags.StringVarP(&opts.filename, "filename", "f", defaultConfigFile, "Path to config file")
+	flags.StringVar(
+		&opts.envFile,
+		"env-file",
+		os.Getenv("DOBI_ENV_FILE"),
+		"Path to a file of environment variables (default .env in the directory of the config file)")
 	flags.BoolVarP(&opts.verbose, "verbose", "v", false, "Verbose")
 	flags.BoolVarP(&opts.quiet, "quiet", "q", false, "Quiet")
+	flags.StringVar(
+		&opts.logLevel,
+		"log-level",
+		os.Getenv("DOBI_LOG_LEVEL"),
+		"Log level (errors, lifecycle, info, debug, trace)")
+	flags.BoolVar(
+		&opts.quietSkip,
+		"quiet-skip",
+		defaultBoolValue("DOBI_QUIET_SKIP"),
+		"Hide the output for tasks which are skipped because they are up-to-date")
 	flags.BoolVar(
 		&opts.noBindMount,
 		"no-bind-mount",
 		defaultBoolValue("DOBI_NO_BIND_MOUNT"),
 		"Provide mounts as a layer in an image instead of a bind mount")
+	flags.BoolVar(
+		&opts.noColor,
+		"no-color",
+		defaultBoolValue("NO_COLOR"),
+		"Disable color in log output and job output prefixes")
+	flags.StringVar(
+		&opts.prefix,
+		"prefix",
+		"auto",
+		"Prefix each line of job output with the task name (auto, on, off). "+
+			"auto prefixes the output when it is a terminal")
+	flags.StringVar(
+		&opts.logOutput,
+		"log-output",
+		logging.OutputStderr,
+		"Write the logs and build output to stdout, stderr, null, or a file")
+	flags.StringVar(
+		&opts.jobOutput,
+		"job-output",
+		logging.OutputStdout,
+		"Write the stdout of jobs to stdout, stderr, null, or a file")
+	flags.StringVar(
+		&opts.logDir,
+		"log-dir",
+		os.Getenv("DOBI_LOG_DIR"),
+		"Also write the output of each job to a file named <task>.log in the directory")
+	flags.StringArrayVar(
+		&opts.vars,
+		"var",
+		nil,
+		"Set a variable used by {var.<name>} in the config (NAME=VALUE), may be repeated")
 	flags.BoolVar(&opts.version, "version", false, "Print version and exit")
+	flags.BoolVar(
+		&opts.matrix,
+		"matrix",
+		false,
+		"Run the tasks once for each Docker host in meta.matrix")
+	flags.BoolVar(
+		&opts.interactive,
+		"interactive",
+		false,
+		"Select the tasks to run from a list when no tasks are given")
+	flags.StringSliceVar(
+		&opts.onlyPaths,
+		"only-paths",
+		nil,
+		"Only run tasks for resources which use files matching the path patterns")
+	flags.StringSliceVar(
+		&opts.skip,
+		"skip",
+		nil,
+		"Treat the resources or tasks as up-to-date and don't run them (NAME, TYPE=NAME, or TASK)")
+	flags.StringSliceVar(
+		&opts.force,
+		"force",
+		nil,
+		"Treat the resources or tasks as stale and always run them (NAME, TYPE=NAME, or TASK)")
+	flags.BoolVar(
+		&opts.strict,
+		"strict",
+		false,
+		"Fail jobs which exit with one of their exit-codes.warn codes")
+	flags.BoolVarP(
+		&opts.keepGoing,
+		"keep-going",
+		"k",
+		false,
+		"Continue to run tasks which don't depend on a failed task")
+	flags.BoolVar(
+		&opts.resume,
+		"continue",
+		false,
+		"Continue the last failed run, skipping the tasks which completed")
+	flags.StringVar(
+		&opts.reportFile,
+		"report",
+		"",
+		"Write the task results to a file (JSON, or JUnit for a .xml file)")
+	flags.StringVar(
+		&opts.traceFile,
+		"trace",
+		"",
+		"Write a trace of the tasks to a file in the Chrome trace event format")
+	flags.StringVar(
+		&opts.hostProfile,
+		"host-profile",
+		os.Getenv("DOBI_HOST_PROFILE"),
+		"Use the meta.small-host settings (auto, small, default)")
+	flags.StringVar(
+		&opts.defaultMem,
+		"default-memory",
+		os.Getenv("DOBI_DEFAULT_MEMORY"),
+		"Memory limit for jobs which don't set memory (ex: 2g)")
 
 	flags.SetInterspersed(false)
+	cmd.SetHelpCommand(newHelpCommand(&opts))
 	cmd.AddCommand(
 		newListCommand(&opts),
 		newCleanCommand(&opts),
+		newUpCommand(&opts),
+		newDownCommand(&opts),
+		newValidateCommand(&opts),
+		newBenchCommand(&opts),
+		newGCCommand(&opts),
+		newSelfUpdateCommand(&opts),
+		newDaemonCommand(&opts),
+		newDepsCommand(&opts),
 	)
 	return cmd
 }
@@ -79,7 +229,13 @@ func runDobi(opts dobiOptions) error {
 		return nil
 	}
 
-	conf, err := config.Load(opts.filename)
+	switch opts.prefix {
+	case "auto", "on", "off":
+	default:
+		return fmt.Errorf("invalid --prefix %q, must be one of: auto, on, off", opts.prefix)
+	}
+
+	conf, err := loadConfig(opts.filename)
 	if err != nil {
 		return err
 	}
@@ -89,28 +245,130 @@ func runDobi(opts dobiOptions) error {
 		return fmt.Errorf("failed to create client: %s", err)
 	}
 
-	return tasks.Run(tasks.RunOptions{
-		Client:    client,
-		Config:    conf,
-		Tasks:     opts.tasks,
-		Quiet:     opts.quiet,
-		BindMount: !opts.noBindMount,
-	})
+	jobOutput, closeJobOutput, err := logging.OpenOutput(opts.jobOutput)
+	if err != nil {
+		return fmt.Errorf("failed to open job output: %s", err)
+	}
+	defer closeJobOutput() // nolint: errcheck
+
+	taskNames, params := splitParams(opts.tasks)
+	if opts.interactive && len(taskNames) == 0 {
+		taskNames, err = pickTasks(os.Stdin, os.Stdout, conf)
+		if err != nil {
+			return err
+		}
+	}
+	runOptions := tasks.RunOptions{
+		Client:        client,
+		NewClient:     buildClientForHost,
+		Config:        conf,
+		Tasks:         taskNames,
+		Params:        params,
+		Variables:     opts.variables,
+		Quiet:         opts.quiet,
+		BindMount:     !opts.noBindMount,
+		PrefixOutput:  usePrefix(opts, jobOutput),
+		Color:         useColor(opts, jobOutput),
+		JobOutput:     jobOutput,
+		LogDir:        opts.logDir,
+		HostProfile:   opts.hostProfile,
+		DefaultMemory: opts.defaultMem,
+		OnlyPaths:     opts.onlyPaths,
+		Skip:          opts.skip,
+		Force:         opts.force,
+		Strict:        opts.strict,
+		KeepGoing:     opts.keepGoing,
+		Continue:      opts.resume,
+		SaveRunState:  true,
+		Summary:       !opts.quiet,
+		ReportFile:    opts.reportFile,
+		TraceFile:     opts.traceFile,
+	}
+	if opts.matrix {
+		return tasks.RunMatrix(runOptions)
+	}
+	return tasks.Run(runOptions)
 }
 
-func initLogging(verbose, quiet bool) {
+// splitParams separates name=value alias parameters from task names
+func splitParams(args []string) ([]string, map[string]string) {
+	taskNames := []string{}
+	params := make(map[string]string)
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) == 2 {
+			params[parts[0]] = parts[1]
+			continue
+		}
+		taskNames = append(taskNames, arg)
+	}
+	return taskNames, params
+}
+
+// parseVariables parses the NAME=VALUE values of --var
+func parseVariables(values []string) (map[string]string, error) {
+	variables := make(map[string]string)
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid --var %q, must be NAME=VALUE", value)
+		}
+		variables[parts[0]] = parts[1]
+	}
+	return variables, nil
+}
+
+func initLogging(opts dobiOptions) error {
 	logger := logging.Log
-	if verbose {
+	quietSkip := opts.quietSkip
+	switch {
+	case opts.logLevel != "":
+		level, hideSkipped, err := logging.ParseVerbosity(opts.logLevel)
+		if err != nil {
+			return err
+		}
+		logger.Level = level
+		quietSkip = quietSkip || hideSkipped
+	case opts.quiet:
+		logger.Level = log.WarnLevel
+	case opts.verbose:
 		logger.Level = log.DebugLevel
 	}
-	if quiet {
-		logger.Level = log.WarnLevel
+	logging.SetQuietSkip(quietSkip)
+
+	// The log output is left open until dobi exits
+	out, _, err := logging.OpenOutput(opts.logOutput)
+	if err != nil {
+		return fmt.Errorf("failed to open log output: %s", err)
 	}
-	logger.Out = os.Stderr
+	logger.Out = out
 
-	formatter := &logging.Formatter{}
+	formatter := &logging.Formatter{
+		NoColor: opts.noColor || logging.IsFileOutput(opts.logOutput),
+	}
 	log.SetFormatter(formatter)
 	logger.Formatter = formatter
+	return nil
+}
+
+// useColor returns true if color output is enabled and out is a terminal
+func useColor(opts dobiOptions, out io.Writer) bool {
+	_, isTerminal := term.GetFdInfo(out)
+	return !opts.noColor && isTerminal
+}
+
+// usePrefix returns true if the job output should be prefixed with the task
+// name. By default the output is only prefixed when it is a terminal, so that
+// the output of a job which is piped to another program is not changed.
+func usePrefix(opts dobiOptions, out io.Writer) bool {
+	switch opts.prefix {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+	_, isTerminal := term.GetFdInfo(out)
+	return isTerminal
 }
 
 func buildClient() (client.DockerClient, error) {
@@ -127,6 +385,85 @@ func buildClient() (client.DockerClient, error) {
 	return client, nil
 }
 
+func buildClientForHost(host string) (client.DockerClient, error) {
+	apiVersion := os.Getenv("DOCKER_API_VERSION")
+	if apiVersion == "" {
+		apiVersion = DefaultDockerAPIVersion
+	}
+	certPath := certPathForHost(host)
+	if certPath == "" {
+		return docker.NewVersionedClient(host, apiVersion)
+	}
+	return docker.NewVersionedTLSClient(
+		host,
+		filepath.Join(certPath, "cert.pem"),
+		filepath.Join(certPath, "key.pem"),
+		filepath.Join(certPath, "ca.pem"),
+		apiVersion)
+}
+
+// certPathForHost returns DOCKER_CERT_PATH if host is the default Docker host
+// set by DOCKER_HOST. The certificates are for the default host, so they are
+// not used for other hosts.
+func certPathForHost(host string) string {
+	if host != os.Getenv("DOCKER_HOST") {
+		return ""
+	}
+	return os.Getenv("DOCKER_CERT_PATH")
+}
+
+// defaultConfigFile is the config file used when --filename is not set
+const defaultConfigFile = "dobi.yaml"
+
+// alternateConfigFiles are the config files which are used, in order, when
+// --filename is not set and the default config file does not exist
+var alternateConfigFiles = []string{"dobi.cue", "dobi.jsonnet"}
+
+// findConfigFile returns the first of the alternate config files which
+// exists, when the filename is the default and does not exist
+func findConfigFile(filename string) string {
+	if filename != defaultConfigFile || fileExists(filename) {
+		return filename
+	}
+	for _, alternate := range alternateConfigFiles {
+		if fileExists(alternate) {
+			return alternate
+		}
+	}
+	return filename
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+// defaultEnvFile is the name of the file of environment variables which is
+// loaded from the directory of the config file when --env-file is not set
+const defaultEnvFile = ".env"
+
+// loadEnvFile sets the variables from the env file in the environment, before
+// the config is loaded. The default file is optional.
+func loadEnvFile(opts dobiOptions) error {
+	if opts.envFile != "" {
+		return execenv.LoadEnvFile(opts.envFile)
+	}
+	filename := filepath.Join(filepath.Dir(opts.filename), defaultEnvFile)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil
+	}
+	return execenv.LoadEnvFile(filename)
+}
+
+// loadConfig loads the config file and checks that the config can be used
+// with this version of dobi
+func loadConfig(filename string) (*config.Config, error) {
+	if err := config.CheckFileVersion(filename, version); err != nil {
+		return nil, err
+	}
+	return config.Load(filename)
+}
+
 func printVersion() {
 	fmt.Printf("dobi version %v (build: %v, date: %s)\n", version, gitsha, buildDate)
 }
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/env"
+)
+
+func TestSplitParams(t *testing.T) {
+	taskNames, params := splitParams(
+		[]string{"deploy", "stage=production", "build:rm", "url=http://a?b=c"})
+	assert.Check(t, is.DeepEqual([]string{"deploy", "build:rm"}, taskNames))
+	expected := map[string]string{"stage": "production", "url": "http://a?b=c"}
+	assert.Check(t, is.DeepEqual(expected, params))
+}
+
+func TestParseVariables(t *testing.T) {
+	variables, err := parseVariables([]string{"version=2.0", "url=http://a?b=c", "empty="})
+	assert.NilError(t, err)
+	expected := map[string]string{"version": "2.0", "url": "http://a?b=c", "empty": ""}
+	assert.Check(t, is.DeepEqual(expected, variables))
+
+	_, err = parseVariables([]string{"version"})
+	assert.Check(t, is.Error(err, `invalid --var "version", must be NAME=VALUE`))
+}
+
+func TestUsePrefix(t *testing.T) {
+	out := new(bytes.Buffer)
+	assert.Check(t, !usePrefix(dobiOptions{prefix: "auto"}, out))
+	assert.Check(t, usePrefix(dobiOptions{prefix: "on"}, out))
+	assert.Check(t, !usePrefix(dobiOptions{prefix: "off"}, out))
+}
+
+func TestCertPathForHost(t *testing.T) {
+	defer env.PatchAll(t, map[string]string{
+		"DOCKER_HOST":      "tcp://default:2376",
+		"DOCKER_CERT_PATH": "/certs",
+	})()
+	assert.Check(t, is.Equal(certPathForHost("tcp://default:2376"), "/certs"))
+	assert.Check(t, is.Equal(certPathForHost("tcp://arm:2376"), ""))
+}
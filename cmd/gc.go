@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/dnephin/dobi/tasks"
+	"github.com/spf13/cobra"
+)
+
+type gcOptions struct {
+	dryRun bool
+}
+
+func newGCCommand(opts *dobiOptions) *cobra.Command {
+	gcOpts := gcOptions{}
+	cmd := &cobra.Command{
+		Use:   "gc [flags]",
+		Short: "Remove old entries from artifact directories",
+		Long: "Remove the entries of the artifact directories of jobs which are " +
+			"not kept by the meta.artifact-retention settings.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGC(opts, gcOpts)
+		},
+	}
+	flags := cmd.Flags()
+	flags.BoolVar(
+		&gcOpts.dryRun,
+		"dry-run",
+		false,
+		"List the entries which would be removed, without removing them")
+	return cmd
+}
+
+func runGC(opts *dobiOptions, gcOpts gcOptions) error {
+	conf, err := loadConfig(opts.filename)
+	if err != nil {
+		return err
+	}
+	return tasks.GC(tasks.GCOptions{
+		Config: conf,
+		DryRun: gcOpts.dryRun,
+		Out:    os.Stdout,
+	})
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/spf13/cobra"
+)
+
+// newHelpCommand returns a help command which shows the help for a command,
+// or for a resource in the config file
+func newHelpCommand(opts *dobiOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "help [COMMAND | RESOURCE]",
+		Short: "Help about a command or a resource",
+		Long: "Help about a command, or a resource from the config file. " +
+			"The help for a resource shows its type, description, dependencies, " +
+			"artifacts, and actions.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			if len(args) == 0 {
+				return root.Help()
+			}
+			if sub, _, err := root.Find(args); err == nil && sub != root {
+				return sub.Help()
+			}
+			return runHelpResource(opts, args[0])
+		},
+	}
+}
+
+func runHelpResource(opts *dobiOptions, name string) error {
+	conf, err := config.Load(opts.filename)
+	if err != nil {
+		return err
+	}
+	resName := task.ParseName(name).Resource()
+	res, ok := conf.Resources[resName]
+	if !ok {
+		return fmt.Errorf("%q is not a command or a resource in %s", name, opts.filename)
+	}
+	fmt.Print(formatResourceHelp(resName, res))
+	return nil
+}
+
+// formatResourceHelp formats the help for a resource in the same layout as
+// the help of a command
+func formatResourceHelp(name string, res config.Resource) string {
+	resType := config.ResourceType(res)
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "%s\n\n", namedResource{name: name, resource: res}.Describe())
+	fmt.Fprintf(buf, "Usage:\n  dobi %s[:ACTION]\n\n", name)
+	fmt.Fprintf(buf, "Type:\n  %s\n", resType)
+
+	if deps := res.Dependencies(); len(deps) > 0 {
+		fmt.Fprintf(buf, "\nDependencies:\n  %s\n", strings.Join(deps, "\n  "))
+	}
+	if job, ok := res.(*config.JobConfig); ok && !job.Artifact.Empty() {
+		fmt.Fprintf(buf, "\nArtifacts:\n  %s\n", strings.Join(job.Artifact.Globs(), "\n  "))
+	}
+	if tags := res.CategoryTags(); len(tags) > 0 {
+		fmt.Fprintf(buf, "\nTags:\n  %s\n", strings.Join(tags, ", "))
+	}
+	if actions := tasks.ResourceActions(res); len(actions) > 0 {
+		buf.WriteString("\nActions:\n")
+		for i, action := range actions {
+			description := action.Description
+			if i == 0 {
+				description += " (default)"
+			}
+			fmt.Fprintf(buf, "  %-10s %s\n", action.Name, description)
+		}
+	}
+	return buf.String()
+}
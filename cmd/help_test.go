@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/renstrom/dedent"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestFormatResourceHelp(t *testing.T) {
+	conf, err := config.LoadFromBytes([]byte(dedent.Dedent(`
+		job=test:
+		  use: builder
+		  mounts: [source]
+		  artifact: dist/
+		  annotations:
+		    description: Run the tests
+		    tags: [ci]
+	`)))
+	assert.NilError(t, err)
+
+	expected := `Run the tests
+
+Usage:
+  dobi test[:ACTION]
+
+Type:
+  job
+
+Dependencies:
+  builder
+  source
+
+Artifacts:
+  dist/
+
+Tags:
+  ci
+
+Actions:
+  run        Run the job (default)
+  start      Start the job in the background, when it is not running
+  stop       Stop the job which was started in the background
+  upload     Upload the artifact to meta.artifact-store
+  download   Download the artifact from meta.artifact-store
+  remove     Remove the artifact of the job
+`
+	actual := formatResourceHelp("test", conf.Resources["test"])
+	assert.Check(t, is.Equal(actual, expected))
+}
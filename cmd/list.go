@@ -1,19 +1,28 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"sort"
-
 	"strings"
 
 	"github.com/dnephin/dobi/config"
 	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
 	"github.com/spf13/cobra"
 )
 
 type listOptions struct {
 	all     bool
 	grouped bool
+	json    bool
+	tree    bool
+	actions bool
 	tags    []string
 }
 
@@ -48,6 +57,15 @@ func newListCommand(opts *dobiOptions) *cobra.Command {
 	flags.StringSliceVarP(
 		&listOpts.tags, "tags", "t", nil,
 		"List tasks matching the tag")
+	flags.BoolVar(
+		&listOpts.json, "json", false,
+		"List resources as JSON")
+	flags.BoolVar(
+		&listOpts.tree, "tree", false,
+		"List resources with a tree of their dependencies")
+	flags.BoolVar(
+		&listOpts.actions, "actions", false,
+		"List the actions of each resource")
 	return cmd
 }
 
@@ -57,14 +75,31 @@ func runList(opts *dobiOptions, listOpts listOptions) error {
 		return err
 	}
 
+	switch {
+	case listOpts.grouped && (listOpts.json || listOpts.tree):
+		return fmt.Errorf("--grouped can not be used with --json or --tree")
+	case listOpts.json && listOpts.tree:
+		return fmt.Errorf("--json can not be used with --tree")
+	case listOpts.json:
+		return printJSON(os.Stdout, filterResources(conf, listOpts), listOpts.actions)
+	case listOpts.tree:
+		resources := filterResources(conf, listOpts)
+		if len(resources) == 0 {
+			logging.Log.Warn("No resources found. Try --all or --tags.")
+			return nil
+		}
+		fmt.Print(formatTree(conf, resources))
+		return nil
+	}
+
 	tags := getTags(conf.Resources)
 	var descriptions []string
 	if listOpts.grouped {
 		resources := filterResourcesTags(conf, listOpts)
-		descriptions = getDescriptionsByTag(resources)
+		descriptions = getDescriptionsByTag(resources, listOpts.actions)
 	} else {
 		resources := filterResources(conf, listOpts)
-		descriptions = getDescriptions(resources)
+		descriptions = getDescriptions(resources, listOpts.actions)
 	}
 
 	if len(descriptions) == 0 {
@@ -150,19 +185,36 @@ func include(res config.Resource, listOpts listOptions) bool {
 	return len(listOpts.tags) == 0 && res.Describe() != ""
 }
 
-func getDescriptions(resources []namedResource) []string {
+func getDescriptions(resources []namedResource, withActions bool) []string {
 	lines := []string{}
 	for _, named := range resources {
 		line := fmt.Sprintf("%-20s %s", named.name, named.Describe())
+		if withActions {
+			line += "\n    actions: " + formatActions(named.resource)
+		}
 		lines = append(lines, line)
 	}
 	return lines
 }
 
-func getDescriptionsByTag(resources []resourceGroup) []string {
+// formatActions returns the names of the actions of the resource, with the
+// default action first
+func formatActions(res config.Resource) string {
+	names := []string{}
+	for i, action := range tasks.ResourceActions(res) {
+		if i == 0 {
+			names = append(names, action.Name+" (default)")
+			continue
+		}
+		names = append(names, action.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func getDescriptionsByTag(resources []resourceGroup, withActions bool) []string {
 	lines := []string{}
 	for _, tag := range resources {
-		descriptions := getDescriptions(tag.resources)
+		descriptions := getDescriptions(tag.resources, withActions)
 		lines = append(lines, formatTags(tag.tag, descriptions))
 	}
 	return lines
@@ -202,6 +254,79 @@ func format(descriptions []string, tags []string) string {
 	return msg
 }
 
+type jsonResource struct {
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	Tags         []string       `json:"tags"`
+	Dependencies []string       `json:"dependencies"`
+	Actions      []types.Action `json:"actions,omitempty"`
+}
+
+func printJSON(out io.Writer, resources []namedResource, withActions bool) error {
+	items := []jsonResource{}
+	for _, named := range resources {
+		item := jsonResource{
+			Name:         named.name,
+			Description:  named.Describe(),
+			Tags:         nonNil(named.resource.CategoryTags()),
+			Dependencies: nonNil(named.resource.Dependencies()),
+		}
+		if withActions {
+			item.Actions = tasks.ResourceActions(named.resource)
+		}
+		items = append(items, item)
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
+
+func nonNil(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
+
+func formatTree(conf *config.Config, resources []namedResource) string {
+	buf := new(bytes.Buffer)
+	for _, named := range resources {
+		fmt.Fprintf(buf, "%-20s %s\n", named.name, named.Describe())
+		writeTree(buf, conf, named.resource.Dependencies(), "  ", map[string]bool{
+			named.name: true,
+		})
+	}
+	return buf.String()
+}
+
+// writeTree writes the dependencies of a resource, indented by their depth.
+// path contains the resources in the current branch, and is used to stop on
+// dependency cycles.
+func writeTree(
+	out io.Writer,
+	conf *config.Config,
+	deps []string,
+	indent string,
+	path map[string]bool,
+) {
+	for i, dep := range deps {
+		branch, next := "├─ ", "│  "
+		if i == len(deps)-1 {
+			branch, next = "└─ ", "   "
+		}
+		fmt.Fprintf(out, "%s%s%s\n", indent, branch, dep)
+
+		resName := task.ParseName(dep).Resource()
+		res, ok := conf.Resources[resName]
+		if !ok || path[resName] {
+			continue
+		}
+		path[resName] = true
+		writeTree(out, conf, res.Dependencies(), indent+next, path)
+		delete(path, resName)
+	}
+}
+
 func formatTags(tag string, descriptions []string) string {
 	msg := fmt.Sprintf("Tag: %s\n", tag)
 	resources := strings.Join(descriptions, "\n  ")
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/dnephin/dobi/config"
@@ -57,3 +58,57 @@ func TestInclude(t *testing.T) {
 		assert.Check(t, is.Equal(testcase.expected, actual))
 	}
 }
+
+func TestFormatTree(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Resources = map[string]config.Resource{
+		"all": &config.AliasConfig{Tasks: []string{"binary", "test"}},
+		"binary": &testconfig.FakeResource{
+			Dependent: config.Dependent{Depends: []string{"builder"}},
+		},
+		"test": &testconfig.FakeResource{
+			Dependent: config.Dependent{Depends: []string{"builder:build"}},
+		},
+		"builder": &testconfig.FakeResource{},
+	}
+	resources := []namedResource{{name: "all", resource: conf.Resources["all"]}}
+
+	expected := `all                  Run tasks: binary, test
+  ├─ binary
+  │  └─ builder
+  └─ test
+     └─ builder:build
+`
+	assert.Equal(t, formatTree(conf, resources), expected)
+}
+
+func TestPrintJSON(t *testing.T) {
+	resources := []namedResource{
+		{name: "one", resource: &testconfig.FakeResource{}},
+	}
+	buf := new(bytes.Buffer)
+	assert.NilError(t, printJSON(buf, resources, false))
+
+	expected := `[
+  {
+    "name": "one",
+    "description": "The resource string",
+    "tags": [],
+    "dependencies": []
+  }
+]
+`
+	assert.Equal(t, buf.String(), expected)
+}
+
+func TestGetDescriptionsWithActions(t *testing.T) {
+	job := &config.JobConfig{Actions: map[string]string{"lint": "make lint"}}
+	job.Annotations.Description = "Run the tests"
+	resources := []namedResource{{name: "test", resource: job}}
+
+	expected := []string{
+		"test                 Run the tests\n" +
+			"    actions: run (default), start, stop, upload, download, remove, lint",
+	}
+	assert.Check(t, is.DeepEqual(getDescriptions(resources, true), expected))
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+)
+
+// pickTasks prints a numbered list of resources to out, and reads the
+// selected tasks from in. Resources with a description are listed, or all
+// resources if none of them have a description. A selection can be either
+// the number or the name of a task.
+func pickTasks(in io.Reader, out io.Writer, conf *config.Config) ([]string, error) {
+	resources := filterResources(conf, listOptions{})
+	if len(resources) == 0 {
+		resources = filterResources(conf, listOptions{all: true})
+	}
+	if len(resources) == 0 {
+		return nil, fmt.Errorf("no resources to select")
+	}
+
+	for i, named := range resources {
+		fmt.Fprintf(out, "%3d) %-20s %s\n", i+1, named.name, named.Describe())
+	}
+	fmt.Fprint(out, "Select tasks to run (numbers or names, separated by spaces): ")
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	selected := []string{}
+	for _, field := range strings.FieldsFunc(line, isSeparator) {
+		name, err := selectedTask(field, resources, conf)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, name)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no tasks selected")
+	}
+	return selected, nil
+}
+
+func isSeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+func selectedTask(
+	field string,
+	resources []namedResource,
+	conf *config.Config,
+) (string, error) {
+	index, err := strconv.Atoi(field)
+	switch {
+	case err != nil:
+		if _, ok := conf.Resources[strings.SplitN(field, ":", 2)[0]]; !ok {
+			return "", fmt.Errorf("unknown task %q", field)
+		}
+		return field, nil
+	case index < 1 || index > len(resources):
+		return "", fmt.Errorf("invalid selection %d, must be from 1 to %d", index, len(resources))
+	default:
+		return resources[index-1].name, nil
+	}
+}
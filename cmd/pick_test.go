@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func newPickConfig() *config.Config {
+	conf := config.NewConfig()
+	conf.Resources = map[string]config.Resource{
+		"build": &config.AliasConfig{
+			Annotations: config.Annotations{
+				Annotations: config.AnnotationFields{Description: "Build it"}},
+		},
+		"test": &config.AliasConfig{
+			Annotations: config.Annotations{
+				Annotations: config.AnnotationFields{Description: "Test it"}},
+		},
+		"hidden": &config.AliasConfig{},
+	}
+	return conf
+}
+
+func TestPickTasks(t *testing.T) {
+	out := new(bytes.Buffer)
+	selected, err := pickTasks(strings.NewReader("2, hidden:run\n"), out, newPickConfig())
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(selected, []string{"test", "hidden:run"}))
+
+	expected := `  1) build                Build it
+  2) test                 Test it
+Select tasks to run (numbers or names, separated by spaces): `
+	assert.Check(t, is.Equal(out.String(), expected))
+}
+
+func TestPickTasksInvalidSelection(t *testing.T) {
+	out := new(bytes.Buffer)
+	_, err := pickTasks(strings.NewReader("3\n"), out, newPickConfig())
+	assert.Check(t, is.ErrorContains(err, "invalid selection 3, must be from 1 to 2"))
+
+	_, err = pickTasks(strings.NewReader("bogus\n"), out, newPickConfig())
+	assert.Check(t, is.ErrorContains(err, `unknown task "bogus"`))
+
+	_, err = pickTasks(strings.NewReader("\n"), out, newPickConfig())
+	assert.Check(t, is.ErrorContains(err, "no tasks selected"))
+}
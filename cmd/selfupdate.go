@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	releaseURL       = "https://github.com/dnephin/dobi/releases"
+	latestReleaseAPI = "https://api.github.com/repos/dnephin/dobi/releases/latest"
+)
+
+type selfUpdateOptions struct {
+	version    string
+	releaseURL string
+	latestURL  string
+	executable string
+}
+
+func newSelfUpdateCommand(opts *dobiOptions) *cobra.Command {
+	updateOpts := selfUpdateOptions{releaseURL: releaseURL, latestURL: latestReleaseAPI}
+	cmd := &cobra.Command{
+		Use:   "self-update [VERSION]",
+		Short: "Replace the dobi binary with a released version",
+		Long: "Replace the dobi binary with a released version. The latest release " +
+			"is used when no version is given. The checksum of the download is " +
+			"verified before the binary is replaced.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				updateOpts.version = args[0]
+			}
+			executable, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			updateOpts.executable = executable
+			warnIfVersionNotAllowed(opts.filename, updateOpts.version)
+			return runSelfUpdate(http.DefaultClient, updateOpts)
+		},
+	}
+	return cmd
+}
+
+// warnIfVersionNotAllowed logs a warning when the version does not match the
+// meta.dobi-version of the project config
+func warnIfVersionNotAllowed(filename string, version string) {
+	conf, err := config.Load(filename)
+	if err != nil || version == "" {
+		return
+	}
+	if err := conf.Meta.CheckVersion(strings.TrimPrefix(version, "v")); err != nil {
+		logging.Log.Warnf("Version %s is not allowed by %s", version, filename)
+	}
+}
+
+func runSelfUpdate(client *http.Client, opts selfUpdateOptions) error {
+	target := opts.version
+	if target == "" {
+		var err error
+		target, err = getLatestVersion(client, opts.latestURL)
+		if err != nil {
+			return err
+		}
+	}
+	if !strings.HasPrefix(target, "v") {
+		target = "v" + target
+	}
+	if target == "v"+version {
+		logging.Log.Infof("dobi %s is already installed", target)
+		return nil
+	}
+
+	asset := releaseAssetName(runtime.GOOS, runtime.GOARCH)
+	baseURL := fmt.Sprintf("%s/download/%s/%s", opts.releaseURL, target, asset)
+	checksum, err := getChecksum(client, baseURL+".sha256")
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(opts.executable), ".dobi-update-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name()) // nolint: errcheck
+
+	logging.Log.Infof("Downloading dobi %s", target)
+	if err := download(client, baseURL, tmpFile, checksum); err != nil {
+		tmpFile.Close() // nolint: errcheck
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpFile.Name(), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile.Name(), opts.executable); err != nil {
+		return errors.Wrapf(err, "failed to replace %s", opts.executable)
+	}
+	logging.Log.Infof("Updated %s to dobi %s", opts.executable, target)
+	return nil
+}
+
+// releaseAssetName returns the name of the release binary, which matches the
+// names created by script/build
+func releaseAssetName(goos, goarch string) string {
+	name := "dobi-" + goos
+	if goarch != "amd64" {
+		name += "-" + goarch
+	}
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+func getLatestVersion(client *http.Client, url string) (string, error) {
+	body, err := httpGet(client, url)
+	if err != nil {
+		return "", err
+	}
+	release := struct {
+		TagName string `json:"tag_name"`
+	}{}
+	if err := json.Unmarshal(body, &release); err != nil {
+		return "", errors.Wrap(err, "failed to parse the latest release")
+	}
+	if release.TagName == "" {
+		return "", errors.New("failed to find the latest release")
+	}
+	return release.TagName, nil
+}
+
+// getChecksum returns the sha256 checksum from a file in the format of
+// sha256sum
+func getChecksum(client *http.Client, url string) (string, error) {
+	body, err := httpGet(client, url)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the checksum of the release")
+	}
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", errors.Errorf("invalid checksum file %s", url)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+func download(client *http.Client, url string, out io.Writer, checksum string) error {
+	body, err := httpGet(client, url)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	if actual := hex.EncodeToString(sum[:]); actual != checksum {
+		return errors.Errorf(
+			"checksum of the download %s does not match the release %s", actual, checksum)
+	}
+	_, err = io.Copy(out, bytes.NewReader(body))
+	return err
+}
+
+func httpGet(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to get %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestRunSelfUpdate(t *testing.T) {
+	binary := []byte("new dobi binary")
+	sum := sha256.Sum256(binary)
+	checksum := hex.EncodeToString(sum[:])
+
+	asset := releaseAssetName(runtime.GOOS, runtime.GOARCH)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"tag_name": "v0.99.0"}`)
+	})
+	mux.HandleFunc("/download/v0.99.0/"+asset, func(w http.ResponseWriter, _ *http.Request) {
+		w.Write(binary) // nolint: errcheck
+	})
+	writeChecksum := func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, "%s  %s\n", checksum, asset)
+	}
+	mux.HandleFunc("/download/v0.99.0/"+asset+".sha256", writeChecksum)
+	mux.HandleFunc("/download/v0.98.0/"+asset, func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("tampered")) // nolint: errcheck
+	})
+	mux.HandleFunc("/download/v0.98.0/"+asset+".sha256", writeChecksum)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dir := fs.NewDir(t, "self-update", fs.WithFile("dobi", "old dobi binary"))
+	defer dir.Remove()
+	opts := selfUpdateOptions{
+		releaseURL: server.URL,
+		latestURL:  server.URL + "/latest",
+		executable: dir.Join("dobi"),
+	}
+
+	opts.version = "0.98.0"
+	err := runSelfUpdate(server.Client(), opts)
+	assert.Check(t, is.ErrorContains(err, "does not match the release"))
+	content, err := ioutil.ReadFile(dir.Join("dobi"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(content), "old dobi binary"))
+
+	opts.version = ""
+	assert.NilError(t, runSelfUpdate(server.Client(), opts))
+	content, err = ioutil.ReadFile(dir.Join("dobi"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(content), "new dobi binary"))
+}
+
+func TestReleaseAssetName(t *testing.T) {
+	assert.Check(t, is.Equal(releaseAssetName("linux", "amd64"), "dobi-linux"))
+	assert.Check(t, is.Equal(releaseAssetName("linux", "arm64"), "dobi-linux-arm64"))
+	assert.Check(t, is.Equal(releaseAssetName("windows", "amd64"), "dobi-windows.exe"))
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dnephin/dobi/tasks"
+	"github.com/spf13/cobra"
+)
+
+func newUpCommand(opts *dobiOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "up",
+		Short: "Start all resources annotated as a service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServices(opts, true)
+		},
+	}
+}
+
+func newDownCommand(opts *dobiOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "down",
+		Short: "Stop all resources annotated as a service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServices(opts, false)
+		},
+	}
+}
+
+func runServices(opts *dobiOptions, up bool) error {
+	conf, err := loadConfig(opts.filename)
+	if err != nil {
+		return err
+	}
+	services := tasks.ServiceNames(conf)
+	if len(services) == 0 {
+		return fmt.Errorf("no resources are annotated with service: true")
+	}
+
+	client, err := buildClient()
+	if err != nil {
+		return fmt.Errorf("failed to create client: %s", err)
+	}
+
+	return tasks.Run(tasks.RunOptions{
+		Client:    client,
+		Config:    conf,
+		Tasks:     services,
+		Variables: opts.variables,
+		Quiet:     opts.quiet,
+		BindMount: !opts.noBindMount,
+		Up:        up,
+		Down:      !up,
+	})
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newValidateCommand(opts *dobiOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the config file for errors",
+		Long: "Check the config file for errors. All errors are reported, " +
+			"and the exit code is non-zero when there are any errors.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runValidate(opts)
+		},
+	}
+}
+
+func runValidate(opts *dobiOptions) error {
+	if _, err := loadConfig(opts.filename); err != nil {
+		return err
+	}
+	fmt.Printf("%s is valid\n", opts.filename)
+	return nil
+}
@@ -9,7 +9,7 @@ import (
 )
 
 // AliasConfig An **alias** resource is a list of other tasks which will be run
-// in the order they are listed.
+// in the order they are listed, or at the same time with ``parallel``.
 // example: An alias that runs three other tasks:
 //
 // .. code-block:: yaml
@@ -22,6 +22,23 @@ type AliasConfig struct {
 	// Tasks The list of tasks
 	// type: list of tasks
 	Tasks []string `config:"required"`
+	// Params Parameters accepted by the alias. Each parameter is available to
+	// the tasks in the alias as the ``{param.<name>}`` variable. The value in
+	// the mapping is the default value of the parameter. Parameters with an
+	// empty default value must be set from the command line using
+	// ``name=value`` arguments (ex: ``dobi deploy stage=production``).
+	// type: mapping ``name: default``
+	Params map[string]string
+	// Parallel When **true** the tasks are run at the same time. Each task
+	// runs after its own dependencies, and the dependencies shared by more
+	// than one of the tasks are run first. The tasks should not depend on
+	// each other. Jobs which would run at the same time must not have
+	// overlapping artifacts, or the run fails before any task is started.
+	Parallel bool
+	// ContinueOnError When **true** all the tasks are run, even when one of
+	// them fails. The alias fails after all the tasks have run, with the
+	// errors from every task which failed.
+	ContinueOnError bool `config:"continue-on-error"`
 	Annotations
 }
 
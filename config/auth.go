@@ -0,0 +1,54 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+// RegistryAuth credentials used to push and pull an image from a registry.
+// Either ``credential-helper`` or ``username`` may be set, but not both.
+type RegistryAuth struct {
+	// Username The username used to authenticate with the registry.
+	Username string
+	// Password The password used to authenticate with the registry.
+	Password string
+	// IdentityToken A token used to authenticate with the registry instead
+	// of a password.
+	IdentityToken string
+	// CredentialHelper The name of a docker credential helper used to look up
+	// the credentials for the registry. The helper is run as
+	// ``docker-credential-<name>``.
+	// example: ``ecr-login``
+	CredentialHelper string
+}
+
+// IsZero returns true if no credentials are set
+func (a RegistryAuth) IsZero() bool {
+	return a == RegistryAuth{}
+}
+
+// Validate the credentials
+func (a RegistryAuth) Validate() error {
+	hasCredentials := a.Username != "" || a.Password != "" || a.IdentityToken != ""
+	switch {
+	case a.CredentialHelper != "" && hasCredentials:
+		return errors.New(
+			"credential-helper can not be used with username, password, or identity-token")
+	case a.Password != "" && a.Username == "":
+		return errors.New("password requires a username")
+	case a.Password != "" && a.IdentityToken != "":
+		return errors.New("password can not be used with identity-token")
+	}
+	return nil
+}
+
+// Resolve resolves variables in the credentials
+func (a RegistryAuth) Resolve(resolver Resolver) (RegistryAuth, error) {
+	var err error
+	for _, field := range []*string{&a.Username, &a.Password, &a.IdentityToken} {
+		*field, err = resolver.Resolve(*field)
+		if err != nil {
+			return a, err
+		}
+	}
+	return a, nil
+}
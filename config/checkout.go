@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/dnephin/configtf"
+	pth "github.com/dnephin/configtf/path"
+)
+
+// CheckoutConfig A **checkout** resource clones a git repository at a ref into
+// a directory managed by dobi. The directory is kept between runs, so later
+// runs only fetch the new commits. A job can use the checkout in ``mounts``
+// like a bind mount of the directory, and the job runs again when the
+// checkout moves to a different commit.
+//
+// A **git** resource is the same as a **checkout** resource, except that
+// ``depth`` defaults to ``1``, so only the commit at the ref is fetched.
+//
+// name: checkout
+// example: Build a service with the protobuf definitions from another
+// repository:
+//
+// .. code-block:: yaml
+//
+//     checkout=protos:
+//         repo: https://github.com/example/protos.git
+//         ref: v1.4.0
+//         depth: 1
+//         sparse: [proto/]
+//         path: /protos
+//
+//     job=generate:
+//         use: builder
+//         mounts: [source, protos]
+//         command: make generate
+//
+type CheckoutConfig struct {
+	// Repo The URL of the git repository. This field supports
+	// :doc:`variables`.
+	Repo string `config:"required"`
+	// Ref The branch, tag, or commit to check out. This field supports
+	// :doc:`variables`.
+	// default: *the default branch of the repository*
+	Ref string
+	// Dir The host directory of the checkout. This field supports
+	// :doc:`variables`.
+	// default: ``.dobi/checkouts/<name>``
+	Dir string
+	// Path The container path of the checkout, when it is used in the
+	// ``mounts`` of a job. This field supports :doc:`variables`.
+	Path string
+	// ReadOnly Mount the checkout read-only when it is used by a job
+	ReadOnly bool
+	// Depth The number of commits to fetch. When the value is ``0`` the full
+	// history is fetched.
+	// default: ``0``, or ``1`` for a **git** resource
+	Depth int `config:"validate"`
+	// Sparse A list of directories to check out. When the list is empty all
+	// of the files are checked out.
+	// type: list of paths
+	Sparse []string `config:"validate"`
+	Dependent
+	Annotations
+}
+
+// Validate the resource
+func (c *CheckoutConfig) Validate(path pth.Path, config *Config) *pth.Error {
+	return nil
+}
+
+// ValidateDepth checks that the depth is not negative
+func (c *CheckoutConfig) ValidateDepth() error {
+	if c.Depth < 0 {
+		return fmt.Errorf("depth must be 0 or more, not %d", c.Depth)
+	}
+	return nil
+}
+
+// ValidateSparse checks that the sparse paths are relative to the repository
+func (c *CheckoutConfig) ValidateSparse() error {
+	for _, sparse := range c.Sparse {
+		if path.IsAbs(sparse) || filepath.IsAbs(sparse) {
+			return fmt.Errorf("sparse path %q must be relative to the repository", sparse)
+		}
+	}
+	return nil
+}
+
+func (c *CheckoutConfig) String() string {
+	ref := c.Ref
+	if ref == "" {
+		ref = "the default branch"
+	}
+	return fmt.Sprintf("Check out %s of %s", ref, c.Repo)
+}
+
+// Directory returns the host directory of the checkout with the resource name
+func (c *CheckoutConfig) Directory(name string) string {
+	if c.Dir != "" {
+		return c.Dir
+	}
+	return filepath.Join(".dobi", "checkouts", name)
+}
+
+// Mount returns the bind mount used by a job which mounts the checkout. The
+// checkout is copied into the image of the job when bind mounts are disabled.
+func (c *CheckoutConfig) Mount(name string) *MountConfig {
+	return &MountConfig{
+		Copy:        true,
+		Bind:        c.Directory(name),
+		Path:        c.Path,
+		ReadOnly:    c.ReadOnly,
+		Annotations: c.Annotations,
+	}
+}
+
+// Resolve resolves variables in the resource
+func (c *CheckoutConfig) Resolve(resolver Resolver) (Resource, error) {
+	conf := *c
+	var err error
+	conf.Repo, err = resolver.Resolve(c.Repo)
+	if err != nil {
+		return &conf, err
+	}
+	conf.Ref, err = resolver.Resolve(c.Ref)
+	if err != nil {
+		return &conf, err
+	}
+	conf.Dir, err = resolver.Resolve(c.Dir)
+	if err != nil {
+		return &conf, err
+	}
+	conf.Path, err = resolver.Resolve(c.Path)
+	return &conf, err
+}
+
+func checkoutFromConfig(name string, values map[string]interface{}) (Resource, error) {
+	checkout := &CheckoutConfig{}
+	return checkout, configtf.Transform(name, values, checkout)
+}
+
+// gitFromConfig returns a checkout which is shallow by default
+func gitFromConfig(name string, values map[string]interface{}) (Resource, error) {
+	checkout := &CheckoutConfig{Depth: 1}
+	return checkout, configtf.Transform(name, values, checkout)
+}
+
+func init() {
+	RegisterResource("checkout", checkoutFromConfig)
+	RegisterResource("git", gitFromConfig)
+}
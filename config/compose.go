@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dnephin/configtf"
 	pth "github.com/dnephin/configtf/path"
@@ -28,17 +29,54 @@ import (
 //     compose=devenv:
 //         files: [docker-compose.yml, docker-compose-dev.yml]
 //         project: 'web-devenv'
+//         environment: ['APP_IMAGE={env.APP_IMAGE:app}:{git.short-sha}']
 //
 type ComposeConfig struct {
-	// Files The Compose files to use. This field supports :doc:`variables`.
+	// Files The Compose files to use. Files are passed to Compose in order,
+	// so each file overrides the values from the files before it, using the
+	// Compose merge rules. Services which use ``extends`` are resolved by
+	// Compose relative to the file which contains them. Each file must exist
+	// when the task runs. Each item in the list supports :doc:`variables`.
 	// type: list of filenames
 	Files []string
+	// Profiles The Compose profiles to enable. Services which are assigned to
+	// a profile are only started when the profile is enabled. Each item in
+	// the list supports :doc:`variables`.
+	// type: list of profile names
+	// example: ``[debug, monitoring]``
+	Profiles []string
+	// Services The services to start. When set, the ``up``, ``attach``,
+	// ``detach``, and ``logs`` actions only use these services, and the
+	// services they depend on. The ``down`` action always removes the whole
+	// project. Each item in the list supports :doc:`variables`.
+	// type: list of service names
+	// example: ``[db, redis]``
+	Services []string
 	// Project The project name used by Compose. This field supports
 	// :doc:`variables`.
 	Project string `config:"required"`
+	// EnvFile A file of ``key=value`` environment variables which are exported
+	// to ``docker-compose``, so they can be used for variable substitution in
+	// the Compose files. This field supports :doc:`variables`.
+	EnvFile string
+	// Environment Environment variables exported to ``docker-compose``.
+	// Variables set here override variables from ``env-file``. This field
+	// supports :doc:`variables`.
+	// type: list of ``key=value`` strings
+	Environment []string `config:"validate"`
 	// StopGrace Seconds to wait for containers to stop before killing them.
 	// default: ``5``
 	StopGrace int
+	// WaitHealthy When true the ``up`` and ``detach`` actions wait for the
+	// containers of the project to be ready before the tasks which depend on
+	// the **compose** resource are run. A container with a ``HEALTHCHECK``
+	// is ready when it is healthy. Other containers are ready when they are
+	// running, and each published TCP port accepts connections.
+	WaitHealthy bool
+	// WaitTimeout The longest time to wait for the containers to be ready
+	// when ``wait-healthy`` is true.
+	// default: ``1m``
+	WaitTimeout string `config:"validate"`
 	Dependent
 	Annotations
 }
@@ -53,6 +91,43 @@ func (c *ComposeConfig) Validate(path pth.Path, config *Config) *pth.Error {
 	return nil
 }
 
+// ValidateEnvironment checks that each variable is a key=value pair
+func (c *ComposeConfig) ValidateEnvironment() error {
+	for _, variable := range c.Environment {
+		if !strings.Contains(variable, "=") {
+			return fmt.Errorf("%q must be a key=value pair", variable)
+		}
+	}
+	return nil
+}
+
+// ValidateWaitTimeout checks that the timeout is a duration
+func (c *ComposeConfig) ValidateWaitTimeout() error {
+	if c.WaitTimeout == "" {
+		return nil
+	}
+	if timeout, err := time.ParseDuration(c.WaitTimeout); err != nil || timeout <= 0 {
+		return fmt.Errorf("invalid wait-timeout %q, must be a duration", c.WaitTimeout)
+	}
+	return nil
+}
+
+// WaitTimeoutDuration returns WaitTimeout as a duration
+func (c *ComposeConfig) WaitTimeoutDuration() time.Duration {
+	// WaitTimeout is validated when the config is loaded
+	timeout, err := time.ParseDuration(c.WaitTimeout)
+	if err != nil || timeout <= 0 {
+		return defaultWaitForTimeout
+	}
+	return timeout
+}
+
+// DefaultNetwork returns the name of the network Compose creates for the
+// project
+func (c *ComposeConfig) DefaultNetwork() string {
+	return strings.ToLower(c.Project) + "_default"
+}
+
 func (c *ComposeConfig) String() string {
 	return fmt.Sprintf("Run Compose project %q from: %v",
 		c.Project, strings.Join(c.Files, ", "))
@@ -66,7 +141,23 @@ func (c *ComposeConfig) Resolve(resolver Resolver) (Resource, error) {
 	if err != nil {
 		return &conf, err
 	}
+	conf.Profiles, err = resolver.ResolveSlice(c.Profiles)
+	if err != nil {
+		return &conf, err
+	}
+	conf.Services, err = resolver.ResolveSlice(c.Services)
+	if err != nil {
+		return &conf, err
+	}
 	conf.Project, err = resolver.Resolve(c.Project)
+	if err != nil {
+		return &conf, err
+	}
+	conf.EnvFile, err = resolver.Resolve(c.EnvFile)
+	if err != nil {
+		return &conf, err
+	}
+	conf.Environment, err = resolver.ResolveSlice(c.Environment)
 	return &conf, err
 }
 
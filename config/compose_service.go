@@ -0,0 +1,234 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// composeFile is the subset of a Compose file used to create an image resource
+// from a service
+type composeFile struct {
+	Services map[string]composeService
+}
+
+type composeService struct {
+	Image string
+	Build composeBuild
+}
+
+type composeBuild struct {
+	Context    string
+	Dockerfile string
+	Args       map[string]string
+	Target     string
+}
+
+// UnmarshalYAML accepts either a context path, or a mapping of build options
+func (b *composeBuild) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var context string
+	if err := unmarshal(&context); err == nil {
+		b.Context = context
+		return nil
+	}
+	raw := struct {
+		Context    string
+		Dockerfile string
+		Args       interface{}
+		Target     string
+	}{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	b.Context, b.Dockerfile, b.Target = raw.Context, raw.Dockerfile, raw.Target
+	var err error
+	b.Args, err = composeArgs(raw.Args)
+	return err
+}
+
+// composeArgs converts build args from either a mapping or a list of
+// key=value strings
+func composeArgs(raw interface{}) (map[string]string, error) {
+	args := map[string]string{}
+	switch value := raw.(type) {
+	case nil:
+	case map[interface{}]interface{}:
+		for key, arg := range value {
+			args[fmt.Sprintf("%v", key)] = fmt.Sprintf("%v", arg)
+		}
+	case []interface{}:
+		for _, item := range value {
+			parts := strings.SplitN(fmt.Sprintf("%v", item), "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("build arg %q must be a key=value pair", item)
+			}
+			args[parts[0]] = parts[1]
+		}
+	default:
+		return nil, fmt.Errorf("build args must be a mapping or list, not %T", raw)
+	}
+	return args, nil
+}
+
+// splitComposeService splits a reference in the form compose.service into
+// the name of a compose resource and the name of a service. It returns false
+// if the name does not reference a compose resource.
+func splitComposeService(config *Config, name string) (string, string, bool) {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	if _, ok := config.Resources[parts[0]].(*ComposeConfig); !ok {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// addComposeServices adds an image resource for each compose service
+// referenced by the use field of a job
+func addComposeServices(config *Config) error {
+	for _, name := range config.Sorted() {
+		job, ok := config.Resources[name].(*JobConfig)
+		if !ok || config.contains(job.Use) {
+			continue
+		}
+		composeName, service, ok := splitComposeService(config, job.Use)
+		if !ok {
+			continue
+		}
+		image, err := imageFromComposeService(
+			config.Resources[composeName].(*ComposeConfig), service, config.WorkingDir)
+		if err != nil {
+			return fmt.Errorf("failed to load service %q from %q: %s",
+				service, composeName, err)
+		}
+		if image.Image == "" {
+			image.Image = strings.ToLower(composeName + "-" + service)
+		}
+		if err := config.add(job.Use, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// imageFromComposeService returns an image resource with the image and build
+// config of a service in the Compose files. The image name is empty if the
+// service does not set one. Services which are defined in more
+// than one file are merged, with later files overriding earlier ones.
+func imageFromComposeService(
+	conf *ComposeConfig,
+	name string,
+	workingDir string,
+) (*ImageConfig, error) {
+	var service *composeService
+	var serviceDir string
+	for _, filename := range conf.Files {
+		if strings.Contains(filename, "{") {
+			return nil, fmt.Errorf(
+				"compose file %q uses variables, it can not be read when the config is loaded",
+				filename)
+		}
+		path := filename
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, path)
+		}
+		file, err := readComposeFile(path)
+		if err != nil {
+			return nil, err
+		}
+		next, ok := file.Services[name]
+		if !ok {
+			continue
+		}
+		if service == nil {
+			service = &composeService{}
+		}
+		mergeComposeService(service, next)
+		if next.Build.Context != "" {
+			serviceDir = filepath.Dir(path)
+		}
+	}
+	if service == nil {
+		return nil, fmt.Errorf("service is not defined in %s", strings.Join(conf.Files, ", "))
+	}
+	return composeServiceImage(conf, name, service, serviceDir, workingDir)
+}
+
+func readComposeFile(path string) (*composeFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := &composeFile{}
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %s", path, err)
+	}
+	return file, nil
+}
+
+func mergeComposeService(service *composeService, next composeService) {
+	if next.Image != "" {
+		service.Image = next.Image
+	}
+	if next.Build.Context != "" {
+		service.Build.Context = next.Build.Context
+	}
+	if next.Build.Dockerfile != "" {
+		service.Build.Dockerfile = next.Build.Dockerfile
+	}
+	if next.Build.Target != "" {
+		service.Build.Target = next.Build.Target
+	}
+	for key, value := range next.Build.Args {
+		if service.Build.Args == nil {
+			service.Build.Args = map[string]string{}
+		}
+		service.Build.Args[key] = value
+	}
+}
+
+func composeServiceImage(
+	conf *ComposeConfig,
+	name string,
+	service *composeService,
+	serviceDir string,
+	workingDir string,
+) (*ImageConfig, error) {
+	image := NewImageConfig()
+	image.Annotations.Annotations.Description = fmt.Sprintf("Image for service %q from %s",
+		name, strings.Join(conf.Files, ", "))
+
+	if service.Image != "" {
+		repo, tag := docker.ParseRepositoryTag(service.Image)
+		image.Image = repo
+		if tag != "" {
+			image.Tags = []string{tag}
+		}
+	}
+
+	if service.Build.Context == "" {
+		if service.Image == "" {
+			return nil, fmt.Errorf("service %q has no image or build", name)
+		}
+		image.Pull = pull{action: pullOnce}
+		return image, nil
+	}
+
+	context := service.Build.Context
+	if !filepath.IsAbs(context) {
+		context = filepath.Join(serviceDir, context)
+	}
+	if rel, err := filepath.Rel(workingDir, context); err == nil {
+		context = rel
+	}
+	image.Context = context
+	image.Dockerfile = service.Build.Dockerfile
+	image.Args = service.Build.Args
+	image.Target = service.Build.Target
+	return image, nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestAddComposeServices(t *testing.T) {
+	dir := fs.NewDir(t, "compose-services",
+		fs.WithFile("docker-compose.yml", `
+services:
+  web:
+    build:
+      context: ./web
+      dockerfile: Dockerfile.dev
+      args: [VERSION=1.2]
+  db:
+    image: postgres:13
+`),
+		fs.WithDir("dev",
+			fs.WithFile("docker-compose.yml", `
+services:
+  web:
+    image: example/web
+    build:
+      context: ../web
+      target: dev
+      args:
+        DEBUG: "true"
+`)))
+	defer dir.Remove()
+
+	config := NewConfig()
+	config.WorkingDir = dir.Path()
+	config.Resources = map[string]Resource{
+		"devenv": &ComposeConfig{
+			Files:   []string{"docker-compose.yml", "dev/docker-compose.yml"},
+			Project: "dev",
+		},
+		"test":    &JobConfig{Use: "devenv.web"},
+		"migrate": &JobConfig{Use: "devenv.db"},
+	}
+	assert.NilError(t, addComposeServices(config))
+
+	web := config.Resources["devenv.web"].(*ImageConfig)
+	assert.Check(t, is.Equal(web.Image, "example/web"))
+	assert.Check(t, is.Equal(web.Context, "web"))
+	assert.Check(t, is.Equal(web.Dockerfile, "Dockerfile.dev"))
+	assert.Check(t, is.Equal(web.Target, "dev"))
+	assert.Check(t, is.DeepEqual(web.Args, map[string]string{"VERSION": "1.2", "DEBUG": "true"}))
+
+	db := config.Resources["devenv.db"].(*ImageConfig)
+	assert.Check(t, is.Equal(db.Image, "postgres"))
+	assert.Check(t, is.DeepEqual(db.Tags, []string{"13"}))
+	assert.Check(t, db.Pull.IsSet())
+}
+
+func TestAddComposeServicesMissingService(t *testing.T) {
+	dir := fs.NewDir(t, "compose-services",
+		fs.WithFile("docker-compose.yml", "services: {web: {build: .}}\n"))
+	defer dir.Remove()
+
+	config := NewConfig()
+	config.WorkingDir = dir.Path()
+	config.Resources = map[string]Resource{
+		"devenv": &ComposeConfig{Files: []string{"docker-compose.yml"}},
+		"test":   &JobConfig{Use: "devenv.worker"},
+	}
+	err := addComposeServices(config)
+	assert.ErrorContains(t, err, `failed to load service "worker" from "devenv"`)
+}
+
+func TestAddComposeServicesDefaultImageName(t *testing.T) {
+	dir := fs.NewDir(t, "compose-services",
+		fs.WithFile("docker-compose.yml", "services: {web: {build: .}}\n"))
+	defer dir.Remove()
+
+	config := NewConfig()
+	config.WorkingDir = dir.Path()
+	config.Resources = map[string]Resource{
+		"devenv": &ComposeConfig{Files: []string{"docker-compose.yml"}},
+		"test":   &JobConfig{Use: "devenv.web"},
+	}
+	assert.NilError(t, addComposeServices(config))
+	web := config.Resources["devenv.web"].(*ImageConfig)
+	assert.Check(t, is.Equal(web.Image, "devenv-web"))
+	assert.Check(t, is.Equal(web.Context, "."))
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestComposeConfigValidateEnvironment(t *testing.T) {
+	conf := &ComposeConfig{Environment: []string{"APP_TAG=v1", "EMPTY="}}
+	assert.NilError(t, conf.ValidateEnvironment())
+
+	conf = &ComposeConfig{Environment: []string{"APP_TAG"}}
+	assert.ErrorContains(t, conf.ValidateEnvironment(), `"APP_TAG" must be a key=value pair`)
+}
+
+func TestComposeConfigValidateWaitTimeout(t *testing.T) {
+	conf := &ComposeConfig{WaitTimeout: "30s"}
+	assert.NilError(t, conf.ValidateWaitTimeout())
+	assert.Equal(t, conf.WaitTimeoutDuration(), 30*time.Second)
+
+	conf = &ComposeConfig{}
+	assert.Equal(t, conf.WaitTimeoutDuration(), time.Minute)
+
+	conf = &ComposeConfig{WaitTimeout: "soon"}
+	assert.ErrorContains(t, conf.ValidateWaitTimeout(), `invalid wait-timeout "soon"`)
+}
@@ -2,7 +2,6 @@ package config
 
 import (
 	"fmt"
-	"io/ioutil"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -71,6 +70,9 @@ func Load(filename string) (*Config, error) {
 	config.WorkingDir = filepath.Dir(absPath)
 	config.FilePath = absPath
 
+	if err = addComposeServices(config); err != nil {
+		return nil, fmtError(err)
+	}
 	if err = validate(config); err != nil {
 		return nil, fmtError(err)
 	}
@@ -78,7 +80,7 @@ func Load(filename string) (*Config, error) {
 }
 
 func loadConfig(filename string) (*Config, error) {
-	data, err := ioutil.ReadFile(filename)
+	data, err := readConfigFile(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -92,20 +94,51 @@ func loadConfig(filename string) (*Config, error) {
 
 // validate validates all the resources in the config
 func validate(config *Config) error {
-	for name, resource := range config.Resources {
-		path := pth.NewPath(name)
-
-		if err := configtf.ValidateFields(path, resource); err != nil {
-			return err
-		}
-		if err := validateResourcesExist(path, config, resource.Dependencies()); err != nil {
-			return err
-		}
-		if err := resource.Validate(path, config); err != nil {
-			return err
+	errs := ValidationErrors{}
+	for _, name := range config.Sorted() {
+		if err := validateResource(config, name); err != nil {
+			errs = append(errs, err)
 		}
 	}
-	return config.Meta.Validate(config)
+	if err := config.Meta.Validate(config); err != nil {
+		errs = append(errs, err)
+	}
+	return errs.errorOrNil()
+}
+
+func validateResource(config *Config, name string) error {
+	resource := config.Resources[name]
+	path := pth.NewPath(name)
+
+	if err := configtf.ValidateFields(path, resource); err != nil {
+		return err
+	}
+	if err := validateResourcesExist(path, config, resource.Dependencies()); err != nil {
+		return err
+	}
+	if err := validateResourcesExist(path.Add("after"), config, RunAfter(resource)); err != nil {
+		return err
+	}
+	if err := resource.Validate(path, config); err != nil {
+		return err
+	}
+	return validateService(path, resource)
+}
+
+// validateService checks that only resources which can run in the background
+// are annotated as a service
+func validateService(path pth.Path, resource Resource) error {
+	service, ok := resource.(interface{ IsService() bool })
+	if !ok || !service.IsService() {
+		return nil
+	}
+	switch resource.(type) {
+	case *JobConfig, *ComposeConfig:
+		return nil
+	default:
+		return pth.Errorf(path.Add("annotations"),
+			"only job and compose resources can be a service")
+	}
 }
 
 // validateResourcesExist checks that the list of resources is defined in the
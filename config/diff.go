@@ -0,0 +1,39 @@
+package config
+
+import (
+	"reflect"
+)
+
+// Changes is the set of resource names which differ between two configs
+type Changes struct {
+	Added   []string `json:"added,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// IsZero returns true if there are no changes
+func (c Changes) IsZero() bool {
+	return len(c.Added) == 0 && len(c.Changed) == 0 && len(c.Removed) == 0
+}
+
+// Diff compares the resources in two configs and returns the names of the
+// resources which were added, changed, or removed in next. Names are
+// returned in alphabetical sort order.
+func Diff(prev, next *Config) Changes {
+	changes := Changes{}
+	for _, name := range next.Sorted() {
+		prevResource, exists := prev.Resources[name]
+		switch {
+		case !exists:
+			changes.Added = append(changes.Added, name)
+		case !reflect.DeepEqual(prevResource, next.Resources[name]):
+			changes.Changed = append(changes.Changed, name)
+		}
+	}
+	for _, name := range prev.Sorted() {
+		if !next.contains(name) {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+	return changes
+}
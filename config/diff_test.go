@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestDiff(t *testing.T) {
+	prev := NewConfig()
+	prev.Resources = map[string]Resource{
+		"builder": &ImageConfig{Image: "builder"},
+		"test":    &JobConfig{Use: "builder", Command: ShlexSlice{original: "go test"}},
+		"old":     &AliasConfig{Tasks: []string{"test"}},
+	}
+	next := NewConfig()
+	next.Resources = map[string]Resource{
+		"builder": &ImageConfig{Image: "builder"},
+		"test":    &JobConfig{Use: "builder", Command: ShlexSlice{original: "go test ./..."}},
+		"new":     &AliasConfig{Tasks: []string{"test"}},
+	}
+
+	changes := Diff(prev, next)
+	expected := Changes{
+		Added:   []string{"new"},
+		Changed: []string{"test"},
+		Removed: []string{"old"},
+	}
+	assert.DeepEqual(t, changes, expected)
+	assert.Assert(t, Diff(next, next).IsZero())
+}
@@ -11,6 +11,13 @@ import (
 // EnvConfig An **env** resource provides environment variables to **job** and
 // **compose** resources.
 //
+// The variables from ``files`` are set first, in the order of the files,
+// followed by ``variables``, so a later value of a variable overrides an
+// earlier one. When a resource depends on more than one **env** resource, they
+// are set in the order of ``depends``, and the last one overrides the others.
+// The files of an **env** resource are read once in a run, the variables are
+// reused by every task that uses the resource.
+//
 // example: Define some variables for a ``job``
 //
 // .. code-block:: yaml
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dnephin/configtf"
+	pth "github.com/dnephin/configtf/path"
+)
+
+// ValidationErrors is a list of errors found in a config
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// errorOrNil returns nil if there are no errors
+func (e ValidationErrors) errorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+const unexpectedKey = "unexpected key"
+
+func isUnexpectedKey(err error) bool {
+	_, ok := err.(*pth.Error)
+	return ok && strings.HasSuffix(err.Error(), ": "+unexpectedKey)
+}
+
+// unexpectedKeyErrors returns an error for each key which is not a field of
+// target. Each error includes the name of a similar field when there is one.
+func unexpectedKeyErrors(
+	name string,
+	values map[string]interface{},
+	target interface{},
+) error {
+	fields := fieldNames(reflect.TypeOf(target))
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	path := pth.NewPath(name)
+	errs := ValidationErrors{}
+	for _, key := range keys {
+		if !containsString(fields, key) {
+			errs = append(errs, unexpectedKeyError(path.Add(key), fields))
+		}
+	}
+	return errs.errorOrNil()
+}
+
+// withSuggestion adds the name of a similar field to an unexpected key error
+// from a nested field of target
+func withSuggestion(err error, target interface{}) error {
+	pathErr, ok := err.(*pth.Error)
+	if !ok || !isUnexpectedKey(err) {
+		return err
+	}
+	path := pathErr.Path()
+	parts := path.Path()
+	if len(parts) < 2 {
+		return err
+	}
+	targetType := reflect.TypeOf(target)
+	for _, part := range parts[1 : len(parts)-1] {
+		targetType = fieldType(targetType, part)
+		if targetType == nil {
+			return err
+		}
+	}
+	return unexpectedKeyError(path, fieldNames(targetType))
+}
+
+func unexpectedKeyError(path pth.Path, fields []string) error {
+	parts := path.Path()
+	if suggestion := suggest(parts[len(parts)-1], fields); suggestion != "" {
+		return pth.Errorf(path, "%s, did you mean %q?", unexpectedKey, suggestion)
+	}
+	return pth.Errorf(path, unexpectedKey)
+}
+
+// fieldNames returns the config field names of a struct type
+func fieldNames(structType reflect.Type) []string {
+	structType = indirect(structType)
+	if structType.Kind() != reflect.Struct {
+		return nil
+	}
+	names := []string{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Anonymous {
+			names = append(names, fieldNames(field.Type)...)
+			continue
+		}
+		names = append(names, fieldTags(field).Name)
+	}
+	return names
+}
+
+// fieldType returns the type of the field with the config name, or nil if the
+// field does not exist
+func fieldType(structType reflect.Type, name string) reflect.Type {
+	structType = indirect(structType)
+	if structType.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Anonymous {
+			if found := fieldType(field.Type, name); found != nil {
+				return found
+			}
+			continue
+		}
+		if fieldTags(field).Name == name {
+			return field.Type
+		}
+	}
+	return nil
+}
+
+func fieldTags(field reflect.StructField) configtf.FieldTags {
+	return configtf.NewFieldTags(field.Name, field.Tag.Get(configtf.StructTagKey))
+}
+
+func indirect(target reflect.Type) reflect.Type {
+	if target.Kind() == reflect.Ptr {
+		return target.Elem()
+	}
+	return target
+}
+
+// suggest returns the option which is closest to name, or an empty string if
+// no option is close enough to be a likely typo
+func suggest(name string, options []string) string {
+	best, bestDistance := "", len(name)/3+2
+	for _, option := range options {
+		if distance := editDistance(name, option); distance < bestDistance {
+			best, bestDistance = option, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(source, target string) int {
+	prev := make([]int, len(target)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(source); i++ {
+		current := make([]int, len(target)+1)
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(prev[j]+1, current[j-1]+1, prev[j-1]+cost)
+		}
+		prev = current
+	}
+	return prev[len(target)]
+}
+
+func minInt(first int, rest ...int) int {
+	for _, value := range rest {
+		if value < first {
+			first = value
+		}
+	}
+	return first
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/renstrom/dedent"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestSuggest(t *testing.T) {
+	options := []string{"artifact", "mounts", "use"}
+	assert.Check(t, is.Equal(suggest("artifacts", options), "artifact"))
+	assert.Check(t, is.Equal(suggest("mount", options), "mounts"))
+	assert.Check(t, is.Equal(suggest("image", options), ""))
+}
+
+func TestLoadFromBytesReportsAllUnexpectedKeys(t *testing.T) {
+	conf := dedent.Dedent(`
+		meta:
+		  projekt: example
+
+		job=build:
+		  use: builder
+		  artifacts: dist/
+		  mount: [source]
+
+		job=lint:
+		  use: builder
+		  exit-codes: {sucess: [0]}
+		  colour: true
+	`)
+
+	_, err := LoadFromBytes([]byte(conf))
+	expected := `invalid "meta" config: ` +
+		`Error at meta.projekt: unexpected key, did you mean "project"?
+invalid config for resource "job=build":
+Error at job=build.artifacts: unexpected key, did you mean "artifact"?
+Error at job=build.mount: unexpected key, did you mean "mounts"?
+invalid config for resource "job=lint":
+Error at job=lint.colour: unexpected key`
+	assert.Check(t, is.Error(err, expected))
+}
+
+func TestLoadFromBytesSuggestsNestedKey(t *testing.T) {
+	conf := dedent.Dedent(`
+		job=lint:
+		  use: builder
+		  exit-codes: {sucess: [0]}
+	`)
+
+	_, err := LoadFromBytes([]byte(conf))
+	assert.Check(t, is.ErrorContains(err,
+		`Error at job=lint.exit-codes.sucess: unexpected key, did you mean "success"?`))
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Resources = map[string]Resource{
+		"one": &JobConfig{Use: "missing"},
+		"two": &AliasConfig{Tasks: []string{"other"}},
+	}
+	err := validate(conf)
+	assert.Check(t, is.Len(err.(ValidationErrors), 2))
+	assert.Check(t, is.ErrorContains(err, "Error at one: missing dependencies: missing"))
+	assert.Check(t, is.ErrorContains(err, "Error at two: missing dependencies: other"))
+}
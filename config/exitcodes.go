@@ -0,0 +1,59 @@
+package config
+
+import "fmt"
+
+// Outcomes of a job, classified by the exit code of the container
+const (
+	OutcomeSuccess = "success"
+	OutcomeWarn    = "warn"
+	OutcomeFail    = "fail"
+)
+
+// ExitCodes maps the exit codes of a job to an outcome. Exit codes which are
+// not listed are a failure.
+type ExitCodes struct {
+	// Success Exit codes which are a success.
+	// default: ``[0]``
+	Success []int
+	// Warn Exit codes which log a warning. The job is a success unless
+	// **dobi** is run with ``--strict``.
+	Warn []int
+}
+
+// Validate the exit codes
+func (e ExitCodes) Validate() error {
+	for _, code := range e.Warn {
+		if containsInt(e.successCodes(), code) {
+			return fmt.Errorf("exit code %d can not be both success and warn", code)
+		}
+	}
+	return nil
+}
+
+func (e ExitCodes) successCodes() []int {
+	if len(e.Success) == 0 {
+		return []int{0}
+	}
+	return e.Success
+}
+
+// Classify returns the outcome of an exit code
+func (e ExitCodes) Classify(code int) string {
+	switch {
+	case containsInt(e.successCodes(), code):
+		return OutcomeSuccess
+	case containsInt(e.Warn, code):
+		return OutcomeWarn
+	default:
+		return OutcomeFail
+	}
+}
+
+func containsInt(values []int, value int) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
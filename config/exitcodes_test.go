@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestExitCodesClassify(t *testing.T) {
+	var testcases = []struct {
+		doc       string
+		exitCodes ExitCodes
+		code      int
+		expected  string
+	}{
+		{doc: "default success", code: 0, expected: OutcomeSuccess},
+		{doc: "default failure", code: 2, expected: OutcomeFail},
+		{
+			doc:       "warn code",
+			exitCodes: ExitCodes{Warn: []int{2}},
+			code:      2,
+			expected:  OutcomeWarn,
+		},
+		{
+			doc:       "success codes replace the default",
+			exitCodes: ExitCodes{Success: []int{3}},
+			code:      0,
+			expected:  OutcomeFail,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.doc, func(t *testing.T) {
+			actual := testcase.exitCodes.Classify(testcase.code)
+			assert.Check(t, is.Equal(actual, testcase.expected))
+		})
+	}
+}
+
+func TestExitCodesValidate(t *testing.T) {
+	assert.NilError(t, ExitCodes{Warn: []int{1, 2}}.Validate())
+
+	err := ExitCodes{Warn: []int{0}}.Validate()
+	assert.Check(t, is.Error(err, "exit code 0 can not be both success and warn"))
+}
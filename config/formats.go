@@ -0,0 +1,40 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// configEvaluators are the commands used to evaluate config files which are
+// not YAML, by file extension. Each command prints the config as JSON, which
+// is loaded the same way as YAML.
+var configEvaluators = map[string][]string{
+	".cue":     {"cue", "export", "--out", "json"},
+	".jsonnet": {"jsonnet"},
+}
+
+// readConfigFile returns the contents of a config file. A CUE or Jsonnet file
+// is evaluated with the cue or jsonnet command to produce the config.
+func readConfigFile(filename string) ([]byte, error) {
+	command, ok := configEvaluators[filepath.Ext(filename)]
+	if !ok {
+		return ioutil.ReadFile(filename)
+	}
+	if _, err := os.Stat(filename); err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(command[0], append(command[1:], filename)...) // nolint: gosec
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate config with %s: %s: %s",
+			command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
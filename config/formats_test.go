@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func patchConfigEvaluator(ext string, command ...string) func() {
+	original, ok := configEvaluators[ext]
+	configEvaluators[ext] = command
+	return func() {
+		if ok {
+			configEvaluators[ext] = original
+			return
+		}
+		delete(configEvaluators, ext)
+	}
+}
+
+func TestLoadEvaluatedConfig(t *testing.T) {
+	// cat prints the file unchanged, like the output of jsonnet for a file
+	// which is already JSON
+	defer patchConfigEvaluator(".jsonnet", "cat")()
+	content := `{
+  "image=builder": {"image": "example/builder", "context": "."},
+  "job=test": {"use": "builder", "command": "go test ./...", "stop-grace": 10}
+}`
+	dir := fs.NewDir(t, "evaluated-config", fs.WithFile("dobi.jsonnet", content))
+	defer dir.Remove()
+
+	config, err := Load(dir.Join("dobi.jsonnet"))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(config.Sorted(), []string{"builder", "test"}))
+	job := config.Resources["test"].(*JobConfig)
+	assert.Check(t, is.Equal(job.StopGrace, 10))
+	assert.Check(t, is.DeepEqual(job.Command.Value(), []string{"go", "test", "./..."}))
+}
+
+func TestLoadEvaluatedConfigFails(t *testing.T) {
+	defer patchConfigEvaluator(".cue", "false")()
+	dir := fs.NewDir(t, "evaluated-config", fs.WithFile("dobi.cue", "package dobi"))
+	defer dir.Remove()
+
+	_, err := Load(dir.Join("dobi.cue"))
+	assert.Check(t, is.ErrorContains(err, "failed to evaluate config with false"))
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/dnephin/configtf"
@@ -46,8 +48,21 @@ type ImageConfig struct {
 	// :doc:`variables`.
 	// type: mapping ``key: value``
 	Args map[string]string
+	// ArgsFrom Names of `env`_ resources, or paths to files of ``key=value``
+	// lines, which provide build args. Each item overrides the args from the
+	// items before it, and ``args`` overrides all of them. The variables of an
+	// `env`_ resource are used as build args, they are not set in the
+	// environment. Paths are relative to the ``dobi.yaml``. Each item in the
+	// list supports :doc:`variables`.
+	// type: list of env resource names or filenames
+	// example: ``[build-env, ./build.args]``
+	ArgsFrom []string
 	// Target The target stage to build in a multi-stage Dockerfile. Defaults to
-	// the last stage.
+	// the last stage. Multiple **image** resources may build different
+	// targets of the same ``Dockerfile`` and ``context``. When ``cache-from``
+	// is set, the images of the other targets are added to the cache, so the
+	// stages they share are not built again. With ``auto-sources`` only the
+	// files copied by the target stage, and the stages it uses, are compared.
 	Target string
 	// PullBaseImageOnBuild If **true** the base image used in the
 	// ``Dockerfile`` will be pulled before building the image.
@@ -56,6 +71,9 @@ type ImageConfig struct {
 	// * ``once`` - only pull if the image:tag does not exist
 	// * ``always`` - always pull the image
 	// * ``never`` - don't pull or build the image. Use one that is already present locally
+	// * ``daily`` - pull if the image hasn't been pulled in at least 24 hours
+	// * ``if-changed`` - query the registry for the digest of each tag, and
+	//   only pull when the digest is different from the local image
 	// * ``<duration>`` - pull if the image hasn't been pulled in at least
 	//   ``duration``. The format of duration is a number followed by a single
 	//   character time unit (ex: ``40s``, ``2h``, ``30min``)
@@ -74,10 +92,95 @@ type ImageConfig struct {
 	// default: ``tags``
 	// type: list of tags
 	RemoteTags []string
+	// TagByDigestOfInputs When **true** the image is tagged with a hash of
+	// the inputs of the build: the ``Dockerfile`` or ``steps``, the files in
+	// the ``context``, the build args, and the images it depends on. The
+	// build is skipped when an image with that tag exists locally, or can be
+	// pulled from the registry. The tag is pushed by the ``push`` action, so
+	// builds on other hosts can use the image.
+	TagByDigestOfInputs bool
+	// AutoSources When **true** only the files copied into the image by the
+	// ``COPY`` and ``ADD`` instructions of the ``Dockerfile`` or ``steps``,
+	// and the ``Dockerfile`` itself, are compared to the image to decide if
+	// the image is stale, instead of all the files in the ``context``. If an
+	// instruction uses a variable in a source path all the files in the
+	// ``context`` are used.
+	AutoSources bool
 	// NetworkMode The network mode to use for each step in the Dockerfile.
 	NetworkMode string
-	// CacheFrom A list of images to use as the cache for a build.
+	// CacheFrom A list of images to use as the cache for a build. Each item
+	// in the list supports :doc:`variables`.
+	// type: list of image references
 	CacheFrom []string
+	// PostBuild Shell commands run on the host after the image is built,
+	// before it can be tagged or pushed. The name of the image is set in the
+	// ``DOBI_IMAGE`` environment variable. If a command fails the task fails,
+	// and the image is built again on the next run. Each item in the list
+	// supports :doc:`variables`.
+	// type: list of shell commands
+	// example: ``['docker run --rm $DOBI_IMAGE ./smoke-test']``
+	PostBuild []string
+	// PrePush Shell commands run on the host before the image is pushed. The
+	// name of the image is set in the ``DOBI_IMAGE`` environment variable. If
+	// a command fails the image is not pushed. Each item in the list supports
+	// :doc:`variables`.
+	// type: list of shell commands
+	PrePush []string
+	// PushRetries The number of times to retry a push which failed because
+	// of a temporary error from the registry, like a timeout or a ``5xx``
+	// response.
+	// default: ``0``
+	PushRetries int `config:"validate"`
+	// PushRetryDelay The time to wait before the first retry of a push. The
+	// time is doubled for each retry after the first.
+	// default: ``1s``
+	PushRetryDelay string `config:"validate"`
+	// CacheTo Where to export the build cache. The only supported value is
+	// ``inline``, which embeds the cache metadata in the image so that the
+	// pushed image can be used in ``cache-from`` by later builds.
+	// type: list of cache exports
+	// example: ``[inline]``
+	CacheTo []string `config:"validate"`
+	// Expires The amount of time a pushed image should be kept by the
+	// registry. The value is added to the image as the ``quay.expires-after``
+	// label, which is used by registries to remove expired tags. The format
+	// is a number followed by a single character time unit (``h``, ``d``, or
+	// ``w``). This field supports :doc:`variables`.
+	// example: ``2w``
+	Expires string `config:"validate"`
+	// Auth Credentials used to push and pull the image. When ``auth`` is not
+	// set the credentials from the docker config file are used. Each value
+	// except ``credential-helper`` supports :doc:`variables`.
+	// type: mapping with keys ``username``, ``password``,
+	// ``identity-token``, and ``credential-helper``
+	// example: ``{username: ci, password: '{env.REGISTRY_PASSWORD}'}``
+	Auth RegistryAuth `config:"validate"`
+	// Builder The name of a builder from ``meta.builders``. The tasks for the
+	// image are run on one of the hosts of the builder. The image only exists
+	// on that host, so it must be pushed to a registry to be used on other
+	// hosts, and it can not be the ``use`` image of a **job**. Can not be
+	// used with ``docker-host``.
+	Builder string
+	// Archive The path of the tarball written by the ``save`` action and read
+	// by the ``load`` action. The tarball contains all the tags of the image,
+	// in the format of ``docker save``. This field supports :doc:`variables`.
+	// default: ``.dobi/archives/<name>.tar``
+	Archive string
+	// Cluster The local Kubernetes cluster used by the ``load-cluster``
+	// action, as ``<tool>[:<name>]``. The tool may be one of ``kind``,
+	// ``k3d``, or ``minikube``. The name is the name of the cluster, or the
+	// ``minikube`` profile, and defaults to the default cluster of the tool.
+	// This field supports :doc:`variables`.
+	// example: ``kind:dev``
+	Cluster string `config:"validate"`
+	// Scan Settings for the ``scan`` action, which scans the image for
+	// vulnerabilities, and fails when a vulnerability at or above the
+	// ``severity`` is found.
+	// type: mapping with keys ``scanner``, ``image``, ``severity``,
+	// ``before-push``, ``baseline``, and ``diff-tag``
+	// example: ``{scanner: grype, severity: critical, before-push: true}``
+	Scan ImageScan `config:"validate"`
+	Hosted
 	Dependent
 	Annotations
 }
@@ -87,9 +190,58 @@ func (c *ImageConfig) Validate(path pth.Path, config *Config) *pth.Error {
 	if err := c.validateBuildOrPull(); err != nil {
 		return pth.Errorf(path, err.Error())
 	}
+	if err := c.validateBuilder(config); err != nil {
+		return pth.Errorf(path, err.Error())
+	}
+	if err := c.validateArgsFrom(config); err != nil {
+		return pth.Errorf(path.Add("args-from"), err.Error())
+	}
+	if c.AutoSources && !c.IsBuildable() {
+		return pth.Errorf(path.Add("auto-sources"),
+			"can only be used with an image which is built")
+	}
+	if c.TagByDigestOfInputs && !c.IsBuildable() {
+		return pth.Errorf(path.Add("tag-by-digest-of-inputs"),
+			"can only be used with an image which is built")
+	}
+	return nil
+}
+
+// validateArgsFrom checks that items which are the name of a resource are
+// the name of an env resource
+func (c *ImageConfig) validateArgsFrom(config *Config) error {
+	for _, name := range c.ArgsFrom {
+		res, ok := config.Resources[name]
+		if !ok {
+			continue
+		}
+		if _, ok := res.(*EnvConfig); !ok {
+			return errors.Errorf("%s is not an env resource", name)
+		}
+	}
 	return nil
 }
 
+func (c *ImageConfig) validateBuilder(config *Config) error {
+	switch {
+	case c.Builder == "":
+		return nil
+	case c.DockerHost != "":
+		return errors.New("builder can not be used with docker-host")
+	case config.Meta == nil:
+		return errors.Errorf("undefined builder %q", c.Builder)
+	}
+	if _, ok := config.Meta.Builders[c.Builder]; !ok {
+		return errors.Errorf("undefined builder %q", c.Builder)
+	}
+	return nil
+}
+
+// BuilderName returns the name of the builder used by the image
+func (c *ImageConfig) BuilderName() string {
+	return c.Builder
+}
+
 func (c *ImageConfig) validateBuildOrPull() error {
 	c.setDefaultContext()
 
@@ -144,6 +296,112 @@ func (c *ImageConfig) ValidateTags() error {
 
 }
 
+var expiresRegex = regexp.MustCompile(`^[0-9]+[hdw]$`)
+
+// ValidateCacheTo validates the cache exports are supported
+func (c *ImageConfig) ValidateCacheTo() error {
+	for _, cacheTo := range c.CacheTo {
+		switch cacheTo {
+		case "inline", "type=inline":
+		default:
+			return fmt.Errorf(
+				"unsupported cache-to %q, only inline cache exports are supported",
+				cacheTo)
+		}
+	}
+	return nil
+}
+
+// ValidateAuth validates the registry credentials
+func (c *ImageConfig) ValidateAuth() error {
+	if err := c.Auth.Validate(); err != nil {
+		return errors.Errorf("invalid auth: %s", err)
+	}
+	return nil
+}
+
+// ValidateScan validates the scan settings
+func (c *ImageConfig) ValidateScan() error {
+	if err := c.Scan.Validate(); err != nil {
+		return errors.Errorf("invalid scan: %s", err)
+	}
+	return nil
+}
+
+// ValidateCluster validates the tool of the cluster
+func (c *ImageConfig) ValidateCluster() error {
+	// Values with variables are validated when the cluster is loaded
+	if c.Cluster == "" || strings.Contains(c.Cluster, "{") {
+		return nil
+	}
+	tool, _ := SplitCluster(c.Cluster)
+	switch tool {
+	case ClusterKind, ClusterK3d, ClusterMinikube:
+		return nil
+	default:
+		return errors.Errorf("invalid cluster %q, the tool must be one of: %s, %s, %s",
+			c.Cluster, ClusterKind, ClusterK3d, ClusterMinikube)
+	}
+}
+
+// Tools used to load images into a local Kubernetes cluster
+const (
+	ClusterKind     = "kind"
+	ClusterK3d      = "k3d"
+	ClusterMinikube = "minikube"
+)
+
+// SplitCluster splits a cluster into the tool and the name of the cluster
+func SplitCluster(cluster string) (string, string) {
+	parts := strings.SplitN(cluster, ":", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// ValidateExpires validates the format of the expires value
+func (c *ImageConfig) ValidateExpires() error {
+	// Values with variables are validated after they are resolved
+	if c.Expires == "" || strings.Contains(c.Expires, "{") {
+		return nil
+	}
+	if !expiresRegex.MatchString(c.Expires) {
+		return errors.Errorf(
+			"invalid expires %q, must be a number followed by h, d, or w", c.Expires)
+	}
+	return nil
+}
+
+// ValidatePushRetries validates that the push retries is not negative
+func (c *ImageConfig) ValidatePushRetries() error {
+	if c.PushRetries < 0 {
+		return errors.Errorf("push-retries must be 0 or more, not %d", c.PushRetries)
+	}
+	return nil
+}
+
+// ValidatePushRetryDelay validates the format of the push retry delay
+func (c *ImageConfig) ValidatePushRetryDelay() error {
+	if c.PushRetryDelay == "" {
+		return nil
+	}
+	if delay, err := time.ParseDuration(c.PushRetryDelay); err != nil || delay <= 0 {
+		return errors.Errorf("invalid push-retry-delay %q, must be a duration", c.PushRetryDelay)
+	}
+	return nil
+}
+
+// PushRetryDelayDuration returns the push retry delay as a duration
+func (c *ImageConfig) PushRetryDelayDuration() time.Duration {
+	// PushRetryDelay is validated when the config is loaded
+	delay, err := time.ParseDuration(c.PushRetryDelay)
+	if err != nil || delay <= 0 {
+		return time.Second
+	}
+	return delay
+}
+
 func (c *ImageConfig) String() string {
 	dir := filepath.Join(c.Context, c.Dockerfile)
 	return fmt.Sprintf("Build image '%s' from '%s'", c.Image, dir)
@@ -163,6 +421,16 @@ func (c *ImageConfig) Resolve(resolver Resolver) (Resource, error) {
 		return &conf, err
 	}
 
+	conf.PostBuild, err = resolver.ResolveSlice(c.PostBuild)
+	if err != nil {
+		return &conf, err
+	}
+
+	conf.PrePush, err = resolver.ResolveSlice(c.PrePush)
+	if err != nil {
+		return &conf, err
+	}
+
 	conf.Image, err = resolver.Resolve(c.Image)
 	if err != nil {
 		return &conf, err
@@ -173,6 +441,39 @@ func (c *ImageConfig) Resolve(resolver Resolver) (Resource, error) {
 		return &conf, err
 	}
 
+	conf.DockerHost, err = resolver.Resolve(c.DockerHost)
+	if err != nil {
+		return &conf, err
+	}
+
+	conf.Archive, err = resolver.Resolve(c.Archive)
+	if err != nil {
+		return &conf, err
+	}
+
+	conf.Cluster, err = resolver.Resolve(c.Cluster)
+	if err != nil {
+		return &conf, err
+	}
+
+	conf.Expires, err = resolver.Resolve(c.Expires)
+	if err != nil {
+		return &conf, err
+	}
+	if err := conf.ValidateExpires(); err != nil {
+		return &conf, err
+	}
+
+	conf.Auth, err = c.Auth.Resolve(resolver)
+	if err != nil {
+		return &conf, err
+	}
+
+	conf.ArgsFrom, err = resolver.ResolveSlice(c.ArgsFrom)
+	if err != nil {
+		return &conf, err
+	}
+
 	for key, value := range c.Args {
 		conf.Args[key], err = resolver.Resolve(value)
 		if err != nil {
@@ -191,6 +492,9 @@ type pullAction func(*time.Time) bool
 
 type pull struct {
 	action pullAction
+	// ifChanged is true when the registry digest is compared to the local
+	// image before the image is pulled
+	ifChanged bool
 }
 
 func (p *pull) TransformConfig(raw reflect.Value) error {
@@ -207,6 +511,11 @@ func (p *pull) TransformConfig(raw reflect.Value) error {
 			p.action = pullNever
 		case "always":
 			p.action = pullAlways
+		case "daily":
+			p.action = pullAfter{duration: 24 * time.Hour}.doPull
+		case "if-changed":
+			p.action = pullAlways
+			p.ifChanged = true
 		default:
 			duration, err := time.ParseDuration(value)
 			if err != nil {
@@ -231,6 +540,12 @@ func (p *pull) IsSet() bool {
 	return p.action != nil
 }
 
+// IfChanged returns true if the image is only pulled when the digest in the
+// registry is different from the local image
+func (p *pull) IfChanged() bool {
+	return p.ifChanged
+}
+
 func pullAlways(_ *time.Time) bool {
 	return true
 }
@@ -82,6 +82,11 @@ func TestImageConfigValidate(t *testing.T) {
 			image:              &ImageConfig{Dockerfile: "Dockerfile"},
 			expectedDockerfile: "Dockerfile",
 		},
+		{
+			doc:         "tag by digest of inputs with pull",
+			image:       &ImageConfig{Pull: pull{action: pullAlways}, TagByDigestOfInputs: true},
+			expectedErr: "tag-by-digest-of-inputs: can only be used with an image which is built",
+		},
 	}
 
 	for _, testcase := range testcases {
@@ -114,6 +119,9 @@ func TestImageConfigResolve(t *testing.T) {
 			"key2": "ok",
 		},
 		CacheFrom: []string{"{one}", "two"},
+		PostBuild: []string{"{one}"},
+		PrePush:   []string{"{three}"},
+		ArgsFrom:  []string{"{two}"},
 	}
 	resolved, err := image.Resolve(resolver)
 	assert.NilError(t, err)
@@ -126,6 +134,9 @@ func TestImageConfigResolve(t *testing.T) {
 			"key2": "ok",
 		},
 		CacheFrom: []string{"thetag", "two"},
+		PostBuild: []string{"thetag"},
+		PrePush:   []string{"last"},
+		ArgsFrom:  []string{"theother"},
 	}
 	assert.Check(t, is.DeepEqual(expected, resolved, cmpConfigOpt))
 }
@@ -142,6 +153,21 @@ func TestPullWithDuration(t *testing.T) {
 	assert.Check(t, p.Required(nil))
 }
 
+func TestPullDailyAndIfChanged(t *testing.T) {
+	p := pull{}
+	assert.NilError(t, p.TransformConfig(reflect.ValueOf("daily")))
+	recent := time.Now().Add(-time.Hour)
+	old := time.Now().Add(-25 * time.Hour)
+	assert.Check(t, !p.Required(&recent))
+	assert.Check(t, p.Required(&old))
+	assert.Check(t, !p.IfChanged())
+
+	p = pull{}
+	assert.NilError(t, p.TransformConfig(reflect.ValueOf("if-changed")))
+	assert.Check(t, p.Required(&recent))
+	assert.Check(t, p.IfChanged())
+}
+
 func TestPullTransformConfig(t *testing.T) {
 	p := pull{}
 	zero := reflect.Value{}
@@ -149,3 +175,90 @@ func TestPullTransformConfig(t *testing.T) {
 
 	assert.Check(t, is.ErrorContains(err, "must be a string"))
 }
+
+func TestImageConfigValidateExpires(t *testing.T) {
+	for _, value := range []string{"", "12h", "2w", "{env.EXPIRES}"} {
+		image := &ImageConfig{Expires: value}
+		assert.Check(t, image.ValidateExpires(), value)
+	}
+
+	image := &ImageConfig{Expires: "2 weeks"}
+	assert.Check(t, is.ErrorContains(image.ValidateExpires(), `invalid expires "2 weeks"`))
+}
+
+func TestImageConfigValidateCacheTo(t *testing.T) {
+	image := &ImageConfig{CacheTo: []string{"inline", "type=inline"}}
+	assert.Check(t, image.ValidateCacheTo())
+
+	image = &ImageConfig{CacheTo: []string{"type=local,dest=.cache"}}
+	assert.Check(t, is.ErrorContains(image.ValidateCacheTo(),
+		`unsupported cache-to "type=local,dest=.cache"`))
+}
+
+func TestImageConfigValidateAuth(t *testing.T) {
+	for _, auth := range []RegistryAuth{
+		{},
+		{Username: "ci", Password: "secret"},
+		{IdentityToken: "token"},
+		{CredentialHelper: "ecr-login"},
+	} {
+		image := &ImageConfig{Auth: auth}
+		assert.Check(t, image.ValidateAuth(), "%+v", auth)
+	}
+
+	image := &ImageConfig{Auth: RegistryAuth{CredentialHelper: "ecr-login", Username: "ci"}}
+	assert.Check(t, is.ErrorContains(image.ValidateAuth(),
+		"invalid auth: credential-helper can not be used with username"))
+
+	image = &ImageConfig{Auth: RegistryAuth{Password: "secret"}}
+	assert.Check(t, is.ErrorContains(image.ValidateAuth(),
+		"invalid auth: password requires a username"))
+}
+
+func TestImageConfigValidateBuilder(t *testing.T) {
+	conf := NewConfig()
+	conf.Meta.Builders = map[string][]string{"farm": {"tcp://build1:2376"}}
+
+	image := &ImageConfig{Image: "app", Context: ".", Builder: "farm"}
+	assert.Check(t, is.Nil(image.Validate(pth.NewPath("app"), conf)))
+
+	image = &ImageConfig{Image: "app", Context: ".", Builder: "other"}
+	assert.Check(t, is.ErrorContains(image.Validate(pth.NewPath("app"), conf),
+		`undefined builder "other"`))
+
+	image = &ImageConfig{Image: "app", Context: ".", Builder: "farm"}
+	image.DockerHost = "tcp://other:2376"
+	assert.Check(t, is.ErrorContains(image.Validate(pth.NewPath("app"), conf),
+		"builder can not be used with docker-host"))
+}
+
+func TestImageConfigValidateArgsFrom(t *testing.T) {
+	conf := NewConfig()
+	conf.Resources["build-env"] = &EnvConfig{Variables: []string{"VERSION=1.0"}}
+	conf.Resources["source"] = &MountConfig{Bind: ".", Path: "/go/src/app"}
+
+	image := &ImageConfig{
+		Image:    "app",
+		Context:  ".",
+		ArgsFrom: []string{"build-env", "build.args"},
+	}
+	assert.Check(t, is.Nil(image.Validate(pth.NewPath("app"), conf)))
+
+	image = &ImageConfig{Image: "app", Context: ".", ArgsFrom: []string{"source"}}
+	assert.Check(t, is.ErrorContains(image.Validate(pth.NewPath("app"), conf),
+		"source is not an env resource"))
+}
+
+func TestImageConfigValidatePushRetries(t *testing.T) {
+	image := &ImageConfig{PushRetries: 3, PushRetryDelay: "500ms"}
+	assert.Check(t, image.ValidatePushRetries())
+	assert.Check(t, image.ValidatePushRetryDelay())
+	assert.Check(t, is.Equal(image.PushRetryDelayDuration(), 500*time.Millisecond))
+
+	image = &ImageConfig{PushRetries: -1, PushRetryDelay: "later"}
+	assert.Check(t, is.ErrorContains(image.ValidatePushRetries(),
+		"push-retries must be 0 or more"))
+	assert.Check(t, is.ErrorContains(image.ValidatePushRetryDelay(),
+		`invalid push-retry-delay "later"`))
+	assert.Check(t, is.Equal((&ImageConfig{}).PushRetryDelayDuration(), time.Second))
+}
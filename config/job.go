@@ -3,10 +3,17 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/dnephin/configtf"
 	pth "github.com/dnephin/configtf/path"
+	"github.com/dnephin/dobi/utils/fs"
+	units "github.com/docker/go-units"
+	docker "github.com/fsouza/go-dockerclient"
 	shlex "github.com/kballard/go-shellquote"
 	"golang.org/x/crypto/ssh/terminal"
 )
@@ -43,13 +50,27 @@ import (
 //
 type JobConfig struct {
 	// Use The name of an `image`_ resource. The referenced image is used
-	// to created the container for the **job**.
+	// to created the container for the **job**. A service from the files of a
+	// `compose`_ resource can be used as ``<compose>.<service>``, which builds
+	// or pulls the image using the ``image`` and ``build`` config of the
+	// service.
+	// example: ``use: devenv.web``
 	Use string `config:"required"`
 	// Artifact File paths or globs identifying the files created by the **job**.
 	// Paths to directories must end with a path separator (``/``).
 	// Paths are relative to the ``dobi.yaml``
 	// type: list of file paths or glob patterns
 	Artifact PathGlobs
+	// RemoteCache When **true** the ``artifact`` is stored in the
+	// ``artifact-store`` of the `meta`_ resource with a hash of the inputs
+	// of the **job**: the ID of the ``use`` image, the ``command``,
+	// ``entrypoint``, ``env``, ``working-dir``, and ``user``, and the files in
+	// ``sources``, or in ``mounts`` when there are no ``sources``. When the
+	// **job** is stale, and an artifact with the same hash exists in the
+	// store, the artifact is downloaded instead of running the **job**. After
+	// the **job** runs the artifact is uploaded, so that runs on other hosts
+	// can use it.
+	RemoteCache bool
 	// Command The command to run in the container.
 	// type: shell quoted string
 	// example: ``"bash -c 'echo something'"``
@@ -57,6 +78,12 @@ type JobConfig struct {
 	// Entrypoint Override the image entrypoint
 	// type: shell quoted string
 	Entrypoint ShlexSlice
+	// Actions Custom actions of the job. Each action runs a different
+	// command in a container with the same config as the job. A custom action
+	// does not create the ``artifact``, so it always runs.
+	// type: mapping ``action: command``
+	// example: ``{coverage: "go test -cover ./...", lint: "golangci-lint run"}``
+	Actions map[string]string `config:"validate"`
 	// Sources File paths or globs of the files used to create the
 	// artifact. The modified time of these files are compared to the modified time
 	// of the artifact to determine if the **job** is stale. If the **sources**
@@ -64,15 +91,37 @@ type JobConfig struct {
 	// ignored.
 	// type: list of file paths or glob patterns
 	Sources PathGlobs
-	// Mounts A list of `mount`_ resources to use when creating the container.
+	// Mounts A list of `mount`_ or `checkout`_ resources to use when creating
+	// the container.
 	// type: list of mount resources
 	Mounts []string
+	// ArtifactsFrom A list of **job** resources which create an ``artifact``
+	// used by this job. The jobs are added to the dependencies of this job,
+	// and their artifacts are mounted read-only in the container, in
+	// ``artifacts-path`` at the same path they have relative to the
+	// ``dobi.yaml``.
+	// type: list of job resources
+	// example: ``[compile]``
+	ArtifactsFrom []string
+	// ArtifactsPath The container path where the artifacts of
+	// ``artifacts-from`` are mounted.
+	// default: ``/artifacts``
+	ArtifactsPath string
 	// Privileged Gives extended privileges to the container
 	Privileged bool
 	// Interactive Makes the container interative and enables a tty.
 	Interactive bool
-	// Env Environment variables to pass to the container. This field
+	// Stdin The path to a file which is sent to the stdin of the container,
+	// or ``-`` to send the stdin of **dobi**. Use the ``artifact`` of a job
+	// that runs first to send the output of that job. Add the file to
+	// ``sources`` to run the job again when the file changes. This field
 	// supports :doc:`variables`.
+	// example: ``fixtures/dump.sql``
+	Stdin string `config:"validate"`
+	// Env Environment variables to pass to the container. This field
+	// supports :doc:`variables`. For each image the job depends on, the
+	// image name and tag is set in ``DOBI_IMAGE_<NAME>_TAG`` and the image
+	// ID is set in ``DOBI_IMAGE_<NAME>_DIGEST``.
 	// type: list of ``key=value`` strings
 	Env []string
 	// ProvideDocker Exposes the docker engine to the container by either
@@ -80,8 +129,29 @@ type JobConfig struct {
 	// variable. All environment variables with a  ``DOCKER_`` prefix in the
 	// environment are set on the container.
 	ProvideDocker bool
+	// ProvideSSH Exposes the ssh-agent on the host to the container by
+	// mounting the agent socket and setting the ``SSH_AUTH_SOCK`` environment
+	// variable. On macOS the socket provided by Docker Desktop is used.
+	ProvideSSH bool `config:"provide-ssh"`
+	// UsernsMode The user namespace mode for the container. Set to ``host`` to
+	// disable user namespace remapping for the job when the Docker daemon is
+	// run with ``userns-remap``.
+	UsernsMode string `config:"validate"`
 	// NetMode The network mode to use. This field supports :doc:`variables`.
 	NetMode string
+	// Networks The networks to connect the container to. An item may be the
+	// name of a `compose`_ resource, to join the default network of the
+	// Compose project, so that the services can be reached by their service
+	// name. The **compose** resource must also be listed in ``depends``.
+	// Other items are the names of existing Docker networks. Each item in the
+	// list supports :doc:`variables`.
+	// type: list of compose resources or network names
+	// example: ``[devenv]``
+	Networks []string
+	// NetworkAliases Additional hostnames for the container on each of the
+	// ``networks``.
+	// type: list of hostnames
+	NetworkAliases []string
 	// WorkingDir The directory to set as the active working directory in the
 	// container. This field supports :doc:`variables`.
 	WorkingDir string
@@ -90,13 +160,107 @@ type JobConfig struct {
 	// Ports Publish ports to the host
 	// type: list of 'host_port:container_port'
 	Ports []string
+	// ExposePortsToHost When set to ``auto`` all the ports exposed by the
+	// image are published to random ports on the host. The mapping of
+	// container ports to host ports is written to ``ports-file`` and set as
+	// environment variables named ``DOBI_PORT_<JOB>_<PORT>``.
+	ExposePortsToHost string `config:"validate"`
+	// PortsFile The path of the JSON file where the port mapping is written
+	// when ``expose-ports-to-host`` is ``auto``.
+	// default: ``.dobi/ports/<job>.json``
+	PortsFile string
+	// CheckWrites When **true** the changes to the filesystem of the container
+	// are written to ``.dobi/diff/<job>.txt`` after the job runs, and a
+	// warning is logged for each file written outside of a mount or
+	// ``tmpfs``. Files written outside of a mount are lost when the container
+	// is removed. Changes in ``/tmp``, ``/var/tmp``, and ``/run`` are ignored.
+	CheckWrites bool
+	// TestResults The path to a file of test results written by the job.
+	// Files with a ``.xml`` extension are parsed as JUnit XML, and files with
+	// a ``.json`` extension are parsed as the output of ``go test -json``.
+	// The number of tests, and the names of the failed tests, are included
+	// in the summary and the ``--report`` file. The file must be in a
+	// ``mount`` or ``artifact`` so that it exists on the host. This field
+	// supports :doc:`variables`.
+	// example: ``dist/junit.xml``
+	TestResults string `config:"validate"`
 	// Devices Maps the host devices you want to connect to a container
 	// type: list of device specs
 	// example: ``{Host: /dev/fb0, Container: /dev/fb0, Permissions: rwm}``
 	Devices []Device
+	// Gpus The GPUs available to the container, the same as the ``--gpus``
+	// flag of ``docker run``. The value may be ``all``, a number of GPUs, or
+	// ``device=`` followed by a comma separated list of device IDs. The
+	// Docker host must have a GPU runtime, like the NVIDIA Container Toolkit.
+	// example: ``all``
+	Gpus string `config:"validate"`
 	// Labels sets the labels of the running job container
 	// type: map of string keys to string values
 	Labels map[string]string
+	// Tmpfs Mount a tmpfs filesystem in the container. Options are the same
+	// as the ``--tmpfs`` flag of ``docker run``.
+	// type: list of ``path[:options]`` strings
+	// example: ``["/run", "/tmp:rw,size=64m"]``
+	Tmpfs []string `config:"validate"`
+	// ShmSize The size of ``/dev/shm``.
+	// type: size with a unit suffix
+	// example: ``256m``
+	ShmSize string `config:"validate"`
+	// StopGrace Seconds to wait for the container to exit after **dobi**
+	// forwards an interrupt signal to it, or stops a container which is
+	// still running when the job ends, before the container is killed.
+	// default: ``5``
+	StopGrace int
+	// ExitCodes Classify the exit codes of the container as a success, a
+	// warning, or a failure. A warning is logged, and shown in the summary,
+	// but does not fail the job unless **dobi** is run with ``--strict``.
+	// type: mapping with keys ``success`` and ``warn``
+	// example: ``{success: [0], warn: [2]}``
+	ExitCodes ExitCodes `config:"validate"`
+	// WaitFor Checks which must pass after the ``:start`` action starts the
+	// container, before the tasks which depend on the job are run. The
+	// ``:start`` action fails when the checks don't pass before the timeout,
+	// or the container exits.
+	// type: mapping with keys ``port``, ``url``, ``healthcheck``, and ``timeout``
+	// example: ``{port: 8080, timeout: 2m}``
+	WaitFor WaitFor `config:"validate"`
+	// Ulimits Resource limits for the container.
+	// type: list of ``name=soft[:hard]`` strings
+	// example: ``["nofile=1024:2048", "nproc=512"]``
+	Ulimits []string `config:"validate"`
+	// Memory The memory limit for the container. Jobs which don't set
+	// ``memory`` use the limit from ``--default-memory``, when it is set.
+	// type: size with a unit suffix
+	// example: ``2g``
+	Memory string `config:"validate"`
+	// Cpus The number of CPUs available to the container.
+	// example: ``'1.5'``
+	Cpus string `config:"validate"`
+	// CapAdd Kernel capabilities to add to the container.
+	// type: list of capabilities
+	// example: ``[SYS_ADMIN]``
+	CapAdd []string
+	// CapDrop Kernel capabilities to remove from the container.
+	// type: list of capabilities
+	CapDrop []string
+	// Symlinks The policy used for symlinks in ``sources``, ``mounts``, and
+	// ``artifact``. The value may be one of:
+	// * ``copy-as-link`` - use the modified time of the link, and copy
+	//   artifacts as links
+	// * ``follow`` - use the modified time of the file the link points to
+	// * ``forbid-escape`` - fail the job if a link points to a path outside
+	//   of the project directory
+	// default: ``copy-as-link``
+	Symlinks string `config:"validate"`
+	// FixOwnership When true the files of the ``artifact`` are changed to be
+	// owned by the user and group which run **dobi**, after the job runs.
+	// Files written to a bind mount by a container which runs as root are
+	// owned by root on the host. The ownership is changed by running
+	// ``chown`` in a container from the image of the job, so the image must
+	// include ``chown``. Only used with bind mounts, on hosts where **dobi**
+	// is not run as root.
+	FixOwnership bool
+	Hosted
 	Dependent
 	Annotations
 }
@@ -110,7 +274,8 @@ type Device struct {
 
 // Dependencies returns the list of implicit and explicit dependencies
 func (c *JobConfig) Dependencies() []string {
-	return append([]string{c.Use}, append(c.Depends, c.Mounts...)...)
+	deps := append([]string{c.Use}, append(c.Depends, c.Mounts...)...)
+	return append(deps, c.ArtifactsFrom...)
 }
 
 // Validate checks that all fields have acceptable values
@@ -118,8 +283,11 @@ func (c *JobConfig) Validate(path pth.Path, config *Config) *pth.Error {
 	validators := []validator{
 		newValidator("use", func() error { return c.validateUse(config) }),
 		newValidator("mounts", func() error { return c.validateMounts(config) }),
+		newValidator("networks", func() error { return c.validateNetworks(config) }),
+		newValidator("artifacts-from", func() error { return c.validateArtifactsFrom(config) }),
 		newValidator("artifact", c.Artifact.Validate),
 		newValidator("sources", c.Sources.Validate),
+		newValidator("remote-cache", func() error { return c.validateRemoteCache(config) }),
 	}
 	for _, validator := range validators {
 		if err := validator.validate(); err != nil {
@@ -129,20 +297,239 @@ func (c *JobConfig) Validate(path pth.Path, config *Config) *pth.Error {
 	return nil
 }
 
+// jobActions are the names of the actions of every job, which can not be used
+// as the name of a custom action
+var jobActions = []string{
+	"run", "remove", "rm", "start", "stop", "upload", "download", "capture",
+}
+
+var actionNameRegex = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// ValidateActions validates the names and commands of the custom actions
+func (c *JobConfig) ValidateActions() error {
+	for name, command := range c.Actions {
+		if !actionNameRegex.MatchString(name) {
+			return fmt.Errorf("invalid action name %q, must be lowercase letters, "+
+				"numbers, and dashes", name)
+		}
+		for _, builtin := range jobActions {
+			if name == builtin {
+				return fmt.Errorf("action %q is already an action of every job", name)
+			}
+		}
+		if command == "" {
+			return fmt.Errorf("a command is required for action %q", name)
+		}
+		if _, err := c.ActionCommand(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ActionCommand returns the command of a custom action
+func (c *JobConfig) ActionCommand(name string) (ShlexSlice, error) {
+	command := ShlexSlice{}
+	return command, command.TransformConfig(reflect.ValueOf(c.Actions[name]))
+}
+
+// ValidateSymlinks validates the symlink policy
+func (c *JobConfig) ValidateSymlinks() error {
+	switch fs.SymlinkPolicy(c.Symlinks) {
+	case "", fs.SymlinkCopyAsLink, fs.SymlinkFollow, fs.SymlinkForbidEscape:
+		return nil
+	default:
+		return fmt.Errorf(
+			"invalid symlinks %q, must be one of copy-as-link, follow, forbid-escape",
+			c.Symlinks)
+	}
+}
+
+// ValidateStdin validates that stdin is not used with interactive
+func (c *JobConfig) ValidateStdin() error {
+	if c.Stdin != "" && c.Interactive {
+		return fmt.Errorf("stdin can not be used with interactive")
+	}
+	return nil
+}
+
+// ValidateTestResults validates the format of the test results file
+func (c *JobConfig) ValidateTestResults() error {
+	if c.TestResults == "" {
+		return nil
+	}
+	switch strings.ToLower(filepath.Ext(c.TestResults)) {
+	case ".xml", ".json":
+		return nil
+	}
+	return fmt.Errorf(
+		"invalid test-results %q, the file extension must be .xml or .json", c.TestResults)
+}
+
+// ValidateExposePortsToHost validates the value is empty or auto
+func (c *JobConfig) ValidateExposePortsToHost() error {
+	switch c.ExposePortsToHost {
+	case "", "auto":
+		return nil
+	default:
+		return fmt.Errorf(
+			"invalid expose-ports-to-host %q, must be auto", c.ExposePortsToHost)
+	}
+}
+
+// ValidateUsernsMode validates the user namespace mode
+func (c *JobConfig) ValidateUsernsMode() error {
+	switch c.UsernsMode {
+	case "", "host":
+		return nil
+	default:
+		return fmt.Errorf("invalid userns-mode %q, must be host", c.UsernsMode)
+	}
+}
+
+// ValidateTmpfs validates the tmpfs paths are absolute
+func (c *JobConfig) ValidateTmpfs() error {
+	for _, tmpfs := range c.Tmpfs {
+		path, _ := SplitTmpfs(tmpfs)
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("tmpfs path %q must be an absolute path", path)
+		}
+	}
+	return nil
+}
+
+// SplitTmpfs splits a tmpfs spec into the container path and mount options
+func SplitTmpfs(tmpfs string) (string, string) {
+	parts := strings.SplitN(tmpfs, ":", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// ValidateWaitFor validates the wait-for checks
+func (c *JobConfig) ValidateWaitFor() error {
+	return c.WaitFor.Validate()
+}
+
+// ValidateExitCodes validates the exit-codes
+func (c *JobConfig) ValidateExitCodes() error {
+	return c.ExitCodes.Validate()
+}
+
+// ValidateShmSize validates the shm-size is a valid size
+func (c *JobConfig) ValidateShmSize() error {
+	_, err := c.ShmSizeBytes()
+	return err
+}
+
+// ShmSizeBytes returns ShmSize as a number of bytes
+func (c *JobConfig) ShmSizeBytes() (int64, error) {
+	if c.ShmSize == "" {
+		return 0, nil
+	}
+	size, err := units.RAMInBytes(c.ShmSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid shm-size %q: %s", c.ShmSize, err)
+	}
+	return size, nil
+}
+
+// ValidateMemory validates the memory limit is a valid size
+func (c *JobConfig) ValidateMemory() error {
+	_, err := parseMemory(c.Memory)
+	return err
+}
+
+// MemoryBytes returns Memory as a number of bytes
+func (c *JobConfig) MemoryBytes() int64 {
+	// Memory is validated when the config is loaded
+	size, _ := parseMemory(c.Memory)
+	return size
+}
+
+// ValidateCpus validates the CPU limit is a positive number
+func (c *JobConfig) ValidateCpus() error {
+	_, err := parseCpus(c.Cpus)
+	return err
+}
+
+// NanoCpus returns Cpus as a number of nano CPUs
+func (c *JobConfig) NanoCpus() int64 {
+	// Cpus is validated when the config is loaded
+	cpus, _ := parseCpus(c.Cpus)
+	return cpus
+}
+
+// ValidateGpus validates the value of gpus
+func (c *JobConfig) ValidateGpus() error {
+	_, err := parseGpus(c.Gpus)
+	return err
+}
+
+// GpuRequests returns the device requests for the gpus of the container
+func (c *JobConfig) GpuRequests() []docker.DeviceRequest {
+	// Gpus is validated when the config is loaded
+	request, _ := parseGpus(c.Gpus)
+	if request == nil {
+		return nil
+	}
+	return []docker.DeviceRequest{*request}
+}
+
+func parseGpus(value string) (*docker.DeviceRequest, error) {
+	request := &docker.DeviceRequest{Capabilities: [][]string{{"gpu"}}}
+	switch {
+	case value == "":
+		return nil, nil
+	case value == "all":
+		request.Count = -1
+	case strings.HasPrefix(value, "device="):
+		for _, id := range strings.Split(strings.TrimPrefix(value, "device="), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				request.DeviceIDs = append(request.DeviceIDs, id)
+			}
+		}
+		if len(request.DeviceIDs) == 0 {
+			return nil, fmt.Errorf("invalid gpus %q, device= requires a device ID", value)
+		}
+	default:
+		count, err := strconv.Atoi(value)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf(
+				"invalid gpus %q, must be all, a positive number, or device=ID[,ID...]", value)
+		}
+		request.Count = count
+	}
+	return request, nil
+}
+
+// ValidateUlimits validates the format of the ulimits
+func (c *JobConfig) ValidateUlimits() error {
+	for _, ulimit := range c.Ulimits {
+		if _, err := units.ParseUlimit(ulimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *JobConfig) validateUse(config *Config) error {
-	err := fmt.Errorf("%s is not an image resource", c.Use)
+	err := fmt.Errorf("%s is not an image resource or compose service", c.Use)
 
 	res, ok := config.Resources[c.Use]
 	if !ok {
 		return err
 	}
 
-	switch res.(type) {
-	case *ImageConfig:
-	default:
+	image, ok := res.(*ImageConfig)
+	if !ok {
 		return err
 	}
-
+	if image.Builder != "" {
+		return fmt.Errorf("%s is built on the hosts of builder %q, "+
+			"and can not be used by a job", c.Use, image.Builder)
+	}
 	return nil
 }
 
@@ -155,8 +542,12 @@ func (c *JobConfig) validateMounts(config *Config) error {
 			return err
 		}
 
-		switch res.(type) {
+		switch res := res.(type) {
 		case *MountConfig:
+		case *CheckoutConfig:
+			if res.Path == "" {
+				return fmt.Errorf("checkout %s must set path to be used as a mount", mount)
+			}
 		default:
 			return err
 		}
@@ -164,6 +555,64 @@ func (c *JobConfig) validateMounts(config *Config) error {
 	return nil
 }
 
+func (c *JobConfig) validateNetworks(config *Config) error {
+	if len(c.Networks) == 0 {
+		return nil
+	}
+	switch c.NetMode {
+	case "host", "none":
+		return fmt.Errorf("networks can not be used with net-mode %s", c.NetMode)
+	}
+	for _, network := range c.Networks {
+		res, ok := config.Resources[network]
+		if !ok {
+			continue
+		}
+		if _, ok := res.(*ComposeConfig); !ok {
+			return fmt.Errorf("%s is not a compose resource", network)
+		}
+		if !c.dependsOn(network) {
+			return fmt.Errorf("compose resource %s must be listed in depends", network)
+		}
+	}
+	return nil
+}
+
+func (c *JobConfig) validateArtifactsFrom(config *Config) error {
+	for _, name := range c.ArtifactsFrom {
+		job, ok := config.Resources[name].(*JobConfig)
+		if !ok {
+			return fmt.Errorf("%s is not a job resource", name)
+		}
+		if job.Artifact.Empty() {
+			return fmt.Errorf("job %s has no artifact", name)
+		}
+	}
+	return nil
+}
+
+func (c *JobConfig) validateRemoteCache(config *Config) error {
+	switch {
+	case !c.RemoteCache:
+		return nil
+	case c.Artifact.Empty():
+		return fmt.Errorf("an artifact is required to use the remote cache")
+	case config.Meta == nil || config.Meta.ArtifactStore == "":
+		return fmt.Errorf("meta.artifact-store is required to use the remote cache")
+	}
+	return nil
+}
+
+// dependsOn returns true if any action of the resource is in Depends
+func (c *JobConfig) dependsOn(resource string) bool {
+	for _, dep := range c.Depends {
+		if strings.SplitN(dep, ":", 2)[0] == resource {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *JobConfig) String() string {
 	artifact, command := "", ""
 	if !c.Artifact.Empty() {
@@ -192,6 +641,26 @@ func (c *JobConfig) Resolve(resolver Resolver) (Resource, error) {
 	if err != nil {
 		return &conf, err
 	}
+	conf.Stdin, err = resolver.Resolve(c.Stdin)
+	if err != nil {
+		return &conf, err
+	}
+	conf.TestResults, err = resolver.Resolve(c.TestResults)
+	if err != nil {
+		return &conf, err
+	}
+	conf.DockerHost, err = resolver.Resolve(c.DockerHost)
+	if err != nil {
+		return &conf, err
+	}
+	conf.Networks, err = resolver.ResolveSlice(c.Networks)
+	if err != nil {
+		return &conf, err
+	}
+	conf.WaitFor.URL, err = resolver.Resolve(c.WaitFor.URL)
+	if err != nil {
+		return &conf, err
+	}
 	conf.NetMode, err = resolver.Resolve(c.NetMode)
 	return &conf, err
 }
@@ -240,7 +709,7 @@ func (s *ShlexSlice) TransformConfig(raw reflect.Value) error {
 
 func jobFromConfig(name string, values map[string]interface{}) (Resource, error) {
 	isTerminal := terminal.IsTerminal(int(os.Stdin.Fd()))
-	cmd := &JobConfig{}
+	cmd := &JobConfig{StopGrace: 5, ArtifactsPath: "/artifacts"}
 	if isTerminal {
 		if _, ok := values["interactive"]; !ok {
 			values["interactive"] = true
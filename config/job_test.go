@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	pth "github.com/dnephin/configtf/path"
+	docker "github.com/fsouza/go-dockerclient"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 )
@@ -39,6 +40,19 @@ func TestJobConfigValidateMissingMount(t *testing.T) {
 	assert.Assert(t, is.ErrorContains(err, "one is not a mount resource"))
 }
 
+func TestJobConfigValidateCheckoutMount(t *testing.T) {
+	conf := NewConfig()
+	conf.Resources["example"] = NewImageConfig()
+	conf.Resources["protos"] = &CheckoutConfig{Repo: "https://example.com/protos.git"}
+	job := &JobConfig{Use: "example", Mounts: []string{"protos"}}
+
+	err := job.Validate(pth.NewPath(""), conf)
+	assert.Assert(t, is.ErrorContains(err, "checkout protos must set path"))
+
+	conf.Resources["protos"].(*CheckoutConfig).Path = "/protos"
+	assert.Assert(t, job.Validate(pth.NewPath(""), conf) == nil)
+}
+
 func TestJobConfigRunFromConfig(t *testing.T) {
 	values := map[string]interface{}{
 		"use":        "image-res",
@@ -62,3 +76,170 @@ func TestShlexSliceTransformConfig(t *testing.T) {
 
 	assert.Check(t, is.ErrorContains(err, "must be a string"))
 }
+
+func TestJobConfigValidateSymlinks(t *testing.T) {
+	job := &JobConfig{Symlinks: "follow"}
+	assert.NilError(t, job.ValidateSymlinks())
+
+	job.Symlinks = "bogus"
+	assert.Check(t, is.ErrorContains(job.ValidateSymlinks(), `invalid symlinks "bogus"`))
+}
+
+func TestJobConfigValidateContainerOptions(t *testing.T) {
+	job := &JobConfig{
+		Tmpfs:   []string{"/run", "/tmp:rw,size=64m"},
+		ShmSize: "256m",
+		Ulimits: []string{"nofile=1024:2048"},
+	}
+	assert.NilError(t, job.ValidateTmpfs())
+	assert.NilError(t, job.ValidateShmSize())
+	assert.NilError(t, job.ValidateUlimits())
+
+	size, err := job.ShmSizeBytes()
+	assert.NilError(t, err)
+	assert.Equal(t, size, int64(256*1024*1024))
+
+	job = &JobConfig{Tmpfs: []string{"tmp"}, ShmSize: "lots", Ulimits: []string{"nofile"}}
+	assert.Check(t, is.ErrorContains(job.ValidateTmpfs(), `"tmp" must be an absolute path`))
+	assert.Check(t, is.ErrorContains(job.ValidateShmSize(), `invalid shm-size "lots"`))
+	assert.Check(t, is.ErrorContains(job.ValidateUlimits(), "nofile"))
+}
+
+func TestJobConfigGpus(t *testing.T) {
+	job := &JobConfig{}
+	assert.NilError(t, job.ValidateGpus())
+	assert.Check(t, is.Len(job.GpuRequests(), 0))
+
+	job.Gpus = "all"
+	assert.NilError(t, job.ValidateGpus())
+	expected := []docker.DeviceRequest{{Count: -1, Capabilities: [][]string{{"gpu"}}}}
+	assert.Check(t, is.DeepEqual(job.GpuRequests(), expected))
+
+	job.Gpus = "2"
+	expected = []docker.DeviceRequest{{Count: 2, Capabilities: [][]string{{"gpu"}}}}
+	assert.Check(t, is.DeepEqual(job.GpuRequests(), expected))
+
+	job.Gpus = "device=0, 2"
+	expected = []docker.DeviceRequest{
+		{DeviceIDs: []string{"0", "2"}, Capabilities: [][]string{{"gpu"}}},
+	}
+	assert.Check(t, is.DeepEqual(job.GpuRequests(), expected))
+
+	for _, value := range []string{"some", "0", "device="} {
+		job.Gpus = value
+		assert.Check(t, is.ErrorContains(job.ValidateGpus(), "invalid gpus"), value)
+	}
+}
+
+func TestJobConfigValidateUsernsMode(t *testing.T) {
+	job := &JobConfig{UsernsMode: "host"}
+	assert.NilError(t, job.ValidateUsernsMode())
+
+	job.UsernsMode = "private"
+	assert.Check(t, is.ErrorContains(job.ValidateUsernsMode(), `invalid userns-mode "private"`))
+}
+
+func TestJobConfigValidateStdin(t *testing.T) {
+	job := &JobConfig{Stdin: "dump.sql"}
+	assert.NilError(t, job.ValidateStdin())
+
+	job.Interactive = true
+	assert.Check(t, is.ErrorContains(job.ValidateStdin(),
+		"stdin can not be used with interactive"))
+}
+
+func TestJobConfigValidateTestResults(t *testing.T) {
+	job := &JobConfig{}
+	assert.NilError(t, job.ValidateTestResults())
+
+	job.TestResults = "dist/junit.XML"
+	assert.NilError(t, job.ValidateTestResults())
+
+	job.TestResults = "dist/results.txt"
+	assert.Check(t, is.ErrorContains(job.ValidateTestResults(),
+		`invalid test-results "dist/results.txt"`))
+}
+
+func TestJobConfigValidateNetworks(t *testing.T) {
+	conf := NewConfig()
+	conf.Resources["example"] = NewImageConfig()
+	conf.Resources["devenv"] = &ComposeConfig{Project: "devenv"}
+	job := &JobConfig{Use: "example", Networks: []string{"devenv", "shared"}}
+
+	err := job.Validate(pth.NewPath(""), conf)
+	assert.Assert(t, is.ErrorContains(err, "compose resource devenv must be listed in depends"))
+
+	job.Depends = []string{"devenv:detach"}
+	assert.Assert(t, job.Validate(pth.NewPath(""), conf) == nil)
+
+	job.Networks = []string{"example"}
+	err = job.Validate(pth.NewPath(""), conf)
+	assert.Assert(t, is.ErrorContains(err, "example is not a compose resource"))
+
+	job.Networks = []string{"shared"}
+	job.NetMode = "host"
+	err = job.Validate(pth.NewPath(""), conf)
+	assert.Assert(t, is.ErrorContains(err, "can not be used with net-mode host"))
+}
+
+func TestJobConfigValidateArtifactsFrom(t *testing.T) {
+	conf := NewConfig()
+	conf.Resources["example"] = NewImageConfig()
+	conf.Resources["compile"] = &JobConfig{Use: "example"}
+	job := &JobConfig{Use: "example", ArtifactsFrom: []string{"compile"}}
+
+	err := job.Validate(pth.NewPath(""), conf)
+	assert.Assert(t, is.ErrorContains(err, "job compile has no artifact"))
+
+	conf.Resources["compile"].(*JobConfig).Artifact = PathGlobs{globs: []string{"dist/app"}}
+	assert.Assert(t, job.Validate(pth.NewPath(""), conf) == nil)
+	assert.Check(t, is.DeepEqual(job.Dependencies(), []string{"example", "compile"}))
+
+	job.ArtifactsFrom = []string{"example"}
+	err = job.Validate(pth.NewPath(""), conf)
+	assert.Assert(t, is.ErrorContains(err, "example is not a job resource"))
+}
+
+func TestJobConfigValidateActions(t *testing.T) {
+	job := &JobConfig{Actions: map[string]string{"coverage": "go test -cover ./..."}}
+	assert.NilError(t, job.ValidateActions())
+	command, err := job.ActionCommand("coverage")
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(command.Value(), []string{"go", "test", "-cover", "./..."}))
+
+	job.Actions = map[string]string{"start": "serve"}
+	assert.Check(t, is.ErrorContains(job.ValidateActions(),
+		`action "start" is already an action`))
+
+	job.Actions = map[string]string{"Lint": "make lint"}
+	assert.Check(t, is.ErrorContains(job.ValidateActions(), `invalid action name "Lint"`))
+
+	job.Actions = map[string]string{"lint": "make 'lint"}
+	assert.Check(t, is.ErrorContains(job.ValidateActions(), "failed to parse command"))
+}
+
+func TestJobConfigValidateRemoteCache(t *testing.T) {
+	conf := NewConfig()
+	conf.Resources["builder"] = NewImageConfig()
+	job := &JobConfig{Use: "builder", RemoteCache: true}
+
+	err := job.Validate(pth.NewPath(""), conf)
+	assert.Check(t, is.ErrorContains(err, "an artifact is required"))
+
+	job.Artifact = PathGlobs{globs: []string{"dist/"}}
+	err = job.Validate(pth.NewPath(""), conf)
+	assert.Check(t, is.ErrorContains(err, "meta.artifact-store is required"))
+
+	conf.Meta.ArtifactStore = "s3://bucket/cache"
+	assert.Check(t, is.Nil(job.Validate(pth.NewPath(""), conf)))
+}
+
+func TestJobConfigValidateUseImageFromBuilder(t *testing.T) {
+	conf := NewConfig()
+	conf.Meta.Builders = map[string][]string{"farm": {"tcp://build1:2376"}}
+	conf.Resources["builder"] = &ImageConfig{Image: "builder", Builder: "farm"}
+	job := &JobConfig{Use: "builder"}
+
+	err := job.Validate(pth.NewPath(""), conf)
+	assert.Check(t, is.ErrorContains(err, `builder is built on the hosts of builder "farm"`))
+}
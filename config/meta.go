@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/dnephin/configtf"
+	pth "github.com/dnephin/configtf/path"
 )
 
 // MetaConfig Configure **dobi** and include other config files.
@@ -38,10 +40,268 @@ type MetaConfig struct {
 	// be overridden with the ``$DOBI_EXEC_ID`` environment variable.
 	// default: ``{user.name}``
 	ExecID string `config:"exec-id"`
+
+	// UniqueExecID When **true** a random suffix is added to the ``exec-id``
+	// for each run of **dobi**, so that concurrent runs don't replace each
+	// other's containers. Job containers left behind by the run are removed
+	// when **dobi** exits.
+	UniqueExecID bool `config:"unique-exec-id"`
+
+	// Orphans The action to take for job containers left behind by an earlier
+	// run with the same ``exec-id`` that did not exit cleanly. The value may
+	// be one of:
+	// * ``remove`` - remove the containers before running any tasks
+	// * ``warn`` - log a warning and leave the containers
+	//
+	// A running container is only removed when the **dobi** process which
+	// created it was on the same host, and is no longer running.
+	// default: ``remove``
+	Orphans string `config:"validate"`
+
+	// Variables Variables used by ``{var.<name>}`` in any resource. These
+	// variables override the variables from ``.dobirc`` files and the
+	// environment, and are overridden by the ``variables`` of a resource.
+	// The values are not resolved.
+	// type: mapping ``name: value``
+	// example: ``{VERSION: 1.2.3, REGISTRY: registry.example.com}``
+	Variables map[string]string
+
+	// Matrix A mapping of names to Docker hosts. When **dobi** is run with
+	// ``--matrix`` the tasks are run once for each host, and the result for
+	// each host is reported at the end. Each value may be a host address or
+	// the name of a Docker context.
+	// type: mapping ``name: host``
+	// example: ``{amd64: 'tcp://10.0.0.2:2376', arm64: arm-builder}``
+	Matrix map[string]string
+
+	// Builders A mapping of builder names to a list of Docker hosts used to
+	// build images. An **image** with a ``builder`` is built on one of the
+	// hosts of the builder. The host is picked from the image name, so an
+	// image is built on the same host each time, and images are spread
+	// across the hosts. A host which is not available is skipped. Each host
+	// may be a host address or the name of a Docker context. An image built
+	// by a builder is not copied to the local Docker host, so it can not be
+	// used by a **job**.
+	// type: mapping ``name: [hosts]``
+	// example: ``{farm: ['tcp://build1:2376', 'tcp://build2:2376']}``
+	Builders map[string][]string `config:"validate"`
+
+	// SmallHost Settings used to scale down jobs and builds when the Docker
+	// host has limited memory or CPUs. The host is checked before any tasks
+	// are run. Use ``--host-profile`` to skip the check.
+	// type: mapping with keys ``memory-below``, ``cpus-below``,
+	// ``job-memory``, ``job-cpus``, ``skip-cache-from``, and ``max-parallel``
+	// example: ``{memory-below: 8g, job-memory: 2g, job-cpus: '1'}``
+	SmallHost HostProfile `config:"validate"`
+
+	// Teardown Settings for stopping the tasks which were started, like the
+	// containers of a **compose** resource, when **dobi** exits. The time to
+	// wait for containers to stop before they are killed is set by the
+	// ``stop-grace`` of each resource.
+	// type: mapping with keys ``order`` and ``on-error``
+	// example: ``{order: parallel, on-error: fail}``
+	Teardown Teardown `config:"validate"`
+
+	// DobiVersion The versions of **dobi** which can be used with the config.
+	// The value is a list of constraints, separated by spaces, which must
+	// all match. Each constraint is an operator (``>=``, ``<=``, ``>``,
+	// ``<``, ``=``, or ``!=``) followed by a version. **dobi** exits with an
+	// error when the running version does not match. The version is checked
+	// before the rest of the config is loaded, so a config which uses fields
+	// added in a newer version fails with an error about the version. Use
+	// ``dobi self-update`` to install a different version.
+	// example: ``'>=0.16 <0.20'``
+	DobiVersion string `config:"validate"`
+
+	// ArtifactStore The URL of remote storage used by the ``upload`` and
+	// ``download`` actions of a **job**, and by the ``remote-cache`` of a
+	// **job**. The artifact of a job is stored as
+	// ``<artifact-store>/<job>.tar.gz``, and cached artifacts are stored in
+	// ``<artifact-store>/cache/``. The URL scheme may be one of:
+	// * ``s3://`` - copied with the ``aws`` CLI
+	// * ``gs://`` - copied with the ``gsutil`` CLI
+	// * ``http://`` or ``https://`` - uploaded with ``PUT`` and downloaded
+	//   with ``GET``
+	// This field supports :doc:`variables`.
+	// example: ``s3://ci-artifacts/{project}/{git.sha}``
+	ArtifactStore string `config:"validate"`
+
+	// ArtifactRetention Settings used by ``dobi gc`` to remove old entries
+	// from the artifact directories of jobs. A directory is an artifact
+	// directory when it is the ``artifact`` of a job, or when it contains the
+	// files matched by an ``artifact`` glob.
+	// type: mapping with keys ``keep-last``, ``max-size``, and ``max-age``
+	// example: ``{keep-last: 5, max-size: 2g, max-age: 30d}``
+	ArtifactRetention ArtifactRetention `config:"validate"`
+
+	// EventsURL An HTTP endpoint which receives the lifecycle events of a
+	// run as JSON ``POST`` requests. An event is sent when the run starts,
+	// when each task starts, when each task finishes, is skipped, or fails,
+	// and when the run finishes. A request which fails is retried. This
+	// field supports :doc:`variables`.
+	// example: ``https://dashboard.example.com/hooks/dobi``
+	EventsURL string `config:"validate"`
+
+	// EventsSecret A secret used to sign the events sent to ``events-url``.
+	// The ``X-Dobi-Signature`` header of each request is ``sha256=`` followed
+	// by the hex encoded HMAC-SHA256 of the request body. This field supports
+	// :doc:`variables`.
+	// example: ``'{env.DOBI_EVENTS_SECRET}'``
+	EventsSecret string
+
+	// Notify Settings for a notification sent to a webhook, like a Slack
+	// incoming webhook, when the run finishes. The notification includes the
+	// status of the run and a summary of the tasks. A notification which
+	// fails to send is logged as a warning.
+	// type: mapping with keys ``url``, ``on``, and ``template``
+	// example: ``{url: '{env.SLACK_WEBHOOK_URL}', on: failure}``
+	Notify Notify `config:"validate"`
+
+	// VariableProviders Settings for the secret stores used by the
+	// ``{vault:<path>#<field>}``, ``{ssm:<name>}``, and
+	// ``{gcp:<secret>[#<version>]}`` variables. Values from a secret store are
+	// read once per run, and are masked in the log output.
+	// type: mapping with keys ``vault``, ``ssm``, and ``gcp``
+	// example: ``{vault: {address: 'https://vault:8200'}, ssm: {region: us-east-1}}``
+	VariableProviders VariableProviders
+
+	// PullMirrors Registry mirrors of Docker Hub which are used when pulling
+	// an image from Docker Hub fails because of a rate limit or a temporary
+	// error. The mirrors are tried in order, and the image pulled from a
+	// mirror is tagged with the Docker Hub name.
+	// type: list of registry hosts
+	// example: ``[mirror.gcr.io, 'registry.internal:5000']``
+	PullMirrors []string
+
+	// Policy Commands which check the task plan before any tasks are run.
+	// Each command is run with ``sh -c`` and receives the plan as JSON on
+	// stdin. The plan includes the tasks to run, and the resolved config of
+	// the images, jobs, and mounts used by those tasks. A command rejects the
+	// plan by exiting with a non-zero status, and the output of the command
+	// is used as the reason. A command may change the plan by printing the
+	// modified plan to stdout. Only the ``image`` of an image, the
+	// ``privileged``, ``user``, ``net-mode``, and ``cap-add`` of a job, and
+	// the ``read-only`` of a mount can be changed. The run fails if a field
+	// of one of those resources uses a variable which can not be resolved
+	// before the tasks run, like a variable set by an `env`_ resource.
+	// type: list of shell commands
+	// example: ``['./scripts/check-policy', './scripts/check-images']``
+	Policy []string
+}
+
+// ValidateArtifactStore validates the scheme of the artifact store URL
+func (m *MetaConfig) ValidateArtifactStore() error {
+	if m.ArtifactStore == "" {
+		return nil
+	}
+	switch scheme := strings.SplitN(m.ArtifactStore, "://", 2)[0]; scheme {
+	case "s3", "gs", "http", "https":
+		return nil
+	default:
+		return fmt.Errorf(
+			"invalid artifact-store %q, the scheme must be one of: s3, gs, http, https",
+			m.ArtifactStore)
+	}
+}
+
+// ValidateEventsURL validates the scheme of the events URL
+func (m *MetaConfig) ValidateEventsURL() error {
+	if m.EventsURL == "" {
+		return nil
+	}
+	switch scheme := strings.SplitN(m.EventsURL, "://", 2)[0]; scheme {
+	case "http", "https":
+		return nil
+	default:
+		return fmt.Errorf(
+			"invalid events-url %q, the scheme must be one of: http, https", m.EventsURL)
+	}
+}
+
+// ValidateNotify validates the notify settings
+func (m *MetaConfig) ValidateNotify() error {
+	if err := m.Notify.Validate(); err != nil {
+		return fmt.Errorf("invalid notify: %s", err)
+	}
+	return nil
+}
+
+// ValidateDobiVersion validates the version constraints
+func (m *MetaConfig) ValidateDobiVersion() error {
+	if m.DobiVersion == "" {
+		return nil
+	}
+	_, err := parseVersionConstraints(m.DobiVersion)
+	return err
+}
+
+// CheckVersion returns an error if version does not match DobiVersion
+func (m *MetaConfig) CheckVersion(version string) error {
+	if m.DobiVersion == "" {
+		return nil
+	}
+	ok, err := VersionSatisfies(version, m.DobiVersion)
+	switch {
+	case err != nil:
+		return err
+	case !ok:
+		return fmt.Errorf(
+			"dobi version %s does not match meta.dobi-version %q, "+
+				"use 'dobi self-update' to install a matching version",
+			version, m.DobiVersion)
+	}
+	return nil
+}
+
+// ValidateBuilders validates that each builder has at least one host
+func (m *MetaConfig) ValidateBuilders() error {
+	for name, hosts := range m.Builders {
+		if len(hosts) == 0 {
+			return fmt.Errorf("builder %q must have at least one host", name)
+		}
+	}
+	return nil
+}
+
+// ValidateSmallHost validates the small host profile
+func (m *MetaConfig) ValidateSmallHost() error {
+	if err := m.SmallHost.Validate(); err != nil {
+		return fmt.Errorf("invalid small-host: %s", err)
+	}
+	return nil
+}
+
+// ValidateTeardown validates the teardown settings
+func (m *MetaConfig) ValidateTeardown() error {
+	if err := m.Teardown.Validate(); err != nil {
+		return fmt.Errorf("invalid teardown: %s", err)
+	}
+	return nil
+}
+
+// ValidateArtifactRetention validates the artifact retention settings
+func (m *MetaConfig) ValidateArtifactRetention() error {
+	if err := m.ArtifactRetention.Validate(); err != nil {
+		return fmt.Errorf("invalid artifact-retention: %s", err)
+	}
+	return nil
+}
+
+// ValidateOrphans validates the orphans action
+func (m *MetaConfig) ValidateOrphans() error {
+	switch m.Orphans {
+	case "", "remove", "warn":
+		return nil
+	default:
+		return fmt.Errorf("invalid orphans %q, must be one of: remove, warn", m.Orphans)
+	}
 }
 
 // Validate the MetaConfig
 func (m *MetaConfig) Validate(config *Config) error {
+	if err := configtf.ValidateFields(pth.NewPath("meta"), m); err != nil {
+		return err
+	}
 	if _, ok := config.Resources[m.Default]; m.Default != "" && !ok {
 		return fmt.Errorf("undefined default resource: %s", m.Default)
 	}
@@ -54,7 +314,13 @@ func (m *MetaConfig) Validate(config *Config) error {
 // IsZero returns true if the struct contains only zero values, except for
 // Includes which is ignored
 func (m *MetaConfig) IsZero() bool {
-	return m.Default == "" && m.Project == "" && m.ExecID == ""
+	return m.Default == "" && m.Project == "" && m.ExecID == "" && m.Orphans == "" &&
+		len(m.Matrix) == 0 && len(m.Variables) == 0 && len(m.Builders) == 0 &&
+		m.SmallHost.IsZero() && m.Teardown.IsZero() && m.DobiVersion == "" &&
+		!m.UniqueExecID &&
+		m.ArtifactStore == "" && m.ArtifactRetention.IsZero() && len(m.Policy) == 0 &&
+		m.EventsURL == "" && m.EventsSecret == "" && m.VariableProviders.IsZero() &&
+		m.Notify.IsZero()
 }
 
 // NewMetaConfig returns a new MetaConfig from config values
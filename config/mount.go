@@ -1,15 +1,18 @@
 package config
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"path"
+	"path/filepath"
 
 	"github.com/dnephin/configtf"
 	pth "github.com/dnephin/configtf/path"
 	"github.com/dnephin/dobi/utils/fs"
 )
 
-// MountConfig A **mount** resource creates a host bind mount or named volume
-// mount.
+// MountConfig A **mount** resource creates a host bind mount, named volume
+// mount, or tmpfs mount.
 // name: mount
 // example: A mount named ``source`` that mounts the current host directory as
 // ``/app/code`` in the container.
@@ -24,22 +27,56 @@ import (
 //         name: app-data
 //         path: /data
 //
+//     mount=scratch:
+//         tmpfs: true
+//         path: /tmp
+//
+//     mount=app-config:
+//         path: /etc/app/config.ini
+//         content: |
+//             [server]
+//             version = {git.sha}
+//
 type MountConfig struct {
 	// Bind The host path to create and mount. This field supports expansion of
-	// `~` to the current users home directory.
+	// `~` to the current users home directory. With Docker Desktop for Mac the
+	// path must be in one of the file sharing directories. Windows paths, and
+	// WSL paths used with a Docker host set by ``DOCKER_HOST``, are converted
+	// to the ``/c/path`` form.
 	Bind string
 	// Path The container path of the mount
 	Path string `config:"required"`
-	// Name The name of a named volume
+	// Name The name of a named volume. The volume is created by the
+	// ``create`` action and removed by the ``remove`` action.
 	Name string
+	// Tmpfs When true mount a tmpfs at ``path``. The tmpfs is created with
+	// the container and removed when the container is removed.
+	Tmpfs bool
 	// ReadOnly Set the mount to be read-only
 	ReadOnly bool
+	// Consistency The consistency requirement for a bind mount. The value may be
+	// one of ``consistent``, ``cached``, or ``delegated``. This setting is
+	// only used by Docker for Mac, and is ignored on other platforms.
+	// default: ``consistent``
+	Consistency string `config:"validate"`
 	// File When true create an empty file instead of a directory
 	File bool
+	// Content The content of a file to mount at ``path``. Variables in the
+	// content are resolved, and the file is written to ``.dobi/mounts/``
+	// and mounted read-only. ``content`` can not be used with ``bind``,
+	// ``name``, or ``tmpfs``.
+	Content string
 	// Mode The file mode to set on the host file or directory when it is
 	// created.
 	// default: ``0755`` *(for directories)*, ``0644`` *(for files)*
 	Mode int `config:"validate"`
+	// Copy When false, and dobi is run with ``--no-bind-mount``, a bind mount
+	// is replaced by a named volume instead of being copied into the
+	// container. Use this for large directories, like caches, which don't
+	// need the files from the host. Artifacts are still copied out of the
+	// container.
+	// default: ``true``
+	Copy bool
 	Annotations
 }
 
@@ -53,22 +90,30 @@ func (c *MountConfig) Validate(path pth.Path, config *Config) *pth.Error {
 	switch {
 	case c.Bind != "" && c.Name != "":
 		return pth.Errorf(path, "\"name\" and \"bind\" can not be used together")
-	case c.Bind == "" && c.Name == "":
-		return pth.Errorf(path, "One of \"name\" or \"bind\" must be set")
+	case c.Tmpfs && (c.Bind != "" || c.Name != ""):
+		return pth.Errorf(path, "\"tmpfs\" can not be used with \"name\" or \"bind\"")
+	case c.Content != "" && (c.Bind != "" || c.Name != "" || c.Tmpfs):
+		return pth.Errorf(path,
+			"\"content\" can not be used with \"name\", \"bind\", or \"tmpfs\"")
+	case c.Bind == "" && c.Name == "" && !c.Tmpfs && c.Content == "":
+		return pth.Errorf(path,
+			"One of \"name\", \"bind\", \"tmpfs\", or \"content\" must be set")
 	case c.Name != "" && c.Mode != 0:
 		return pth.Errorf(path, "\"mode\" can not be used with named volumes")
 	case c.Name != "" && c.File:
 		return pth.Errorf(path, "\"file\" can not be used with named volumes")
+	case c.Tmpfs && c.File:
+		return pth.Errorf(path, "\"file\" can not be used with tmpfs")
 	}
 	return nil
 }
 
 // ValidateMode validates Mode and sets a default
 func (c *MountConfig) ValidateMode() error {
-	if c.Mode != 0 || c.Name != "" {
+	if c.Mode != 0 || c.Name != "" || c.Tmpfs {
 		return nil
 	}
-	switch c.File {
+	switch c.File || c.Content != "" {
 	case true:
 		c.Mode = 0644
 	default:
@@ -77,6 +122,21 @@ func (c *MountConfig) ValidateMode() error {
 	return nil
 }
 
+// ValidateConsistency validates the Consistency value
+func (c *MountConfig) ValidateConsistency() error {
+	switch c.Consistency {
+	case "", "consistent", "cached", "delegated":
+	default:
+		return fmt.Errorf(
+			"invalid consistency %q, must be one of consistent, cached, delegated",
+			c.Consistency)
+	}
+	if c.Consistency != "" && !c.IsBind() {
+		return fmt.Errorf("consistency can only be used with bind mounts")
+	}
+	return nil
+}
+
 func (c *MountConfig) String() string {
 	var mount string
 	switch {
@@ -84,6 +144,8 @@ func (c *MountConfig) String() string {
 		mount = fmt.Sprintf("file %q", c.Bind)
 	case c.Name != "":
 		mount = "named volume"
+	case c.Tmpfs:
+		mount = "tmpfs"
 	default:
 		mount = fmt.Sprintf("directory %q", c.Bind)
 	}
@@ -107,6 +169,16 @@ func (c *MountConfig) Resolve(resolver Resolver) (Resource, error) {
 	if err != nil {
 		return &conf, err
 	}
+	if c.Content != "" {
+		conf.Content, err = resolver.Resolve(c.Content)
+		if err != nil {
+			return &conf, err
+		}
+		conf.Bind = contentFilePath(conf.Path, conf.Content)
+		conf.File = true
+		conf.ReadOnly = true
+		return &conf, nil
+	}
 	bind, err := resolver.Resolve(c.Bind)
 	if err != nil {
 		return &conf, err
@@ -115,8 +187,16 @@ func (c *MountConfig) Resolve(resolver Resolver) (Resource, error) {
 	return &conf, err
 }
 
+// contentFilePath returns the host path of the file written for a mount with
+// content. The filename includes a hash of the content, so a change to the
+// content creates a new file.
+func contentFilePath(containerPath string, content string) string {
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+	return filepath.Join(".dobi", "mounts", hash[:12]+"-"+path.Base(containerPath))
+}
+
 func mountFromConfig(name string, values map[string]interface{}) (Resource, error) {
-	mount := &MountConfig{}
+	mount := &MountConfig{Copy: true}
 	return mount, configtf.Transform(name, values, mount)
 }
 
@@ -3,9 +3,12 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	pth "github.com/dnephin/configtf/path"
 	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
 )
 
 func TestResolveBind(t *testing.T) {
@@ -21,3 +24,78 @@ func TestResolveBind(t *testing.T) {
 	expected := filepath.Join(os.Getenv("HOME"), "bar")
 	assert.Equal(t, res.(*MountConfig).Bind, expected)
 }
+
+func TestResolveContent(t *testing.T) {
+	resolver := newFakeResolver(map[string]string{
+		"version = {git.sha}": "version = abcd",
+	})
+	mount := &MountConfig{Path: "/etc/app/config.ini", Content: "version = {git.sha}"}
+
+	res, err := mount.Resolve(resolver)
+	assert.NilError(t, err)
+	resolved := res.(*MountConfig)
+	assert.Check(t, is.Equal(resolved.Content, "version = abcd"))
+	expected := contentFilePath("/etc/app/config.ini", "version = abcd")
+	assert.Check(t, is.Equal(resolved.Bind, expected))
+	assert.Check(t, strings.HasSuffix(resolved.Bind, "-config.ini"))
+	assert.Check(t, resolved.File)
+	assert.Check(t, resolved.ReadOnly)
+}
+
+func TestMountConfigValidate(t *testing.T) {
+	var testcases = []struct {
+		doc         string
+		mount       *MountConfig
+		expectedErr string
+	}{
+		{
+			doc:   "tmpfs",
+			mount: &MountConfig{Path: "/tmp", Tmpfs: true},
+		},
+		{
+			doc:         "tmpfs with bind",
+			mount:       &MountConfig{Path: "/tmp", Bind: ".", Tmpfs: true},
+			expectedErr: `"tmpfs" can not be used with "name" or "bind"`,
+		},
+		{
+			doc:         "missing source",
+			mount:       &MountConfig{Path: "/tmp"},
+			expectedErr: `One of "name", "bind", "tmpfs", or "content" must be set`,
+		},
+		{
+			doc:   "content",
+			mount: &MountConfig{Path: "/etc/app.ini", Content: "debug = true"},
+		},
+		{
+			doc: "content with bind",
+			mount: &MountConfig{
+				Path:    "/etc/app.ini",
+				Bind:    "app.ini",
+				Content: "debug = true",
+			},
+			expectedErr: `"content" can not be used with "name", "bind", or "tmpfs"`,
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.doc, func(t *testing.T) {
+			err := testcase.mount.Validate(pth.NewPath("."), NewConfig())
+			if testcase.expectedErr != "" {
+				assert.Assert(t, is.ErrorContains(err, testcase.expectedErr))
+				return
+			}
+			assert.Assert(t, err == nil)
+		})
+	}
+}
+
+func TestMountConfigValidateConsistency(t *testing.T) {
+	mount := &MountConfig{Bind: ".", Path: "/app", Consistency: "delegated"}
+	assert.NilError(t, mount.ValidateConsistency())
+
+	mount.Consistency = "bogus"
+	assert.Check(t, is.ErrorContains(mount.ValidateConsistency(), `invalid consistency "bogus"`))
+
+	mount = &MountConfig{Name: "data", Path: "/data", Consistency: "cached"}
+	assert.Check(t, is.ErrorContains(mount.ValidateConsistency(),
+		"consistency can only be used with bind mounts"))
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	// NotifyAlways sends a notification when a run finishes
+	NotifyAlways = "always"
+	// NotifyFailure sends a notification only when a run fails
+	NotifyFailure = "failure"
+	// NotifySuccess sends a notification only when a run succeeds
+	NotifySuccess = "success"
+)
+
+// Notify settings for the notification sent when a run of **dobi** finishes.
+// The notification is a ``POST`` request with a JSON body, which by default is
+// a Slack-compatible message with the status of the run and a summary of the
+// tasks.
+type Notify struct {
+	// URL The webhook URL which receives the notification. This field
+	// supports :doc:`variables`.
+	URL string `config:"url"`
+	// On When to send the notification. The value may be one of:
+	// * ``always`` - send a notification when the run finishes
+	// * ``failure`` - send a notification only when the run fails
+	// * ``success`` - send a notification only when the run succeeds
+	// default: ``always``
+	On string
+	// Template A Go template used to render the body of the request. The
+	// template is rendered with the fields ``.Project``, ``.Endpoint``,
+	// ``.Status`` (``success`` or ``failure``), ``.Error``, ``.Tasks`` (a
+	// list with ``.Name``, ``.Status``, ``.Duration``, and ``.Error``),
+	// ``.Summary`` (the table printed by ``--summary``), and ``.Text`` (the
+	// text of the default message). Use the ``json`` function to quote a
+	// value as a JSON string.
+	// default: ``{"text": {{ json .Text }}}``
+	Template string
+}
+
+// IsZero returns true if the notify has no settings
+func (n Notify) IsZero() bool {
+	return n == Notify{}
+}
+
+// Validate the notify values
+func (n Notify) Validate() error {
+	if n.IsZero() {
+		return nil
+	}
+	if n.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	switch scheme := strings.SplitN(n.URL, "://", 2)[0]; scheme {
+	case "http", "https":
+	default:
+		return fmt.Errorf("invalid url %q, the scheme must be one of: http, https", n.URL)
+	}
+	switch n.On {
+	case "", NotifyAlways, NotifyFailure, NotifySuccess:
+	default:
+		return fmt.Errorf("invalid on %q, must be one of: %s, %s, %s",
+			n.On, NotifyAlways, NotifyFailure, NotifySuccess)
+	}
+	if n.Template != "" {
+		_, err := template.New("notify").Funcs(NotifyTemplateFuncs).Parse(n.Template)
+		if err != nil {
+			return fmt.Errorf("invalid template: %s", err)
+		}
+	}
+	return nil
+}
+
+// ShouldSend returns true if a notification is sent for a run which failed
+// or succeeded
+func (n Notify) ShouldSend(failed bool) bool {
+	switch n.On {
+	case NotifyFailure:
+		return failed
+	case NotifySuccess:
+		return !failed
+	default:
+		return true
+	}
+}
+
+// NotifyTemplateFuncs are the functions available in a notify template
+var NotifyTemplateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		raw, err := json.Marshal(value)
+		return string(raw), err
+	},
+}
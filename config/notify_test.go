@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestNotifyValidate(t *testing.T) {
+	for _, notify := range []Notify{
+		{},
+		{URL: "https://hooks.slack.com/services/T0/B0/x"},
+		{
+			URL:      "http://localhost:8080/hook",
+			On:       NotifyFailure,
+			Template: `{"status": {{ json .Status }}}`,
+		},
+	} {
+		assert.Check(t, notify.Validate(), "%+v", notify)
+	}
+
+	notify := Notify{On: NotifyFailure}
+	assert.Check(t, is.ErrorContains(notify.Validate(), "url is required"))
+
+	notify = Notify{URL: "ftp://example.com"}
+	assert.Check(t, is.ErrorContains(notify.Validate(), `invalid url "ftp://example.com"`))
+
+	notify = Notify{URL: "https://example.com", On: "sometimes"}
+	assert.Check(t, is.ErrorContains(notify.Validate(), `invalid on "sometimes"`))
+
+	notify = Notify{URL: "https://example.com", Template: "{{ .Status"}
+	assert.Check(t, is.ErrorContains(notify.Validate(), "invalid template"))
+}
+
+func TestNotifyShouldSend(t *testing.T) {
+	assert.Check(t, Notify{}.ShouldSend(true))
+	assert.Check(t, Notify{On: NotifyAlways}.ShouldSend(false))
+	assert.Check(t, Notify{On: NotifyFailure}.ShouldSend(true))
+	assert.Check(t, !Notify{On: NotifyFailure}.ShouldSend(false))
+	assert.Check(t, Notify{On: NotifySuccess}.ShouldSend(false))
+	assert.Check(t, !Notify{On: NotifySuccess}.ShouldSend(true))
+}
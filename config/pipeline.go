@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dnephin/configtf"
+	pth "github.com/dnephin/configtf/path"
+)
+
+// stageActionPrefix is the prefix of the action which runs a stage of a
+// pipeline
+const stageActionPrefix = "stage-"
+
+// PipelineConfig A **pipeline** resource runs stages of tasks in order. Each
+// stage is a single task, or a list of tasks which are run at the same time.
+// A stage starts after every task in the previous stage is complete.
+// example: A pipeline which runs the tests and the linter at the same time,
+// and then publishes to a channel:
+//
+// .. code-block:: yaml
+//
+//     pipeline=release:
+//         params: {channel: stable}
+//         stages:
+//           - [test, lint]
+//           - build
+//           - publish
+//
+// Run the pipeline with ``dobi release channel=beta``.
+//
+// name: pipeline
+type PipelineConfig struct {
+	// Stages The stages of the pipeline, which are run in order.
+	// type: list of tasks, or lists of tasks
+	// example: ``[[test, lint], build, publish]``
+	Stages []PipelineStage `config:"required"`
+	// Params Parameters accepted by the pipeline. Each parameter is
+	// available to the tasks in the pipeline as the ``{param.<name>}``
+	// variable. The value in the mapping is the default value of the
+	// parameter. Parameters with an empty default value must be set from the
+	// command line using ``name=value`` arguments.
+	// type: mapping ``name: default``
+	Params map[string]string
+	Annotations
+}
+
+// PipelineStage is a list of tasks which are run at the same time
+type PipelineStage struct {
+	Tasks []string
+}
+
+// TransformConfig from a task name, or a list of task names
+func (s *PipelineStage) TransformConfig(raw reflect.Value) error {
+	if !raw.IsValid() {
+		return fmt.Errorf("must be a task or a list of tasks, was undefined")
+	}
+
+	switch value := raw.Interface().(type) {
+	case string:
+		s.Tasks = []string{value}
+	case []interface{}:
+		for _, item := range value {
+			name, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("item %v must be a string, not %T", item, item)
+			}
+			s.Tasks = append(s.Tasks, name)
+		}
+	default:
+		return fmt.Errorf("must be a task or a list of tasks, not %T", value)
+	}
+	return nil
+}
+
+// Dependencies returns the tasks of every stage
+func (c *PipelineConfig) Dependencies() []string {
+	deps := []string{}
+	for _, stage := range c.Stages {
+		deps = append(deps, stage.Tasks...)
+	}
+	return deps
+}
+
+// Validate the resource
+func (c *PipelineConfig) Validate(path pth.Path, config *Config) *pth.Error {
+	stagesPath := path.Add("stages")
+	for i, stage := range c.Stages {
+		if len(stage.Tasks) == 0 {
+			return pth.Errorf(stagesPath.Add(strconv.Itoa(i)), "a stage requires a task")
+		}
+	}
+	return nil
+}
+
+func (c *PipelineConfig) String() string {
+	stages := []string{}
+	for _, stage := range c.Stages {
+		stages = append(stages, strings.Join(stage.Tasks, " + "))
+	}
+	return fmt.Sprintf("Run stages: %v", strings.Join(stages, ", "))
+}
+
+// Resolve resolves variables in the resource
+func (c *PipelineConfig) Resolve(_ Resolver) (Resource, error) {
+	copy := *c
+	return &copy, nil
+}
+
+// StageAction returns the name of the action which runs the stage at index
+func StageAction(index int) string {
+	return stageActionPrefix + strconv.Itoa(index+1)
+}
+
+// Stage returns the index of the stage run by the action, and false if the
+// action does not run a stage of the pipeline
+func (c *PipelineConfig) Stage(action string) (int, bool) {
+	if !strings.HasPrefix(action, stageActionPrefix) {
+		return 0, false
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(action, stageActionPrefix))
+	if err != nil || index < 1 || index > len(c.Stages) {
+		return 0, false
+	}
+	return index - 1, true
+}
+
+func pipelineFromConfig(name string, values map[string]interface{}) (Resource, error) {
+	pipeline := &PipelineConfig{}
+	return pipeline, configtf.Transform(name, values, pipeline)
+}
+
+func init() {
+	RegisterResource("pipeline", pipelineFromConfig)
+}
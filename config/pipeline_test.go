@@ -0,0 +1,41 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestPipelineStageTransformConfig(t *testing.T) {
+	stage := PipelineStage{}
+	assert.NilError(t, stage.TransformConfig(reflect.ValueOf("build")))
+	assert.Check(t, is.DeepEqual(stage.Tasks, []string{"build"}))
+
+	stage = PipelineStage{}
+	raw := []interface{}{"test", "lint"}
+	assert.NilError(t, stage.TransformConfig(reflect.ValueOf(raw)))
+	assert.Check(t, is.DeepEqual(stage.Tasks, []string{"test", "lint"}))
+
+	stage = PipelineStage{}
+	err := stage.TransformConfig(reflect.ValueOf([]interface{}{"test", 3}))
+	assert.Check(t, is.ErrorContains(err, "must be a string"))
+}
+
+func TestPipelineConfigStage(t *testing.T) {
+	pipeline := &PipelineConfig{Stages: []PipelineStage{
+		{Tasks: []string{"build"}},
+		{Tasks: []string{"test", "lint"}},
+	}}
+	assert.Check(t, is.DeepEqual(pipeline.Dependencies(), []string{"build", "test", "lint"}))
+
+	index, ok := pipeline.Stage(StageAction(1))
+	assert.Check(t, ok)
+	assert.Check(t, is.Equal(index, 1))
+
+	for _, action := range []string{"run", "stage-0", "stage-3", "stage-x"} {
+		_, ok := pipeline.Stage(action)
+		assert.Check(t, !ok, action)
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+
+	units "github.com/docker/go-units"
+)
+
+// HostProfile settings which are applied when **dobi** runs against a small
+// Docker host. A host is small when it has less memory than ``memory-below``
+// or fewer CPUs than ``cpus-below``.
+type HostProfile struct {
+	// MemoryBelow Hosts with less total memory than this value are small.
+	// example: ``8g``
+	MemoryBelow string
+	// CpusBelow Hosts with fewer CPUs than this value are small.
+	CpusBelow int
+	// JobMemory The memory limit for jobs which don't set ``memory``.
+	JobMemory string
+	// JobCpus The CPU limit for jobs which don't set ``cpus``.
+	JobCpus string
+	// SkipCacheFrom Ignore ``cache-from`` when building images, to avoid
+	// pulling large cache images.
+	SkipCacheFrom bool
+	// MaxParallel The maximum number of tasks of a parallel alias or stage
+	// which run at the same time. The limit is also lowered to the number
+	// of CPUs of the Docker host which are not busy.
+	// default: the number of CPUs
+	MaxParallel int
+}
+
+// IsZero returns true if the profile has no settings
+func (p HostProfile) IsZero() bool {
+	return p == HostProfile{}
+}
+
+// Validate the profile values
+func (p HostProfile) Validate() error {
+	for _, size := range []string{p.MemoryBelow, p.JobMemory} {
+		if _, err := parseMemory(size); err != nil {
+			return err
+		}
+	}
+	if p.MaxParallel < 0 {
+		return fmt.Errorf("invalid max-parallel %d, must not be negative", p.MaxParallel)
+	}
+	_, err := parseCpus(p.JobCpus)
+	return err
+}
+
+// IsSmall returns true if a host with the memory and CPUs matches the profile
+func (p HostProfile) IsSmall(memory int64, cpus int) bool {
+	memoryBelow, _ := parseMemory(p.MemoryBelow)
+	switch {
+	case memoryBelow > 0 && memory < memoryBelow:
+		return true
+	case p.CpusBelow > 0 && cpus < p.CpusBelow:
+		return true
+	default:
+		return false
+	}
+}
+
+// JobMemoryBytes returns JobMemory as a number of bytes
+func (p HostProfile) JobMemoryBytes() int64 {
+	// JobMemory is validated when the config is loaded
+	size, _ := parseMemory(p.JobMemory)
+	return size
+}
+
+// JobNanoCpus returns JobCpus as a number of nano CPUs
+func (p HostProfile) JobNanoCpus() int64 {
+	// JobCpus is validated when the config is loaded
+	cpus, _ := parseCpus(p.JobCpus)
+	return cpus
+}
+
+func parseMemory(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	size, err := units.RAMInBytes(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory %q: %s", value, err)
+	}
+	return size, nil
+}
+
+func parseCpus(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	cpus, err := strconv.ParseFloat(value, 64)
+	if err != nil || cpus <= 0 {
+		return 0, fmt.Errorf("invalid cpus %q, must be a positive number", value)
+	}
+	return int64(cpus * 1e9), nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestMetaConfigSmallHostFromConfig(t *testing.T) {
+	meta, err := NewMetaConfig("meta", map[string]interface{}{
+		"small-host": map[string]interface{}{
+			"memory-below": "8g",
+			"cpus-below":   4,
+			"job-memory":   "2g",
+			"job-cpus":     "1.5",
+		},
+	})
+	assert.NilError(t, err)
+	expected := HostProfile{MemoryBelow: "8g", CpusBelow: 4, JobMemory: "2g", JobCpus: "1.5"}
+	assert.Check(t, is.DeepEqual(meta.SmallHost, expected))
+	assert.Check(t, is.Equal(meta.SmallHost.JobMemoryBytes(), int64(2*1024*1024*1024)))
+	assert.Check(t, is.Equal(meta.SmallHost.JobNanoCpus(), int64(1500000000)))
+}
+
+func TestMetaConfigSmallHostInvalid(t *testing.T) {
+	meta, err := NewMetaConfig("meta", map[string]interface{}{
+		"small-host": map[string]interface{}{"job-cpus": "lots"},
+	})
+	assert.NilError(t, err)
+	err = meta.Validate(NewConfig())
+	assert.Check(t, is.ErrorContains(err, `invalid cpus "lots"`))
+}
+
+func TestHostProfileIsSmall(t *testing.T) {
+	profile := HostProfile{MemoryBelow: "8g", CpusBelow: 4}
+	gig := int64(1024 * 1024 * 1024)
+
+	assert.Check(t, profile.IsSmall(4*gig, 8))
+	assert.Check(t, profile.IsSmall(16*gig, 2))
+	assert.Check(t, !profile.IsSmall(16*gig, 8))
+	assert.Check(t, !HostProfile{}.IsSmall(gig, 1))
+}
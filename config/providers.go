@@ -0,0 +1,50 @@
+package config
+
+// VariableProviders settings for the secret stores used to resolve the
+// ``{vault:...}``, ``{ssm:...}``, and ``{gcp:...}`` variables. Each setting
+// supports :doc:`variables`.
+type VariableProviders struct {
+	// Vault Settings for HashiCorp Vault.
+	// type: mapping with keys ``address``, ``token``, and ``namespace``
+	Vault VaultProvider
+	// SSM Settings for AWS Systems Manager Parameter Store.
+	// type: mapping with keys ``region`` and ``profile``
+	SSM SSMProvider `config:"ssm"`
+	// GCP Settings for GCP Secret Manager.
+	// type: mapping with key ``project``
+	GCP GCPProvider `config:"gcp"`
+}
+
+// VaultProvider settings for reading secrets from HashiCorp Vault
+type VaultProvider struct {
+	// Address The address of the Vault server.
+	// default: ``$VAULT_ADDR``
+	Address string
+	// Token The token used to authenticate with Vault.
+	// default: ``$VAULT_TOKEN``, or the contents of ``~/.vault-token``
+	Token string
+	// Namespace The Vault Enterprise namespace.
+	// default: ``$VAULT_NAMESPACE``
+	Namespace string
+}
+
+// SSMProvider settings for reading parameters from AWS Systems Manager
+// Parameter Store with the ``aws`` CLI
+type SSMProvider struct {
+	// Region The AWS region.
+	Region string
+	// Profile The AWS CLI profile.
+	Profile string
+}
+
+// GCPProvider settings for reading secrets from GCP Secret Manager with the
+// ``gcloud`` CLI
+type GCPProvider struct {
+	// Project The GCP project which contains the secrets.
+	Project string
+}
+
+// IsZero returns true if none of the providers have settings
+func (p VariableProviders) IsZero() bool {
+	return p == VariableProviders{}
+}
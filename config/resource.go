@@ -1,6 +1,8 @@
 package config
 
 import (
+	"path/filepath"
+
 	pth "github.com/dnephin/configtf/path"
 	"github.com/dnephin/dobi/logging"
 	"github.com/pkg/errors"
@@ -16,12 +18,64 @@ type Resource interface {
 	String() string
 }
 
+// ResourceType returns the name of the type of the resource, as it is used in
+// the config file
+func ResourceType(res Resource) string {
+	switch res.(type) {
+	case *AliasConfig:
+		return "alias"
+	case *CheckoutConfig:
+		return "checkout"
+	case *ComposeConfig:
+		return "compose"
+	case *EnvConfig:
+		return "env"
+	case *ImageConfig:
+		return "image"
+	case *JobConfig:
+		return "job"
+	case *MountConfig:
+		return "mount"
+	case *PipelineConfig:
+		return "pipeline"
+	case *TemplateConfig:
+		return "template"
+	default:
+		return "unknown"
+	}
+}
+
+// resourceTypeAliases are other names for a resource type
+var resourceTypeAliases = map[string]string{
+	"git": "checkout",
+}
+
+// IsResourceType returns true if resType is the type of the resource, or
+// another name for the type
+func IsResourceType(res Resource, resType string) bool {
+	if alias, ok := resourceTypeAliases[resType]; ok {
+		resType = alias
+	}
+	return ResourceType(res) == resType
+}
+
 // Annotations provides a description and tags to a resource
 type Annotations struct {
 	// Description of a resource
 	// Deprecated use Annotations.Description
 	Description string `config:"validate"`
 	Annotations AnnotationFields
+	// Variables Variables used by ``{var.<name>}`` in the fields of the
+	// resource. These variables override the variables from
+	// ``meta.variables``. Not supported by **env** resources, which use
+	// ``variables`` for environment variables.
+	// type: mapping ``name: value``
+	// example: ``{VERSION: 2.0.0}``
+	Variables map[string]string
+	// AllowFailure If **true** a failure of a task of the resource is
+	// reported in the summary, but does not stop the run. Tasks which depend
+	// on the resource are still run.
+	AllowFailure bool
 }
 
 // Describe returns the resource description
@@ -33,11 +87,40 @@ func (a *Annotations) Describe() string {
 	return a.Description
 }
 
+// ResourceVariables returns the variables of the resource
+func (a *Annotations) ResourceVariables() map[string]string {
+	return a.Variables
+}
+
+// AllowsFailure returns true if a failure of the resource does not stop the
+// run
+func (a *Annotations) AllowsFailure() bool {
+	return a.AllowFailure
+}
+
+// IsService returns true if the resource is started by dobi up
+func (a *Annotations) IsService() bool {
+	return a.Annotations.Service
+}
+
 // CategoryTags tags returns the list of tags
 func (a *Annotations) CategoryTags() []string {
 	return a.Annotations.Tags
 }
 
+// ResourceDir returns the directory used to resolve relative paths of the
+// resource
+func (a *Annotations) ResourceDir(projectDir string) string {
+	switch dir := a.Annotations.WorkingDir; {
+	case dir == "":
+		return projectDir
+	case filepath.IsAbs(dir):
+		return dir
+	default:
+		return filepath.Join(projectDir, dir)
+	}
+}
+
 // ValidateDescription prints a warning if set
 func (a *Annotations) ValidateDescription() error {
 	if a.Description != "" && a.Annotations.Description != "" {
@@ -60,13 +143,32 @@ type AnnotationFields struct {
 	// multiple tags per resource. Adding a tag to a resource outputs a
 	// grouped list from ``dobi list -g``.
 	Tags []string
+	// WorkingDir The directory used to resolve the relative paths of the
+	// resource, including the bind paths of the mounts used by a job.
+	// Relative values are relative to the ``dobi.yaml``.
+	// default: *the directory of the* ``dobi.yaml``
+	WorkingDir string
+	// Service If **true** the resource is started by ``dobi up`` and stopped
+	// by ``dobi down``. Only `job`_ and `compose`_ resources can be a service.
+	Service bool
 }
 
 // Dependent can be used to provide part of the Resource interface
 type Dependent struct {
-	// Depends The list of task dependencies.
+	// Depends The list of task dependencies. Each item may be the name of a
+	// resource, which uses the default action of the resource, or a task
+	// with an action, like ``db:up``. The dependencies are run first, and
+	// the resource is considered stale when a dependency was modified.
 	// type: list of tasks
+	// example: ``[db:up, migrate, app:build]``
 	Depends []string
+	// After The list of tasks which are run before the tasks of the
+	// resource, without being dependencies. A task in ``after`` which was
+	// modified does not make the resource stale. The tasks are not run
+	// before the ``remove`` action of the resource.
+	// type: list of tasks
+	// example: ``[lint]``
+	After []string
 }
 
 // Dependencies returns the list of tasks
@@ -74,6 +176,36 @@ func (d *Dependent) Dependencies() []string {
 	return d.Depends
 }
 
+// RunAfter returns the list of tasks which are run before the resource, but
+// are not dependencies
+func (d *Dependent) RunAfter() []string {
+	return d.After
+}
+
+// RunAfter returns the tasks from the after field of the resource, or nil if
+// the resource does not have the field
+func RunAfter(resource Resource) []string {
+	if res, ok := resource.(interface{ RunAfter() []string }); ok {
+		return res.RunAfter()
+	}
+	return nil
+}
+
+// Hosted can be used to provide the Docker host used by a resource
+type Hosted struct {
+	// DockerHost The Docker host used to run the tasks for this resource. The
+	// value may be a host address (ex: ``tcp://10.0.0.2:2376``) or the name
+	// of a Docker context. ``DOCKER_CERT_PATH`` is only used when the value
+	// is the same as ``DOCKER_HOST``. This field supports :doc:`variables`.
+	// default: *the host from the environment*
+	DockerHost string
+}
+
+// Host returns the Docker host of the resource
+func (h *Hosted) Host() string {
+	return h.DockerHost
+}
+
 // Resolver is an interface for a type that returns values for variables
 type Resolver interface {
 	Resolve(tmpl string) (string, error)
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestAnnotationsResourceDir(t *testing.T) {
+	var testcases = []struct {
+		workingDir string
+		expected   string
+	}{
+		{workingDir: "", expected: "/project"},
+		{workingDir: "services/api", expected: "/project/services/api"},
+		{workingDir: "/srv/api", expected: "/srv/api"},
+	}
+	for _, tc := range testcases {
+		annotations := &Annotations{Annotations: AnnotationFields{WorkingDir: tc.workingDir}}
+		assert.Check(t, is.Equal(annotations.ResourceDir("/project"), tc.expected))
+	}
+}
+
+func TestGitResourceIsShallowCheckout(t *testing.T) {
+	res, err := gitFromConfig("protos", map[string]interface{}{
+		"repo": "https://example.com/protos.git",
+	})
+	assert.NilError(t, err)
+	checkout, ok := res.(*CheckoutConfig)
+	assert.Assert(t, ok)
+	assert.Check(t, is.Equal(checkout.Depth, 1))
+	assert.Check(t, IsResourceType(checkout, "git"))
+	assert.Check(t, IsResourceType(checkout, "checkout"))
+	assert.Check(t, !IsResourceType(checkout, "mount"))
+
+	res, err = gitFromConfig("protos", map[string]interface{}{
+		"repo":  "https://example.com/protos.git",
+		"depth": 0,
+	})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(res.(*CheckoutConfig).Depth, 0))
+}
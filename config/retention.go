@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArtifactRetention settings used by ``dobi gc`` to remove old build outputs
+// from the artifact directories of jobs. An entry in an artifact directory is
+// removed when any of the settings would remove it. The newest entries are
+// kept first.
+type ArtifactRetention struct {
+	// KeepLast The number of the newest entries to keep in each directory.
+	KeepLast int `config:"keep-last"`
+	// MaxSize The total size of the entries to keep in each directory.
+	// example: ``2g``
+	MaxSize string
+	// MaxAge Entries which were last modified longer ago than this value are
+	// removed. The value is a number of days (ex: ``30d``), or a duration
+	// (ex: ``12h``).
+	MaxAge string
+}
+
+// IsZero returns true if the retention has no settings
+func (r ArtifactRetention) IsZero() bool {
+	return r == ArtifactRetention{}
+}
+
+// Validate the retention values
+func (r ArtifactRetention) Validate() error {
+	if r.KeepLast < 0 {
+		return fmt.Errorf("keep-last must be 0 or more, not %d", r.KeepLast)
+	}
+	if _, err := parseMemory(r.MaxSize); err != nil {
+		return fmt.Errorf("invalid max-size %q", r.MaxSize)
+	}
+	_, err := parseAge(r.MaxAge)
+	return err
+}
+
+// MaxSizeBytes returns MaxSize as a number of bytes
+func (r ArtifactRetention) MaxSizeBytes() int64 {
+	// MaxSize is validated when the config is loaded
+	size, _ := parseMemory(r.MaxSize)
+	return size
+}
+
+// MaxAgeDuration returns MaxAge as a duration
+func (r ArtifactRetention) MaxAgeDuration() time.Duration {
+	// MaxAge is validated when the config is loaded
+	age, _ := parseAge(r.MaxAge)
+	return age
+}
+
+func parseAge(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		count, err := strconv.Atoi(days)
+		if err == nil && count > 0 {
+			return time.Duration(count) * 24 * time.Hour, nil
+		}
+	} else if age, err := time.ParseDuration(value); err == nil && age > 0 {
+		return age, nil
+	}
+	return 0, fmt.Errorf("invalid max-age %q, must be a number of days or a duration", value)
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestArtifactRetentionValidate(t *testing.T) {
+	for _, retention := range []ArtifactRetention{
+		{},
+		{KeepLast: 5, MaxSize: "2g", MaxAge: "30d"},
+		{MaxAge: "12h"},
+	} {
+		assert.Check(t, retention.Validate(), "%+v", retention)
+	}
+
+	retention := ArtifactRetention{KeepLast: -1}
+	assert.Check(t, is.ErrorContains(retention.Validate(), "keep-last must be 0 or more"))
+
+	retention = ArtifactRetention{MaxSize: "big"}
+	assert.Check(t, is.ErrorContains(retention.Validate(), `invalid max-size "big"`))
+
+	for _, age := range []string{"month", "0d", "-3h", "3w"} {
+		retention = ArtifactRetention{MaxAge: age}
+		assert.Check(t, is.ErrorContains(retention.Validate(), "invalid max-age"), age)
+	}
+}
+
+func TestArtifactRetentionValues(t *testing.T) {
+	retention := ArtifactRetention{MaxSize: "2k", MaxAge: "2d"}
+	assert.Check(t, is.Equal(retention.MaxSizeBytes(), int64(2048)))
+	assert.Check(t, is.Equal(retention.MaxAgeDuration(), 48*time.Hour))
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scanners supported by the scan action of an image
+const (
+	ScannerTrivy = "trivy"
+	ScannerGrype = "grype"
+)
+
+// severities are the vulnerability severities, from lowest to highest
+var severities = []string{"low", "medium", "high", "critical"}
+
+// ImageScan settings for the ``scan`` action of an image, which scans the
+// image for vulnerabilities. The scanner is run in a container, with the
+// socket of the Docker host mounted so that it can read the image.
+type ImageScan struct {
+	// Scanner The vulnerability scanner to run. The value may be one of
+	// ``trivy`` or ``grype``.
+	// default: ``trivy``
+	Scanner string
+	// Image The image used to run the scanner.
+	// default: ``aquasec/trivy:latest`` or ``anchore/grype:latest``
+	Image string
+	// Severity The lowest severity of a vulnerability which fails the scan.
+	// The value may be one of ``low``, ``medium``, ``high``, or
+	// ``critical``.
+	// default: ``high``
+	Severity string
+	// BeforePush When true the ``push`` action depends on the ``scan``
+	// action, so that an image is only pushed after the scan passes.
+	BeforePush bool
+	// Baseline The path to a file of known vulnerabilities, relative to the
+	// ``dobi.yaml``. The file has one vulnerability ID on each line, and
+	// lines which start with ``#`` are ignored. Only the vulnerabilities
+	// which are not in the baseline fail the scan.
+	// example: ``.dobi-scan-baseline``
+	Baseline string
+	// DiffTag The tag of the image which is compared to the canonical tag
+	// by the ``scan:diff`` action.
+	// default: ``latest``
+	DiffTag string
+}
+
+// IsZero returns true if the scan has no settings
+func (s ImageScan) IsZero() bool {
+	return s == ImageScan{}
+}
+
+// Validate the scan values
+func (s ImageScan) Validate() error {
+	switch s.Scanner {
+	case "", ScannerTrivy, ScannerGrype:
+	default:
+		return fmt.Errorf("invalid scanner %q, must be one of: %s, %s",
+			s.Scanner, ScannerTrivy, ScannerGrype)
+	}
+	if s.Severity != "" && severityIndex(s.Severity) < 0 {
+		return fmt.Errorf("invalid severity %q, must be one of: %s",
+			s.Severity, strings.Join(severities, ", "))
+	}
+	return nil
+}
+
+// ScannerImage returns the image used to run the scanner
+func (s ImageScan) ScannerImage() string {
+	switch {
+	case s.Image != "":
+		return s.Image
+	case s.Scanner == ScannerGrype:
+		return "anchore/grype:latest"
+	default:
+		return "aquasec/trivy:latest"
+	}
+}
+
+// Command returns the arguments of the scanner which scan the image, and exit
+// with a non-zero status when a vulnerability at or above the severity is
+// found
+func (s ImageScan) Command(image string) []string {
+	severity := strings.ToLower(s.Severity)
+	if severity == "" {
+		severity = "high"
+	}
+	switch s.Scanner {
+	case ScannerGrype:
+		return []string{image, "--fail-on", severity}
+	default:
+		failOn := []string{}
+		for _, value := range severities[severityIndex(severity):] {
+			failOn = append(failOn, strings.ToUpper(value))
+		}
+		return []string{
+			"image", "--no-progress", "--exit-code", "1",
+			"--severity", strings.Join(failOn, ","),
+			image,
+		}
+	}
+}
+
+// ReportCommand returns the arguments of the scanner which print every
+// vulnerability found in the image as JSON
+func (s ImageScan) ReportCommand(image string) []string {
+	switch s.Scanner {
+	case ScannerGrype:
+		return []string{image, "--output", "json"}
+	default:
+		return []string{"image", "--no-progress", "--format", "json", image}
+	}
+}
+
+// FailsOn returns true if a vulnerability with the severity fails the scan
+func (s ImageScan) FailsOn(severity string) bool {
+	threshold := s.Severity
+	if threshold == "" {
+		threshold = "high"
+	}
+	index := severityIndex(severity)
+	return index >= 0 && index >= severityIndex(threshold)
+}
+
+// GetDiffTag returns the tag compared by the scan:diff action
+func (s ImageScan) GetDiffTag() string {
+	if s.DiffTag == "" {
+		return "latest"
+	}
+	return s.DiffTag
+}
+
+func severityIndex(severity string) int {
+	for i, value := range severities {
+		if value == strings.ToLower(severity) {
+			return i
+		}
+	}
+	return -1
+}
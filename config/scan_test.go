@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestImageScanValidate(t *testing.T) {
+	for _, scan := range []ImageScan{
+		{},
+		{Scanner: ScannerTrivy, Severity: "medium"},
+		{Scanner: ScannerGrype, Severity: "CRITICAL", BeforePush: true},
+	} {
+		assert.Check(t, scan.Validate(), "%+v", scan)
+	}
+
+	scan := ImageScan{Scanner: "clair"}
+	assert.Check(t, is.ErrorContains(scan.Validate(), `invalid scanner "clair"`))
+
+	scan = ImageScan{Severity: "urgent"}
+	assert.Check(t, is.ErrorContains(scan.Validate(), `invalid severity "urgent"`))
+}
+
+func TestImageScanCommand(t *testing.T) {
+	scan := ImageScan{}
+	assert.Check(t, is.Equal(scan.ScannerImage(), "aquasec/trivy:latest"))
+	assert.Check(t, is.DeepEqual(scan.Command("app:1.0"), []string{
+		"image", "--no-progress", "--exit-code", "1",
+		"--severity", "HIGH,CRITICAL",
+		"app:1.0",
+	}))
+
+	scan = ImageScan{Scanner: ScannerGrype, Severity: "Medium"}
+	assert.Check(t, is.Equal(scan.ScannerImage(), "anchore/grype:latest"))
+	assert.Check(t, is.DeepEqual(scan.Command("app:1.0"),
+		[]string{"app:1.0", "--fail-on", "medium"}))
+
+	scan = ImageScan{Scanner: ScannerGrype, Image: "registry.internal/grype:0.40"}
+	assert.Check(t, is.Equal(scan.ScannerImage(), "registry.internal/grype:0.40"))
+}
+
+func TestImageScanFailsOn(t *testing.T) {
+	scan := ImageScan{}
+	assert.Check(t, scan.FailsOn("CRITICAL"))
+	assert.Check(t, scan.FailsOn("High"))
+	assert.Check(t, !scan.FailsOn("medium"))
+	assert.Check(t, !scan.FailsOn("UNKNOWN"))
+
+	scan = ImageScan{Severity: "critical"}
+	assert.Check(t, !scan.FailsOn("high"))
+	assert.Check(t, is.Equal(scan.GetDiffTag(), "latest"))
+}
@@ -0,0 +1,59 @@
+package config
+
+import "fmt"
+
+const (
+	// TeardownReverse stops tasks one at a time in the reverse of the order
+	// they were started
+	TeardownReverse = "reverse"
+	// TeardownParallel stops tasks at the same time, except that a task is
+	// stopped only after the tasks that depend on it
+	TeardownParallel = "parallel"
+
+	// TeardownWarn logs a warning when a task fails to stop
+	TeardownWarn = "warn"
+	// TeardownFail exits with an error when a task fails to stop
+	TeardownFail = "fail"
+)
+
+// Teardown settings for stopping the tasks which were started, like the
+// containers of a **compose** resource, when **dobi** exits. A task is always
+// stopped after the tasks which depend on it, so the containers of a job are
+// removed before the **compose** project used by the job is stopped.
+type Teardown struct {
+	// Order The order used to stop tasks. The value may be one of:
+	// * ``reverse`` - stop one task at a time, in the reverse of the order
+	//   they were started
+	// * ``parallel`` - stop tasks at the same time, except that a task is
+	//   stopped only after the tasks which depend on it
+	// default: ``reverse``
+	Order string
+	// OnError The action to take when a task fails to stop. The value may be
+	// one of:
+	// * ``warn`` - log a warning
+	// * ``fail`` - stop the rest of the tasks, then exit with an error
+	// default: ``warn``
+	OnError string `config:"on-error"`
+}
+
+// IsZero returns true if the teardown has no settings
+func (t Teardown) IsZero() bool {
+	return t == Teardown{}
+}
+
+// Validate the teardown values
+func (t Teardown) Validate() error {
+	switch t.Order {
+	case "", TeardownReverse, TeardownParallel:
+	default:
+		return fmt.Errorf("invalid order %q, must be one of: %s, %s",
+			t.Order, TeardownReverse, TeardownParallel)
+	}
+	switch t.OnError {
+	case "", TeardownWarn, TeardownFail:
+	default:
+		return fmt.Errorf("invalid on-error %q, must be one of: %s, %s",
+			t.OnError, TeardownWarn, TeardownFail)
+	}
+	return nil
+}
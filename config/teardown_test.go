@@ -0,0 +1,24 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestTeardownValidate(t *testing.T) {
+	for _, teardown := range []Teardown{
+		{},
+		{Order: TeardownReverse, OnError: TeardownWarn},
+		{Order: TeardownParallel, OnError: TeardownFail},
+	} {
+		assert.Check(t, teardown.Validate(), "%+v", teardown)
+	}
+
+	teardown := Teardown{Order: "random"}
+	assert.Check(t, is.ErrorContains(teardown.Validate(), `invalid order "random"`))
+
+	teardown = Teardown{OnError: "ignore"}
+	assert.Check(t, is.ErrorContains(teardown.Validate(), `invalid on-error "ignore"`))
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/dnephin/configtf"
+	pth "github.com/dnephin/configtf/path"
+)
+
+// TemplateConfig A **template** resource renders a file from a `Go template
+// <https://golang.org/pkg/text/template/>`_. The output file is only written
+// when the rendered content changes, so tasks which depend on the template
+// only run again when the output is modified.
+//
+// The template has access to the values in the ``values`` mapping as
+// ``.Values``, and to the following functions:
+//
+// * ``env "NAME"`` - the value of an environment variable
+// * ``variable "git.sha"`` - the value of any of the :doc:`variables`
+//
+// name: template
+// example: Render a Kubernetes manifest with the current git sha:
+//
+// .. code-block:: yaml
+//
+//     template=manifest:
+//         source: k8s/deployment.yaml.tmpl
+//         output: dist/deployment.yaml
+//         values:
+//           replicas: '3'
+//
+type TemplateConfig struct {
+	// Source The path to the template file. This field supports
+	// :doc:`variables`.
+	Source string `config:"required"`
+	// Output The path of the file to render. This field supports
+	// :doc:`variables`.
+	Output string `config:"required"`
+	// Values Values available to the template as ``.Values``. Values in the
+	// mapping support :doc:`variables`.
+	// type: mapping ``key: value``
+	Values map[string]string
+	// Mode The file mode of the output file.
+	// default: ``0644``
+	Mode int `config:"validate"`
+	Dependent
+	Annotations
+}
+
+// Validate the resource
+func (c *TemplateConfig) Validate(path pth.Path, config *Config) *pth.Error {
+	return nil
+}
+
+// ValidateMode sets the default file mode
+func (c *TemplateConfig) ValidateMode() error {
+	if c.Mode == 0 {
+		c.Mode = 0644
+	}
+	return nil
+}
+
+func (c *TemplateConfig) String() string {
+	return fmt.Sprintf("Render %q from template %q", c.Output, c.Source)
+}
+
+// Resolve resolves variables in the resource
+func (c *TemplateConfig) Resolve(resolver Resolver) (Resource, error) {
+	conf := *c
+	var err error
+	conf.Source, err = resolver.Resolve(c.Source)
+	if err != nil {
+		return &conf, err
+	}
+	conf.Output, err = resolver.Resolve(c.Output)
+	if err != nil {
+		return &conf, err
+	}
+
+	conf.Values = make(map[string]string, len(c.Values))
+	for key, value := range c.Values {
+		conf.Values[key], err = resolver.Resolve(value)
+		if err != nil {
+			return &conf, err
+		}
+	}
+	return &conf, nil
+}
+
+func templateFromConfig(name string, values map[string]interface{}) (Resource, error) {
+	tmpl := &TemplateConfig{}
+	return tmpl, configtf.Transform(name, values, tmpl)
+}
+
+func init() {
+	RegisterResource("template", templateFromConfig)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	yaml "gopkg.in/yaml.v2"
@@ -14,10 +15,18 @@ const (
 
 var (
 	reservedNames = map[string]bool{
-		"autoclean": true,
-		"list":      true,
-		"help":      true,
-		META:        true,
+		"autoclean":   true,
+		"list":        true,
+		"help":        true,
+		"up":          true,
+		"down":        true,
+		"validate":    true,
+		"bench":       true,
+		"gc":          true,
+		"deps":        true,
+		"daemon":      true,
+		"self-update": true,
+		META:          true,
 	}
 
 	resourceTypeRegistry = map[string]resourceFactory{}
@@ -45,36 +54,195 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return err
 	}
 
+	errs := ValidationErrors{}
 	if value, ok := values[META]; ok {
 		if err := c.loadMeta(value); err != nil {
-			return err
+			errs = append(errs, err)
 		}
 		delete(values, META)
 	}
-	for name, value := range values {
+	raw := make(map[string]rawResource)
+	for _, name := range sortedKeys(values) {
 		resType, resName, err := parseResourceName(name)
 		if err != nil {
-			return err
+			errs = append(errs, err)
+			continue
 		}
 
 		if err = validateName(resName); err != nil {
-			return err
+			errs = append(errs, err)
+			continue
+		}
+		raw[resName] = rawResource{key: name, resType: resType, values: values[name]}
+	}
+
+	for _, resName := range sortedRawKeys(raw) {
+		res := raw[resName]
+		name := res.key
+		value, err := extendValues(raw, resName, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid config for resource %q:\n%s", name, err))
+			continue
 		}
 
-		resource, err := unmarshalResource(name, resType, value)
+		resource, err := unmarshalResource(name, res.resType, value)
 		if err != nil {
-			return fmt.Errorf("invalid config for resource %q:\n%s", name, err)
+			errs = append(errs, fmt.Errorf("invalid config for resource %q:\n%s", name, err))
+			continue
 		}
 		if err := c.add(resName, resource); err != nil {
-			return err
+			errs = append(errs, err)
 		}
 	}
-	return nil
+	return errs.errorOrNil()
+}
+
+func sortedKeys(values map[string]map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedRawKeys(raw map[string]rawResource) []string {
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// extendsKey is the field used by a resource to inherit the fields of another
+// resource
+const extendsKey = "extends"
+
+type rawResource struct {
+	key     string
+	resType string
+	values  map[string]interface{}
+}
+
+// extendValues returns the values of a resource merged with the values of the
+// resource it extends. Fields set on the resource replace the same field from
+// the base resource. Mappings are merged so that only the keys set on the
+// resource are replaced, and lists are replaced entirely.
+func extendValues(
+	raw map[string]rawResource,
+	name string,
+	seen []string,
+) (map[string]interface{}, error) {
+	res := raw[name]
+	var baseValues map[string]interface{}
+	if base, ok := res.values[extendsKey]; ok {
+		var err error
+		baseValues, err = extendedValues(raw, name, base, seen)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// values are always copied because the maps are modified when they are
+	// transformed into a resource
+	merged, _ := toMap(mergeValues(baseValues, res.values))
+	values := make(map[string]interface{}, len(merged))
+	for key, value := range merged {
+		values[fmt.Sprintf("%v", key)] = value
+	}
+	delete(values, extendsKey)
+	return values, nil
+}
+
+// extendedValues returns the values of the base resource
+func extendedValues(
+	raw map[string]rawResource,
+	name string,
+	base interface{},
+	seen []string,
+) (map[string]interface{}, error) {
+	res := raw[name]
+	baseName, ok := base.(string)
+	if !ok {
+		return nil, fmt.Errorf("extends must be a resource name, not %T", base)
+	}
+	seen = append(seen, name)
+	for _, previous := range seen {
+		if previous == baseName {
+			return nil, fmt.Errorf("extends has a cycle: %s -> %s",
+				strings.Join(seen, " -> "), baseName)
+		}
+	}
+
+	baseRes, ok := raw[baseName]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("extends %q which is not defined in the same file", baseName)
+	case baseRes.resType != res.resType:
+		return nil, fmt.Errorf("can not extend %q, a %s can only extend another %s",
+			baseName, res.resType, res.resType)
+	}
+	return extendValues(raw, baseName, seen)
+}
+
+// mergeValues returns a copy of the base value with override applied to it.
+// Nested mappings and lists are copied as well, so that resources which
+// extend the same base never share them.
+func mergeValues(base, override interface{}) interface{} {
+	overrideMap, overrideIsMap := toMap(override)
+	if !overrideIsMap {
+		return copyValue(override)
+	}
+	baseMap, _ := toMap(base)
+	merged := make(map[interface{}]interface{}, len(baseMap)+len(overrideMap))
+	for key, value := range baseMap {
+		merged[key] = copyValue(value)
+	}
+	for key, value := range overrideMap {
+		merged[key] = mergeValues(baseMap[key], value)
+	}
+	return merged
+}
+
+// copyValue returns a deep copy of the mappings and lists in a value
+func copyValue(value interface{}) interface{} {
+	if mapping, ok := toMap(value); ok {
+		copied := make(map[interface{}]interface{}, len(mapping))
+		for key, item := range mapping {
+			copied[key] = copyValue(item)
+		}
+		return copied
+	}
+	if list, ok := value.([]interface{}); ok {
+		copied := make([]interface{}, len(list))
+		for i, item := range list {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	}
+	return value
+}
+
+// toMap converts the mappings decoded by yaml to a single type
+func toMap(value interface{}) (map[interface{}]interface{}, bool) {
+	switch typed := value.(type) {
+	case map[interface{}]interface{}:
+		return typed, true
+	case map[string]interface{}:
+		converted := make(map[interface{}]interface{}, len(typed))
+		for key, item := range typed {
+			converted[key] = item
+		}
+		return converted, true
+	default:
+		return nil, false
+	}
 }
 
 func (c *Config) loadMeta(value map[string]interface{}) error {
 	var err error
-	c.Meta, err = NewMetaConfig(META, value)
+	c.Meta, err = newMetaConfigWithSuggestions(value)
 	if err != nil {
 		return fmt.Errorf("invalid \"meta\" config: %s", err)
 	}
@@ -117,7 +285,36 @@ func unmarshalResource(name, resType string, value map[string]interface{}) (Reso
 	if !ok {
 		return nil, fmt.Errorf("invalid resource type %q", resType)
 	}
-	return fromConfigFunc(name, value)
+	original := copyValues(value)
+	resource, err := fromConfigFunc(name, value)
+	if err != nil && isUnexpectedKey(err) && resource != nil {
+		if keyErr := unexpectedKeyErrors(name, original, resource); keyErr != nil {
+			return nil, keyErr
+		}
+		return nil, withSuggestion(err, resource)
+	}
+	return resource, err
+}
+
+func newMetaConfigWithSuggestions(value map[string]interface{}) (*MetaConfig, error) {
+	original := copyValues(value)
+	meta, err := NewMetaConfig(META, value)
+	if err != nil && isUnexpectedKey(err) {
+		if keyErr := unexpectedKeyErrors(META, original, meta); keyErr != nil {
+			return meta, keyErr
+		}
+		return meta, withSuggestion(err, meta)
+	}
+	return meta, err
+}
+
+// copyValues returns a shallow copy of the values
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+	return copied
 }
 
 // LoadFromBytes loads a configuration from a bytes slice
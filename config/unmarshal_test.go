@@ -55,10 +55,13 @@ func TestLoadFromBytes(t *testing.T) {
 			"vol-def": &MountConfig{
 				Bind: "dist/",
 				Path: "/target",
+				Copy: true,
 			},
 			"cmd-def": &JobConfig{
-				Use:    "image-def",
-				Mounts: []string{"vol-def"},
+				Use:           "image-def",
+				Mounts:        []string{"vol-def"},
+				StopGrace:     5,
+				ArtifactsPath: "/artifacts",
 			},
 			"alias-def": &AliasConfig{
 				Tasks: []string{"vol-def", "cmd-def"},
@@ -90,6 +93,12 @@ func TestLoadFromBytesWithReservedName(t *testing.T) {
 	assert.Check(t, is.ErrorContains(err, `"autoclean" is reserved`))
 }
 
+func TestValidateNameReservedForCommands(t *testing.T) {
+	for _, name := range []string{"deps", "daemon", "self-update"} {
+		assert.Check(t, is.ErrorContains(validateName(name), "is reserved"), name)
+	}
+}
+
 func TestLoadFromBytesWithInvalidName(t *testing.T) {
 	conf := dedent.Dedent(`
 		image=image:latest:
@@ -100,3 +109,130 @@ func TestLoadFromBytesWithInvalidName(t *testing.T) {
 	_, err := LoadFromBytes([]byte(conf))
 	assert.Check(t, is.ErrorContains(err, `invalid character ":"`))
 }
+
+func TestLoadFromBytesWithExtends(t *testing.T) {
+	conf := dedent.Dedent(`
+		job=test-base:
+		  use: builder
+		  mounts: [source]
+		  env: [DEBUG=1]
+		  labels:
+		    team: core
+		    tier: test
+
+		job=test-unit:
+		  extends: test-base
+		  command: go test ./...
+		  env: [DEBUG=0]
+		  labels:
+		    tier: unit
+
+		job=test-integration:
+		  extends: test-unit
+		  command: go test -tags integration ./...
+	`)
+
+	config, err := LoadFromBytes([]byte(conf))
+	assert.NilError(t, err)
+
+	unit := config.Resources["test-unit"].(*JobConfig)
+	assert.Check(t, is.Equal(unit.Use, "builder"))
+	assert.Check(t, is.DeepEqual(unit.Mounts, []string{"source"}))
+	assert.Check(t, is.DeepEqual(unit.Env, []string{"DEBUG=0"}))
+	assert.Check(t, is.DeepEqual(unit.Labels, map[string]string{"team": "core", "tier": "unit"}))
+
+	integration := config.Resources["test-integration"].(*JobConfig)
+	assert.Check(t, is.Equal(integration.Use, "builder"))
+	assert.Check(t, is.Equal(integration.Command.String(), "go test -tags integration ./..."))
+	assert.Check(t, is.DeepEqual(integration.Env, []string{"DEBUG=0"}))
+}
+
+func TestLoadFromBytesWithTwoResourcesExtendingTheSameBase(t *testing.T) {
+	conf := dedent.Dedent(`
+		job=test-base:
+		  use: builder
+		  labels:
+		    team: core
+		    tier: test
+
+		job=test-unit:
+		  extends: test-base
+		  labels:
+		    tier: unit
+
+		job=test-lint:
+		  extends: test-base
+		  labels:
+		    owner: lint
+	`)
+
+	config, err := LoadFromBytes([]byte(conf))
+	assert.NilError(t, err)
+
+	unit := config.Resources["test-unit"].(*JobConfig)
+	assert.Check(t, is.DeepEqual(unit.Labels, map[string]string{"team": "core", "tier": "unit"}))
+	lint := config.Resources["test-lint"].(*JobConfig)
+	expected := map[string]string{"team": "core", "tier": "test", "owner": "lint"}
+	assert.Check(t, is.DeepEqual(lint.Labels, expected))
+	base := config.Resources["test-base"].(*JobConfig)
+	assert.Check(t, is.DeepEqual(base.Labels, map[string]string{"team": "core", "tier": "test"}))
+}
+
+func TestMergeValuesCopiesNestedValues(t *testing.T) {
+	base := map[interface{}]interface{}{
+		"labels": map[interface{}]interface{}{"team": "core"},
+		"env":    []interface{}{"DEBUG=1"},
+	}
+	first := mergeValues(base, map[string]interface{}{}).(map[interface{}]interface{})
+	second := mergeValues(base, map[string]interface{}{}).(map[interface{}]interface{})
+
+	first["labels"].(map[interface{}]interface{})["team"] = "changed"
+	first["env"].([]interface{})[0] = "changed"
+
+	assert.Check(t, is.Equal(second["labels"].(map[interface{}]interface{})["team"], "core"))
+	assert.Check(t, is.Equal(second["env"].([]interface{})[0], "DEBUG=1"))
+	assert.Check(t, is.Equal(base["labels"].(map[interface{}]interface{})["team"], "core"))
+}
+
+func TestLoadFromBytesWithExtendsErrors(t *testing.T) {
+	var testcases = []struct {
+		doc      string
+		conf     string
+		expected string
+	}{
+		{
+			doc: "cycle",
+			conf: `
+				job=one:
+				  extends: two
+				job=two:
+				  extends: one
+			`,
+			expected: "extends has a cycle",
+		},
+		{
+			doc: "missing",
+			conf: `
+				job=one:
+				  extends: two
+			`,
+			expected: `extends "two" which is not defined in the same file`,
+		},
+		{
+			doc: "different type",
+			conf: `
+				job=one:
+				  extends: two
+				image=two:
+				  image: example
+			`,
+			expected: "a job can only extend another job",
+		},
+	}
+	for _, testcase := range testcases {
+		t.Run(testcase.doc, func(t *testing.T) {
+			_, err := LoadFromBytes([]byte(dedent.Dedent(testcase.conf)))
+			assert.ErrorContains(t, err, testcase.expected)
+		})
+	}
+}
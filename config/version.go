@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+type versionConstraint struct {
+	operator string
+	version  []int
+}
+
+func (c versionConstraint) matches(version []int) bool {
+	cmp := compareVersions(version, c.version)
+	switch c.operator {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+var constraintOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// parseVersionConstraints parses a list of constraints separated by spaces or
+// commas, all of which must match. Each constraint is an optional operator
+// followed by a version (ex: ``>=0.16 <0.20``).
+func parseVersionConstraints(value string) ([]versionConstraint, error) {
+	constraints := []versionConstraint{}
+	for _, field := range strings.Fields(strings.Replace(value, ",", " ", -1)) {
+		constraint := versionConstraint{}
+		for _, operator := range constraintOperators {
+			if strings.HasPrefix(field, operator) {
+				constraint.operator = operator
+				break
+			}
+		}
+		version, err := parseVersion(strings.TrimPrefix(field, constraint.operator))
+		if err != nil {
+			return nil, err
+		}
+		constraint.version = version
+		constraints = append(constraints, constraint)
+	}
+	if len(constraints) == 0 {
+		return nil, fmt.Errorf("invalid version constraint %q", value)
+	}
+	return constraints, nil
+}
+
+// parseVersion parses a version with up to three numbers (ex: ``0.15.1``).
+// Missing numbers are 0.
+func parseVersion(value string) ([]int, error) {
+	parts := strings.Split(strings.TrimPrefix(value, "v"), ".")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid version %q", value)
+	}
+	version := make([]int, 3)
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("invalid version %q", value)
+		}
+		version[i] = number
+	}
+	return version, nil
+}
+
+func compareVersions(a, b []int) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// VersionSatisfies returns true if version matches all of the constraints
+func VersionSatisfies(version string, constraints string) (bool, error) {
+	parsed, err := parseVersionConstraints(constraints)
+	if err != nil {
+		return false, err
+	}
+	current, err := parseVersion(version)
+	if err != nil {
+		return false, err
+	}
+	for _, constraint := range parsed {
+		if !constraint.matches(current) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// CheckFileVersion returns an error if the meta.dobi-version of the config
+// file does not match version. Only meta.dobi-version is read, so the check
+// can be done before the config is loaded. A config which uses fields added
+// in a newer version of dobi fails with an error about the version instead of
+// errors about unexpected fields. Errors reading the file are ignored, they
+// are reported when the config is loaded.
+func CheckFileVersion(filename string, version string) error {
+	raw, err := readConfigFile(filename)
+	if err != nil {
+		return nil
+	}
+	values := struct {
+		Meta struct {
+			DobiVersion string `yaml:"dobi-version"`
+		} `yaml:"meta"`
+	}{}
+	if err := yaml.Unmarshal(raw, &values); err != nil {
+		return nil
+	}
+	meta := &MetaConfig{DobiVersion: values.Meta.DobiVersion}
+	if err := meta.CheckVersion(version); err != nil {
+		return fmt.Errorf("failed to load config from %q: %s", filename, err)
+	}
+	return nil
+}
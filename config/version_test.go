@@ -0,0 +1,68 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestVersionSatisfies(t *testing.T) {
+	var testcases = []struct {
+		version     string
+		constraints string
+		expected    bool
+	}{
+		{version: "0.16.0", constraints: ">=0.16 <0.20", expected: true},
+		{version: "0.19.3", constraints: ">=0.16, <0.20", expected: true},
+		{version: "0.15.0", constraints: ">=0.16 <0.20", expected: false},
+		{version: "0.20.0", constraints: ">=0.16 <0.20", expected: false},
+		{version: "0.15.1", constraints: "0.15.1", expected: true},
+		{version: "0.15.1", constraints: "!=0.15.1", expected: false},
+		{version: "1.0.0", constraints: ">0.20", expected: true},
+	}
+	for _, tc := range testcases {
+		actual, err := VersionSatisfies(tc.version, tc.constraints)
+		assert.Check(t, err)
+		assert.Check(t, is.Equal(actual, tc.expected), "%s %s", tc.version, tc.constraints)
+	}
+}
+
+func TestMetaConfigValidateDobiVersion(t *testing.T) {
+	meta := &MetaConfig{DobiVersion: ">=0.16 <0.20"}
+	assert.Check(t, meta.ValidateDobiVersion())
+
+	meta = &MetaConfig{DobiVersion: ">=0.16 <zero"}
+	assert.Check(t, is.ErrorContains(meta.ValidateDobiVersion(), `invalid version "zero"`))
+}
+
+func TestMetaConfigCheckVersion(t *testing.T) {
+	meta := &MetaConfig{DobiVersion: ">=0.16"}
+	assert.Check(t, meta.CheckVersion("0.16.2"))
+	assert.Check(t, is.ErrorContains(meta.CheckVersion("0.15.0"),
+		`dobi version 0.15.0 does not match meta.dobi-version ">=0.16"`))
+
+	meta = &MetaConfig{}
+	assert.Check(t, meta.CheckVersion("0.15.0"))
+}
+
+func TestCheckFileVersion(t *testing.T) {
+	content := `
+meta:
+    dobi-version: '>=0.16'
+    field-from-the-future: true
+`
+	file := fs.NewFile(t, "dobi-version", fs.WithContent(content))
+	defer file.Remove()
+
+	assert.Check(t, CheckFileVersion(file.Path(), "0.16.2"))
+	err := CheckFileVersion(file.Path(), "0.15.0")
+	assert.Check(t, is.ErrorContains(err,
+		`dobi version 0.15.0 does not match meta.dobi-version ">=0.16"`))
+
+	_, err = Load(file.Path())
+	assert.Check(t, is.ErrorContains(err, "field-from-the-future"))
+
+	assert.Check(t, CheckFileVersion("/does/not/exist.yaml", "0.15.0"))
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultWaitForTimeout is the timeout used when wait-for does not set one
+const defaultWaitForTimeout = time.Minute
+
+// WaitFor checks that the container of a job started by the ``:start`` action
+// is ready. All the checks which are set must pass before the tasks which
+// depend on the job are run.
+type WaitFor struct {
+	// Port A container port which must accept TCP connections. The port must
+	// be published to the host with ``ports`` or ``expose-ports-to-host``.
+	Port int
+	// URL An HTTP URL which must return a status code less than 400. This
+	// field supports :doc:`variables`.
+	// example: ``http://localhost:8080/health``
+	URL string `config:"url"`
+	// Healthcheck Wait for the ``HEALTHCHECK`` of the image to report the
+	// container as healthy.
+	Healthcheck bool
+	// Timeout The longest time to wait for the checks to pass.
+	// default: ``1m``
+	Timeout string
+}
+
+// IsZero returns true if no checks are set
+func (w WaitFor) IsZero() bool {
+	return w.Port == 0 && w.URL == "" && !w.Healthcheck
+}
+
+// Validate the checks
+func (w WaitFor) Validate() error {
+	if w.Port < 0 || w.Port > 65535 {
+		return fmt.Errorf("invalid port %d", w.Port)
+	}
+	if w.URL != "" && !strings.HasPrefix(w.URL, "http://") &&
+		!strings.HasPrefix(w.URL, "https://") {
+		return fmt.Errorf("invalid url %q, must be an http:// or https:// URL", w.URL)
+	}
+	if w.Timeout == "" {
+		return nil
+	}
+	if timeout, err := time.ParseDuration(w.Timeout); err != nil || timeout <= 0 {
+		return fmt.Errorf("invalid timeout %q, must be a duration", w.Timeout)
+	}
+	return nil
+}
+
+// TimeoutDuration returns Timeout as a duration
+func (w WaitFor) TimeoutDuration() time.Duration {
+	// Timeout is validated when the config is loaded
+	timeout, err := time.ParseDuration(w.Timeout)
+	if err != nil || timeout <= 0 {
+		return defaultWaitForTimeout
+	}
+	return timeout
+}
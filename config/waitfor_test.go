@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestWaitForValidate(t *testing.T) {
+	for _, waitFor := range []WaitFor{
+		{},
+		{Port: 8080, Timeout: "30s"},
+		{URL: "https://localhost:8443/health", Healthcheck: true},
+	} {
+		assert.Check(t, waitFor.Validate(), "%+v", waitFor)
+	}
+
+	waitFor := WaitFor{Port: 70000}
+	assert.Check(t, is.ErrorContains(waitFor.Validate(), "invalid port 70000"))
+
+	waitFor = WaitFor{URL: "localhost:8080"}
+	assert.Check(t, is.ErrorContains(waitFor.Validate(), `invalid url "localhost:8080"`))
+
+	waitFor = WaitFor{Port: 80, Timeout: "soon"}
+	assert.Check(t, is.ErrorContains(waitFor.Validate(), `invalid timeout "soon"`))
+}
+
+func TestWaitForTimeoutDuration(t *testing.T) {
+	assert.Check(t, is.Equal(WaitFor{}.TimeoutDuration(), time.Minute))
+	assert.Check(t, is.Equal(WaitFor{Timeout: "5s"}.TimeoutDuration(), 5*time.Second))
+}
@@ -29,11 +29,14 @@ func writeDocs() error {
 	}{
 		{"meta.rst", config.MetaConfig{}},
 		{"alias.rst", config.AliasConfig{}},
+		{"pipeline.rst", config.PipelineConfig{}},
 		{"compose.rst", config.ComposeConfig{}},
 		{"image.rst", config.ImageConfig{}},
 		{"mount.rst", config.MountConfig{}},
 		{"job.rst", config.JobConfig{}},
 		{"env.rst", config.EnvConfig{}},
+		{"template.rst", config.TemplateConfig{}},
+		{"checkout.rst", config.CheckoutConfig{}},
 		{"annotationFields.rst", config.AnnotationFields{}},
 	} {
 		fmt.Printf("Generating doc %q\n", basePath+item.filename)
@@ -0,0 +1,31 @@
+package execenv
+
+import (
+	"os"
+
+	"github.com/dnephin/dobi/logging"
+	"github.com/docker/cli/opts"
+	"github.com/pkg/errors"
+)
+
+// LoadEnvFile sets the variables from a file of key=value lines in the
+// environment of dobi, so they can be used by the {env.<name>} variable and
+// are set for the processes started by dobi. Variables which are already set
+// in the environment are not changed.
+func LoadEnvFile(filename string) error {
+	lines, err := opts.ParseEnvFile(filename)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read environment from %s", filename)
+	}
+	for _, line := range lines {
+		key, value := splitVariable(line)
+		if _, exists := os.LookupEnv(key); exists {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return errors.Wrapf(err, "failed to set %s", key)
+		}
+	}
+	logging.Log.Debugf("Loaded environment from %s", filename)
+	return nil
+}
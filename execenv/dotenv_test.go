@@ -0,0 +1,33 @@
+package execenv
+
+import (
+	"os"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	file := fs.NewFile(t, "test-load-env-file",
+		fs.WithContent("# comment\nDOBI_TEST_REGION=eu\nDOBI_TEST_STAGE=dev\n"))
+	defer file.Remove()
+	defer env.Patch(t, "DOBI_TEST_STAGE", "prod")()
+	defer os.Unsetenv("DOBI_TEST_REGION") // nolint: errcheck
+
+	assert.NilError(t, LoadEnvFile(file.Path()))
+	assert.Check(t, is.Equal(os.Getenv("DOBI_TEST_REGION"), "eu"))
+	assert.Check(t, is.Equal(os.Getenv("DOBI_TEST_STAGE"), "prod"))
+
+	execEnv := NewExecEnv("exec", "project", "cwd")
+	value, err := execEnv.Resolve("{env.DOBI_TEST_REGION}")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(value, "eu"))
+}
+
+func TestLoadEnvFileMissing(t *testing.T) {
+	err := LoadEnvFile("/does/not/exist/.env")
+	assert.Check(t, is.ErrorContains(err, "failed to read environment from /does/not/exist/.env"))
+}
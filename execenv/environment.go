@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dnephin/dobi/logging"
@@ -24,11 +25,25 @@ const (
 
 // ExecEnv is a data object which contains variables for an ExecuteContext
 type ExecEnv struct {
-	ExecID     string
-	Project    string
-	tmplCache  map[string]string
-	workingDir string
-	startTime  time.Time
+	ExecID  string
+	Project string
+	// mu guards tmplCache and secrets, which are written while tasks are run
+	// in parallel. Copies from WithVariables share the lock.
+	mu        *sync.Mutex
+	tmplCache map[string]string
+	params    map[string]string
+	// variables are read from the .dobirc files
+	variables map[string]string
+	// metaVariables are set by meta.variables
+	metaVariables map[string]string
+	// resourceVariables are set by the variables of a resource
+	resourceVariables map[string]string
+	// commandLineVariables are set by --var
+	commandLineVariables map[string]string
+	providers            map[string]Provider
+	secrets              map[string]string
+	workingDir           string
+	startTime            time.Time
 }
 
 // Unique returns a unique id for this execution
@@ -36,9 +51,58 @@ func (e *ExecEnv) Unique() string {
 	return e.Project + "-" + e.ExecID
 }
 
+// SetParam sets the value of a parameter used by the {param.<name>} variable
+func (e *ExecEnv) SetParam(name, value string) {
+	e.params[name] = value
+}
+
+// SetVariables sets the variables from meta.variables, used by the
+// {var.<name>} variable
+func (e *ExecEnv) SetVariables(variables map[string]string) {
+	e.metaVariables = variables
+}
+
+// SetCommandLineVariables sets the variables from --var, which override all
+// other variables used by the {var.<name>} variable
+func (e *ExecEnv) SetCommandLineVariables(variables map[string]string) {
+	e.commandLineVariables = variables
+}
+
+// WithVariables returns a copy of the ExecEnv which resolves {var.<name>} from
+// the variables of a resource before any other variables
+func (e *ExecEnv) WithVariables(variables map[string]string) *ExecEnv {
+	if len(variables) == 0 {
+		return e
+	}
+	env := *e
+	env.tmplCache = make(map[string]string)
+	env.resourceVariables = variables
+	return &env
+}
+
+// variable returns the value of the {var.<name>} variable. The value is
+// taken from --var, then the variables of the resource, then meta.variables,
+// then the .dobirc files, then the environment.
+func (e *ExecEnv) variable(name string) string {
+	for _, variables := range []map[string]string{
+		e.commandLineVariables,
+		e.resourceVariables,
+		e.metaVariables,
+		e.variables,
+	} {
+		if value, ok := variables[name]; ok {
+			return value
+		}
+	}
+	return os.Getenv(name)
+}
+
 // Resolve template variables to a string value and cache the value
 func (e *ExecEnv) Resolve(tmpl string) (string, error) {
-	if val, ok := e.tmplCache[tmpl]; ok {
+	e.mu.Lock()
+	val, ok := e.tmplCache[tmpl]
+	e.mu.Unlock()
+	if ok {
 		return val, nil
 	}
 
@@ -50,7 +114,9 @@ func (e *ExecEnv) Resolve(tmpl string) (string, error) {
 	buff := &bytes.Buffer{}
 	_, err = template.ExecuteFunc(buff, e.templateContext)
 	if err == nil {
+		e.mu.Lock()
 		e.tmplCache[tmpl] = buff.String()
+		e.mu.Unlock()
 	}
 	return buff.String(), err
 }
@@ -70,6 +136,13 @@ func (e *ExecEnv) ResolveSlice(tmpls []string) ([]string, error) {
 
 // nolint: gocyclo
 func (e *ExecEnv) templateContext(out io.Writer, tag string) (int, error) {
+	if provider, ref, ok := e.splitProvider(tag); ok {
+		val, err := e.lookupSecret(tag, provider, ref)
+		if err != nil {
+			return 0, err
+		}
+		return out.Write([]byte(val))
+	}
 	tag, defValue, hasDefault := splitDefault(tag)
 
 	write := func(val string, err error) (int, error) {
@@ -93,12 +166,21 @@ func (e *ExecEnv) templateContext(out io.Writer, tag string) (int, error) {
 		return valueFromGit(out, e.workingDir, suffix, defValue)
 	case "time":
 		return write(fmtdate.Format(suffix, e.startTime), nil)
+	case "date":
+		return write(e.startTime.UTC().Format(suffix), nil)
+	case "semver":
+		val, err := valueFromSemver(e.workingDir, suffix)
+		return write(val, err)
 	case "fs":
 		val, err := valueFromFilesystem(suffix, e.workingDir)
 		return write(val, err)
 	case "user":
 		val, err := valueFromUser(suffix)
 		return write(val, err)
+	case "param":
+		return write(e.params[suffix], nil)
+	case "var":
+		return write(e.variable(suffix), nil)
 	}
 
 	switch tag {
@@ -192,6 +274,10 @@ func splitPrefix(tag string) (string, string) {
 func NewExecEnvFromConfig(execID, project, workingDir string) (*ExecEnv, error) {
 	env := NewExecEnv(defaultExecID(), getProjectName(project, workingDir), workingDir)
 	var err error
+	env.variables, err = loadVariables(workingDir)
+	if err != nil {
+		return env, err
+	}
 	env.ExecID, err = getExecID(execID, env)
 	return env, err
 }
@@ -201,7 +287,12 @@ func NewExecEnv(execID, project, workingDir string) *ExecEnv {
 	return &ExecEnv{
 		ExecID:     execID,
 		Project:    project,
+		mu:         &sync.Mutex{},
 		tmplCache:  make(map[string]string),
+		params:     make(map[string]string),
+		variables:  make(map[string]string),
+		providers:  make(map[string]Provider),
+		secrets:    make(map[string]string),
 		startTime:  time.Now(),
 		workingDir: workingDir,
 	}
@@ -122,6 +122,15 @@ func TestResolveTime(t *testing.T) {
 	assert.Equal(t, execEnv.tmplCache[tmpl], expected)
 }
 
+func TestResolveDate(t *testing.T) {
+	execEnv := NewExecEnv("exec", "project", "cwd")
+	execEnv.startTime = time.Date(2016, 4, 5, 13, 0, 0, 0, time.FixedZone("A", 3600))
+	value, err := execEnv.Resolve("build-{date.2006-01-02.15}")
+
+	assert.NilError(t, err)
+	assert.Equal(t, value, "build-2016-04-05.12")
+}
+
 func TestSplitDefault(t *testing.T) {
 	tag := "time.19:01:01:default"
 	value, defVal, hasDefault := splitDefault(tag)
@@ -200,3 +209,15 @@ func TestValueFromGit_DetachedHead(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveParam(t *testing.T) {
+	execEnv := NewExecEnv("exec", "project", "cwd")
+	execEnv.SetParam("stage", "staging")
+
+	value, err := execEnv.Resolve("deploy-{param.stage}")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "deploy-staging")
+
+	_, err = execEnv.Resolve("{param.missing}")
+	assert.Assert(t, is.ErrorContains(err, `required for variable "param.missing"`))
+}
@@ -0,0 +1,206 @@
+package execenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dnephin/dobi/logging"
+	"github.com/pkg/errors"
+)
+
+// Provider looks up the value of a variable in a secret store
+type Provider interface {
+	// Lookup returns the value of the secret identified by ref
+	Lookup(ref string) (string, error)
+}
+
+// SetProvider sets the provider used to resolve the {<name>:<ref>} variables
+func (e *ExecEnv) SetProvider(name string, provider Provider) {
+	e.providers[name] = provider
+}
+
+// splitProvider returns the name of the provider and the reference of a
+// {<name>:<ref>} variable, and false if the tag does not use a provider
+func (e *ExecEnv) splitProvider(tag string) (Provider, string, bool) {
+	parts := strings.SplitN(tag, ":", 2)
+	if len(parts) != 2 {
+		return nil, "", false
+	}
+	provider, ok := e.providers[parts[0]]
+	return provider, parts[1], ok
+}
+
+// lookupSecret returns the value from the provider. Values are cached, so
+// that each secret is only read once per run, and are masked in the logs.
+func (e *ExecEnv) lookupSecret(tag string, provider Provider, ref string) (string, error) {
+	e.mu.Lock()
+	value, ok := e.secrets[tag]
+	e.mu.Unlock()
+	if ok {
+		return value, nil
+	}
+	value, err := provider.Lookup(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed resolving variable {%s}: %s", tag, err)
+	}
+	if value == "" {
+		return "", fmt.Errorf("a value is required for variable %q", tag)
+	}
+	logging.AddSecret(value)
+	e.mu.Lock()
+	e.secrets[tag] = value
+	e.mu.Unlock()
+	return value, nil
+}
+
+// VaultProvider reads secrets from the KV secrets engine of HashiCorp Vault.
+// A reference is a path followed by #<field>.
+type VaultProvider struct {
+	Address   string
+	Token     string
+	Namespace string
+	client    *http.Client
+}
+
+// NewVaultProvider returns a VaultProvider. Empty values are read from the
+// environment variables used by the vault CLI.
+func NewVaultProvider(address, token, namespace string) *VaultProvider {
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if token == "" {
+		token = vaultTokenFromEnv()
+	}
+	if namespace == "" {
+		namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	return &VaultProvider{
+		Address:   strings.TrimSuffix(address, "/"),
+		Token:     token,
+		Namespace: namespace,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func vaultTokenFromEnv() string {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token
+	}
+	raw, err := ioutil.ReadFile(filepath.Join(os.Getenv("HOME"), ".vault-token"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// Lookup reads the field of the secret at the path
+func (p *VaultProvider) Lookup(ref string) (string, error) {
+	index := strings.LastIndex(ref, "#")
+	if index == -1 {
+		return "", errors.Errorf("reference %q must end with #<field>", ref)
+	}
+	path, field := strings.TrimPrefix(ref[:index], "/"), ref[index+1:]
+	if p.Address == "" {
+		return "", errors.New("vault address is not set")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, p.Address+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.Token)
+	if p.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.Namespace)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("vault returned %s for %s", resp.Status, path)
+	}
+
+	secret := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", errors.Wrap(err, "failed to parse vault response")
+	}
+	data := secret.Data
+	// Version 2 of the KV engine nests the secret in data.data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = nested
+		}
+	}
+	value, ok := data[field]
+	if !ok {
+		return "", errors.Errorf("secret %s has no field %q", path, field)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// SSMProvider reads parameters from AWS Systems Manager Parameter Store using
+// the aws CLI. A reference is the name of a parameter.
+type SSMProvider struct {
+	Region  string
+	Profile string
+}
+
+// Lookup reads the decrypted value of the parameter
+func (p *SSMProvider) Lookup(ref string) (string, error) {
+	args := []string{
+		"ssm", "get-parameter",
+		"--name", ref,
+		"--with-decryption",
+		"--query", "Parameter.Value",
+		"--output", "text",
+	}
+	if p.Region != "" {
+		args = append(args, "--region", p.Region)
+	}
+	if p.Profile != "" {
+		args = append(args, "--profile", p.Profile)
+	}
+	out, err := commandOutput("aws", args...)
+	return strings.TrimSuffix(out, "\n"), err
+}
+
+// GCPProvider reads secrets from GCP Secret Manager using the gcloud CLI. A
+// reference is the name of a secret, optionally followed by #<version>.
+type GCPProvider struct {
+	Project string
+}
+
+// Lookup reads the version of the secret, or the latest version
+func (p *GCPProvider) Lookup(ref string) (string, error) {
+	name, version := ref, "latest"
+	if index := strings.LastIndex(ref, "#"); index != -1 {
+		name, version = ref[:index], ref[index+1:]
+	}
+	args := []string{"secrets", "versions", "access", version, "--secret", name}
+	if p.Project != "" {
+		args = append(args, "--project", p.Project)
+	}
+	return commandOutput("gcloud", args...)
+}
+
+func commandOutput(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "%s failed: %s", name, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,86 @@
+package execenv
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dnephin/dobi/logging"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type fakeProvider struct {
+	values  map[string]string
+	lookups int
+}
+
+func (p *fakeProvider) Lookup(ref string) (string, error) {
+	p.lookups++
+	value, ok := p.values[ref]
+	if !ok {
+		return "", fmt.Errorf("no secret %s", ref)
+	}
+	return value, nil
+}
+
+func TestResolveWithProvider(t *testing.T) {
+	provider := &fakeProvider{values: map[string]string{"/ci/password": "hunter2"}}
+	execEnv := NewExecEnv("exec", "project", "/dir")
+	execEnv.SetProvider("ssm", provider)
+
+	value, err := execEnv.Resolve("pass={ssm:/ci/password}")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(value, "pass=hunter2"))
+
+	value, err = execEnv.Resolve("{ssm:/ci/password}")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(value, "hunter2"))
+	assert.Check(t, is.Equal(provider.lookups, 1), "value should be cached")
+	assert.Check(t, is.Equal(logging.Mask("password is hunter2"), "password is ******"))
+
+	_, err = execEnv.Resolve("{ssm:/ci/missing}")
+	assert.Check(t, is.ErrorContains(err,
+		"failed resolving variable {ssm:/ci/missing}: no secret"))
+}
+
+func TestResolveDefaultIsNotAProvider(t *testing.T) {
+	execEnv := NewExecEnv("exec", "project", "/dir")
+	execEnv.SetProvider("ssm", &fakeProvider{})
+
+	value, err := execEnv.Resolve("{env.DOBI_TEST_UNSET:fallback}")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(value, "fallback"))
+}
+
+func TestVaultProviderLookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("X-Vault-Token") != "token":
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/v1/secret/data/ci":
+			fmt.Fprint(w, `{"data": {"data": {"token": "abc"}, "metadata": {"version": 3}}}`)
+		case r.URL.Path == "/v1/kv/ci":
+			fmt.Fprint(w, `{"data": {"token": "xyz"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewVaultProvider(server.URL, "token", "")
+	value, err := provider.Lookup("secret/data/ci#token")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(value, "abc"))
+
+	value, err = provider.Lookup("kv/ci#token")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(value, "xyz"))
+
+	_, err = provider.Lookup("kv/ci#missing")
+	assert.Check(t, is.ErrorContains(err, `secret kv/ci has no field "missing"`))
+
+	_, err = provider.Lookup("kv/ci")
+	assert.Check(t, is.ErrorContains(err, "must end with #<field>"))
+}
@@ -0,0 +1,87 @@
+package execenv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	git "github.com/gogits/git-module"
+	"github.com/pkg/errors"
+)
+
+type semver struct {
+	major, minor, patch int
+}
+
+func (v semver) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+}
+
+func (v semver) less(other semver) bool {
+	switch {
+	case v.major != other.major:
+		return v.major < other.major
+	case v.minor != other.minor:
+		return v.minor < other.minor
+	default:
+		return v.patch < other.patch
+	}
+}
+
+// parseSemver parses a tag of the form v1.2.3 or 1.2.3. Pre-release and build
+// suffixes are not supported.
+func parseSemver(tag string) (semver, bool) {
+	parts := strings.Split(strings.TrimPrefix(tag, "v"), ".")
+	if len(parts) != 3 {
+		return semver{}, false
+	}
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return semver{}, false
+		}
+		numbers[i] = number
+	}
+	return semver{major: numbers[0], minor: numbers[1], patch: numbers[2]}, true
+}
+
+// latestSemver returns the highest version in tags, or 0.0.0 if none of the
+// tags are a version
+func latestSemver(tags []string) semver {
+	latest := semver{}
+	for _, tag := range tags {
+		if version, ok := parseSemver(tag); ok && latest.less(version) {
+			latest = version
+		}
+	}
+	return latest
+}
+
+func valueFromSemver(cwd string, name string) (string, error) {
+	repo, err := git.OpenRepository(cwd)
+	if err != nil {
+		return "", err
+	}
+	tags, err := repo.GetTags()
+	if err != nil {
+		return "", err
+	}
+	return semverValue(latestSemver(tags), name)
+}
+
+func semverValue(version semver, name string) (string, error) {
+	switch name {
+	case "current":
+		return version.String(), nil
+	case "next.major":
+		return semver{major: version.major + 1}.String(), nil
+	case "next.minor":
+		return semver{major: version.major, minor: version.minor + 1}.String(), nil
+	case "next.patch":
+		version.patch++
+		return version.String(), nil
+	default:
+		return "", errors.Errorf("unknown variable \"semver.%s\"", name)
+	}
+}
@@ -0,0 +1,31 @@
+package execenv
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestLatestSemver(t *testing.T) {
+	tags := []string{"v1.2.3", "release", "1.10.0", "v1.9.12", "v2.0.0-rc1"}
+	assert.Check(t, is.Equal(latestSemver(tags).String(), "1.10.0"))
+	assert.Check(t, is.Equal(latestSemver(nil).String(), "0.0.0"))
+}
+
+func TestSemverValue(t *testing.T) {
+	version := semver{major: 1, minor: 4, patch: 2}
+	for name, expected := range map[string]string{
+		"current":    "1.4.2",
+		"next.major": "2.0.0",
+		"next.minor": "1.5.0",
+		"next.patch": "1.4.3",
+	} {
+		value, err := semverValue(version, name)
+		assert.Check(t, err, name)
+		assert.Check(t, is.Equal(value, expected), name)
+	}
+
+	_, err := semverValue(version, "next")
+	assert.Check(t, is.ErrorContains(err, `unknown variable "semver.next"`))
+}
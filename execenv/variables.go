@@ -0,0 +1,74 @@
+package execenv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dnephin/dobi/logging"
+	"github.com/docker/cli/opts"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// variablesFilename is the name of the file which contains variables used by
+// the {var.<name>} variable
+const variablesFilename = ".dobirc"
+
+// loadVariables reads variables from the .dobirc file in the users home
+// directory and the project directory. Variables in the project file override
+// variables from the home directory.
+func loadVariables(workingDir string) (map[string]string, error) {
+	variables := make(map[string]string)
+	for _, dir := range []string{os.Getenv("HOME"), workingDir} {
+		if dir == "" {
+			continue
+		}
+		filename := filepath.Join(dir, variablesFilename)
+		fileVars, err := readVariablesFile(filename)
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return nil, errors.Wrapf(err, "failed to read variables from %s", filename)
+		}
+		logging.Log.Debugf("Loaded variables from %s", filename)
+		for key, value := range fileVars {
+			variables[key] = value
+		}
+	}
+	return variables, nil
+}
+
+// readVariablesFile reads a file of variables. The file may be either a YAML
+// mapping or a file of key=value lines.
+func readVariablesFile(filename string) (map[string]string, error) {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := make(map[string]string)
+	if err := yaml.Unmarshal(raw, &variables); err == nil {
+		return variables, nil
+	}
+
+	lines, err := opts.ParseEnvFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		key, value := splitVariable(line)
+		variables[key] = value
+	}
+	return variables, nil
+}
+
+func splitVariable(line string) (string, string) {
+	parts := strings.SplitN(line, "=", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
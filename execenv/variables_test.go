@@ -0,0 +1,65 @@
+package execenv
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestLoadVariables(t *testing.T) {
+	home := fs.NewDir(t, "test-load-variables-home",
+		fs.WithFile(".dobirc", "# user variables\nREGISTRY=docker.io\nREGION=us\n"))
+	defer home.Remove()
+	project := fs.NewDir(t, "test-load-variables-project",
+		fs.WithFile(".dobirc", "REGION: eu\nstage: dev\n"))
+	defer project.Remove()
+	defer env.Patch(t, "HOME", home.Path())()
+
+	variables, err := loadVariables(project.Path())
+	assert.NilError(t, err)
+	expected := map[string]string{
+		"REGISTRY": "docker.io",
+		"REGION":   "eu",
+		"stage":    "dev",
+	}
+	assert.Check(t, is.DeepEqual(expected, variables))
+}
+
+func TestResolveVar(t *testing.T) {
+	execEnv := NewExecEnv("exec", "project", "cwd")
+	execEnv.variables["REGION"] = "eu"
+
+	value, err := execEnv.Resolve("{var.REGION}-{var.ZONE:a}")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "eu-a")
+}
+
+func TestResolveVarPrecedence(t *testing.T) {
+	defer env.Patch(t, "STAGE", "from-env")()
+	execEnv := NewExecEnv("exec", "project", "cwd")
+	execEnv.variables["REGION"] = "us"
+	execEnv.variables["ZONE"] = "a"
+	execEnv.SetVariables(map[string]string{"REGION": "eu", "VERSION": "1.2.3"})
+
+	value, err := execEnv.Resolve("{var.REGION}-{var.ZONE}-{var.VERSION}-{var.STAGE}")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "eu-a-1.2.3-from-env")
+
+	resourceEnv := execEnv.WithVariables(map[string]string{"VERSION": "2.0.0"})
+	value, err = resourceEnv.Resolve("{var.REGION}-{var.VERSION}")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "eu-2.0.0")
+
+	value, err = execEnv.Resolve("{var.REGION}-{var.VERSION}")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "eu-1.2.3")
+
+	execEnv.SetCommandLineVariables(map[string]string{"VERSION": "3.0.0"})
+	resourceEnv = execEnv.WithVariables(map[string]string{"VERSION": "2.0.0"})
+	value, err = resourceEnv.Resolve("{var.VERSION}")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "3.0.0")
+}
@@ -9,6 +9,7 @@ require (
 	github.com/docker/cli v0.0.0-20200303215952-eb310fca4956
 	github.com/docker/docker v17.12.0-ce-rc1.0.20200309214505-aa6a9891b09c+incompatible
 	github.com/docker/go-connections v0.4.0
+	github.com/docker/go-units v0.4.0
 	github.com/fsouza/go-dockerclient v1.6.4
 	github.com/gogits/git-module v0.0.0-20170608205522-1de103dca47a
 	github.com/golang/mock v1.1.1
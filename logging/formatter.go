@@ -21,24 +21,33 @@ type LogRepresenter interface {
 }
 
 // Formatter formats a log entry in a human readable way
-type Formatter struct{}
+type Formatter struct {
+	// NoColor disables the color of the log level
+	NoColor bool
+}
 
 // Format implements the log.Formatter interface
 func (f *Formatter) Format(entry *log.Entry) ([]byte, error) {
 	buff := &bytes.Buffer{}
-	buff.WriteString(writeLevel(entry.Level))
+	buff.WriteString(writeLevel(entry.Level, !f.NoColor))
 	buff.WriteString(writeData(entry.Data))
 	buff.WriteString(entry.Message)
 	buff.WriteString("\n")
-	return buff.Bytes(), nil
+	return []byte(Mask(buff.String())), nil
 }
 
 func withColor(color int, msg string) string {
 	return fmt.Sprintf("\x1b[%dm%s\x1b[0m", color, msg)
 }
 
-func writeLevel(level log.Level) string {
+func writeLevel(level log.Level, color bool) string {
+	withColor := withColor
+	if !color {
+		withColor = func(_ int, msg string) string { return msg }
+	}
 	switch level {
+	case log.TraceLevel:
+		return fmt.Sprintf("[%s] ", withColor(gray, "TRACE"))
 	case log.DebugLevel:
 		return fmt.Sprintf("[%s] ", withColor(gray, "DEBUG"))
 	case log.WarnLevel:
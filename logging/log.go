@@ -1,12 +1,18 @@
 package logging
 
 import (
+	"fmt"
+
 	log "github.com/sirupsen/logrus"
 )
 
 var (
 	// Log is the logger used by dobi
 	Log = log.New()
+
+	// quietSkip hides the log lines for tasks which were skipped because they
+	// are up-to-date
+	quietSkip bool
 )
 
 // ForTask returns a logger for a task which implemented LogRepresenter. The
@@ -14,3 +20,49 @@ var (
 func ForTask(repr LogRepresenter) *log.Entry {
 	return Log.WithFields(log.Fields{"task": repr})
 }
+
+// SetQuietSkip sets whether tasks which are skipped are logged at info level
+func SetQuietSkip(quiet bool) {
+	quietSkip = quiet
+}
+
+// Skipped logs that a task was skipped because it is up-to-date. The message
+// is logged at debug level when quiet skip is enabled.
+func Skipped(repr LogRepresenter) {
+	if quietSkip {
+		ForTask(repr).Debug("is fresh")
+		return
+	}
+	ForTask(repr).Info("is fresh")
+}
+
+// Verbosity levels which can be selected by a user
+const (
+	LevelErrors    = "errors"
+	LevelLifecycle = "lifecycle"
+	LevelInfo      = "info"
+	LevelDebug     = "debug"
+	LevelTrace     = "trace"
+)
+
+// ParseVerbosity returns the log level for a verbosity level name, and true if
+// skipped tasks should be hidden. The lifecycle level is the same as info, but
+// hides the tasks which are skipped.
+func ParseVerbosity(name string) (log.Level, bool, error) {
+	switch name {
+	case LevelErrors:
+		return log.ErrorLevel, false, nil
+	case LevelLifecycle:
+		return log.InfoLevel, true, nil
+	case LevelInfo:
+		return log.InfoLevel, false, nil
+	case LevelDebug:
+		return log.DebugLevel, false, nil
+	case LevelTrace:
+		return log.TraceLevel, false, nil
+	default:
+		return log.InfoLevel, false, fmt.Errorf(
+			"invalid log level %q, must be one of: errors, lifecycle, info, debug, trace",
+			name)
+	}
+}
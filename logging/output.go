@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Output targets which are not a file
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputNull   = "null"
+)
+
+// OpenOutput returns a writer for an output target. The target is one of
+// stdout, stderr, null, or the path to a file which is created or truncated.
+// The returned close function must be called when the output is no longer used.
+func OpenOutput(target string) (io.Writer, func() error, error) {
+	noClose := func() error { return nil }
+	switch target {
+	case OutputStdout:
+		return os.Stdout, noClose, nil
+	case OutputStderr:
+		return os.Stderr, noClose, nil
+	case OutputNull:
+		return ioutil.Discard, noClose, nil
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, file.Close, nil
+}
+
+// IsFileOutput returns true if the target is the path to a file, or null
+func IsFileOutput(target string) bool {
+	return target != OutputStdout && target != OutputStderr
+}
+
+// OpenTaskLog creates or truncates the log file for a task in dir. The file
+// is named after the task, with the ':' replaced by a '-'.
+func OpenTaskLog(dir string, task string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(TaskLogPath(dir, task))
+}
+
+// TaskLogPath returns the path of the log file for a task in dir
+func TaskLogPath(dir string, task string) string {
+	return filepath.Join(dir, strings.Replace(task, ":", "-", -1)+".log")
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestOpenOutput(t *testing.T) {
+	out, closer, err := OpenOutput(OutputStdout)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(out, os.Stdout))
+	assert.Check(t, closer())
+
+	out, closer, err = OpenOutput(OutputNull)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(out, ioutil.Discard))
+	assert.Check(t, closer())
+}
+
+func TestOpenOutputFile(t *testing.T) {
+	dir := fs.NewDir(t, "output")
+	defer dir.Remove()
+	filename := filepath.Join(dir.Path(), "job.log")
+
+	out, closer, err := OpenOutput(filename)
+	assert.NilError(t, err)
+	_, err = out.Write([]byte("result\n"))
+	assert.NilError(t, err)
+	assert.NilError(t, closer())
+
+	content, err := ioutil.ReadFile(filename)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(content), "result\n"))
+	assert.Check(t, IsFileOutput(filename))
+	assert.Check(t, !IsFileOutput(OutputStderr))
+}
+
+func TestOpenTaskLog(t *testing.T) {
+	dir := fs.NewDir(t, "output")
+	defer dir.Remove()
+	logDir := filepath.Join(dir.Path(), "logs")
+
+	file, err := OpenTaskLog(logDir, "test:run")
+	assert.NilError(t, err)
+	_, err = file.Write([]byte("ok\n"))
+	assert.NilError(t, err)
+	assert.NilError(t, file.Close())
+
+	content, err := ioutil.ReadFile(filepath.Join(logDir, "test-run.log"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(content), "ok\n"))
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"bytes"
+	"hash/fnv"
+	"io"
+	"sync"
+)
+
+var prefixColors = []int{36, 33, 32, 35, 34, 96, 93, 92, 95, 94}
+
+// PrefixWriter is an io.Writer which writes each line with a prefix of
+// ``[name]``, so that the output from multiple tasks can be told apart.
+// Partial lines are buffered until the rest of the line is written, or
+// Flush is called.
+type PrefixWriter struct {
+	out    io.Writer
+	prefix []byte
+	buf    bytes.Buffer
+	mu     sync.Mutex
+}
+
+// NewPrefixWriter returns a new PrefixWriter. If color is true the prefix is
+// colored, using the same color for the same name.
+func NewPrefixWriter(out io.Writer, name string, color bool) *PrefixWriter {
+	prefix := "[" + name + "]"
+	if color {
+		prefix = withColor(colorForName(name), prefix)
+	}
+	return &PrefixWriter{out: out, prefix: []byte(prefix + " ")}
+}
+
+func colorForName(name string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(name)) // nolint: errcheck
+	return prefixColors[hash.Sum32()%uint32(len(prefixColors))]
+}
+
+// Write implements io.Writer
+func (w *PrefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		index := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if index == -1 {
+			return len(p), nil
+		}
+		if err := w.writeLine(w.buf.Next(index + 1)); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+func (w *PrefixWriter) writeLine(line []byte) error {
+	_, err := w.out.Write(append(append([]byte{}, w.prefix...), line...))
+	return err
+}
+
+// Flush writes any partial line which has not been written yet
+func (w *PrefixWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	line := append(w.buf.Next(w.buf.Len()), '\n')
+	return w.writeLine(line)
+}
@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	writer := NewPrefixWriter(buf, "test", false)
+
+	_, err := writer.Write([]byte("one\ntw"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(buf.String(), "[test] one\n"))
+
+	_, err = writer.Write([]byte("o\nthree"))
+	assert.NilError(t, err)
+	assert.NilError(t, writer.Flush())
+	assert.Check(t, is.Equal(buf.String(), "[test] one\n[test] two\n[test] three\n"))
+}
+
+func TestPrefixWriterWithColor(t *testing.T) {
+	buf := new(bytes.Buffer)
+	writer := NewPrefixWriter(buf, "test", true)
+
+	_, err := writer.Write([]byte("one\n"))
+	assert.NilError(t, err)
+	expected := withColor(colorForName("test"), "[test]") + " one\n"
+	assert.Check(t, is.Equal(buf.String(), expected))
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// secretMask replaces the value of a secret in the log output
+const secretMask = "******"
+
+var secrets = struct {
+	sync.RWMutex
+	values   []string
+	replacer *strings.Replacer
+}{replacer: strings.NewReplacer()}
+
+// AddSecret adds a value which is masked in the log output
+func AddSecret(value string) {
+	if value == "" {
+		return
+	}
+	secrets.Lock()
+	defer secrets.Unlock()
+	secrets.values = append(secrets.values, value, secretMask)
+	secrets.replacer = strings.NewReplacer(secrets.values...)
+}
+
+// Mask replaces every secret in msg
+func Mask(msg string) string {
+	secrets.RLock()
+	defer secrets.RUnlock()
+	return secrets.replacer.Replace(msg)
+}
+
+// MaskWriter is an io.Writer which replaces every secret in the output. The
+// end of a write which could be the start of a secret is buffered until the
+// next write, or Flush is called.
+type MaskWriter struct {
+	out io.Writer
+	buf []byte
+	mu  sync.Mutex
+}
+
+// NewMaskWriter returns a new MaskWriter
+func NewMaskWriter(out io.Writer) *MaskWriter {
+	return &MaskWriter{out: out}
+}
+
+// Write implements io.Writer
+func (w *MaskWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	masked := Mask(string(w.buf) + string(p))
+	partial := partialSecretLen(masked)
+	w.buf = []byte(masked[len(masked)-partial:])
+	if _, err := io.WriteString(w.out, masked[:len(masked)-partial]); err != nil {
+		return len(p), err
+	}
+	return len(p), nil
+}
+
+// Flush writes the output which has not been written yet
+func (w *MaskWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.out.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// partialSecretLen returns the length of the longest suffix of msg which is
+// the start of a secret
+func partialSecretLen(msg string) int {
+	secrets.RLock()
+	defer secrets.RUnlock()
+
+	longest := 0
+	// values holds each secret followed by the mask
+	for i := 0; i < len(secrets.values); i += 2 {
+		secret := secrets.values[i]
+		for size := len(secret) - 1; size > longest; size-- {
+			if strings.HasSuffix(msg, secret[:size]) {
+				longest = size
+				break
+			}
+		}
+	}
+	return longest
+}
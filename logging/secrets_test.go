@@ -0,0 +1,30 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestMaskWriter(t *testing.T) {
+	AddSecret("hunter2")
+	buf := new(bytes.Buffer)
+	writer := NewMaskWriter(buf)
+
+	_, err := writer.Write([]byte("password is hun"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(buf.String(), "password is "))
+
+	_, err = writer.Write([]byte("ter2\nhunt"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(buf.String(), "password is ******\n"))
+
+	_, err = writer.Write([]byte("ing"))
+	assert.NilError(t, err)
+	_, err = writer.Write([]byte(" hu"))
+	assert.NilError(t, err)
+	assert.NilError(t, writer.Flush())
+	assert.Check(t, is.Equal(buf.String(), "password is ******\nhunting hu"))
+}
@@ -1,12 +1,22 @@
 package main
 
 import (
+	"os"
+
 	"github.com/dnephin/dobi/cmd"
 	"github.com/dnephin/dobi/logging"
 )
 
 func main() {
 	if err := cmd.NewRootCommand().Execute(); err != nil {
-		logging.Log.Fatal(err)
+		logging.Log.Error(err)
+		os.Exit(exitCode(err))
+	}
+}
+
+func exitCode(err error) int {
+	if exitErr, ok := err.(interface{ ExitCode() int }); ok {
+		return exitErr.ExitCode()
 	}
+	return 1
 }
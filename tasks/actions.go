@@ -0,0 +1,30 @@
+package tasks
+
+import (
+	"sort"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+// ResourceActions returns the actions of a resource. The first action is the
+// default. The custom actions of a job follow the actions of every job.
+func ResourceActions(res config.Resource) []types.Action {
+	actions := append([]types.Action{}, types.Actions(config.ResourceType(res))...)
+	job, ok := res.(*config.JobConfig)
+	if !ok {
+		return actions
+	}
+	names := make([]string, 0, len(job.Actions))
+	for name := range job.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		actions = append(actions, types.Action{
+			Name:        name,
+			Description: "Run " + job.Actions[name],
+		})
+	}
+	return actions
+}
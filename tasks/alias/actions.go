@@ -8,6 +8,14 @@ import (
 	"github.com/dnephin/dobi/tasks/types"
 )
 
+func init() {
+	types.RegisterActions(
+		"alias",
+		types.Action{Name: "run", Description: "Run the tasks in the alias"},
+		types.Action{Name: "remove", Description: "Run the remove action of the tasks in the alias"},
+	)
+}
+
 // GetTaskConfig returns a new TaskConfig for the action
 func GetTaskConfig(name, act string, conf *config.AliasConfig) (types.TaskConfig, error) {
 	switch act {
@@ -30,3 +30,8 @@ func (t *Task) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error)
 	logging.ForTask(t).Info("Done")
 	return depsModified, nil
 }
+
+// IsStale returns false, an alias is only stale when a dependency is modified
+func (t *Task) IsStale(_ *context.ExecuteContext) (bool, error) {
+	return false, nil
+}
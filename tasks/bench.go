@@ -0,0 +1,255 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dnephin/dobi/logging"
+)
+
+// DefaultBenchBaseline is the path of the baseline file used by Bench,
+// relative to the working directory
+const DefaultBenchBaseline = ".dobi/bench.json"
+
+// benchNoise is the smallest change in duration which is reported as a
+// regression. Smaller changes are common between runs of fast tasks.
+const benchNoise = 100 * time.Millisecond
+
+// BenchOptions are the options used by Bench
+type BenchOptions struct {
+	RunOptions
+	// Runs is the number of times the tasks are run
+	Runs int
+	// Clean runs the remove action of the resources used by the tasks before
+	// each run
+	Clean bool
+	// Baseline is the path of the file with the results of an earlier
+	// benchmark
+	Baseline string
+	// SaveBaseline writes the results to the baseline file
+	SaveBaseline bool
+	// Threshold is the increase in the median duration of a task, as a
+	// fraction of the baseline, which fails the benchmark
+	Threshold float64
+	// Out receives the table of results
+	Out io.Writer
+}
+
+// BenchResult is the distribution of the durations of a task over all runs
+type BenchResult struct {
+	Runs   int     `json:"runs"`
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	P90    float64 `json:"p90"`
+	Max    float64 `json:"max"`
+}
+
+// BenchResults are the results of a benchmark, keyed by task name
+type BenchResults struct {
+	Tasks map[string]BenchResult `json:"tasks"`
+}
+
+// Bench runs the tasks multiple times, and records the distribution of the
+// durations of each task. The results are compared to the baseline, and an
+// error is returned when a task is slower than the baseline by more than the
+// threshold.
+func Bench(options BenchOptions) error {
+	if options.Runs < 1 {
+		return fmt.Errorf("runs must be at least 1, not %d", options.Runs)
+	}
+	durations := make(map[string][]time.Duration)
+	order := []string{}
+	for run := 1; run <= options.Runs; run++ {
+		if options.Clean {
+			if err := benchClean(options.RunOptions); err != nil {
+				return fmt.Errorf("failed to clean before run %d: %s", run, err)
+			}
+		}
+
+		logging.Log.Infof("Benchmark run %d of %d", run, options.Runs)
+		runOptions := options.RunOptions
+		runOptions.Report = &Report{}
+		runOptions.Summary = false
+		if err := Run(runOptions); err != nil {
+			return fmt.Errorf("run %d failed: %s", run, err)
+		}
+		for _, result := range runOptions.Report.Tasks {
+			if _, ok := durations[result.Name]; !ok {
+				order = append(order, result.Name)
+			}
+			durations[result.Name] = append(durations[result.Name], result.Duration)
+		}
+	}
+
+	results := newBenchResults(durations)
+	baseline, err := readBenchBaseline(options.Baseline)
+	if err != nil {
+		return err
+	}
+	if err := results.WriteTable(options.Out, order, baseline); err != nil {
+		return err
+	}
+	if options.SaveBaseline {
+		if err := results.WriteFile(options.Baseline); err != nil {
+			return fmt.Errorf("failed to write baseline: %s", err)
+		}
+		logging.Log.Infof("Wrote baseline to %s", options.Baseline)
+		return nil
+	}
+	return results.regressions(order, baseline, options.Threshold)
+}
+
+// benchClean runs the remove action of each resource used by the tasks, in
+// the reverse of the order they are run
+func benchClean(options RunOptions) error {
+	options.Tasks = getNames(options)
+	collection, err := collectTasks(options)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	names := []string{}
+	all := collection.All()
+	for i := len(all) - 1; i >= 0; i-- {
+		resource := all[i].Name().Resource()
+		if !seen[resource] {
+			seen[resource] = true
+			names = append(names, resource+":rm")
+		}
+	}
+	options.Tasks = names
+	options.Summary = false
+	return Run(options)
+}
+
+func newBenchResults(durations map[string][]time.Duration) *BenchResults {
+	results := &BenchResults{Tasks: make(map[string]BenchResult)}
+	for name, values := range durations {
+		sorted := append([]time.Duration{}, values...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		results.Tasks[name] = BenchResult{
+			Runs:   len(sorted),
+			Min:    sorted[0].Seconds(),
+			Median: percentile(sorted, 50).Seconds(),
+			P90:    percentile(sorted, 90).Seconds(),
+			Max:    sorted[len(sorted)-1].Seconds(),
+		}
+	}
+	return results
+}
+
+// percentile returns the nearest-rank percentile of the sorted durations
+func percentile(sorted []time.Duration, pct int) time.Duration {
+	rank := (pct*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func readBenchBaseline(path string) (*BenchResults, error) {
+	raw, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		logging.Log.Infof("No baseline found at %s", path)
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	baseline := &BenchResults{}
+	if err := json.Unmarshal(raw, baseline); err != nil {
+		return nil, fmt.Errorf("failed to read baseline %s: %s", path, err)
+	}
+	return baseline, nil
+}
+
+// WriteFile writes the results to path as JSON
+func (r *BenchResults) WriteFile(path string) error {
+	raw, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, raw, 0644)
+}
+
+// WriteTable writes a table of the results, and the change in the median
+// duration from the baseline, to out
+func (r *BenchResults) WriteTable(
+	out io.Writer,
+	order []string,
+	baseline *BenchResults,
+) error {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "TASK\tRUNS\tMIN\tMEDIAN\tP90\tMAX\tBASELINE\tCHANGE")
+	for _, name := range order {
+		result := r.Tasks[name]
+		base, change := "-", "-"
+		if previous, ok := baseline.get(name); ok {
+			base = formatSeconds(previous.Median)
+			change = formatChange(previous.Median, result.Median)
+		}
+		fmt.Fprintf(writer, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			name, result.Runs,
+			formatSeconds(result.Min), formatSeconds(result.Median),
+			formatSeconds(result.P90), formatSeconds(result.Max),
+			base, change)
+	}
+	return writer.Flush()
+}
+
+// regressions returns an error if the median duration of any task increased
+// by more than threshold, as a fraction of the baseline
+func (r *BenchResults) regressions(
+	order []string,
+	baseline *BenchResults,
+	threshold float64,
+) error {
+	slower := []string{}
+	for _, name := range order {
+		previous, ok := baseline.get(name)
+		if !ok {
+			continue
+		}
+		current := r.Tasks[name].Median
+		delta := current - previous.Median
+		if delta > benchNoise.Seconds() && delta > previous.Median*threshold {
+			slower = append(slower, fmt.Sprintf("%s (%s)",
+				name, formatChange(previous.Median, current)))
+		}
+	}
+	if len(slower) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d tasks are slower than the baseline by more than %.0f%%: %s",
+		len(slower), threshold*100, strings.Join(slower, ", "))
+}
+
+func (r *BenchResults) get(name string) (BenchResult, bool) {
+	if r == nil {
+		return BenchResult{}, false
+	}
+	result, ok := r.Tasks[name]
+	return result, ok
+}
+
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond).String()
+}
+
+func formatChange(previous, current float64) string {
+	if previous == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", (current-previous)/previous*100)
+}
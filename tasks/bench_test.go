@@ -0,0 +1,93 @@
+package tasks
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func benchDurations(values ...float64) []time.Duration {
+	durations := []time.Duration{}
+	for _, value := range values {
+		durations = append(durations, time.Duration(value*float64(time.Second)))
+	}
+	return durations
+}
+
+func TestNewBenchResults(t *testing.T) {
+	results := newBenchResults(map[string][]time.Duration{
+		"test:run": benchDurations(3, 1, 2, 5, 4),
+	})
+	expected := BenchResult{Runs: 5, Min: 1, Median: 3, P90: 5, Max: 5}
+	assert.Check(t, is.DeepEqual(results.Tasks["test:run"], expected))
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := benchDurations(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	assert.Check(t, is.Equal(percentile(sorted, 50), 5*time.Second))
+	assert.Check(t, is.Equal(percentile(sorted, 90), 9*time.Second))
+	assert.Check(t, is.Equal(percentile(benchDurations(4), 90), 4*time.Second))
+}
+
+func TestBenchResultsRegressions(t *testing.T) {
+	baseline := &BenchResults{Tasks: map[string]BenchResult{
+		"build:build": {Median: 10},
+		"test:run":    {Median: 4},
+		"lint:run":    {Median: 0.05},
+	}}
+	results := &BenchResults{Tasks: map[string]BenchResult{
+		"build:build": {Median: 10.5},
+		"test:run":    {Median: 6},
+		"lint:run":    {Median: 0.1},
+		"new:run":     {Median: 30},
+	}}
+	order := []string{"build:build", "test:run", "lint:run", "new:run"}
+
+	assert.Check(t, results.regressions(order, nil, 0.1))
+	err := results.regressions(order, baseline, 0.1)
+	assert.Check(t, is.Error(err,
+		"1 tasks are slower than the baseline by more than 10%: test:run (+50.0%)"))
+	assert.Check(t, results.regressions(order, baseline, 0.6))
+}
+
+func TestBenchResultsWriteTable(t *testing.T) {
+	baseline := &BenchResults{Tasks: map[string]BenchResult{"test:run": {Median: 2}}}
+	results := &BenchResults{Tasks: map[string]BenchResult{
+		"build:build": {Runs: 2, Min: 1, Median: 1.5, P90: 2, Max: 2},
+		"test:run":    {Runs: 2, Min: 2, Median: 2.5, P90: 3, Max: 3},
+	}}
+
+	buf := new(bytes.Buffer)
+	assert.NilError(t, results.WriteTable(buf, []string{"build:build", "test:run"}, baseline))
+	expected := `TASK         RUNS  MIN  MEDIAN  P90  MAX  BASELINE  CHANGE
+build:build  2     1s   1.5s    2s   2s   -         -
+test:run     2     2s   2.5s    3s   3s   2s        +25.0%
+`
+	assert.Check(t, is.Equal(buf.String(), expected))
+}
+
+func TestBenchBaselineRoundTrip(t *testing.T) {
+	dir := fs.NewDir(t, "bench-baseline")
+	defer dir.Remove()
+	path := filepath.Join(dir.Path(), ".dobi", "bench.json")
+
+	baseline, err := readBenchBaseline(path)
+	assert.NilError(t, err)
+	assert.Check(t, is.Nil(baseline))
+
+	results := newBenchResults(map[string][]time.Duration{"test:run": benchDurations(1, 2)})
+	assert.NilError(t, results.WriteFile(path))
+	baseline, err = readBenchBaseline(path)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(baseline, results))
+}
+
+func TestBenchRequiresRuns(t *testing.T) {
+	err := Bench(BenchOptions{Runs: 0})
+	assert.Check(t, is.Error(err, "runs must be at least 1, not 0"))
+}
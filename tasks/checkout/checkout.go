@@ -0,0 +1,230 @@
+package checkout
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+func init() {
+	types.RegisterActions(
+		"checkout",
+		types.Action{Name: "fetch", Description: "Fetch the ref and check it out"},
+		types.Action{Name: "remove", Description: "Remove the checkout directory"},
+	)
+}
+
+// GetTaskConfig returns a new task for the action
+func GetTaskConfig(
+	name, action string,
+	conf *config.CheckoutConfig,
+) (types.TaskConfig, error) {
+	switch action {
+	case "", "fetch":
+		return types.NewTaskConfig(
+			task.NewDefaultName(name, "fetch"), conf, deps(conf), newTask), nil
+	case "remove", "rm":
+		return types.NewTaskConfig(
+			task.NewName(name, "rm"), conf, task.NoDependencies, newRemoveTask), nil
+	default:
+		return nil, fmt.Errorf("invalid checkout action %q for task %q", action, name)
+	}
+}
+
+func deps(conf *config.CheckoutConfig) func() []string {
+	return func() []string {
+		return conf.Dependencies()
+	}
+}
+
+var commitPattern = regexp.MustCompile("^[0-9a-f]{40}$")
+
+// Task fetches the ref of a git repository into the checkout directory
+type Task struct {
+	types.NoStop
+	name   task.Name
+	config *config.CheckoutConfig
+}
+
+func newTask(name task.Name, conf config.Resource) types.Task {
+	return &Task{name: name, config: conf.(*config.CheckoutConfig)}
+}
+
+// Name returns the name of the task
+func (t *Task) Name() task.Name {
+	return t.name
+}
+
+// Repr formats the task for logging
+func (t *Task) Repr() string {
+	return fmt.Sprintf("%s %s", t.name.Format("checkout"), t.config.Repo)
+}
+
+// Run fetches the ref and checks it out. The task is modified when the
+// checkout moves to a different commit.
+func (t *Task) Run(ctx *context.ExecuteContext, _ bool) (bool, error) {
+	dir := absPath(ctx.WorkingDir, t.config.Directory(t.name.Resource()))
+	if err := t.init(dir); err != nil {
+		return false, err
+	}
+
+	before := currentCommit(dir)
+	// A commit never changes, so there is nothing to fetch when it is
+	// already checked out
+	if before != "" && before == t.config.Ref && commitPattern.MatchString(t.config.Ref) {
+		logging.Skipped(t)
+		return false, nil
+	}
+
+	fetch := []string{"fetch", "--quiet"}
+	if t.config.Depth > 0 {
+		fetch = append(fetch, "--depth", strconv.Itoa(t.config.Depth))
+	}
+	fetch = append(fetch, "origin", t.ref())
+	if _, err := git(dir, fetch...); err != nil {
+		return false, err
+	}
+	_, err := git(dir, "checkout", "--quiet", "--force", "--detach", "FETCH_HEAD")
+	if err != nil {
+		return false, err
+	}
+	// apply changes to the sparse paths when the commit has not changed
+	if _, err := git(dir, "read-tree", "-mu", "HEAD"); err != nil {
+		return false, err
+	}
+
+	after := currentCommit(dir)
+	if after == before {
+		logging.Skipped(t)
+		return false, nil
+	}
+	logging.ForTask(t).Infof("Checked out %s", after)
+	return true, nil
+}
+
+func (t *Task) ref() string {
+	if t.config.Ref == "" {
+		return "HEAD"
+	}
+	return t.config.Ref
+}
+
+// init creates the repository in dir, or updates the remote and sparse paths
+// of an existing repository
+func (t *Task) init(dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if _, err := git(dir, "init", "--quiet"); err != nil {
+			return err
+		}
+		if _, err := git(dir, "remote", "add", "origin", t.config.Repo); err != nil {
+			return err
+		}
+	} else if _, err := git(dir, "remote", "set-url", "origin", t.config.Repo); err != nil {
+		return err
+	}
+	return t.setSparse(dir)
+}
+
+func (t *Task) setSparse(dir string) error {
+	sparse := len(t.config.Sparse) > 0
+	_, err := git(dir, "config", "core.sparseCheckout", strconv.FormatBool(sparse))
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(dir, ".git", "info", "sparse-checkout")
+	if !sparse {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, []byte(sparsePatterns(t.config.Sparse)), 0644)
+}
+
+// sparsePatterns returns the patterns of the sparse-checkout file, which
+// match each path from the root of the repository
+func sparsePatterns(paths []string) string {
+	buf := new(bytes.Buffer)
+	for _, path := range paths {
+		fmt.Fprintf(buf, "/%s\n", strings.TrimPrefix(filepath.ToSlash(path), "/"))
+	}
+	return buf.String()
+}
+
+func currentCommit(dir string) string {
+	out, err := git(dir, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+func git(dir string, args ...string) (string, error) {
+	logging.Log.Debugf("Running git %s in %s", strings.Join(args, " "), dir)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s %s",
+			args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func absPath(workingDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(workingDir, path)
+}
+
+// RemoveTask removes the checkout directory
+type RemoveTask struct {
+	types.NoStop
+	name   task.Name
+	config *config.CheckoutConfig
+}
+
+func newRemoveTask(name task.Name, conf config.Resource) types.Task {
+	return &RemoveTask{name: name, config: conf.(*config.CheckoutConfig)}
+}
+
+// Name returns the name of the task
+func (t *RemoveTask) Name() task.Name {
+	return t.name
+}
+
+// Repr formats the task for logging
+func (t *RemoveTask) Repr() string {
+	return fmt.Sprintf("%s %s", t.name.Format("checkout"), t.config.Repo)
+}
+
+// Run removes the checkout directory
+func (t *RemoveTask) Run(ctx *context.ExecuteContext, _ bool) (bool, error) {
+	dir := absPath(ctx.WorkingDir, t.config.Directory(t.name.Resource()))
+	if err := os.RemoveAll(dir); err != nil {
+		return false, err
+	}
+	logging.ForTask(t).Info("Removed")
+	return true, nil
+}
@@ -0,0 +1,83 @@
+package checkout
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func commit(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NilError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NilError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com",
+			"commit", "--quiet", "-m", "change"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		assert.NilError(t, err, string(out))
+	}
+}
+
+func TestTaskRun(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := fs.NewDir(t, "test-checkout-repo")
+	defer repo.Remove()
+	_, err := git(repo.Path(), "init", "--quiet")
+	assert.NilError(t, err)
+	commit(t, repo.Path(), map[string]string{"proto/api.proto": "v1", "README": "readme"})
+
+	work := fs.NewDir(t, "test-checkout-work")
+	defer work.Remove()
+	ctx := &context.ExecuteContext{WorkingDir: work.Path()}
+	checkoutTask := newTask(task.NewName("protos", "fetch"), &config.CheckoutConfig{
+		Repo:   repo.Path(),
+		Depth:  1,
+		Sparse: []string{"proto/"},
+	})
+
+	modified, err := checkoutTask.Run(ctx, false)
+	assert.NilError(t, err)
+	assert.Assert(t, modified)
+
+	dir := work.Join(".dobi", "checkouts", "protos")
+	content, err := ioutil.ReadFile(filepath.Join(dir, "proto", "api.proto"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(content), "v1"))
+	_, err = os.Stat(filepath.Join(dir, "README"))
+	assert.Check(t, os.IsNotExist(err), "sparse checkout should not include README")
+
+	// Next run is a no-op
+	modified, err = checkoutTask.Run(ctx, false)
+	assert.NilError(t, err)
+	assert.Assert(t, !modified)
+
+	commit(t, repo.Path(), map[string]string{"proto/api.proto": "v2"})
+	modified, err = checkoutTask.Run(ctx, false)
+	assert.NilError(t, err)
+	assert.Assert(t, modified)
+	content, err = ioutil.ReadFile(filepath.Join(dir, "proto", "api.proto"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(content), "v2"))
+}
+
+func TestSparsePatterns(t *testing.T) {
+	assert.Check(t, is.Equal(sparsePatterns([]string{"proto/", "/docs"}), "/proto/\n/docs\n"))
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveHost returns the address of a Docker host. If host is a URL it is
+// returned unmodified, otherwise it is used as the name of a Docker context
+// and the address is read from the context metadata.
+func ResolveHost(host string) (string, error) {
+	if strings.Contains(host, "://") {
+		return host, nil
+	}
+	return hostFromContext(contextMetaDir(), host)
+}
+
+type contextMeta struct {
+	Endpoints map[string]struct {
+		Host string
+	}
+}
+
+func contextMetaDir() string {
+	configDir := os.Getenv("DOCKER_CONFIG")
+	if configDir == "" {
+		configDir = filepath.Join(os.Getenv("HOME"), ".docker")
+	}
+	return filepath.Join(configDir, "contexts", "meta")
+}
+
+func hostFromContext(metaDir string, name string) (string, error) {
+	digest := fmt.Sprintf("%x", sha256.Sum256([]byte(name)))
+	raw, err := ioutil.ReadFile(filepath.Join(metaDir, digest, "meta.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read docker context %q: %s", name, err)
+	}
+	meta := contextMeta{}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return "", fmt.Errorf("invalid docker context %q: %s", name, err)
+	}
+	endpoint, ok := meta.Endpoints["docker"]
+	if !ok || endpoint.Host == "" {
+		return "", fmt.Errorf("docker context %q has no docker endpoint", name)
+	}
+	return endpoint.Host, nil
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestHostFromContext(t *testing.T) {
+	digest := fmt.Sprintf("%x", sha256.Sum256([]byte("arm-builder")))
+	dir := fs.NewDir(t, "test-host-from-context",
+		fs.WithDir(digest, fs.WithFile("meta.json",
+			`{"Name":"arm-builder","Endpoints":{"docker":{"Host":"tcp://arm:2376"}}}`)))
+	defer dir.Remove()
+
+	host, err := hostFromContext(dir.Path(), "arm-builder")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("tcp://arm:2376", host))
+
+	_, err = hostFromContext(dir.Path(), "missing")
+	assert.Check(t, is.ErrorContains(err, `failed to read docker context "missing"`))
+}
+
+func TestResolveHostWithAddress(t *testing.T) {
+	host, err := ResolveHost("tcp://10.0.0.2:2375")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("tcp://10.0.0.2:2375", host))
+}
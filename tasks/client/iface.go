@@ -1,6 +1,7 @@
 package client
 
 import (
+	"github.com/docker/docker/api/types/registry"
 	docker "github.com/fsouza/go-dockerclient"
 )
 
@@ -14,16 +15,28 @@ type DockerClient interface {
 	PullImage(docker.PullImageOptions, docker.AuthConfiguration) error
 	RemoveImage(string) error
 	TagImage(string, docker.TagImageOptions) error
+	ExportImages(docker.ExportImagesOptions) error
+	LoadImage(docker.LoadImageOptions) error
+	InspectDistribution(string) (*registry.DistributionInspect, error)
 
 	AttachToContainerNonBlocking(docker.AttachToContainerOptions) (docker.CloseWaiter, error)
 	CreateContainer(docker.CreateContainerOptions) (*docker.Container, error)
+	InspectContainer(string) (*docker.Container, error)
+	Ping() error
+	ContainerChanges(string) ([]docker.Change, error)
 	KillContainer(docker.KillContainerOptions) error
+	StopContainer(string, uint) error
+	ListContainers(docker.ListContainersOptions) ([]docker.APIContainers, error)
 	RemoveContainer(docker.RemoveContainerOptions) error
 	StartContainer(string, *docker.HostConfig) error
 	WaitContainer(string) (int, error)
 	DownloadFromContainer(id string, opts docker.DownloadFromContainerOptions) error
+	ConnectNetwork(id string, opts docker.NetworkConnectionOptions) error
+	RemoveNetwork(id string) error
 
 	CreateVolume(opts docker.CreateVolumeOptions) (*docker.Volume, error)
 	RemoveVolume(name string) error
 	ResizeContainerTTY(id string, height, width int) error
+
+	Info() (*docker.DockerInfo, error)
 }
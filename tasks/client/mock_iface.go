@@ -4,6 +4,7 @@
 package client
 
 import (
+	registry "github.com/docker/docker/api/types/registry"
 	go_dockerclient "github.com/fsouza/go-dockerclient"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
@@ -105,6 +106,43 @@ func (_mr *MockDockerClientMockRecorder) TagImage(arg0, arg1 interface{}) *gomoc
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "TagImage", reflect.TypeOf((*MockDockerClient)(nil).TagImage), arg0, arg1)
 }
 
+// ExportImages mocks base method
+func (_m *MockDockerClient) ExportImages(_param0 go_dockerclient.ExportImagesOptions) error {
+	ret := _m.ctrl.Call(_m, "ExportImages", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportImages indicates an expected call of ExportImages
+func (_mr *MockDockerClientMockRecorder) ExportImages(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "ExportImages", reflect.TypeOf((*MockDockerClient)(nil).ExportImages), arg0)
+}
+
+// LoadImage mocks base method
+func (_m *MockDockerClient) LoadImage(_param0 go_dockerclient.LoadImageOptions) error {
+	ret := _m.ctrl.Call(_m, "LoadImage", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LoadImage indicates an expected call of LoadImage
+func (_mr *MockDockerClientMockRecorder) LoadImage(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "LoadImage", reflect.TypeOf((*MockDockerClient)(nil).LoadImage), arg0)
+}
+
+// InspectDistribution mocks base method
+func (_m *MockDockerClient) InspectDistribution(_param0 string) (*registry.DistributionInspect, error) {
+	ret := _m.ctrl.Call(_m, "InspectDistribution", _param0)
+	ret0, _ := ret[0].(*registry.DistributionInspect)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InspectDistribution indicates an expected call of InspectDistribution
+func (_mr *MockDockerClientMockRecorder) InspectDistribution(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "InspectDistribution", reflect.TypeOf((*MockDockerClient)(nil).InspectDistribution), arg0)
+}
+
 // AttachToContainerNonBlocking mocks base method
 func (_m *MockDockerClient) AttachToContainerNonBlocking(_param0 go_dockerclient.AttachToContainerOptions) (go_dockerclient.CloseWaiter, error) {
 	ret := _m.ctrl.Call(_m, "AttachToContainerNonBlocking", _param0)
@@ -167,6 +205,30 @@ func (_mr *MockDockerClientMockRecorder) StartContainer(arg0, arg1 interface{})
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "StartContainer", reflect.TypeOf((*MockDockerClient)(nil).StartContainer), arg0, arg1)
 }
 
+// ConnectNetwork mocks base method
+func (_m *MockDockerClient) ConnectNetwork(_param0 string, _param1 go_dockerclient.NetworkConnectionOptions) error {
+	ret := _m.ctrl.Call(_m, "ConnectNetwork", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConnectNetwork indicates an expected call of ConnectNetwork
+func (_mr *MockDockerClientMockRecorder) ConnectNetwork(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "ConnectNetwork", reflect.TypeOf((*MockDockerClient)(nil).ConnectNetwork), arg0, arg1)
+}
+
+// RemoveNetwork mocks base method
+func (_m *MockDockerClient) RemoveNetwork(_param0 string) error {
+	ret := _m.ctrl.Call(_m, "RemoveNetwork", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveNetwork indicates an expected call of RemoveNetwork
+func (_mr *MockDockerClientMockRecorder) RemoveNetwork(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "RemoveNetwork", reflect.TypeOf((*MockDockerClient)(nil).RemoveNetwork), arg0)
+}
+
 // WaitContainer mocks base method
 func (_m *MockDockerClient) WaitContainer(_param0 string) (int, error) {
 	ret := _m.ctrl.Call(_m, "WaitContainer", _param0)
@@ -228,3 +290,79 @@ func (_m *MockDockerClient) ResizeContainerTTY(id string, height int, width int)
 func (_mr *MockDockerClientMockRecorder) ResizeContainerTTY(arg0, arg1, arg2 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "ResizeContainerTTY", reflect.TypeOf((*MockDockerClient)(nil).ResizeContainerTTY), arg0, arg1, arg2)
 }
+
+// ListContainers mocks base method
+func (_m *MockDockerClient) ListContainers(_param0 go_dockerclient.ListContainersOptions) ([]go_dockerclient.APIContainers, error) {
+	ret := _m.ctrl.Call(_m, "ListContainers", _param0)
+	ret0, _ := ret[0].([]go_dockerclient.APIContainers)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListContainers indicates an expected call of ListContainers
+func (_mr *MockDockerClientMockRecorder) ListContainers(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "ListContainers", reflect.TypeOf((*MockDockerClient)(nil).ListContainers), arg0)
+}
+
+// InspectContainer mocks base method
+func (_m *MockDockerClient) InspectContainer(_param0 string) (*go_dockerclient.Container, error) {
+	ret := _m.ctrl.Call(_m, "InspectContainer", _param0)
+	ret0, _ := ret[0].(*go_dockerclient.Container)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InspectContainer indicates an expected call of InspectContainer
+func (_mr *MockDockerClientMockRecorder) InspectContainer(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "InspectContainer", reflect.TypeOf((*MockDockerClient)(nil).InspectContainer), arg0)
+}
+
+// Info mocks base method
+func (_m *MockDockerClient) Info() (*go_dockerclient.DockerInfo, error) {
+	ret := _m.ctrl.Call(_m, "Info")
+	ret0, _ := ret[0].(*go_dockerclient.DockerInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Info indicates an expected call of Info
+func (_mr *MockDockerClientMockRecorder) Info() *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "Info", reflect.TypeOf((*MockDockerClient)(nil).Info))
+}
+
+// ContainerChanges mocks base method
+func (_m *MockDockerClient) ContainerChanges(_param0 string) ([]go_dockerclient.Change, error) {
+	ret := _m.ctrl.Call(_m, "ContainerChanges", _param0)
+	ret0, _ := ret[0].([]go_dockerclient.Change)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContainerChanges indicates an expected call of ContainerChanges
+func (_mr *MockDockerClientMockRecorder) ContainerChanges(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "ContainerChanges", reflect.TypeOf((*MockDockerClient)(nil).ContainerChanges), arg0)
+}
+
+// Ping mocks base method
+func (_m *MockDockerClient) Ping() error {
+	ret := _m.ctrl.Call(_m, "Ping")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping
+func (_mr *MockDockerClientMockRecorder) Ping() *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "Ping", reflect.TypeOf((*MockDockerClient)(nil).Ping))
+}
+
+// StopContainer mocks base method
+func (_m *MockDockerClient) StopContainer(_param0 string, _param1 uint) error {
+	ret := _m.ctrl.Call(_m, "StopContainer", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StopContainer indicates an expected call of StopContainer
+func (_mr *MockDockerClientMockRecorder) StopContainer(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "StopContainer", reflect.TypeOf((*MockDockerClient)(nil).StopContainer), arg0, arg1)
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Factory creates a new DockerClient connected to host
+type Factory func(host string) (DockerClient, error)
+
+// Pool is a collection of DockerClient keyed by host. Clients are created
+// the first time they are requested, and reused after that.
+type Pool struct {
+	mu            sync.Mutex
+	defaultClient DockerClient
+	factory       Factory
+	clients       map[string]DockerClient
+}
+
+// NewPool returns a new Pool. defaultClient is returned for an empty host.
+// factory may be nil, in which case only the default client is available.
+func NewPool(defaultClient DockerClient, factory Factory) *Pool {
+	return &Pool{
+		defaultClient: defaultClient,
+		factory:       factory,
+		clients:       make(map[string]DockerClient),
+	}
+}
+
+// Get returns the client for host. The host may be a Docker host address or
+// the name of a Docker context.
+func (p *Pool) Get(host string) (DockerClient, error) {
+	if host == "" {
+		return p.defaultClient, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if client, ok := p.clients[host]; ok {
+		return client, nil
+	}
+	if p.factory == nil {
+		return nil, fmt.Errorf("no client available for docker host %q", host)
+	}
+
+	address, err := ResolveHost(host)
+	if err != nil {
+		return nil, err
+	}
+	client, err := p.factory(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for %q: %s", host, err)
+	}
+	p.clients[host] = client
+	return client, nil
+}
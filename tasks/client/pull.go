@@ -0,0 +1,49 @@
+package client
+
+import "sync"
+
+// PullManager deduplicates the pulls of an image. A pull of an image which is
+// already being pulled waits for that pull to finish, and an image which was
+// pulled successfully is not pulled again.
+type PullManager struct {
+	mu    sync.Mutex
+	pulls map[string]*pull
+}
+
+type pull struct {
+	done chan struct{}
+	err  error
+}
+
+// NewPullManager returns a new PullManager
+func NewPullManager() *PullManager {
+	return &PullManager{pulls: make(map[string]*pull)}
+}
+
+// Pull calls pullFunc to pull the image identified by key, unless the image
+// is being pulled or was already pulled. It returns true if pullFunc was
+// called. A failed pull is not recorded, so the next call pulls the image
+// again. A nil PullManager always calls pullFunc.
+func (m *PullManager) Pull(key string, pullFunc func() error) (bool, error) {
+	if m == nil {
+		return true, pullFunc()
+	}
+	m.mu.Lock()
+	if existing, ok := m.pulls[key]; ok {
+		m.mu.Unlock()
+		<-existing.done
+		return false, existing.err
+	}
+	current := &pull{done: make(chan struct{})}
+	m.pulls[key] = current
+	m.mu.Unlock()
+
+	current.err = pullFunc()
+	if current.err != nil {
+		m.mu.Lock()
+		delete(m.pulls, key)
+		m.mu.Unlock()
+	}
+	close(current.done)
+	return true, current.err
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestPullManagerDeduplicatesConcurrentPulls(t *testing.T) {
+	manager := NewPullManager()
+	var calls int32
+	release := make(chan struct{})
+	pullFunc := func() error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil
+	}
+
+	wg := sync.WaitGroup{}
+	pulledCount := int32(0)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pulled, err := manager.Pull("alpine:3.12", pullFunc)
+			assert.Check(t, err)
+			if pulled {
+				atomic.AddInt32(&pulledCount, 1)
+			}
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	pulled, err := manager.Pull("alpine:3.12", pullFunc)
+	assert.NilError(t, err)
+	assert.Check(t, !pulled)
+	assert.Check(t, is.Equal(atomic.LoadInt32(&calls), int32(1)))
+	assert.Check(t, is.Equal(atomic.LoadInt32(&pulledCount), int32(1)))
+}
+
+func TestPullManagerRetriesFailedPull(t *testing.T) {
+	manager := NewPullManager()
+	pulled, err := manager.Pull("alpine:3.12", func() error {
+		return fmt.Errorf("toomanyrequests")
+	})
+	assert.Check(t, pulled)
+	assert.Check(t, is.Error(err, "toomanyrequests"))
+
+	pulled, err = manager.Pull("alpine:3.12", func() error { return nil })
+	assert.NilError(t, err)
+	assert.Check(t, pulled)
+}
@@ -9,6 +9,20 @@ import (
 	"github.com/dnephin/dobi/tasks/types"
 )
 
+func init() {
+	types.RegisterActions(
+		"compose",
+		types.Action{
+			Name:        "up",
+			Description: "Start the Compose project, and stop it when dobi exits",
+		},
+		types.Action{Name: "attach", Description: "Start the Compose project in the foreground"},
+		types.Action{Name: "detach", Description: "Start the Compose project and leave it running"},
+		types.Action{Name: "logs", Description: "Follow the logs of the running Compose project"},
+		types.Action{Name: "down", Description: "Stop and remove the Compose project"},
+	)
+}
+
 // GetTaskConfig returns a new task for the action
 func GetTaskConfig(name, action string, conf *config.ComposeConfig) (types.TaskConfig, error) {
 	act, err := getAction(action, name, conf)
@@ -52,6 +66,8 @@ func getAction(name string, resname string, conf *config.ComposeConfig) (action,
 	case "detach":
 		return newAction(
 			task.NewDefaultName(resname, "detach"), RunUp, nil, deps(conf))
+	case "logs":
+		return newAction(task.NewName(resname, "logs"), RunLogs, nil, noDeps)
 	default:
 		return action{}, fmt.Errorf("invalid compose action %q for task %q", name, resname)
 	}
@@ -70,21 +86,32 @@ func NewTask(run actionFunc, stop actionFunc) func(task.Name, config.Resource) t
 }
 
 // RunUp starts the Compose project
-func RunUp(_ *context.ExecuteContext, t *Task) error {
+func RunUp(ctx *context.ExecuteContext, t *Task) error {
 	t.logger().Info("project up")
-	return t.execCompose("up", "-d")
+	if err := t.execCompose(ctx, withServices(t.config, "up", "-d")...); err != nil {
+		return err
+	}
+	if t.config.WaitHealthy {
+		return waitHealthy(ctx, t)
+	}
+	return nil
 }
 
 // StopUp stops the project
-func StopUp(_ *context.ExecuteContext, t *Task) error {
+func StopUp(ctx *context.ExecuteContext, t *Task) error {
 	t.logger().Info("project stop")
-	return t.execCompose("stop", "-t", t.config.StopGraceString())
+	return t.execCompose(ctx, withServices(t.config, "stop", "-t", t.config.StopGraceString())...)
 }
 
 // RunDown removes all the project resources
-func RunDown(_ *context.ExecuteContext, t *Task) error {
+func RunDown(ctx *context.ExecuteContext, t *Task) error {
 	t.logger().Info("project down")
-	return t.execCompose("down")
+	return t.execCompose(ctx, "down")
+}
+
+// withServices appends the services from the config to the args
+func withServices(conf *config.ComposeConfig, args ...string) []string {
+	return append(args, conf.Services...)
 }
 
 func deps(conf *config.ComposeConfig) func() []string {
@@ -12,8 +12,24 @@ import (
 // RunUpAttached starts the Compose project
 func RunUpAttached(ctx *context.ExecuteContext, t *Task) error {
 	t.logger().Info("project up")
+	args := withServices(t.config, "up", "-t", t.config.StopGraceString())
+	return runForeground(ctx, t, args...)
+}
+
+// RunLogs follows the logs of the running Compose project. The project is not
+// started.
+func RunLogs(ctx *context.ExecuteContext, t *Task) error {
+	t.logger().Info("project logs")
+	return runForeground(ctx, t, withServices(t.config, "logs", "-f")...)
+}
 
-	cmd := t.buildCommand("up", "-t", t.config.StopGraceString())
+// runForeground runs a docker-compose command which stays attached until it
+// exits or is interrupted. Signals are forwarded to the command.
+func runForeground(ctx *context.ExecuteContext, t *Task, args ...string) error {
+	cmd, err := t.buildCommand(ctx, args...)
+	if err != nil {
+		return err
+	}
 	if err := cmd.Start(); err != nil {
 		return err
 	}
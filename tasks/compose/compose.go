@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/dnephin/dobi/config"
 	"github.com/dnephin/dobi/logging"
 	"github.com/dnephin/dobi/tasks/context"
 	"github.com/dnephin/dobi/tasks/task"
+	"github.com/docker/cli/opts"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -52,27 +54,79 @@ func StopNothing(_ *context.ExecuteContext, _ *Task) error {
 	return nil
 }
 
+// buildCommandArgs returns the global arguments for docker-compose. The files
+// are passed in order, so Compose merges each file over the previous files.
 func buildCommandArgs(conf *config.ComposeConfig) []string {
 	args := []string{}
 	for _, filename := range conf.Files {
 		args = append(args, "-f", filename)
 	}
+	for _, profile := range conf.Profiles {
+		args = append(args, "--profile", profile)
+	}
 	return append(args, "-p", conf.Project)
 }
 
-func (t *Task) execCompose(args ...string) error {
-	if err := t.buildCommand(args...).Run(); err != nil {
+// checkFilesExist returns an error if any of the Compose files are missing.
+// Compose would fail with the same error, but only after some of the
+// containers may have been created.
+func checkFilesExist(conf *config.ComposeConfig, workingDir string) error {
+	for _, filename := range conf.Files {
+		if _, err := os.Stat(absPath(filename, workingDir)); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("compose file %q does not exist", filename)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func absPath(path string, wd string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(wd, path)
+}
+
+func buildCommandEnv(conf *config.ComposeConfig, workingDir string) ([]string, error) {
+	env := os.Environ()
+	if conf.EnvFile != "" {
+		vars, err := opts.ParseEnvFile(absPath(conf.EnvFile, workingDir))
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, vars...)
+	}
+	return append(env, conf.Environment...), nil
+}
+
+func (t *Task) execCompose(ctx *context.ExecuteContext, args ...string) error {
+	cmd, err := t.buildCommand(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Run(); err != nil {
 		return err
 	}
 	t.logger().Info("Done")
 	return nil
 }
 
-func (t *Task) buildCommand(args ...string) *exec.Cmd {
+func (t *Task) buildCommand(ctx *context.ExecuteContext, args ...string) (*exec.Cmd, error) {
+	if err := checkFilesExist(t.config, ctx.WorkingDir); err != nil {
+		return nil, err
+	}
+	env, err := buildCommandEnv(t.config, ctx.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
 	args = append(buildCommandArgs(t.config), args...)
 	cmd := exec.Command("docker-compose", args...)
 	t.logger().Debugf("Args: %s", args)
-	cmd.Stdout = os.Stdout
+	cmd.Dir = ctx.WorkingDir
+	cmd.Env = env
+	cmd.Stdout = logging.Log.Out
 	cmd.Stderr = os.Stderr
-	return cmd
+	return cmd, nil
 }
@@ -0,0 +1,66 @@
+package compose
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestBuildCommandEnv(t *testing.T) {
+	envFile := fs.NewFile(t, "compose-env", fs.WithContent("APP_TAG=v1\nAPP_PORT=8080\n"))
+	defer envFile.Remove()
+
+	env, err := buildCommandEnv(&config.ComposeConfig{
+		EnvFile:     envFile.Path(),
+		Environment: []string{"APP_TAG=v2"},
+	}, "/project")
+	assert.NilError(t, err)
+	n := len(env)
+	assert.Assert(t, n >= 3)
+	expected := []string{"APP_TAG=v1", "APP_PORT=8080", "APP_TAG=v2"}
+	assert.Check(t, is.DeepEqual(env[n-3:], expected))
+}
+
+func TestBuildCommandEnvMissingFile(t *testing.T) {
+	_, err := buildCommandEnv(&config.ComposeConfig{EnvFile: "/does/not/exist.env"}, "/project")
+	assert.ErrorContains(t, err, "exist.env")
+}
+
+func TestBuildCommandArgs(t *testing.T) {
+	args := buildCommandArgs(&config.ComposeConfig{
+		Files:    []string{"docker-compose.yml", "docker-compose.override.yml"},
+		Profiles: []string{"debug"},
+		Project:  "web",
+	})
+	expected := []string{
+		"-f", "docker-compose.yml",
+		"-f", "docker-compose.override.yml",
+		"--profile", "debug",
+		"-p", "web",
+	}
+	assert.Check(t, is.DeepEqual(args, expected))
+}
+
+func TestWithServices(t *testing.T) {
+	conf := &config.ComposeConfig{}
+	assert.Check(t, is.DeepEqual(withServices(conf, "up", "-d"), []string{"up", "-d"}))
+
+	conf.Services = []string{"db", "redis"}
+	assert.Check(t, is.DeepEqual(withServices(conf, "up", "-d"),
+		[]string{"up", "-d", "db", "redis"}))
+}
+
+func TestCheckFilesExist(t *testing.T) {
+	dir := fs.NewDir(t, "compose-files", fs.WithFile("docker-compose.yml", ""))
+	defer dir.Remove()
+
+	conf := &config.ComposeConfig{Files: []string{"docker-compose.yml"}}
+	assert.Check(t, checkFilesExist(conf, dir.Path()))
+
+	conf.Files = append(conf.Files, "docker-compose.dev.yml")
+	assert.Check(t, is.ErrorContains(checkFilesExist(conf, dir.Path()),
+		`compose file "docker-compose.dev.yml" does not exist`))
+}
@@ -0,0 +1,122 @@
+package compose
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// Labels set by Compose on the containers of a project
+const (
+	projectLabel = "com.docker.compose.project"
+	serviceLabel = "com.docker.compose.service"
+	oneoffLabel  = "com.docker.compose.oneoff"
+)
+
+// healthyInterval is the time between the checks of wait-healthy
+var healthyInterval = time.Second
+
+// notReady is returned by a check which failed, and may pass later
+type notReady struct {
+	reason string
+}
+
+func (e *notReady) Error() string {
+	return e.reason
+}
+
+// waitHealthy waits for all the containers of the project to be ready. It
+// returns an error if the containers are not ready before the timeout, or if
+// a container exited.
+func waitHealthy(ctx *context.ExecuteContext, t *Task) error {
+	timeout := t.config.WaitTimeoutDuration()
+	deadline := time.Now().Add(timeout)
+	t.logger().Infof("Waiting up to %s for the services to be healthy", timeout)
+	for {
+		err := checkProjectHealthy(ctx, strings.ToLower(t.config.Project))
+		if err == nil {
+			t.logger().Info("Services are healthy")
+			return nil
+		}
+		if _, ok := err.(*notReady); !ok {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("services were not healthy after %s: %s", timeout, err)
+		}
+		t.logger().Debugf("Not healthy: %s", err)
+		time.Sleep(healthyInterval)
+	}
+}
+
+func checkProjectHealthy(ctx *context.ExecuteContext, project string) error {
+	containers, err := ctx.Client.ListContainers(docker.ListContainersOptions{
+		All: true,
+		Filters: map[string][]string{
+			"label": {projectLabel + "=" + project, oneoffLabel + "=False"},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the containers of the project: %s", err)
+	}
+	if len(containers) == 0 {
+		return &notReady{reason: "no containers were found for the project"}
+	}
+	for _, apiContainer := range containers {
+		container, err := ctx.Client.InspectContainer(apiContainer.ID)
+		if err != nil {
+			return fmt.Errorf("failed to inspect container: %s", err)
+		}
+		if err := checkContainerHealthy(ctx, container); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkContainerHealthy(ctx *context.ExecuteContext, container *docker.Container) error {
+	service := container.Name
+	if container.Config != nil && container.Config.Labels[serviceLabel] != "" {
+		service = container.Config.Labels[serviceLabel]
+	}
+	switch {
+	case container.State.Restarting:
+		return &notReady{reason: fmt.Sprintf("service %s is restarting", service)}
+	case container.State.Status == "created":
+		return &notReady{reason: fmt.Sprintf("service %s has not started", service)}
+	case !container.State.Running && container.State.ExitCode == 0:
+		// A service which runs once, like a migration, has completed
+		return nil
+	case !container.State.Running:
+		return fmt.Errorf("service %s exited with code %d", service, container.State.ExitCode)
+	}
+
+	switch status := container.State.Health.Status; status {
+	case "healthy":
+		return nil
+	case "":
+	default:
+		return &notReady{reason: fmt.Sprintf("service %s is %s", service, status)}
+	}
+
+	if container.NetworkSettings == nil {
+		return nil
+	}
+	for port, bindings := range container.NetworkSettings.Ports {
+		if port.Proto() != "tcp" || len(bindings) == 0 {
+			continue
+		}
+		address := net.JoinHostPort(ctx.DockerHostname(), bindings[0].HostPort)
+		conn, err := net.DialTimeout("tcp", address, healthyInterval)
+		if err != nil {
+			return &notReady{reason: fmt.Sprintf(
+				"port %s of service %s is not accepting connections", port.Port(), service)}
+		}
+		conn.Close() // nolint: errcheck
+	}
+	return nil
+}
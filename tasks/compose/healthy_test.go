@@ -0,0 +1,92 @@
+package compose
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func patchHealthyInterval(interval time.Duration) func() {
+	original := healthyInterval
+	healthyInterval = interval
+	return func() { healthyInterval = original }
+}
+
+func newHealthyTask(timeout string) *Task {
+	return &Task{
+		name:   task.NewName("devenv", "up"),
+		config: &config.ComposeConfig{Project: "WebApp", WaitHealthy: true, WaitTimeout: timeout},
+	}
+}
+
+func expectProjectContainers(mockClient *client.MockDockerClient) *gomock.Call {
+	return mockClient.EXPECT().ListContainers(docker.ListContainersOptions{
+		All: true,
+		Filters: map[string][]string{
+			"label": {"com.docker.compose.project=webapp", "com.docker.compose.oneoff=False"},
+		},
+	}).Return([]docker.APIContainers{{ID: "db-id"}, {ID: "migrate-id"}}, nil)
+}
+
+func serviceContainer(service string, state docker.State) *docker.Container {
+	return &docker.Container{
+		Config: &docker.Config{Labels: map[string]string{serviceLabel: service}},
+		State:  state,
+	}
+}
+
+func TestWaitHealthy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	defer patchHealthyInterval(time.Millisecond)()
+	ctx := &context.ExecuteContext{Client: mockClient}
+
+	migrate := serviceContainer("migrate", docker.State{Status: "exited"})
+	gomock.InOrder(
+		expectProjectContainers(mockClient),
+		mockClient.EXPECT().InspectContainer("db-id").Return(serviceContainer("db",
+			docker.State{Running: true, Health: docker.Health{Status: "starting"}}), nil),
+		expectProjectContainers(mockClient),
+		mockClient.EXPECT().InspectContainer("db-id").Return(serviceContainer("db",
+			docker.State{Running: true, Health: docker.Health{Status: "healthy"}}), nil),
+		mockClient.EXPECT().InspectContainer("migrate-id").Return(migrate, nil),
+	)
+	assert.NilError(t, waitHealthy(ctx, newHealthyTask("1m")))
+}
+
+func TestWaitHealthyExitedService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	ctx := &context.ExecuteContext{Client: mockClient}
+
+	expectProjectContainers(mockClient)
+	mockClient.EXPECT().InspectContainer("db-id").Return(serviceContainer("db",
+		docker.State{Status: "exited", ExitCode: 3}), nil)
+	err := waitHealthy(ctx, newHealthyTask("1m"))
+	assert.Check(t, is.Error(err, "service db exited with code 3"))
+}
+
+func TestWaitHealthyTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	defer patchHealthyInterval(time.Millisecond)()
+	ctx := &context.ExecuteContext{Client: mockClient}
+
+	expectProjectContainers(mockClient).AnyTimes()
+	mockClient.EXPECT().InspectContainer("db-id").Return(serviceContainer("db",
+		docker.State{Running: true, Health: docker.Health{Status: "unhealthy"}}), nil).AnyTimes()
+	err := waitHealthy(ctx, newHealthyTask("10ms"))
+	assert.Check(t, is.ErrorContains(err, "services were not healthy after 10ms"))
+	assert.Check(t, is.ErrorContains(err, "service db is unhealthy"))
+}
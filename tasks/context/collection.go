@@ -1,6 +1,8 @@
 package context
 
 import (
+	"sync"
+
 	"github.com/dnephin/dobi/config"
 )
 
@@ -8,43 +10,111 @@ import (
 // TODO: this type can be removed if config.Config is changed to store resources
 // grouped by type, instead of as a single map
 type ResourceCollection struct {
-	mounts map[string]*config.MountConfig
-	images map[string]*config.ImageConfig
+	// mu guards the maps, because tasks in a parallel alias add resources
+	// concurrently
+	mu       sync.RWMutex
+	mounts   map[string]*config.MountConfig
+	images   map[string]*config.ImageConfig
+	envs     map[string]*config.EnvConfig
+	composes map[string]*config.ComposeConfig
+	jobs     map[string]*config.JobConfig
 }
 
 // Add a resource to the collection
 func (c *ResourceCollection) Add(name string, resource config.Resource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	switch resource := resource.(type) {
 	case *config.MountConfig:
 		c.mounts[name] = resource
 	case *config.ImageConfig:
 		c.images[name] = resource
+	case *config.EnvConfig:
+		c.envs[name] = resource
+	case *config.ComposeConfig:
+		c.composes[name] = resource
+	case *config.JobConfig:
+		c.jobs[name] = resource
+	case *config.CheckoutConfig:
+		c.mounts[name] = resource.Mount(name)
 	}
 }
 
 // Mount returns a config.MountConfig by name
 func (c *ResourceCollection) Mount(name string) *config.MountConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.mounts[name]
 }
 
 // Image returns an config.ImageConfig by name
 func (c *ResourceCollection) Image(name string) *config.ImageConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.images[name]
 }
 
+// Images returns a copy of all the image configs in the collection, keyed by
+// resource name
+func (c *ResourceCollection) Images() map[string]*config.ImageConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	images := make(map[string]*config.ImageConfig, len(c.images))
+	for name, image := range c.images {
+		images[name] = image
+	}
+	return images
+}
+
+// Env returns a config.EnvConfig by name, or nil if there is no env resource
+// with the name
+func (c *ResourceCollection) Env(name string) *config.EnvConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.envs[name]
+}
+
+// Job returns a config.JobConfig by name, or nil if there is no job resource
+// with the name
+func (c *ResourceCollection) Job(name string) *config.JobConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.jobs[name]
+}
+
+// Compose returns a config.ComposeConfig by name, or nil if there is no
+// compose resource with the name
+func (c *ResourceCollection) Compose(name string) *config.ComposeConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.composes[name]
+}
+
+// addEnvs adds all the env resources. They are used by the args-from of an
+// image, which does not depend on the env resource.
+func (c *ResourceCollection) addEnvs(resources map[string]config.Resource) {
+	for name, resource := range resources {
+		if env, ok := resource.(*config.EnvConfig); ok {
+			c.envs[name] = env
+		}
+	}
+}
+
 type eachMountFunc func(name string, vol *config.MountConfig)
 
 // EachMount iterates all the mounts in names and calls f for each
 func (c *ResourceCollection) EachMount(names []string, f eachMountFunc) {
 	for _, name := range names {
-		mount := c.mounts[name]
-		f(name, mount)
+		f(name, c.Mount(name))
 	}
 }
 
 func newResourceCollection() *ResourceCollection {
 	return &ResourceCollection{
-		mounts: make(map[string]*config.MountConfig),
-		images: make(map[string]*config.ImageConfig),
+		mounts:   make(map[string]*config.MountConfig),
+		images:   make(map[string]*config.ImageConfig),
+		envs:     make(map[string]*config.EnvConfig),
+		composes: make(map[string]*config.ComposeConfig),
+		jobs:     make(map[string]*config.JobConfig),
 	}
 }
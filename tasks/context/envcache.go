@@ -0,0 +1,37 @@
+package context
+
+import "sync"
+
+// EnvCache stores the variables of each env resource, so that the files of an
+// env resource are read once in a run, even when the variables are used by
+// many tasks
+type EnvCache struct {
+	mu   sync.Mutex
+	vars map[string][]string
+}
+
+// NewEnvCache returns a new EnvCache
+func NewEnvCache() *EnvCache {
+	return &EnvCache{vars: make(map[string][]string)}
+}
+
+// Get returns the variables of the env resource identified by name. The
+// variables are loaded by calling load the first time the resource is used. A
+// failed load is not stored, so the next call loads the variables again. A nil
+// EnvCache always calls load.
+func (c *EnvCache) Get(name string, load func() ([]string, error)) ([]string, error) {
+	if c == nil {
+		return load()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if vars, ok := c.vars[name]; ok {
+		return vars, nil
+	}
+	vars, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.vars[name] = vars
+	return vars, nil
+}
@@ -1,6 +1,12 @@
 package context
 
 import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"os"
+	"sync"
+
 	"github.com/dnephin/dobi/config"
 	"github.com/dnephin/dobi/execenv"
 	"github.com/dnephin/dobi/logging"
@@ -11,21 +17,91 @@ import (
 
 // ExecuteContext contains all the context for task execution
 type ExecuteContext struct {
-	modified    map[string]bool
+	modified    *modifiedTasks
 	Resources   *ResourceCollection
 	Client      client.DockerClient
+	Clients     *client.Pool
 	authConfigs *docker.AuthConfigurations
 	WorkingDir  string
 	ConfigFile  string
+	// Endpoint is the name of the matrix endpoint used by this execution
+	Endpoint string
+	// Builders are the hosts of each builder from meta.builders
+	Builders map[string][]string
+	// ArtifactStore is the URL used by the job upload and download actions
+	ArtifactStore string
+	// PullMirrors are the registry mirrors used when a pull from Docker Hub
+	// fails
+	PullMirrors []string
+	// Teardown are the settings used to stop the tasks which were started
+	Teardown config.Teardown
+	// Host is the Docker host used by Client, empty for the default host
+	Host string
+	// Tracker records the temporary resources created by tasks
+	Tracker *Tracker
+	// Pulls deduplicates the image pulls of all the tasks
+	Pulls *client.PullManager
+	// Envs stores the variables of the env resources used by the tasks
+	Envs *EnvCache
+	// HostProfile is set when the Docker host matches the small host profile
+	HostProfile *config.HostProfile
+	// Parallelism is the maximum number of tasks of a parallel step which run
+	// at the same time. Zero is no limit.
+	Parallelism int
 	Env         *execenv.ExecEnv
 	Settings    Settings
+	interrupt   *interrupt
+}
+
+// interrupt records the signal which interrupted the execution. It is shared
+// by all the copies of an ExecuteContext.
+type interrupt struct {
+	mu     sync.Mutex
+	signal os.Signal
+}
+
+// Interrupt marks the execution as interrupted by sig. Only the first signal
+// is recorded.
+func (ctx *ExecuteContext) Interrupt(sig os.Signal) {
+	if ctx.interrupt == nil {
+		return
+	}
+	ctx.interrupt.mu.Lock()
+	defer ctx.interrupt.mu.Unlock()
+	if ctx.interrupt.signal == nil {
+		ctx.interrupt.signal = sig
+	}
+}
+
+// Interrupted returns the signal which interrupted the execution, or nil if
+// the execution was not interrupted
+func (ctx *ExecuteContext) Interrupted() os.Signal {
+	if ctx.interrupt == nil {
+		return nil
+	}
+	ctx.interrupt.mu.Lock()
+	defer ctx.interrupt.mu.Unlock()
+	return ctx.interrupt.signal
+}
+
+// modifiedTasks records the tasks which were modified. It is shared by all the
+// copies of an ExecuteContext, and tasks in a parallel alias set it
+// concurrently.
+type modifiedTasks struct {
+	mu    sync.Mutex
+	names map[string]bool
 }
 
 // IsModified returns true if any of the tasks named in names has been modified
 // during this execution
 func (ctx *ExecuteContext) IsModified(names ...task.Name) bool {
+	if ctx.modified == nil {
+		return false
+	}
+	ctx.modified.mu.Lock()
+	defer ctx.modified.mu.Unlock()
 	for _, name := range names {
-		if modified := ctx.modified[name.MapKey()]; modified {
+		if modified := ctx.modified.names[name.MapKey()]; modified {
 			return true
 		}
 	}
@@ -34,10 +110,103 @@ func (ctx *ExecuteContext) IsModified(names ...task.Name) bool {
 
 // SetModified sets the task name as modified
 func (ctx *ExecuteContext) SetModified(name task.Name) {
+	if ctx.modified == nil {
+		return
+	}
+	ctx.modified.mu.Lock()
+	defer ctx.modified.mu.Unlock()
 	// Add both the key and the string name so that it matches against
 	// dependencies specified with or without an action
-	ctx.modified[name.MapKey()] = true
-	ctx.modified[name.Name()] = true
+	ctx.modified.names[name.MapKey()] = true
+	ctx.modified.names[name.Name()] = true
+}
+
+// ForHost returns a copy of the ExecuteContext which uses the client for the
+// Docker host. If host is empty the ExecuteContext is returned unmodified.
+func (ctx *ExecuteContext) ForHost(host string) (*ExecuteContext, error) {
+	if host == "" {
+		return ctx, nil
+	}
+	if ctx.Clients == nil {
+		return nil, fmt.Errorf("no client available for docker host %q", host)
+	}
+	hostClient, err := ctx.Clients.Get(host)
+	if err != nil {
+		return nil, err
+	}
+	hostCtx := *ctx
+	hostCtx.Client = hostClient
+	hostCtx.Host = host
+	return &hostCtx, nil
+}
+
+// Track adds a temporary resource on the Docker host of the context to the
+// Tracker. It returns a function which releases the resource, and must be
+// called after the resource is removed.
+func (ctx *ExecuteContext) Track(kind string, id string) func() {
+	if ctx.Tracker == nil {
+		return func() {}
+	}
+	resource := TrackedResource{Kind: kind, ID: id}
+	if kind != TrackedDir {
+		resource.Host = ctx.Host
+	}
+	ctx.Tracker.Track(resource)
+	return func() { ctx.Tracker.Release(resource) }
+}
+
+// DockerHostname returns the hostname used to connect to the ports published
+// by the Docker host
+func (ctx *ExecuteContext) DockerHostname() string {
+	address := ctx.Host
+	if address == "" {
+		address = os.Getenv("DOCKER_HOST")
+	}
+	host, err := url.Parse(address)
+	if err != nil || host.Scheme != "tcp" || host.Hostname() == "" {
+		return "127.0.0.1"
+	}
+	return host.Hostname()
+}
+
+// ForBuilder returns a copy of the ExecuteContext which uses the client for
+// one of the hosts of the builder. The first host is picked using key, so the
+// same key uses the same host. Hosts which do not respond are skipped.
+func (ctx *ExecuteContext) ForBuilder(builder string, key string) (*ExecuteContext, error) {
+	hosts, ok := ctx.Builders[builder]
+	if !ok || len(hosts) == 0 {
+		return nil, fmt.Errorf("undefined builder %q", builder)
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(key)) // nolint: errcheck
+	start := int(hash.Sum32() % uint32(len(hosts)))
+	for i := range hosts {
+		host := hosts[(start+i)%len(hosts)]
+		hostCtx, err := ctx.ForHost(host)
+		if err == nil {
+			err = hostCtx.Client.Ping()
+		}
+		if err != nil {
+			logging.Log.Warnf("Builder %q host %q is not available: %s", builder, host, err)
+			continue
+		}
+		logging.Log.Debugf("Using builder %q host %q for %s", builder, host, key)
+		return hostCtx, nil
+	}
+	return nil, fmt.Errorf("no hosts of builder %q are available", builder)
+}
+
+// ForWorkingDir returns a copy of the ExecuteContext which uses dir as the
+// working directory. If dir is the current working directory the
+// ExecuteContext is returned unmodified.
+func (ctx *ExecuteContext) ForWorkingDir(dir string) *ExecuteContext {
+	if dir == ctx.WorkingDir {
+		return ctx
+	}
+	dirCtx := *ctx
+	dirCtx.WorkingDir = dir
+	return &dirCtx
 }
 
 // GetAuthConfig returns the auth configuration for the repo
@@ -62,10 +231,40 @@ func (ctx *ExecuteContext) GetAuthConfigs() docker.AuthConfigurations {
 	return *ctx.authConfigs
 }
 
+// artifactStore returns the artifact store from the config, which may not
+// have a Meta
+func artifactStore(config *config.Config) string {
+	if config.Meta == nil {
+		return ""
+	}
+	return config.Meta.ArtifactStore
+}
+
+func pullMirrors(config *config.Config) []string {
+	if config.Meta == nil {
+		return nil
+	}
+	return config.Meta.PullMirrors
+}
+
+func teardown(conf *config.Config) config.Teardown {
+	if conf.Meta == nil {
+		return config.Teardown{}
+	}
+	return conf.Meta.Teardown
+}
+
+func builders(config *config.Config) map[string][]string {
+	if config.Meta == nil {
+		return nil
+	}
+	return config.Meta.Builders
+}
+
 // NewExecuteContext craetes a new empty ExecuteContext
 func NewExecuteContext(
 	config *config.Config,
-	client client.DockerClient,
+	dockerClient client.DockerClient,
 	execEnv *execenv.ExecEnv,
 	settings Settings,
 ) *ExecuteContext {
@@ -74,14 +273,24 @@ func NewExecuteContext(
 		logging.Log.Warnf("Failed to load auth config: %s", err)
 	}
 
+	resources := newResourceCollection()
+	resources.addEnvs(config.Resources)
+
 	return &ExecuteContext{
-		modified:    make(map[string]bool),
-		Resources:   newResourceCollection(),
-		WorkingDir:  config.WorkingDir,
-		Client:      client,
-		authConfigs: authConfigs,
-		ConfigFile:  config.FilePath,
-		Env:         execEnv,
-		Settings:    settings,
+		modified:      &modifiedTasks{names: make(map[string]bool)},
+		Resources:     resources,
+		WorkingDir:    config.WorkingDir,
+		Client:        dockerClient,
+		authConfigs:   authConfigs,
+		ConfigFile:    config.FilePath,
+		ArtifactStore: artifactStore(config),
+		Builders:      builders(config),
+		PullMirrors:   pullMirrors(config),
+		Teardown:      teardown(config),
+		Pulls:         client.NewPullManager(),
+		Envs:          NewEnvCache(),
+		Env:           execEnv,
+		Settings:      settings,
+		interrupt:     &interrupt{},
 	}
 }
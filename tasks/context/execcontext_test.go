@@ -1,10 +1,13 @@
 package context
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/dnephin/dobi/tasks/client"
 	"github.com/dnephin/dobi/tasks/task"
 	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 )
@@ -16,7 +19,7 @@ func TestExecuteContext_GetAuthConfig_NoAuthConfig(t *testing.T) {
 }
 
 func TestExecuteContext_IsModified(t *testing.T) {
-	context := &ExecuteContext{modified: make(map[string]bool)}
+	context := &ExecuteContext{modified: &modifiedTasks{names: make(map[string]bool)}}
 	context.SetModified(task.ParseName("task1"))
 	context.SetModified(task.NewDefaultName("task2", "pull"))
 	context.SetModified(task.ParseName("task3:rm"))
@@ -62,3 +65,64 @@ func TestExecuteContext_IsModified(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteContext_ForHost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	remote := client.NewMockDockerClient(ctrl)
+
+	hosts := []string{}
+	ctx := &ExecuteContext{}
+	ctx.Clients = client.NewPool(nil, func(host string) (client.DockerClient, error) {
+		hosts = append(hosts, host)
+		return remote, nil
+	})
+
+	same, err := ctx.ForHost("")
+	assert.NilError(t, err)
+	assert.Check(t, same == ctx)
+
+	for i := 0; i < 2; i++ {
+		hostCtx, err := ctx.ForHost("tcp://remote:2376")
+		assert.NilError(t, err)
+		assert.Check(t, hostCtx.Client == remote)
+	}
+	assert.Check(t, is.DeepEqual([]string{"tcp://remote:2376"}, hosts))
+	assert.Check(t, ctx.Client == nil)
+}
+
+func TestExecuteContext_ForWorkingDir(t *testing.T) {
+	ctx := &ExecuteContext{WorkingDir: "/project"}
+	assert.Check(t, ctx.ForWorkingDir("/project") == ctx)
+
+	dirCtx := ctx.ForWorkingDir("/project/services/api")
+	assert.Check(t, is.Equal(dirCtx.WorkingDir, "/project/services/api"))
+	assert.Check(t, is.Equal(ctx.WorkingDir, "/project"))
+}
+
+func TestExecuteContext_ForBuilder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	clients := map[string]*client.MockDockerClient{
+		"tcp://build1:2376": client.NewMockDockerClient(ctrl),
+		"tcp://build2:2376": client.NewMockDockerClient(ctrl),
+	}
+	clients["tcp://build1:2376"].EXPECT().Ping().Return(fmt.Errorf("unavailable")).AnyTimes()
+	clients["tcp://build2:2376"].EXPECT().Ping().Return(nil).AnyTimes()
+
+	ctx := &ExecuteContext{
+		Builders: map[string][]string{"farm": {"tcp://build1:2376", "tcp://build2:2376"}},
+	}
+	ctx.Clients = client.NewPool(nil, func(host string) (client.DockerClient, error) {
+		return clients[host], nil
+	})
+
+	for _, key := range []string{"one", "two", "three"} {
+		hostCtx, err := ctx.ForBuilder("farm", key)
+		assert.NilError(t, err)
+		assert.Check(t, hostCtx.Client == clients["tcp://build2:2376"], key)
+	}
+
+	_, err := ctx.ForBuilder("bogus", "one")
+	assert.Check(t, is.ErrorContains(err, `undefined builder "bogus"`))
+}
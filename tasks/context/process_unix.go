@@ -0,0 +1,13 @@
+// +build !windows
+
+package context
+
+import (
+	"syscall"
+)
+
+// ProcessRunning returns true if a process with the pid exists
+func ProcessRunning(pid int) bool {
+	err := syscall.Kill(pid, syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,15 @@
+package context
+
+import (
+	"os"
+)
+
+// ProcessRunning returns true if a process with the pid exists
+func ProcessRunning(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	proc.Release() // nolint: errcheck
+	return true
+}
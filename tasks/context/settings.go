@@ -1,10 +1,45 @@
 package context
 
+import (
+	"io"
+	"os"
+)
+
 // Settings are flags that can be set by a user to change the behaviour of some
 // tasks
 type Settings struct {
 	Quiet     bool
 	BindMount bool
+	// PrefixOutput prefixes each line of job output with the name of the task
+	PrefixOutput bool
+	// Color enables color in the prefix of job output
+	Color bool
+	// JobOutput receives the stdout of jobs. Defaults to os.Stdout.
+	JobOutput io.Writer
+	// LogDir is the directory where the output of each job is also written to
+	// a log file. Empty disables the log files.
+	LogDir string
+	// Skip is the set of resource and task names which are treated as
+	// up-to-date
+	Skip map[string]bool
+	// Force is the set of resource and task names which are treated as stale
+	Force map[string]bool
+	// Completed is the set of task names which completed in the failed run
+	// that is being continued. The value is true if the task was modified.
+	Completed map[string]bool
+	// Strict fails jobs which exit with a warning exit code
+	Strict bool
+	// DefaultMemory is the memory limit in bytes for jobs which don't set
+	// memory. Zero means no limit.
+	DefaultMemory int64
+}
+
+// Output returns the writer used for the stdout of jobs
+func (s Settings) Output() io.Writer {
+	if s.JobOutput == nil {
+		return os.Stdout
+	}
+	return s.JobOutput
 }
 
 // NewSettings returns a new Settings
@@ -0,0 +1,201 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/client"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const trackerDir = ".dobi/run"
+
+// The kinds of temporary resources which are tracked
+const (
+	TrackedContainer = "container"
+	TrackedImage     = "image"
+	TrackedNetwork   = "network"
+	TrackedVolume    = "volume"
+	TrackedDir       = "dir"
+)
+
+// TrackedResource is a temporary resource created by a task
+type TrackedResource struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	// Host is the Docker host of the resource, empty for the default host
+	Host string `json:"host,omitempty"`
+}
+
+// Tracker records the temporary resources created during a run, so that any
+// resource which was not removed by the task that created it is removed when
+// the run ends. The resources are also written to a state file, so that the
+// resources of a run which was killed are removed by the next run.
+type Tracker struct {
+	mu        sync.Mutex
+	path      string
+	resources []TrackedResource
+}
+
+// NewTracker returns a Tracker which writes its state file to the .dobi
+// directory of the project
+func NewTracker(workingDir string, execID string) *Tracker {
+	name := fmt.Sprintf("%d-%s.json", os.Getpid(), execID)
+	return &Tracker{path: filepath.Join(workingDir, trackerDir, name)}
+}
+
+// Track adds a resource to the tracker
+func (t *Tracker) Track(resource TrackedResource) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resources = append(t.resources, resource)
+	t.save()
+}
+
+// Release removes a resource from the tracker, once it has been removed by
+// the task which created it
+func (t *Tracker) Release(resource TrackedResource) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, tracked := range t.resources {
+		if tracked == resource {
+			t.resources = append(t.resources[:i], t.resources[i+1:]...)
+			break
+		}
+	}
+	t.save()
+}
+
+// Cleanup removes all the resources which have not been released, in the
+// reverse of the order they were created
+func (t *Tracker) Cleanup(clients *client.Pool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	remaining := removeTracked(clients, t.resources)
+	t.resources = remaining
+	t.save()
+	if len(remaining) > 0 {
+		return fmt.Errorf("failed to remove %d temporary resources", len(remaining))
+	}
+	return nil
+}
+
+// save writes the state file, or removes it when there are no resources.
+// Errors are logged, because the state file is only used to recover from a
+// run which did not exit cleanly.
+func (t *Tracker) save() {
+	if len(t.resources) == 0 {
+		if err := os.Remove(t.path); err != nil && !os.IsNotExist(err) {
+			logging.Log.Warnf("Failed to remove %s: %s", t.path, err)
+		}
+		return
+	}
+	raw, err := json.Marshal(t.resources)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(t.path), 0755)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(t.path, raw, 0644)
+	}
+	if err != nil {
+		logging.Log.Warnf("Failed to write %s: %s", t.path, err)
+	}
+}
+
+// RemoveAbandoned removes the resources recorded in the state files of runs
+// which are no longer running
+func RemoveAbandoned(workingDir string, clients *client.Pool) {
+	paths, _ := filepath.Glob(filepath.Join(workingDir, trackerDir, "*.json"))
+	for _, path := range paths {
+		pid, err := strconv.Atoi(strings.SplitN(filepath.Base(path), "-", 2)[0])
+		if err != nil || pid == os.Getpid() || ProcessRunning(pid) {
+			continue
+		}
+		resources, err := readTrackerFile(path)
+		if err != nil {
+			logging.Log.Warnf("Failed to read %s: %s", path, err)
+			continue
+		}
+		logging.Log.Infof("Removing %d temporary resources from a previous run",
+			len(resources))
+		tracker := &Tracker{path: path, resources: resources}
+		if err := tracker.Cleanup(clients); err != nil {
+			logging.Log.Warn(err)
+		}
+	}
+}
+
+func readTrackerFile(path string) ([]TrackedResource, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	resources := []TrackedResource{}
+	return resources, json.Unmarshal(raw, &resources)
+}
+
+// removeTracked removes the resources in reverse order, and returns the
+// resources which could not be removed
+func removeTracked(clients *client.Pool, resources []TrackedResource) []TrackedResource {
+	remaining := []TrackedResource{}
+	for i := len(resources) - 1; i >= 0; i-- {
+		resource := resources[i]
+		if err := removeResource(clients, resource); err != nil {
+			logging.Log.Warnf("Failed to remove %s %s: %s", resource.Kind, resource.ID, err)
+			remaining = append([]TrackedResource{resource}, remaining...)
+			continue
+		}
+		logging.Log.Debugf("Removed %s %s", resource.Kind, resource.ID)
+	}
+	return remaining
+}
+
+func removeResource(clients *client.Pool, resource TrackedResource) error {
+	if resource.Kind == TrackedDir {
+		return os.RemoveAll(resource.ID)
+	}
+	if clients == nil {
+		return fmt.Errorf("no client available for docker host %q", resource.Host)
+	}
+	client, err := clients.Get(resource.Host)
+	if err != nil {
+		return err
+	}
+
+	switch resource.Kind {
+	case TrackedContainer:
+		err = client.RemoveContainer(docker.RemoveContainerOptions{
+			ID:            resource.ID,
+			RemoveVolumes: true,
+			Force:         true,
+		})
+		if _, ok := err.(*docker.NoSuchContainer); ok {
+			return nil
+		}
+	case TrackedImage:
+		err = client.RemoveImage(resource.ID)
+		if err == docker.ErrNoSuchImage {
+			return nil
+		}
+	case TrackedNetwork:
+		err = client.RemoveNetwork(resource.ID)
+		if _, ok := err.(*docker.NoSuchNetwork); ok {
+			return nil
+		}
+	case TrackedVolume:
+		err = client.RemoveVolume(resource.ID)
+		if err == docker.ErrNoSuchVolume {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", resource.Kind)
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package context
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dnephin/dobi/tasks/client"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestTrackerTrackAndRelease(t *testing.T) {
+	dir := fs.NewDir(t, "tracker")
+	defer dir.Remove()
+
+	tracker := NewTracker(dir.Path(), "exec")
+	container := TrackedResource{Kind: TrackedContainer, ID: "one"}
+	tracker.Track(container)
+	tracker.Track(TrackedResource{Kind: TrackedDir, ID: "/tmp/two"})
+
+	resources, err := readTrackerFile(tracker.path)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(resources, 2))
+
+	tracker.Release(container)
+	resources, err = readTrackerFile(tracker.path)
+	assert.NilError(t, err)
+	expected := []TrackedResource{{Kind: TrackedDir, ID: "/tmp/two"}}
+	assert.Check(t, is.DeepEqual(resources, expected))
+
+	tracker.Release(TrackedResource{Kind: TrackedDir, ID: "/tmp/two"})
+	_, err = os.Stat(tracker.path)
+	assert.Check(t, os.IsNotExist(err))
+}
+
+func TestTrackerCleanup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	dir := fs.NewDir(t, "tracker", fs.WithDir("tmp"))
+	defer dir.Remove()
+
+	tracker := NewTracker(dir.Path(), "exec")
+	tracker.Track(TrackedResource{Kind: TrackedDir, ID: dir.Join("tmp")})
+	tracker.Track(TrackedResource{Kind: TrackedImage, ID: "example:job"})
+	tracker.Track(TrackedResource{Kind: TrackedContainer, ID: "job"})
+
+	gomock.InOrder(
+		mockClient.EXPECT().RemoveContainer(docker.RemoveContainerOptions{
+			ID:            "job",
+			RemoveVolumes: true,
+			Force:         true,
+		}).Return(&docker.NoSuchContainer{ID: "job"}),
+		mockClient.EXPECT().RemoveImage("example:job").Return(nil),
+	)
+	assert.NilError(t, tracker.Cleanup(client.NewPool(mockClient, nil)))
+
+	_, err := os.Stat(dir.Join("tmp"))
+	assert.Check(t, os.IsNotExist(err))
+	_, err = os.Stat(tracker.path)
+	assert.Check(t, os.IsNotExist(err))
+}
+
+func TestTrackerCleanupKeepsFailedResources(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	dir := fs.NewDir(t, "tracker")
+	defer dir.Remove()
+
+	tracker := NewTracker(dir.Path(), "exec")
+	volume := TrackedResource{Kind: TrackedVolume, ID: "data"}
+	tracker.Track(volume)
+
+	mockClient.EXPECT().RemoveVolume("data").Return(docker.ErrVolumeInUse)
+	err := tracker.Cleanup(client.NewPool(mockClient, nil))
+	assert.Check(t, is.Error(err, "failed to remove 1 temporary resources"))
+
+	resources, err := readTrackerFile(tracker.path)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(resources, []TrackedResource{volume}))
+}
+
+func TestRemoveAbandoned(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	dir := fs.NewDir(t, "tracker",
+		fs.WithDir(".dobi", fs.WithDir("run",
+			fs.WithFile("999999999-exec.json", `[{"kind": "network", "id": "net"}]`))))
+	defer dir.Remove()
+
+	// The state file of this process is not removed
+	current := NewTracker(dir.Path(), "exec")
+	current.Track(TrackedResource{Kind: TrackedContainer, ID: "running"})
+
+	mockClient.EXPECT().RemoveNetwork("net").Return(nil)
+	RemoveAbandoned(dir.Path(), client.NewPool(mockClient, nil))
+
+	files, err := ioutil.ReadDir(filepath.Join(dir.Path(), trackerDir))
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(files, 1))
+	expected := filepath.Join(dir.Path(), trackerDir, files[0].Name())
+	assert.Check(t, is.Equal(expected, current.path))
+}
@@ -0,0 +1,173 @@
+package tasks
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+// DepsOptions are the options used by Deps
+type DepsOptions struct {
+	Client    client.DockerClient
+	NewClient client.Factory
+	Config    *config.Config
+	Tasks     []string
+	Params    map[string]string
+	Variables map[string]string
+	// Out receives the table of tasks
+	Out io.Writer
+}
+
+const (
+	depStale    = "stale"
+	depUpToDate = "up-to-date"
+	depUnknown  = "unknown"
+)
+
+// depState is the state of a task in the dependencies of the tasks
+type depState struct {
+	resource string
+	action   string
+	state    string
+	reason   string
+}
+
+// Deps prints every task which is run by the tasks in options, in the order
+// they are run, and if each task is stale. The tasks are not run.
+func Deps(options DepsOptions) error {
+	runOptions := RunOptions{Config: options.Config, Tasks: options.Tasks}
+	runOptions.Tasks = getNames(runOptions)
+	if len(runOptions.Tasks) == 0 {
+		return fmt.Errorf("no task given, and no default task defined")
+	}
+
+	execEnv, err := execenv.NewExecEnvFromConfig(
+		options.Config.Meta.ExecID,
+		options.Config.Meta.Project,
+		options.Config.WorkingDir,
+	)
+	if err != nil {
+		return err
+	}
+	if err := setVariableProviders(execEnv, options.Config.Meta.VariableProviders); err != nil {
+		return err
+	}
+	execEnv.SetVariables(options.Config.Meta.Variables)
+	execEnv.SetCommandLineVariables(options.Variables)
+
+	tasks, err := collectTasks(runOptions)
+	if err != nil {
+		return err
+	}
+	if err := setParams(execEnv, tasks, options.Params); err != nil {
+		return err
+	}
+
+	ctx := context.NewExecuteContext(
+		options.Config, options.Client, execEnv, context.NewSettings(true, true))
+	ctx.Clients = client.NewPool(options.Client, options.NewClient)
+	states, err := dependencyStates(ctx, tasks)
+	if err != nil {
+		return err
+	}
+	return writeDepsTable(options.Out, states)
+}
+
+// dependencyStates returns the state of each task in the collection. A task
+// is stale when one of its dependencies is stale, because the dependency is
+// modified when it runs.
+func dependencyStates(ctx *context.ExecuteContext, tasks *TaskCollection) ([]depState, error) {
+	// unresolved are the tasks with a resource which could not be resolved
+	stale, unresolved := make(map[string]bool), make(map[string]bool)
+	states := []depState{}
+	for _, taskConfig := range tasks.All() {
+		state, resolved, err := dependencyState(ctx, taskConfig, stale, unresolved)
+		if err != nil {
+			return nil, err
+		}
+		// Add both the key and the string name so that it matches against
+		// dependencies specified with or without an action
+		name := taskConfig.Name()
+		if state.state == depStale {
+			stale[name.MapKey()], stale[name.Name()] = true, true
+		}
+		if !resolved {
+			unresolved[name.MapKey()], unresolved[name.Name()] = true, true
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// dependencyState returns the state of the task, and false if the resource of
+// the task could not be resolved
+func dependencyState(
+	ctx *context.ExecuteContext,
+	taskConfig types.TaskConfig,
+	stale map[string]bool,
+	unresolved map[string]bool,
+) (depState, bool, error) {
+	name := taskConfig.Name()
+	state := depState{resource: name.Resource(), action: name.Action()}
+
+	resolver := resolverForResource(ctx, taskConfig.Resource())
+	resource, err := taskConfig.Resource().Resolve(resolver)
+	if err != nil {
+		// The variables set by env resources are not available because the
+		// tasks are not run, so a resource which uses them may not resolve
+		state.state, state.reason = depUnknown, err.Error()
+		return state, false, nil
+	}
+	ctx.Resources.Add(name.Resource(), resource)
+
+	for _, dep := range taskConfig.Dependencies() {
+		key := task.ParseName(dep).MapKey()
+		switch {
+		case stale[key]:
+			state.state, state.reason = depStale, "depends on "+dep
+			return state, true, nil
+		case unresolved[key]:
+			state.state, state.reason = depUnknown, "depends on "+dep
+		}
+	}
+	if state.state != "" {
+		return state, true, nil
+	}
+
+	checker, ok := taskConfig.Task(resource).(types.StaleChecker)
+	if !ok {
+		state.state, state.reason = depUnknown, "the task can not be checked without running it"
+		return state, true, nil
+	}
+	taskCtx, err := contextForResource(ctx, resource)
+	if err != nil {
+		return state, true, err
+	}
+	isStale, err := checker.IsStale(taskCtx)
+	switch {
+	case err != nil:
+		state.state, state.reason = depUnknown, err.Error()
+	case isStale:
+		state.state = depStale
+	default:
+		state.state = depUpToDate
+	}
+	return state, true, nil
+}
+
+func writeDepsTable(out io.Writer, states []depState) error {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "TASK\tACTION\tSTATE\tREASON")
+	for _, state := range states {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n",
+			state.resource, state.action, state.state, state.reason)
+	}
+	return writer.Flush()
+}
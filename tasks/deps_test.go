@@ -0,0 +1,74 @@
+package tasks
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type staleTask struct {
+	fakeTask
+	stale bool
+	err   error
+}
+
+func (t *staleTask) IsStale(_ *context.ExecuteContext) (bool, error) {
+	return t.stale, t.err
+}
+
+func TestDependencyStates(t *testing.T) {
+	ran := []string{}
+	newTaskConfig := func(name string, build types.TaskBuilder, deps ...string) types.TaskConfig {
+		return types.NewTaskConfig(
+			task.NewName(name, "run"),
+			&config.EnvConfig{},
+			func() []string { return deps },
+			build)
+	}
+	checked := func(stale bool, err error) types.TaskBuilder {
+		return func(name task.Name, _ config.Resource) types.Task {
+			return &staleTask{fakeTask: fakeTask{name: name, ran: &ran}, stale: stale, err: err}
+		}
+	}
+	unchecked := func(name task.Name, _ config.Resource) types.Task {
+		return &fakeTask{name: name, ran: &ran}
+	}
+	tasks := newTaskCollection()
+	tasks.add(newTaskConfig("base", checked(false, nil)))
+	tasks.add(newTaskConfig("lint", checked(false, fmt.Errorf("no image"))))
+	tasks.add(newTaskConfig("build", checked(true, nil), "base:run"))
+	tasks.add(newTaskConfig("vars", unchecked))
+	tasks.add(newTaskConfig("test", checked(false, nil), "build:run", "vars:run"))
+
+	ctx := context.NewExecuteContext(
+		config.NewConfig(), nil, execenv.NewExecEnv("exec", "project", "/dir"), context.Settings{})
+	states, err := dependencyStates(ctx, tasks)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(ran, 0))
+
+	summary := []string{}
+	for _, state := range states {
+		summary = append(summary, fmt.Sprintf("%s:%s %s %s",
+			state.resource, state.action, state.state, state.reason))
+	}
+	expected := []string{
+		"base:run up-to-date ",
+		"lint:run unknown no image",
+		"build:run stale ",
+		"vars:run unknown the task can not be checked without running it",
+		"test:run stale depends on build:run",
+	}
+	assert.Check(t, is.DeepEqual(summary, expected))
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, writeDepsTable(out, states))
+	assert.Check(t, is.Contains(out.String(), "TASK   ACTION  STATE       REASON\n"))
+}
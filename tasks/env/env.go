@@ -3,6 +3,7 @@ package env
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/dnephin/dobi/config"
@@ -13,6 +14,13 @@ import (
 	"github.com/docker/cli/opts"
 )
 
+func init() {
+	types.RegisterActions(
+		"env",
+		types.Action{Name: "set", Description: "Set the environment variables"},
+	)
+}
+
 // GetTaskConfig returns a new task for the action
 func GetTaskConfig(name, action string, conf *config.EnvConfig) (types.TaskConfig, error) {
 	switch action {
@@ -49,28 +57,46 @@ func (t *Task) Repr() string {
 }
 
 // Run sets environment variables
-func (t *Task) Run(_ *context.ExecuteContext, _ bool) (bool, error) {
-	var modified int
-	for _, filename := range t.config.Files {
-		vars, err := opts.ParseEnvFile(filename)
-		if err != nil {
-			return false, err
-		}
-		count, err := setVariables(vars)
-		if err != nil {
-			return false, err
-		}
-		modified += count
+func (t *Task) Run(ctx *context.ExecuteContext, _ bool) (bool, error) {
+	vars, err := Variables(ctx, t.name.Resource(), t.config)
+	if err != nil {
+		return false, err
 	}
-	count, err := setVariables(t.config.Variables)
+	modified, err := setVariables(vars)
 	if err != nil {
 		return false, err
 	}
-	modified += count
 	logging.ForTask(t).Info("Done")
 	return modified > 0, nil
 }
 
+// Variables returns the key=value pairs of the env resource. The variables
+// from each file are first, in the order of the files, followed by the
+// variables of the resource, so a later value of a key overrides an earlier
+// value. Relative paths are relative to the working directory of ctx. The
+// variables are cached by the name of the resource, so the files are only
+// read once in a run.
+func Variables(
+	ctx *context.ExecuteContext,
+	name string,
+	conf *config.EnvConfig,
+) ([]string, error) {
+	return ctx.Envs.Get(name, func() ([]string, error) {
+		vars := []string{}
+		for _, filename := range conf.Files {
+			if !filepath.IsAbs(filename) {
+				filename = filepath.Join(ctx.WorkingDir, filename)
+			}
+			fileVars, err := opts.ParseEnvFile(filename)
+			if err != nil {
+				return nil, err
+			}
+			vars = append(vars, fileVars...)
+		}
+		return append(vars, conf.Variables...), nil
+	})
+}
+
 func setVariables(vars []string) (int, error) {
 	var count int
 	for _, variable := range vars {
@@ -1,14 +1,16 @@
 package env
 
 import (
-	"testing"
-
+	"io/ioutil"
 	"os"
+	"testing"
 
 	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
 	"github.com/dnephin/dobi/tasks/task"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
 )
 
 func TestTask_Run(t *testing.T) {
@@ -43,7 +45,7 @@ func TestTask_Run(t *testing.T) {
 				Variables: toSlice(tc.vars),
 			})
 
-			modified, err := envTask.Run(nil, false)
+			modified, err := envTask.Run(&context.ExecuteContext{}, false)
 			assert.NilError(t, err)
 			assert.Equal(t, modified, tc.expected)
 
@@ -61,3 +63,19 @@ func toSlice(m map[string]string) []string {
 	}
 	return p
 }
+
+func TestVariablesAreCached(t *testing.T) {
+	dir := fs.NewDir(t, "env-cache", fs.WithFile("one.env", "FOO=one\nBAR=one\n"))
+	defer dir.Remove()
+
+	ctx := &context.ExecuteContext{WorkingDir: dir.Path(), Envs: context.NewEnvCache()}
+	conf := &config.EnvConfig{Files: []string{"one.env"}, Variables: []string{"BAR=two"}}
+	vars, err := Variables(ctx, "settings", conf)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, vars, []string{"FOO=one", "BAR=one", "BAR=two"})
+
+	assert.NilError(t, ioutil.WriteFile(dir.Join("one.env"), []byte("FOO=changed\n"), 0644))
+	vars, err = Variables(ctx, "settings", conf)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, vars, []string{"FOO=one", "BAR=one", "BAR=two"})
+}
@@ -0,0 +1,228 @@
+package tasks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/logging"
+)
+
+// Names of the events sent to meta.events-url
+const (
+	eventRunStarted   = "run.started"
+	eventRunFinished  = "run.finished"
+	eventTaskStarted  = "task.started"
+	eventTaskFinished = "task.finished"
+	eventTaskSkipped  = "task.skipped"
+	eventTaskFailed   = "task.failed"
+)
+
+// eventSignatureHeader is the header with the HMAC of the request body
+const eventSignatureHeader = "X-Dobi-Signature"
+
+var (
+	// eventAttempts is the number of times a request is sent before the
+	// event is dropped
+	eventAttempts = 3
+	// eventRetryDelay is the delay before the first retry. The delay doubles
+	// for each retry.
+	eventRetryDelay = 500 * time.Millisecond
+	// eventCloseTimeout is the longest time to wait for queued events to be
+	// sent when the run finishes
+	eventCloseTimeout = 30 * time.Second
+)
+
+// event is the JSON body of a request sent to meta.events-url
+type event struct {
+	Event    string    `json:"event"`
+	Run      string    `json:"run"`
+	Project  string    `json:"project"`
+	Endpoint string    `json:"endpoint,omitempty"`
+	Time     time.Time `json:"time"`
+	Task     string    `json:"task,omitempty"`
+	Tasks    []string  `json:"tasks,omitempty"`
+	Status   string    `json:"status,omitempty"`
+	Duration *float64  `json:"duration,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// eventStream sends events to meta.events-url in the order they occur. The
+// events are sent from a separate goroutine so that a slow endpoint does not
+// slow down the tasks. All the methods are safe to call on a nil eventStream,
+// which is used when no events-url is set.
+type eventStream struct {
+	url     string
+	secret  string
+	base    event
+	client  *http.Client
+	queue   chan event
+	stopped chan struct{}
+}
+
+func newEventStream(
+	meta *config.MetaConfig,
+	execEnv *execenv.ExecEnv,
+	endpoint string,
+) (*eventStream, error) {
+	if meta.EventsURL == "" {
+		return nil, nil
+	}
+	url, err := execEnv.Resolve(meta.EventsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve events-url: %s", err)
+	}
+	secret, err := execEnv.Resolve(meta.EventsSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve events-secret: %s", err)
+	}
+	run, err := randomSuffix()
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &eventStream{
+		url:     url,
+		secret:  secret,
+		base:    event{Run: run, Project: execEnv.Project, Endpoint: endpoint},
+		client:  &http.Client{Timeout: 10 * time.Second},
+		queue:   make(chan event, 256),
+		stopped: make(chan struct{}),
+	}
+	go stream.run()
+	return stream, nil
+}
+
+func (s *eventStream) run() {
+	defer close(s.stopped)
+	for current := range s.queue {
+		if err := s.post(current); err != nil {
+			logging.Log.Warnf("Failed to send %s event to events-url: %s", current.Event, err)
+		}
+	}
+}
+
+func (s *eventStream) send(current event) {
+	if s == nil {
+		return
+	}
+	current.Run = s.base.Run
+	current.Project = s.base.Project
+	current.Endpoint = s.base.Endpoint
+	current.Time = time.Now().UTC()
+	s.queue <- current
+}
+
+func (s *eventStream) runStarted(tasks *TaskCollection) {
+	if s == nil {
+		return
+	}
+	names := []string{}
+	for _, taskConfig := range tasks.All() {
+		names = append(names, taskConfig.Name().Name())
+	}
+	s.send(event{Event: eventRunStarted, Tasks: names})
+}
+
+func (s *eventStream) runFinished(err error) {
+	finished := event{Event: eventRunFinished, Status: "success"}
+	if err != nil {
+		finished.Status = "failure"
+		finished.Error = logging.Mask(err.Error())
+	}
+	s.send(finished)
+}
+
+func (s *eventStream) taskStarted(name string) {
+	s.send(event{Event: eventTaskStarted, Task: name})
+}
+
+func (s *eventStream) taskResult(result TaskResult) {
+	current := event{
+		Event:  eventTaskFinished,
+		Task:   result.Name,
+		Status: result.Status,
+		Error:  result.Error,
+	}
+	switch result.Status {
+	case StatusSkipped, StatusNotRun:
+		current.Event = eventTaskSkipped
+	case StatusFailed, StatusAllowedFailure:
+		current.Event = eventTaskFailed
+	}
+	if !result.Start.IsZero() {
+		duration := result.Duration.Seconds()
+		current.Duration = &duration
+	}
+	s.send(current)
+}
+
+// close waits for the queued events to be sent
+func (s *eventStream) close() {
+	if s == nil {
+		return
+	}
+	close(s.queue)
+	select {
+	case <-s.stopped:
+	case <-time.After(eventCloseTimeout):
+		logging.Log.Warn("Timed out sending events to events-url")
+	}
+}
+
+// post sends the event, and retries when the request fails or the endpoint
+// returns a server error
+func (s *eventStream) post(current event) error {
+	body, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	delay := eventRetryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := s.postOnce(body)
+		if err == nil || !retry || attempt >= eventAttempts {
+			return err
+		}
+		logging.Log.Debugf("Retrying %s event after error: %s", current.Event, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (s *eventStream) postOnce(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		req.Header.Set(eventSignatureHeader, "sha256="+signEvent(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("events-url returned %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("events-url returned %s", resp.Status)
+	}
+	return false, nil
+}
+
+// signEvent returns the hex encoded HMAC-SHA256 of body
+func signEvent(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body) // nolint: errcheck
+	return hex.EncodeToString(mac.Sum(nil))
+}
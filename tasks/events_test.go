@@ -0,0 +1,142 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/context"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type eventServer struct {
+	mu         sync.Mutex
+	events     []event
+	signatures []string
+	// failures is the number of requests which fail before a request is
+	// accepted
+	failures int
+}
+
+func (s *eventServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	received := event{}
+	if err := json.Unmarshal(body, &received); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.events = append(s.events, received)
+	s.signatures = append(s.signatures, r.Header.Get(eventSignatureHeader)+" "+string(body))
+}
+
+func patchEventRetryDelay(delay time.Duration) func() {
+	original := eventRetryDelay
+	eventRetryDelay = delay
+	return func() { eventRetryDelay = original }
+}
+
+func newTestEventStream(t *testing.T, url, secret string) *eventStream {
+	meta := &config.MetaConfig{EventsURL: url, EventsSecret: secret}
+	stream, err := newEventStream(meta, execenv.NewExecEnv("exec", "project", "/dir"), "")
+	assert.NilError(t, err)
+	return stream
+}
+
+func TestNewEventStreamWithoutURL(t *testing.T) {
+	stream, err := newEventStream(
+		&config.MetaConfig{}, execenv.NewExecEnv("exec", "project", "/dir"), "")
+	assert.NilError(t, err)
+	assert.Check(t, stream == nil)
+
+	// methods are safe to call on a nil stream
+	stream.taskStarted("one")
+	stream.close()
+}
+
+func TestEventStreamRetriesAndSigns(t *testing.T) {
+	defer patchEventRetryDelay(time.Millisecond)()
+	server := &eventServer{failures: 2}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	stream := newTestEventStream(t, httpServer.URL, "sekret")
+	stream.taskStarted("one:run")
+	stream.close()
+
+	assert.Assert(t, is.Len(server.events, 1))
+	assert.Check(t, is.Equal(server.events[0].Event, eventTaskStarted))
+	assert.Check(t, is.Equal(server.events[0].Task, "one:run"))
+	assert.Check(t, is.Equal(server.events[0].Project, "project"))
+
+	var signature, body string
+	_, err := fmt.Sscanf(server.signatures[0], "%s %s", &signature, &body)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(signature, "sha256="+signEvent("sekret", []byte(body))))
+}
+
+func TestEventStreamDropsEventAfterAttempts(t *testing.T) {
+	defer patchEventRetryDelay(time.Millisecond)()
+	server := &eventServer{failures: eventAttempts}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	stream := newTestEventStream(t, httpServer.URL, "")
+	stream.taskStarted("dropped:run")
+	stream.taskStarted("sent:run")
+	stream.close()
+
+	assert.Assert(t, is.Len(server.events, 1))
+	assert.Check(t, is.Equal(server.events[0].Task, "sent:run"))
+	assert.Check(t, is.Equal(server.signatures[0][:1], " "), "no signature without a secret")
+}
+
+func TestExecuteTasksSendsEvents(t *testing.T) {
+	server := &eventServer{}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	s := newScheduleTasks()
+	s.add("one", nil, nil)
+	s.add("two", fmt.Errorf("oops"), nil, "one:run")
+
+	ctx := context.NewExecuteContext(
+		config.NewConfig(), nil, execenv.NewExecEnv("exec", "project", "/dir"), context.Settings{})
+	report := &Report{events: newTestEventStream(t, httpServer.URL, "")}
+	report.events.runStarted(s.tasks)
+	err := executeTasks(ctx, s.tasks, report, false)
+	report.events.runFinished(err)
+	report.events.close()
+	assert.Check(t, is.ErrorContains(err, "oops"))
+
+	names := []string{}
+	for _, received := range server.events {
+		names = append(names, received.Event+" "+received.Task)
+	}
+	expected := []string{
+		"run.started ",
+		"task.started one:run",
+		"task.finished one:run",
+		"task.started two:run",
+		"task.failed two:run",
+		"run.finished ",
+	}
+	assert.Check(t, is.DeepEqual(names, expected))
+	assert.Check(t, is.DeepEqual(server.events[0].Tasks, []string{"one:run", "two:run"}))
+	assert.Check(t, is.Equal(server.events[4].Error, "oops"))
+	assert.Check(t, is.Equal(server.events[5].Status, "failure"))
+}
@@ -0,0 +1,248 @@
+package tasks
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	units "github.com/docker/go-units"
+)
+
+// GCOptions are the options used by GC
+type GCOptions struct {
+	Config *config.Config
+	// DryRun lists the entries which would be removed, without removing them
+	DryRun bool
+	// Out receives the list of entries which are removed
+	Out io.Writer
+}
+
+// gcEntry is a file or directory in an artifact directory
+type gcEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+	reason  string
+}
+
+// GC removes the entries of the artifact directories of jobs which are not
+// kept by meta.artifact-retention
+func GC(options GCOptions) error {
+	retention := options.Config.Meta.ArtifactRetention
+	if retention.IsZero() {
+		logging.Log.Info("Nothing to remove, meta.artifact-retention is not set")
+		return nil
+	}
+
+	groups, err := artifactEntries(options.Config)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	removed := []gcEntry{}
+	for _, entries := range groups {
+		removed = append(removed, expiredEntries(entries, retention, now)...)
+	}
+	if err := writeGCTable(options.Out, options.Config.WorkingDir, removed, now); err != nil {
+		return err
+	}
+
+	if options.DryRun {
+		return nil
+	}
+
+	var freed int64
+	for _, entry := range removed {
+		if err := os.RemoveAll(entry.path); err != nil {
+			return fmt.Errorf("failed to remove %s: %s", entry.path, err)
+		}
+		freed += entry.size
+	}
+	logging.Log.Infof("Removed %d entries, freed %s",
+		len(removed), units.HumanSize(float64(freed)))
+	return nil
+}
+
+// artifactEntries returns the entries of each artifact directory. When the
+// artifact of a job is a directory, the entries are the files in the
+// directory. When the artifact is a glob, the entries are the matched files,
+// grouped by directory.
+func artifactEntries(conf *config.Config) ([][]gcEntry, error) {
+	groups := make(map[string][]gcEntry)
+	order := []string{}
+	seen := make(map[string]bool)
+	add := func(dir, path string) error {
+		if seen[path] {
+			return nil
+		}
+		seen[path] = true
+		entry, err := newGCEntry(path)
+		if err != nil {
+			return err
+		}
+		if _, ok := groups[dir]; !ok {
+			order = append(order, dir)
+		}
+		groups[dir] = append(groups[dir], entry)
+		return nil
+	}
+
+	for _, name := range conf.Sorted() {
+		job, ok := conf.Resources[name].(*config.JobConfig)
+		if !ok {
+			continue
+		}
+		for _, glob := range job.Artifact.Globs() {
+			if !filepath.IsAbs(glob) {
+				glob = filepath.Join(conf.WorkingDir, glob)
+			}
+			matches, err := filepath.Glob(glob)
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				if err := checkArtifactPath(conf.WorkingDir, match); err != nil {
+					return nil, fmt.Errorf("refusing to remove artifact of %q: %s", name, err)
+				}
+				if err := addArtifact(match, hasGlobMeta(glob), add); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	result := [][]gcEntry{}
+	for _, dir := range order {
+		result = append(result, groups[dir])
+	}
+	return result, nil
+}
+
+// checkArtifactPath returns an error if the path is not inside the project
+// directory, or is in the .git directory, so that the project, and the files
+// outside of it, are never removed
+func checkArtifactPath(projectDir, path string) error {
+	rel, err := filepath.Rel(evalPath(projectDir), evalPath(path))
+	if err != nil {
+		return err
+	}
+	first := strings.SplitN(rel, string(filepath.Separator), 2)[0]
+	switch {
+	case rel == ".":
+		return fmt.Errorf("%s is the project directory", path)
+	case first == "..":
+		return fmt.Errorf("%s is not inside the project directory", path)
+	case first == ".git":
+		return fmt.Errorf("%s is in the .git directory", path)
+	}
+	return nil
+}
+
+// evalPath returns the path with symlinks resolved, or the clean path when
+// the symlinks can not be resolved
+func evalPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+func addArtifact(match string, isGlob bool, add func(dir, path string) error) error {
+	info, err := os.Stat(match)
+	switch {
+	case err != nil:
+		return err
+	case isGlob || !info.IsDir():
+		return add(filepath.Dir(match), match)
+	}
+	children, err := ioutil.ReadDir(match)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := add(match, filepath.Join(match, child.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasGlobMeta(glob string) bool {
+	return strings.ContainsAny(glob, `*?[\`)
+}
+
+// newGCEntry returns the total size, and the most recent modified time, of
+// the files in path
+func newGCEntry(path string) (gcEntry, error) {
+	entry := gcEntry{path: path}
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			entry.size += info.Size()
+		}
+		if info.ModTime().After(entry.modTime) {
+			entry.modTime = info.ModTime()
+		}
+		return nil
+	})
+	return entry, err
+}
+
+// expiredEntries returns the entries which are not kept by the retention,
+// with the setting which removes each entry
+func expiredEntries(
+	entries []gcEntry,
+	retention config.ArtifactRetention,
+	now time.Time,
+) []gcEntry {
+	sorted := append([]gcEntry{}, entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].modTime.After(sorted[j].modTime)
+	})
+
+	maxSize, maxAge := retention.MaxSizeBytes(), retention.MaxAgeDuration()
+	expired := []gcEntry{}
+	var kept int64
+	for i, entry := range sorted {
+		switch {
+		case retention.KeepLast > 0 && i >= retention.KeepLast:
+			entry.reason = "keep-last"
+		case maxAge > 0 && now.Sub(entry.modTime) > maxAge:
+			entry.reason = "max-age"
+		case maxSize > 0 && kept+entry.size > maxSize:
+			entry.reason = "max-size"
+		default:
+			kept += entry.size
+			continue
+		}
+		expired = append(expired, entry)
+	}
+	return expired
+}
+
+func writeGCTable(out io.Writer, workingDir string, entries []gcEntry, now time.Time) error {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "PATH\tSIZE\tMODIFIED\tREASON")
+	for _, entry := range entries {
+		path, err := filepath.Rel(workingDir, entry.path)
+		if err != nil {
+			path = entry.path
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s ago\t%s\n",
+			path,
+			units.HumanSize(float64(entry.size)),
+			units.HumanDuration(now.Sub(entry.modTime)),
+			entry.reason)
+	}
+	return writer.Flush()
+}
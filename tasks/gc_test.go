@@ -0,0 +1,145 @@
+package tasks
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func gcEntryAt(path string, size int64, age time.Duration, now time.Time) gcEntry {
+	return gcEntry{path: path, size: size, modTime: now.Add(-age)}
+}
+
+func gcReasons(entries []gcEntry) map[string]string {
+	reasons := make(map[string]string)
+	for _, entry := range entries {
+		reasons[entry.path] = entry.reason
+	}
+	return reasons
+}
+
+func TestExpiredEntries(t *testing.T) {
+	now := time.Now()
+	entries := []gcEntry{
+		gcEntryAt("old", 10, 40*24*time.Hour, now),
+		gcEntryAt("new", 600, time.Hour, now),
+		gcEntryAt("big", 600, 2*time.Hour, now),
+		gcEntryAt("small", 10, 3*time.Hour, now),
+		gcEntryAt("oldest", 10, 50*24*time.Hour, now),
+	}
+	retention := config.ArtifactRetention{KeepLast: 4, MaxSize: "1k", MaxAge: "30d"}
+
+	expected := map[string]string{
+		"big":    "max-size",
+		"old":    "max-age",
+		"oldest": "keep-last",
+	}
+	assert.Check(t, is.DeepEqual(gcReasons(expiredEntries(entries, retention, now)), expected))
+}
+
+func TestExpiredEntriesKeepsEverythingWithoutLimits(t *testing.T) {
+	now := time.Now()
+	entries := []gcEntry{gcEntryAt("one", 10, time.Hour, now)}
+	assert.Check(t, is.Len(expiredEntries(entries, config.ArtifactRetention{}, now), 0))
+}
+
+func TestGC(t *testing.T) {
+	dir := fs.NewDir(t, "test-gc",
+		fs.WithDir("dist",
+			fs.WithFile("app-1.tar.gz", "one"),
+			fs.WithFile("app-2.tar.gz", "two"),
+			fs.WithFile("app-3.tar.gz", "three")),
+		fs.WithDir("reports",
+			fs.WithDir("run-1", fs.WithFile("report.xml", "one")),
+			fs.WithDir("run-2", fs.WithFile("report.xml", "two"))))
+	defer dir.Remove()
+
+	now := time.Now()
+	for i, path := range []string{"dist/app-1.tar.gz", "dist/app-2.tar.gz", "dist/app-3.tar.gz"} {
+		modTime := now.Add(-time.Duration(3-i) * time.Hour)
+		assert.NilError(t, os.Chtimes(dir.Join(path), modTime, modTime))
+	}
+	for i, path := range []string{"reports/run-1/report.xml", "reports/run-2/report.xml"} {
+		modTime := now.Add(-time.Duration(2-i) * time.Hour)
+		assert.NilError(t, os.Chtimes(dir.Join(path), modTime, modTime))
+	}
+
+	conf, err := config.LoadFromBytes([]byte(`
+meta:
+  artifact-retention:
+    keep-last: 1
+
+image=builder:
+  image: builder
+
+job=package:
+  use: builder
+  artifact: dist/*.tar.gz
+
+job=test:
+  use: builder
+  artifact: reports/
+`))
+	assert.NilError(t, err)
+	conf.WorkingDir = dir.Path()
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, GC(GCOptions{Config: conf, DryRun: true, Out: out}))
+	assert.Check(t, is.Contains(out.String(), "dist/app-1.tar.gz"))
+	assert.Check(t, is.Contains(out.String(), "dist/app-2.tar.gz"))
+	assert.Check(t, is.Contains(out.String(), "reports/run-1"))
+	assert.Check(t, !bytes.Contains(out.Bytes(), []byte("app-3")))
+	_, err = os.Stat(dir.Join("dist", "app-1.tar.gz"))
+	assert.NilError(t, err, "dry run should not remove files")
+
+	out.Reset()
+	assert.NilError(t, GC(GCOptions{Config: conf, Out: out}))
+	for _, path := range []string{"dist/app-1.tar.gz", "dist/app-2.tar.gz", "reports/run-1"} {
+		_, err := os.Stat(dir.Join(path))
+		assert.Check(t, os.IsNotExist(err), path)
+	}
+	for _, path := range []string{"dist/app-3.tar.gz", "reports/run-2"} {
+		_, err := os.Stat(dir.Join(path))
+		assert.Check(t, err, path)
+	}
+}
+
+func TestGCRefusesArtifactsOutsideTheProject(t *testing.T) {
+	outside := fs.NewDir(t, "test-gc-outside", fs.WithFile("file", "one"))
+	defer outside.Remove()
+	dir := fs.NewDir(t, "test-gc",
+		fs.WithDir(".git", fs.WithFile("HEAD", "ref")),
+		fs.WithDir("src", fs.WithFile("main.go", "package main")),
+		fs.WithSymlink("link", "."))
+	defer dir.Remove()
+
+	for _, artifact := range []string{".", "..", "./", "src/..", "link", ".git", outside.Path()} {
+		conf, err := config.LoadFromBytes([]byte(`
+meta:
+  artifact-retention:
+    keep-last: 1
+
+image=builder:
+  image: builder
+
+job=package:
+  use: builder
+  artifact: ` + artifact + `
+`))
+		assert.NilError(t, err)
+		conf.WorkingDir = dir.Path()
+
+		err = GC(GCOptions{Config: conf, Out: new(bytes.Buffer)})
+		assert.Check(t, is.ErrorContains(err, "refusing to remove artifact"), artifact)
+	}
+	_, err := os.Stat(dir.Join("src", "main.go"))
+	assert.NilError(t, err)
+	_, err = os.Stat(outside.Join("file"))
+	assert.NilError(t, err)
+}
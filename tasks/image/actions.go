@@ -9,6 +9,28 @@ import (
 	"github.com/dnephin/dobi/tasks/types"
 )
 
+func init() {
+	types.RegisterActions(
+		"image",
+		types.Action{
+			Name:        "build",
+			Description: "Build the image, or pull it when it can not be built",
+		},
+		types.Action{Name: "pull", Description: "Pull the image"},
+		types.Action{Name: "tag", Description: "Tag the image with each of the tags"},
+		types.Action{Name: "push", Description: "Push the tags of the image"},
+		types.Action{Name: "scan", Description: "Scan the image for vulnerabilities"},
+		types.Action{Name: "scan:diff", Description: "Compare the vulnerabilities to scan.diff-tag"},
+		types.Action{Name: "save", Description: "Save the image to a tarball"},
+		types.Action{Name: "load", Description: "Load the image from a tarball"},
+		types.Action{
+			Name:        "load-cluster",
+			Description: "Load the image into the local Kubernetes cluster",
+		},
+		types.Action{Name: "remove", Description: "Remove the tags of the image"},
+	)
+}
+
 // GetTaskConfig returns a new TaskConfig for the action
 func GetTaskConfig(name, action string, conf *config.ImageConfig) (types.TaskConfig, error) {
 	var taskName task.Name
@@ -19,7 +41,7 @@ func GetTaskConfig(name, action string, conf *config.ImageConfig) (types.TaskCon
 	} else {
 		taskName = task.NewName(name, action)
 	}
-	imageAction, err := getAction(action, name)
+	imageAction, err := getAction(action, name, conf)
 	if err != nil {
 		return nil, err
 	}
@@ -43,18 +65,31 @@ func newAction(name string, run runFunc, deps []string) (action, error) {
 	return action{name: name, run: run, dependencies: deps}, nil
 }
 
-func getAction(name string, task string) (action, error) {
+func getAction(name string, task string, conf *config.ImageConfig) (action, error) {
 	switch name {
 	case "build":
 		return newAction("build", RunBuild, nil)
 	case "pull":
 		return newAction("pull", RunPull, nil)
 	case "push":
+		if conf.Scan.BeforePush {
+			return newAction("push", RunPush, imageDeps(task, "scan", "tag"))
+		}
 		return newAction("push", RunPush, imageDeps(task, "tag"))
+	case "scan":
+		return newAction("scan", RunScan, imageDeps(task, defaultAction(conf)))
+	case "scan:diff":
+		return newAction("scan:diff", RunScanDiff, imageDeps(task, defaultAction(conf)))
 	case "tag":
 		return newAction("tag", RunTag, imageDeps(task, "build"))
 	case "remove", "rm":
 		return newAction("remove", RunRemove, nil)
+	case "save":
+		return newAction("save", RunSave, imageDeps(task, defaultAction(conf)))
+	case "load":
+		return newAction("load", RunLoad, nil)
+	case "load-cluster":
+		return newAction("load-cluster", RunLoadCluster, imageDeps(task, defaultAction(conf)))
 	default:
 		return action{}, fmt.Errorf("invalid image action %q for task %q", name, task)
 	}
@@ -0,0 +1,158 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const archiveDir = ".dobi/archives"
+
+// RunSave writes the image to the archive, if the archive is older than the
+// image
+func RunSave(ctx *context.ExecuteContext, t *Task, hasModifiedDeps bool) (bool, error) {
+	path := archivePath(ctx, t)
+	if !hasModifiedDeps {
+		stale, err := saveIsStale(ctx, t, path)
+		switch {
+		case err != nil:
+			return false, err
+		case !stale:
+			logging.Skipped(t)
+			return false, nil
+		}
+	}
+
+	tags := []string{}
+	if err := t.forEachLocalTag(ctx, func(tag string) error {
+		tags = append(tags, tag)
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	if err := saveImage(ctx, tags, path); err != nil {
+		return false, err
+	}
+	t.logger().Infof("Saved to %s", path)
+	return true, nil
+}
+
+func saveIsStale(ctx *context.ExecuteContext, t *Task, path string) (bool, error) {
+	image, err := GetImage(ctx, t.config)
+	if err != nil {
+		return true, fmt.Errorf("failed to get image %q: %s", GetImageName(ctx, t.config), err)
+	}
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		t.logger().Debug("Archive does not exist")
+		return true, nil
+	case err != nil:
+		return true, err
+	}
+
+	record, err := getImageRecord(recordPath(ctx, t.config))
+	if err != nil {
+		return info.ModTime().Before(image.Created), nil
+	}
+	return image.ID != record.ImageID || info.ModTime().Before(record.Info.ModTime()), nil
+}
+
+// saveImage writes the tags to a temporary file which replaces the archive, so
+// that a failed save does not leave a partial archive
+func saveImage(ctx *context.ExecuteContext, tags []string, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name()) // nolint: errcheck
+
+	err = ctx.Client.ExportImages(docker.ExportImagesOptions{
+		Names:        tags,
+		OutputStream: tmpFile,
+	})
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save image: %s", err)
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// RunLoad loads the image from the archive, if the image does not exist or is
+// older than the archive
+func RunLoad(ctx *context.ExecuteContext, t *Task, _ bool) (bool, error) {
+	path := archivePath(ctx, t)
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read archive: %s", err)
+	}
+	if !loadIsStale(ctx, t, info) {
+		logging.Skipped(t)
+		return false, nil
+	}
+
+	if err := loadImage(ctx, path); err != nil {
+		return false, err
+	}
+	image, err := GetImage(ctx, t.config)
+	if err != nil {
+		return false, fmt.Errorf("archive %s does not contain image %q: %s",
+			path, GetImageName(ctx, t.config), err)
+	}
+	record := imageModifiedRecord{ImageID: image.ID}
+	if err := updateImageRecord(recordPath(ctx, t.config), record); err != nil {
+		t.logger().Warnf("Failed to update image record: %s", err)
+	}
+	t.logger().Infof("Loaded from %s", path)
+	return true, nil
+}
+
+func loadIsStale(ctx *context.ExecuteContext, t *Task, archive os.FileInfo) bool {
+	image, err := GetImage(ctx, t.config)
+	if err != nil {
+		t.logger().Debug("Image does not exist")
+		return true
+	}
+	record, err := getImageRecord(recordPath(ctx, t.config))
+	if err != nil {
+		return image.Created.Before(archive.ModTime())
+	}
+	return image.ID != record.ImageID || record.Info.ModTime().Before(archive.ModTime())
+}
+
+func loadImage(ctx *context.ExecuteContext, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close() // nolint: errcheck
+
+	out := new(bytes.Buffer)
+	if err := ctx.Client.LoadImage(docker.LoadImageOptions{
+		InputStream:  file,
+		OutputStream: out,
+	}); err != nil {
+		return fmt.Errorf("failed to load image: %s", err)
+	}
+	logging.Log.Debug(out.String())
+	return nil
+}
+
+// archivePath returns the path of the archive used by save and load
+func archivePath(ctx *context.ExecuteContext, t *Task) string {
+	if t.config.Archive != "" {
+		return absPath(t.config.Archive, ctx.WorkingDir)
+	}
+	return filepath.Join(ctx.WorkingDir, archiveDir, t.name.Resource()+".tar")
+}
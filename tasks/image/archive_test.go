@@ -0,0 +1,92 @@
+package image
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestRunSave(t *testing.T) {
+	dir := fs.NewDir(t, "test-image-save")
+	defer dir.Remove()
+	mockClient, teardown := setupMockClient(t)
+	defer teardown()
+
+	image := &docker.Image{ID: "sha256:abcd", Created: time.Now().Add(-time.Hour)}
+	mockClient.EXPECT().InspectImage("imagename:tag").Return(image, nil).Times(2)
+	mockClient.EXPECT().ExportImages(gomock.Any()).Do(func(opts docker.ExportImagesOptions) {
+		assert.Check(t, is.DeepEqual(opts.Names, []string{"imagename:tag", "imagename:v1"}))
+		_, err := opts.OutputStream.Write([]byte("the tarball"))
+		assert.Check(t, err)
+	})
+
+	ctx := &context.ExecuteContext{Client: mockClient, WorkingDir: dir.Path()}
+	saveTask := &Task{
+		name:   task.NewName("app", "save"),
+		config: &config.ImageConfig{Image: "imagename", Tags: []string{"tag", "v1"}},
+	}
+
+	modified, err := RunSave(ctx, saveTask, false)
+	assert.NilError(t, err)
+	assert.Assert(t, modified)
+	content, err := ioutil.ReadFile(dir.Join(".dobi", "archives", "app.tar"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(content), "the tarball"))
+
+	// The archive is newer than the image, so the next run is a no-op
+	modified, err = RunSave(ctx, saveTask, false)
+	assert.NilError(t, err)
+	assert.Assert(t, !modified)
+}
+
+func TestRunLoad(t *testing.T) {
+	dir := fs.NewDir(t, "test-image-load", fs.WithFile("app.tar", "the tarball"))
+	defer dir.Remove()
+	mockClient, teardown := setupMockClient(t)
+	defer teardown()
+
+	image := &docker.Image{ID: "sha256:abcd", Created: time.Now().Add(-time.Hour)}
+	gomock.InOrder(
+		mockClient.EXPECT().InspectImage("imagename:tag").Return(nil, docker.ErrNoSuchImage),
+		mockClient.EXPECT().LoadImage(gomock.Any()).Do(func(opts docker.LoadImageOptions) {
+			content, err := ioutil.ReadAll(opts.InputStream)
+			assert.Check(t, err)
+			assert.Check(t, is.Equal(string(content), "the tarball"))
+		}),
+		mockClient.EXPECT().InspectImage("imagename:tag").Return(image, nil).Times(2),
+	)
+
+	ctx := &context.ExecuteContext{Client: mockClient, WorkingDir: dir.Path()}
+	loadTask := &Task{
+		name:   task.NewName("app", "load"),
+		config: &config.ImageConfig{Image: "imagename", Tags: []string{"tag"}, Archive: "app.tar"},
+	}
+
+	modified, err := RunLoad(ctx, loadTask, false)
+	assert.NilError(t, err)
+	assert.Assert(t, modified)
+
+	// The image record is newer than the archive, so the next run is a no-op
+	modified, err = RunLoad(ctx, loadTask, false)
+	assert.NilError(t, err)
+	assert.Assert(t, !modified)
+}
+
+func TestRunLoadMissingArchive(t *testing.T) {
+	ctx := &context.ExecuteContext{WorkingDir: "/does-not-exist"}
+	loadTask := &Task{
+		name:   task.NewName("app", "load"),
+		config: &config.ImageConfig{Image: "imagename", Tags: []string{"tag"}},
+	}
+	_, err := RunLoad(ctx, loadTask, false)
+	assert.Check(t, is.ErrorContains(err, "failed to read archive"))
+}
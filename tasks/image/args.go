@@ -0,0 +1,49 @@
+package image
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	envtask "github.com/dnephin/dobi/tasks/env"
+	"github.com/docker/cli/opts"
+)
+
+// mergedBuildArgs returns the build args from args-from, overridden by args
+func mergedBuildArgs(
+	ctx *context.ExecuteContext,
+	conf *config.ImageConfig,
+) (map[string]string, error) {
+	args := map[string]string{}
+	for _, item := range conf.ArgsFrom {
+		vars, err := argsFromItem(ctx, item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read args-from %q: %s", item, err)
+		}
+		for _, variable := range vars {
+			parts := strings.SplitN(variable, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid build arg %q in args-from %q", variable, item)
+			}
+			args[parts[0]] = parts[1]
+		}
+	}
+	for key, value := range conf.Args {
+		args[key] = value
+	}
+	return args, nil
+}
+
+// argsFromItem returns the key=value pairs from an env resource, or a file
+func argsFromItem(ctx *context.ExecuteContext, item string) ([]string, error) {
+	env := ctx.Resources.Env(item)
+	if env == nil {
+		return opts.ParseEnvFile(absPath(item, ctx.WorkingDir))
+	}
+	resolved, err := env.Resolve(ctx.Env)
+	if err != nil {
+		return nil, err
+	}
+	return envtask.Variables(ctx, item, resolved.(*config.EnvConfig))
+}
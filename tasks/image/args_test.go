@@ -0,0 +1,56 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/context"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestMergedBuildArgs(t *testing.T) {
+	dir := fs.NewDir(t, "args-from",
+		fs.WithFile("build.args", "VERSION=1.0\nDEBUG=false\n"),
+		fs.WithFile("env.args", "REGION=us\n"))
+	defer dir.Remove()
+
+	conf := &config.Config{
+		WorkingDir: dir.Path(),
+		Resources: map[string]config.Resource{
+			"build-env": &config.EnvConfig{
+				Files:     []string{"env.args"},
+				Variables: []string{"VERSION=0.9", "OWNER=ci"},
+			},
+		},
+	}
+	ctx := context.NewExecuteContext(
+		conf, nil, execenv.NewExecEnv("exec", "project", dir.Path()), context.Settings{})
+
+	image := &config.ImageConfig{
+		ArgsFrom: []string{"build-env", "build.args"},
+		Args:     map[string]string{"DEBUG": "true"},
+	}
+	args, err := mergedBuildArgs(ctx, image)
+	assert.NilError(t, err)
+	expected := map[string]string{
+		"REGION":  "us",
+		"OWNER":   "ci",
+		"VERSION": "1.0",
+		"DEBUG":   "true",
+	}
+	assert.Check(t, is.DeepEqual(args, expected))
+}
+
+func TestMergedBuildArgsMissingFile(t *testing.T) {
+	ctx := context.NewExecuteContext(
+		&config.Config{WorkingDir: "/does-not-exist"},
+		nil,
+		execenv.NewExecEnv("exec", "project", "/does-not-exist"),
+		context.Settings{})
+
+	_, err := mergedBuildArgs(ctx, &config.ImageConfig{ArgsFrom: []string{"build.args"}})
+	assert.ErrorContains(t, err, `failed to read args-from "build.args"`)
+}
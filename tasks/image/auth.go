@@ -0,0 +1,70 @@
+package image
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"strings"
+
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/pkg/errors"
+)
+
+// identityTokenUsername is the username returned by credential helpers when
+// the secret is an identity token
+const identityTokenUsername = "<token>"
+
+// getAuthConfig returns the credentials for the registry from the auth
+// config of the image, or from the docker config file if the image has none
+func (t *Task) getAuthConfig(
+	ctx *context.ExecuteContext,
+	registry string,
+) (docker.AuthConfiguration, error) {
+	auth := t.config.Auth
+	switch {
+	case auth.IsZero():
+		return ctx.GetAuthConfig(registry), nil
+	case auth.CredentialHelper != "":
+		return authFromHelper(auth.CredentialHelper, registry)
+	default:
+		return docker.AuthConfiguration{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			IdentityToken: auth.IdentityToken,
+			ServerAddress: registry,
+		}, nil
+	}
+}
+
+func authFromHelper(helper string, registry string) (docker.AuthConfiguration, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(registry)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return docker.AuthConfiguration{}, errors.Wrapf(err,
+			"credential helper %q failed: %s", helper, strings.TrimSpace(stderr.String()))
+	}
+	return parseHelperCredentials(out, registry)
+}
+
+func parseHelperCredentials(out []byte, registry string) (docker.AuthConfiguration, error) {
+	creds := struct {
+		Username string
+		Secret   string
+	}{}
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return docker.AuthConfiguration{}, errors.Wrap(err,
+			"failed to parse credential helper output")
+	}
+	auth := docker.AuthConfiguration{ServerAddress: registry}
+	if creds.Username == identityTokenUsername {
+		auth.IdentityToken = creds.Secret
+		return auth, nil
+	}
+	auth.Username = creds.Username
+	auth.Password = creds.Secret
+	return auth, nil
+}
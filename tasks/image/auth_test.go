@@ -0,0 +1,46 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestGetAuthConfigFromImageConfig(t *testing.T) {
+	imageTask := &Task{config: &config.ImageConfig{
+		Auth: config.RegistryAuth{Username: "ci", Password: "secret"},
+	}}
+	auth, err := imageTask.getAuthConfig(&context.ExecuteContext{}, "registry.example.com")
+	assert.NilError(t, err)
+	expected := docker.AuthConfiguration{
+		Username:      "ci",
+		Password:      "secret",
+		ServerAddress: "registry.example.com",
+	}
+	assert.Check(t, is.DeepEqual(auth, expected))
+}
+
+func TestParseHelperCredentials(t *testing.T) {
+	out := []byte(`{"ServerURL": "registry.example.com", "Username": "ci", "Secret": "secret"}`)
+	auth, err := parseHelperCredentials(out, "registry.example.com")
+	assert.NilError(t, err)
+	expected := docker.AuthConfiguration{
+		Username:      "ci",
+		Password:      "secret",
+		ServerAddress: "registry.example.com",
+	}
+	assert.Check(t, is.DeepEqual(auth, expected))
+
+	out = []byte(`{"Username": "<token>", "Secret": "the-token"}`)
+	auth, err = parseHelperCredentials(out, "registry.example.com")
+	assert.NilError(t, err)
+	expected = docker.AuthConfiguration{
+		IdentityToken: "the-token",
+		ServerAddress: "registry.example.com",
+	}
+	assert.Check(t, is.DeepEqual(auth, expected))
+}
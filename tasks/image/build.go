@@ -5,9 +5,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
 	"github.com/dnephin/dobi/tasks/context"
 	"github.com/dnephin/dobi/utils/fs"
 	"github.com/docker/cli/cli/command/image/build"
@@ -18,18 +20,24 @@ import (
 
 // RunBuild builds an image if it is out of date
 func RunBuild(ctx *context.ExecuteContext, t *Task, hasModifiedDeps bool) (bool, error) {
+	if t.config.TagByDigestOfInputs {
+		return runBuildByInputDigest(ctx, t)
+	}
 	if !hasModifiedDeps {
 		stale, err := buildIsStale(ctx, t)
 		switch {
 		case err != nil:
 			return false, err
 		case !stale:
-			t.logger().Info("is fresh")
+			logging.Skipped(t)
 			return false, nil
 		}
 	}
 	t.logger().Debug("is stale")
+	return runBuild(ctx, t)
+}
 
+func runBuild(ctx *context.ExecuteContext, t *Task) (bool, error) {
 	if !t.config.IsBuildable() {
 		return false, errors.Errorf(
 			"%s is not buildable, missing required fields", t.name.Resource())
@@ -48,6 +56,13 @@ func RunBuild(ctx *context.ExecuteContext, t *Task, hasModifiedDeps bool) (bool,
 	if err := updateImageRecord(recordPath(ctx, t.config), record); err != nil {
 		t.logger().Warnf("Failed to update image record: %s", err)
 	}
+	if err := t.runHooks(ctx, "post-build", t.config.PostBuild); err != nil {
+		// Remove the record so the image is built again on the next run
+		if err := os.Remove(recordPath(ctx, t.config)); err != nil && !os.IsNotExist(err) {
+			t.logger().Warnf("Failed to remove image record: %s", err)
+		}
+		return false, err
+	}
 	t.logger().Info("Created")
 	return true, nil
 }
@@ -66,12 +81,17 @@ func buildIsStale(ctx *context.ExecuteContext, t *Task) (bool, error) {
 	}
 
 	paths := []string{t.config.Context}
+	if t.config.AutoSources {
+		if sources, ok := autoSourcePaths(ctx, t); ok {
+			paths = sources
+		}
+	}
 	// TODO: polymorphic config for different types of images
 	if t.config.Steps != "" && ctx.ConfigFile != "" {
 		paths = append(paths, ctx.ConfigFile)
 	}
 
-	excludes, err := build.ReadDockerignore(t.config.Context)
+	excludes, err := build.ReadDockerignore(absPath(t.config.Context, ctx.WorkingDir))
 	if err != nil {
 		t.logger().Warnf("Failed to read .dockerignore file.")
 	}
@@ -112,11 +132,14 @@ func absPath(path string, wd string) string {
 }
 
 func buildImage(ctx *context.ExecuteContext, t *Task) error {
-	var err error
+	args, err := mergedBuildArgs(ctx, t.config)
+	if err != nil {
+		return err
+	}
 	if t.config.Steps != "" {
-		err = t.buildImageFromSteps(ctx)
+		err = t.buildImageFromSteps(ctx, args)
 	} else {
-		err = t.buildImageFromDockerfile(ctx)
+		err = t.buildImageFromDockerfile(ctx, args)
 	}
 	if err != nil {
 		return err
@@ -129,11 +152,14 @@ func buildImage(ctx *context.ExecuteContext, t *Task) error {
 	return updateImageRecord(recordPath(ctx, t.config), record)
 }
 
-func (t *Task) buildImageFromDockerfile(ctx *context.ExecuteContext) error {
-	return Stream(os.Stdout, func(out io.Writer) error {
-		opts := t.commonBuildImageOptions(ctx, out)
+func (t *Task) buildImageFromDockerfile(
+	ctx *context.ExecuteContext,
+	args map[string]string,
+) error {
+	return Stream(logging.Log.Out, func(out io.Writer) error {
+		opts := t.commonBuildImageOptions(ctx, out, args)
 		opts.Dockerfile = t.config.Dockerfile
-		opts.ContextDir = t.config.Context
+		opts.ContextDir = absPath(t.config.Context, ctx.WorkingDir)
 		return ctx.Client.BuildImage(opts)
 	})
 }
@@ -141,14 +167,16 @@ func (t *Task) buildImageFromDockerfile(ctx *context.ExecuteContext) error {
 func (t *Task) commonBuildImageOptions(
 	ctx *context.ExecuteContext,
 	out io.Writer,
+	args map[string]string,
 ) docker.BuildImageOptions {
 	return docker.BuildImageOptions{
 		Name:           GetImageName(ctx, t.config),
-		BuildArgs:      buildArgs(t.config.Args),
+		BuildArgs:      buildArgs(args, t.config.CacheTo),
+		Labels:         buildLabels(t.config),
 		Target:         t.config.Target,
 		Pull:           t.config.PullBaseImageOnBuild,
 		NetworkMode:    t.config.NetworkMode,
-		CacheFrom:      t.config.CacheFrom,
+		CacheFrom:      cacheFrom(ctx, t.config),
 		RmTmpContainer: true,
 		OutputStream:   out,
 		RawJSONStream:  true,
@@ -157,29 +185,80 @@ func (t *Task) commonBuildImageOptions(
 	}
 }
 
-func buildArgs(args map[string]string) []docker.BuildArg {
+// cacheFrom returns the images used as the build cache. When cache-from is set
+// the builder only uses those images as the cache, so the images of the other
+// targets of the same Dockerfile are added, to reuse the stages they share.
+func cacheFrom(ctx *context.ExecuteContext, conf *config.ImageConfig) []string {
+	if ctx.HostProfile != nil && ctx.HostProfile.SkipCacheFrom {
+		return nil
+	}
+	if len(conf.CacheFrom) == 0 {
+		return nil
+	}
+	images := append([]string{}, conf.CacheFrom...)
+	return append(images, otherTargetImages(ctx, conf)...)
+}
+
+// otherTargetImages returns the names of the images of the resources which
+// build a different target from the same Dockerfile and context
+func otherTargetImages(ctx *context.ExecuteContext, conf *config.ImageConfig) []string {
+	images := []string{}
+	for _, other := range ctx.Resources.Images() {
+		if other == conf || !sameDockerfile(other, conf) || other.Target == conf.Target {
+			continue
+		}
+		images = append(images, GetImageName(ctx, other))
+	}
+	sort.Strings(images)
+	return images
+}
+
+func sameDockerfile(a, b *config.ImageConfig) bool {
+	return a.Steps == "" && b.Steps == "" &&
+		a.Dockerfile == b.Dockerfile &&
+		filepath.Clean(a.Context) == filepath.Clean(b.Context)
+}
+
+// inlineCacheArg is the build arg which enables inline cache metadata
+const inlineCacheArg = "BUILDKIT_INLINE_CACHE"
+
+func buildArgs(args map[string]string, cacheTo []string) []docker.BuildArg {
 	out := []docker.BuildArg{}
 	for key, value := range args {
 		out = append(out, docker.BuildArg{Name: key, Value: value})
 	}
+	// cache-to only supports inline exports, which is validated by the config
+	if _, ok := args[inlineCacheArg]; len(cacheTo) > 0 && !ok {
+		out = append(out, docker.BuildArg{Name: inlineCacheArg, Value: "1"})
+	}
 	return out
 }
 
-func (t *Task) buildImageFromSteps(ctx *context.ExecuteContext) error {
-	buildContext, dockerfile, err := getBuildContext(t.config)
+// expiresLabel is the label used by registries to expire image tags
+const expiresLabel = "quay.expires-after"
+
+func buildLabels(conf *config.ImageConfig) map[string]string {
+	if conf.Expires == "" {
+		return nil
+	}
+	return map[string]string{expiresLabel: conf.Expires}
+}
+
+func (t *Task) buildImageFromSteps(ctx *context.ExecuteContext, args map[string]string) error {
+	buildContext, dockerfile, err := getBuildContext(
+		absPath(t.config.Context, ctx.WorkingDir), t.config.Steps)
 	if err != nil {
 		return err
 	}
-	return Stream(os.Stdout, func(out io.Writer) error {
-		opts := t.commonBuildImageOptions(ctx, out)
+	return Stream(logging.Log.Out, func(out io.Writer) error {
+		opts := t.commonBuildImageOptions(ctx, out, args)
 		opts.InputStream = buildContext
 		opts.Dockerfile = dockerfile
 		return ctx.Client.BuildImage(opts)
 	})
 }
 
-func getBuildContext(config *config.ImageConfig) (io.Reader, string, error) {
-	contextDir := config.Context
+func getBuildContext(contextDir string, steps string) (io.Reader, string, error) {
 	excludes, err := build.ReadDockerignore(contextDir)
 	if err != nil {
 		return nil, "", err
@@ -194,6 +273,6 @@ func getBuildContext(config *config.ImageConfig) (io.Reader, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
-	dockerfileCtx := ioutil.NopCloser(strings.NewReader(config.Steps))
+	dockerfileCtx := ioutil.NopCloser(strings.NewReader(steps))
 	return build.AddDockerfileToBuildContext(dockerfileCtx, buildCtx)
 }
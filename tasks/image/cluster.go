@@ -0,0 +1,120 @@
+package image
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+)
+
+const clusterRecordDir = ".dobi/clusters"
+
+// RunLoadCluster loads the image into a local Kubernetes cluster, if the
+// image was changed since it was last loaded into the cluster
+func RunLoadCluster(ctx *context.ExecuteContext, t *Task, hasModifiedDeps bool) (bool, error) {
+	if t.config.Cluster == "" {
+		return false, fmt.Errorf("cluster is required by the load-cluster action")
+	}
+	if err := t.config.ValidateCluster(); err != nil {
+		return false, err
+	}
+	image, err := GetImage(ctx, t.config)
+	if err != nil {
+		return false, fmt.Errorf("failed to get image %q: %s", GetImageName(ctx, t.config), err)
+	}
+	record := clusterRecordPath(ctx, t)
+	if !hasModifiedDeps && loadedImageID(record) == image.ID {
+		logging.Skipped(t)
+		return false, nil
+	}
+
+	tags := []string{}
+	if err := t.forEachLocalTag(ctx, func(tag string) error {
+		tags = append(tags, tag)
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	if err := loadIntoCluster(ctx, t.config.Cluster, tags); err != nil {
+		return false, err
+	}
+	if err := writeLoadedImageID(record, image.ID); err != nil {
+		t.logger().Warnf("Failed to update cluster record: %s", err)
+	}
+	t.logger().Infof("Loaded into %s", t.config.Cluster)
+	return true, nil
+}
+
+// loadIntoCluster saves the tags to a tarball, and loads the tarball into the
+// cluster with the command line tool of the cluster
+func loadIntoCluster(ctx *context.ExecuteContext, cluster string, tags []string) error {
+	dir, err := ioutil.TempDir("", "dobi-cluster-")
+	if err != nil {
+		return err
+	}
+	defer ctx.Track(context.TrackedDir, dir)()
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	path := filepath.Join(dir, "image.tar")
+	if err := saveImage(ctx, tags, path); err != nil {
+		return err
+	}
+	command := clusterLoadCommand(cluster, path)
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = logging.Log.Out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to load image into %s with %s: %s",
+			cluster, strings.Join(command[:3], " "), err)
+	}
+	return nil
+}
+
+// clusterLoadCommand returns the command which loads the tarball at path into
+// the cluster
+func clusterLoadCommand(cluster string, path string) []string {
+	tool, name := config.SplitCluster(cluster)
+	switch tool {
+	case config.ClusterKind:
+		return withClusterName([]string{"kind", "load", "image-archive", path}, "--name", name)
+	case config.ClusterK3d:
+		return withClusterName([]string{"k3d", "image", "import", path}, "--cluster", name)
+	default:
+		return withClusterName([]string{"minikube", "image", "load", path}, "--profile", name)
+	}
+}
+
+func withClusterName(command []string, flag string, name string) []string {
+	if name == "" {
+		return command
+	}
+	return append(command, flag, name)
+}
+
+// clusterRecordPath returns the path of the file which stores the ID of the
+// image which was last loaded into the cluster
+func clusterRecordPath(ctx *context.ExecuteContext, t *Task) string {
+	cluster := strings.Replace(t.config.Cluster, ":", "-", -1)
+	return filepath.Join(ctx.WorkingDir, clusterRecordDir, cluster, t.name.Resource())
+}
+
+func loadedImageID(path string) string {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+func writeLoadedImageID(path string, imageID string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(imageID+"\n"), 0644)
+}
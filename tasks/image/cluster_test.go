@@ -0,0 +1,67 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	docker "github.com/fsouza/go-dockerclient"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestClusterLoadCommand(t *testing.T) {
+	var testcases = []struct {
+		cluster  string
+		expected []string
+	}{
+		{cluster: "kind", expected: []string{"kind", "load", "image-archive", "a.tar"}},
+		{
+			cluster:  "kind:dev",
+			expected: []string{"kind", "load", "image-archive", "a.tar", "--name", "dev"},
+		},
+		{
+			cluster:  "k3d:dev",
+			expected: []string{"k3d", "image", "import", "a.tar", "--cluster", "dev"},
+		},
+		{
+			cluster:  "minikube:dev",
+			expected: []string{"minikube", "image", "load", "a.tar", "--profile", "dev"},
+		},
+	}
+	for _, tc := range testcases {
+		assert.Check(t, is.DeepEqual(clusterLoadCommand(tc.cluster, "a.tar"), tc.expected))
+	}
+}
+
+func TestRunLoadClusterSkipsLoadedImage(t *testing.T) {
+	dir := fs.NewDir(t, "test-load-cluster",
+		fs.WithDir(".dobi", fs.WithDir("clusters",
+			fs.WithDir("kind-dev", fs.WithFile("app", "sha256:abcd\n")))))
+	defer dir.Remove()
+	mockClient, teardown := setupMockClient(t)
+	defer teardown()
+
+	image := &docker.Image{ID: "sha256:abcd"}
+	mockClient.EXPECT().InspectImage("imagename:tag").Return(image, nil)
+
+	ctx := &context.ExecuteContext{Client: mockClient, WorkingDir: dir.Path()}
+	loadTask := &Task{
+		name:   task.NewName("app", "load-cluster"),
+		config: &config.ImageConfig{Image: "imagename", Tags: []string{"tag"}, Cluster: "kind:dev"},
+	}
+	modified, err := RunLoadCluster(ctx, loadTask, false)
+	assert.NilError(t, err)
+	assert.Check(t, !modified)
+}
+
+func TestRunLoadClusterInvalidTool(t *testing.T) {
+	loadTask := &Task{
+		name:   task.NewName("app", "load-cluster"),
+		config: &config.ImageConfig{Image: "imagename", Cluster: "docker-desktop"},
+	}
+	_, err := RunLoadCluster(&context.ExecuteContext{}, loadTask, false)
+	assert.Check(t, is.ErrorContains(err, `invalid cluster "docker-desktop"`))
+}
@@ -0,0 +1,207 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/docker/cli/cli/command/image/build"
+	"github.com/docker/docker/pkg/fileutils"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// inputTagPrefix is the prefix of the tag created by tag-by-digest-of-inputs
+const inputTagPrefix = "inputs-"
+
+// runBuildByInputDigest tags the image with the digest of the inputs of the
+// build. The build is skipped when the image with that tag exists locally, or
+// can be pulled.
+func runBuildByInputDigest(ctx *context.ExecuteContext, t *Task) (bool, error) {
+	inputImage, err := inputImageName(ctx, t.config)
+	if err != nil {
+		return false, err
+	}
+
+	found, err := findInputImage(ctx, t, inputImage)
+	if err != nil {
+		return false, err
+	}
+	if found {
+		return tagFromInputImage(ctx, t, inputImage)
+	}
+
+	if _, err := runBuild(ctx, t); err != nil {
+		return false, err
+	}
+	if err := tagImage(ctx, t.config, inputImage); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// findInputImage returns true if the image exists locally, or was pulled
+func findInputImage(ctx *context.ExecuteContext, t *Task, inputImage string) (bool, error) {
+	_, err := ctx.Client.InspectImage(inputImage)
+	switch err {
+	case nil:
+		t.logger().Debugf("Found %s", inputImage)
+		return true, nil
+	case docker.ErrNoSuchImage:
+	default:
+		return false, err
+	}
+
+	if err := pullImage(ctx, t, inputImage); err != nil {
+		t.logger().Debugf("Failed to pull %s: %s", inputImage, err)
+		return false, nil
+	}
+	t.logger().Infof("Pulled %s", inputImage)
+	return true, nil
+}
+
+// tagFromInputImage adds the canonical tag to the image with the input tag
+func tagFromInputImage(ctx *context.ExecuteContext, t *Task, inputImage string) (bool, error) {
+	image, err := ctx.Client.InspectImage(inputImage)
+	if err != nil {
+		return false, err
+	}
+	current, err := GetImage(ctx, t.config)
+	if err == nil && current.ID == image.ID {
+		logging.Skipped(t)
+		return false, nil
+	}
+
+	repo, tag := docker.ParseRepositoryTag(GetImageName(ctx, t.config))
+	err = ctx.Client.TagImage(inputImage, docker.TagImageOptions{
+		Repo:  repo,
+		Tag:   tag,
+		Force: true,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to add tag %q: %s", tag, err)
+	}
+	record := imageModifiedRecord{ImageID: image.ID}
+	if err := updateImageRecord(recordPath(ctx, t.config), record); err != nil {
+		t.logger().Warnf("Failed to update image record: %s", err)
+	}
+	t.logger().Infof("Using %s", inputImage)
+	return true, nil
+}
+
+// inputImageName returns the name of the image tagged with the digest of the
+// inputs of the build
+func inputImageName(ctx *context.ExecuteContext, conf *config.ImageConfig) (string, error) {
+	args, err := mergedBuildArgs(ctx, conf)
+	if err != nil {
+		return "", err
+	}
+	digest, err := inputDigest(ctx, conf, args)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute the digest of the build inputs: %s", err)
+	}
+	return conf.Image + ":" + inputTagPrefix + digest[:16], nil
+}
+
+// inputDigest returns a hex encoded sha256 of the Dockerfile or steps, the
+// build args, the IDs of the images the build depends on, and the files in
+// the build context
+func inputDigest(
+	ctx *context.ExecuteContext,
+	conf *config.ImageConfig,
+	args map[string]string,
+) (string, error) {
+	digest := sha256.New()
+	fmt.Fprintf(digest, "dockerfile:%s\nsteps:%s\ntarget:%s\n",
+		conf.Dockerfile, conf.Steps, conf.Target)
+
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(digest, "arg:%s=%s\n", key, args[key])
+	}
+
+	for _, dep := range conf.Dependencies() {
+		depConf := ctx.Resources.Image(task.ParseName(dep).Resource())
+		if depConf == nil {
+			continue
+		}
+		image, err := GetImage(ctx, depConf)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(digest, "image:%s\n", image.ID)
+	}
+
+	if err := hashContext(digest, absPath(conf.Context, ctx.WorkingDir)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// hashContext writes the path, mode, and content of each file in the build
+// context to digest. Files excluded by the .dockerignore are not included.
+func hashContext(digest hash.Hash, contextDir string) error {
+	excludes, err := build.ReadDockerignore(contextDir)
+	if err != nil {
+		return err
+	}
+	pm, err := fileutils.NewPatternMatcher(append(excludes, ".dobi"))
+	if err != nil {
+		return err
+	}
+
+	// filepath.Walk visits files in lexical order, so the digest is stable
+	return filepath.Walk(contextDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(contextDir, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		skip, err := pm.Matches(relPath)
+		switch {
+		case err != nil:
+			return err
+		case skip && info.IsDir():
+			return filepath.SkipDir
+		case skip:
+			return nil
+		}
+
+		fmt.Fprintf(digest, "file:%s:%s:%d\n", filepath.ToSlash(relPath), info.Mode(), info.Size())
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(digest, "link:%s\n", target)
+		case info.Mode().IsRegular():
+			return hashFile(digest, path)
+		}
+		return nil
+	})
+}
+
+func hashFile(digest io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close() // nolint: errcheck
+	_, err = io.Copy(digest, file)
+	return err
+}
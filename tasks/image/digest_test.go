@@ -0,0 +1,88 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func contextDigest(t *testing.T, dir string) string {
+	digest := sha256.New()
+	assert.NilError(t, hashContext(digest, dir))
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+func TestHashContext(t *testing.T) {
+	dir := fs.NewDir(t, "test-hash-context",
+		fs.WithFile("Dockerfile", "FROM alpine:3.12\n"),
+		fs.WithFile(".dockerignore", "logs\n"),
+		fs.WithDir("src", fs.WithFile("main.go", "package main\n")),
+		fs.WithDir("logs", fs.WithFile("debug.log", "one\n")),
+		fs.WithDir(".dobi", fs.WithFile("record", "one\n")))
+	defer dir.Remove()
+
+	original := contextDigest(t, dir.Path())
+	assert.Check(t, is.Equal(original, contextDigest(t, dir.Path())))
+
+	fs.Apply(t, dir, fs.WithDir("logs", fs.WithFile("debug.log", "two\n")))
+	fs.Apply(t, dir, fs.WithDir(".dobi", fs.WithFile("record", "two\n")))
+	assert.Check(t, is.Equal(original, contextDigest(t, dir.Path())),
+		"excluded files should not change the digest")
+
+	fs.Apply(t, dir, fs.WithDir("src", fs.WithFile("main.go", "package other\n")))
+	assert.Check(t, original != contextDigest(t, dir.Path()))
+}
+
+func TestRunBuildByInputDigestUsesExistingImage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+
+	dir := fs.NewDir(t, "test-input-digest", fs.WithFile("Dockerfile", "FROM alpine\n"))
+	defer dir.Remove()
+
+	ctx := context.NewExecuteContext(
+		&config.Config{WorkingDir: dir.Path()},
+		mockClient,
+		execenv.NewExecEnv("exec", "project", dir.Path()),
+		context.NewSettings(true, true))
+	conf := &config.ImageConfig{
+		Image:               "example/app",
+		Dockerfile:          "Dockerfile",
+		Context:             ".",
+		Tags:                []string{"latest"},
+		TagByDigestOfInputs: true,
+	}
+	imageTask := &Task{name: task.NewName("app", "build"), config: conf}
+
+	inputImage, err := inputImageName(ctx, conf)
+	assert.NilError(t, err)
+	assert.Check(t, strings.HasPrefix(inputImage, "example/app:inputs-"))
+
+	image := &docker.Image{ID: "sha256:abcd"}
+	gomock.InOrder(
+		mockClient.EXPECT().InspectImage(inputImage).Return(image, nil).Times(2),
+		mockClient.EXPECT().InspectImage("example/app:latest").Return(
+			nil, docker.ErrNoSuchImage),
+		mockClient.EXPECT().TagImage(inputImage, docker.TagImageOptions{
+			Repo:  "example/app",
+			Tag:   "latest",
+			Force: true,
+		}).Return(nil),
+	)
+	modified, err := runBuildByInputDigest(ctx, imageTask)
+	assert.NilError(t, err)
+	assert.Check(t, modified)
+}
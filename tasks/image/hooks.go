@@ -0,0 +1,29 @@
+package image
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+)
+
+// runHooks runs each hook command with the image name in the environment
+func (t *Task) runHooks(ctx *context.ExecuteContext, hook string, commands []string) error {
+	for _, command := range commands {
+		t.logger().Infof("Running %s hook: %s", hook, command)
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Dir = ctx.WorkingDir
+		cmd.Env = append(os.Environ(), "DOBI_IMAGE="+GetImageName(ctx, t.config))
+		stdout, stderr := logging.NewMaskWriter(logging.Log.Out), logging.NewMaskWriter(os.Stderr)
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+		err := cmd.Run()
+		stdout.Flush() // nolint: errcheck
+		stderr.Flush() // nolint: errcheck
+		if err != nil {
+			return fmt.Errorf("%s hook %q failed: %s", hook, command, err)
+		}
+	}
+	return nil
+}
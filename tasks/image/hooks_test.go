@@ -0,0 +1,35 @@
+package image
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestRunHooks(t *testing.T) {
+	dir := fs.NewDir(t, "test-image-hooks")
+	defer dir.Remove()
+
+	ctx := &context.ExecuteContext{WorkingDir: dir.Path()}
+	imageTask := &Task{
+		name:   task.NewName("app", "build"),
+		config: &config.ImageConfig{Image: "example/app", Tags: []string{"v1"}},
+	}
+
+	err := imageTask.runHooks(ctx, "post-build", []string{"echo $DOBI_IMAGE > hook.out"})
+	assert.NilError(t, err)
+	out, err := ioutil.ReadFile(dir.Join("hook.out"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(out), "example/app:v1\n"))
+
+	err = imageTask.runHooks(ctx, "pre-push", []string{"true", "exit 3", "touch never"})
+	assert.Check(t, is.ErrorContains(err, `pre-push hook "exit 3" failed`))
+	_, err = ioutil.ReadFile(dir.Join("never"))
+	assert.Check(t, err != nil)
+}
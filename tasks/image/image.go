@@ -42,6 +42,19 @@ func (t *Task) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error)
 	return t.runFunc(ctx, t, depsModified)
 }
 
+// IsStale returns true if the image would be built. Only the build action can
+// be checked without running it.
+func (t *Task) IsStale(ctx *context.ExecuteContext) (bool, error) {
+	switch {
+	case t.name.Action() != "build":
+		return false, fmt.Errorf("the %s action can not be checked without running it",
+			t.name.Action())
+	case t.config.TagByDigestOfInputs:
+		return false, fmt.Errorf("the tag of the image is the digest of the inputs")
+	}
+	return buildIsStale(ctx, t)
+}
+
 // ForEachTag runs a function for each tag
 func (t *Task) ForEachTag(ctx *context.ExecuteContext, each func(string) error) error {
 	if err := t.forEachLocalTag(ctx, each); err != nil {
@@ -103,7 +116,11 @@ func Stream(out io.Writer, streamer func(out io.Writer) error) error {
 		errChan <- err
 	}()
 
-	err := streamer(wpipe)
+	masked := logging.NewMaskWriter(wpipe)
+	err := streamer(masked)
+	if flushErr := masked.Flush(); err == nil {
+		err = flushErr
+	}
 	wpipe.Close() // nolint: errcheck
 	if err != nil {
 		<-errChan
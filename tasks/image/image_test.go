@@ -4,7 +4,11 @@ import (
 	"testing"
 
 	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
 	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
 )
 
 func TestForEachTag(t *testing.T) {
@@ -27,3 +31,43 @@ func TestForEachTag(t *testing.T) {
 	assert.NilError(t, err)
 	assert.DeepEqual(t, expected, tags)
 }
+
+func TestBuildArgsWithInlineCache(t *testing.T) {
+	args := buildArgs(map[string]string{"VERSION": "1.0"}, []string{"inline"})
+	expected := []docker.BuildArg{
+		{Name: "VERSION", Value: "1.0"},
+		{Name: "BUILDKIT_INLINE_CACHE", Value: "1"},
+	}
+	assert.Check(t, is.DeepEqual(args, expected))
+
+	args = buildArgs(map[string]string{"VERSION": "1.0"}, nil)
+	assert.Check(t, is.DeepEqual(args, []docker.BuildArg{{Name: "VERSION", Value: "1.0"}}))
+}
+
+func TestCacheFromAddsOtherTargets(t *testing.T) {
+	ctx := context.NewExecuteContext(
+		config.NewConfig(), nil, execenv.NewExecEnv("exec", "project", "/dir"), context.Settings{})
+	release := &config.ImageConfig{
+		Image:      "example/app",
+		Tags:       []string{"latest"},
+		Dockerfile: "Dockerfile",
+		Context:    ".",
+		Target:     "release",
+		CacheFrom:  []string{"registry.example.com/app:cache"},
+	}
+	ctx.Resources.Add("release", release)
+	ctx.Resources.Add("test", &config.ImageConfig{
+		Image: "example/app-test", Tags: []string{"latest"},
+		Dockerfile: "Dockerfile", Context: "./", Target: "test",
+	})
+	ctx.Resources.Add("other", &config.ImageConfig{
+		Image: "example/other", Tags: []string{"latest"},
+		Dockerfile: "Dockerfile", Context: "other", Target: "test",
+	})
+
+	expected := []string{"registry.example.com/app:cache", "example/app-test:latest"}
+	assert.Check(t, is.DeepEqual(cacheFrom(ctx, release), expected))
+
+	release.CacheFrom = nil
+	assert.Check(t, is.Len(cacheFrom(ctx, release), 0))
+}
@@ -2,9 +2,11 @@ package image
 
 import (
 	"io"
-	"os"
+	"io/ioutil"
+	"strings"
 	"time"
 
+	"github.com/dnephin/dobi/logging"
 	"github.com/dnephin/dobi/tasks/context"
 	docker "github.com/fsouza/go-dockerclient"
 )
@@ -19,6 +21,10 @@ func RunPull(ctx *context.ExecuteContext, t *Task, _ bool) (bool, error) {
 	case err != nil:
 		t.logger().Warnf("Failed to get image record: %s", err)
 	}
+	if t.config.Pull.IfChanged() && !remoteChanged(ctx, t) {
+		t.logger().Debugf("Pull not required, the registry digest has not changed")
+		return false, nil
+	}
 
 	pullTag := func(tag string) error {
 		return pullImage(ctx, t, tag)
@@ -41,6 +47,41 @@ func RunPull(ctx *context.ExecuteContext, t *Task, _ bool) (bool, error) {
 	return true, nil
 }
 
+// remoteChanged returns true if the digest of any remote tag in the registry
+// is different from the local image. It also returns true when the digests
+// can not be compared, so that the image is pulled.
+func remoteChanged(ctx *context.ExecuteContext, t *Task) bool {
+	changed := false
+	err := t.ForEachRemoteTag(ctx, func(imageTag string) error {
+		if !changed {
+			changed = digestChanged(ctx, t, imageTag)
+		}
+		return nil
+	})
+	return changed || err != nil
+}
+
+func digestChanged(ctx *context.ExecuteContext, t *Task, imageTag string) bool {
+	image, err := ctx.Client.InspectImage(imageTag)
+	if err != nil {
+		t.logger().Debugf("Failed to inspect %s: %s", imageTag, err)
+		return true
+	}
+	remote, err := ctx.Client.InspectDistribution(imageTag)
+	if err != nil {
+		t.logger().Warnf("Failed to get the digest of %s from the registry: %s", imageTag, err)
+		return true
+	}
+	digest := "@" + remote.Descriptor.Digest.String()
+	for _, repoDigest := range image.RepoDigests {
+		if strings.HasSuffix(repoDigest, digest) {
+			return false
+		}
+	}
+	t.logger().Debugf("The registry digest of %s has changed", imageTag)
+	return true
+}
+
 func now() *time.Time {
 	now := time.Now()
 	return &now
@@ -49,13 +90,121 @@ func now() *time.Time {
 func pullImage(ctx *context.ExecuteContext, t *Task, imageTag string) error {
 	registry := parseAuthRepo(t.config.Image)
 	repo, tag := docker.ParseRepositoryTag(imageTag)
-	return Stream(os.Stdout, func(out io.Writer) error {
-		return ctx.Client.PullImage(docker.PullImageOptions{
-			Repository:    repo,
-			Tag:           tag,
-			OutputStream:  out,
-			RawJSONStream: true,
-			// TODO: timeout
-		}, ctx.GetAuthConfig(registry))
+	auth, err := t.getAuthConfig(ctx, registry)
+	if err != nil {
+		return err
+	}
+	pulled, err := pullRepoTag(ctx, repo, tag, auth)
+	if !pulled && err == nil {
+		t.logger().Debugf("%s was already pulled", imageTag)
+	}
+	if err == nil || !isTransientRegistryError(err) || !isDockerHub(repo) {
+		return err
+	}
+
+	for _, mirror := range ctx.PullMirrors {
+		t.logger().Warnf("Failed to pull %s: %s. Trying mirror %s", imageTag, err, mirror)
+		if mirrorErr := pullFromMirror(ctx, t, mirror, repo, tag); mirrorErr != nil {
+			t.logger().Warnf("Failed to pull from mirror %s: %s", mirror, mirrorErr)
+			continue
+		}
+		t.logger().Infof("Pulled %s from mirror %s", imageTag, mirror)
+		return nil
+	}
+	return err
+}
+
+// pullRepoTag pulls the image, and shows the progress of each layer unless
+// --quiet is set. An image which is already being pulled by another task is
+// not pulled again. It returns true if the image was pulled by this call.
+func pullRepoTag(
+	ctx *context.ExecuteContext,
+	repo string,
+	tag string,
+	auth docker.AuthConfiguration,
+) (bool, error) {
+	output := logging.Log.Out
+	if ctx.Settings.Quiet {
+		output = ioutil.Discard
+	}
+	return ctx.Pulls.Pull(ctx.Host+"/"+repo+":"+tag, func() error {
+		return Stream(output, func(out io.Writer) error {
+			return ctx.Client.PullImage(docker.PullImageOptions{
+				Repository:    repo,
+				Tag:           tag,
+				OutputStream:  out,
+				RawJSONStream: true,
+				// TODO: timeout
+			}, auth)
+		})
+	})
+}
+
+// pullFromMirror pulls the image from a mirror of Docker Hub, and tags it with
+// the Docker Hub name
+func pullFromMirror(ctx *context.ExecuteContext, t *Task, mirror, repo, tag string) error {
+	mirrorRepo := mirrorRepository(mirror, repo)
+	auth, err := t.getAuthConfig(ctx, parseAuthRepo(mirrorRepo))
+	if err != nil {
+		return err
+	}
+	if _, err := pullRepoTag(ctx, mirrorRepo, tag, auth); err != nil {
+		return err
+	}
+	return ctx.Client.TagImage(mirrorRepo+":"+tag, docker.TagImageOptions{
+		Repo:  repo,
+		Tag:   tag,
+		Force: true,
 	})
 }
+
+var dockerHubPrefixes = []string{"docker.io/", "index.docker.io/", "registry-1.docker.io/"}
+
+// isDockerHub returns true if the repository is hosted on Docker Hub
+func isDockerHub(repo string) bool {
+	for _, prefix := range dockerHubPrefixes {
+		if strings.HasPrefix(repo, prefix) {
+			return true
+		}
+	}
+	return parseAuthRepo(repo) == defaultRepo
+}
+
+// mirrorRepository returns the name of a Docker Hub repository on the mirror
+func mirrorRepository(mirror, repo string) string {
+	for _, prefix := range dockerHubPrefixes {
+		repo = strings.TrimPrefix(repo, prefix)
+	}
+	if !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+	mirror = strings.TrimPrefix(strings.TrimPrefix(mirror, "https://"), "http://")
+	return strings.TrimSuffix(mirror, "/") + "/" + repo
+}
+
+// transientRegistryErrors are parts of the error messages returned when a pull
+// or push fails because of a rate limit or a temporary problem with the
+// registry
+var transientRegistryErrors = []string{
+	"toomanyrequests",
+	"rate limit",
+	"429",
+	"500 internal server error",
+	"502 bad gateway",
+	"503 service unavailable",
+	"504 gateway timeout",
+	"timeout",
+	"connection reset",
+	"connection refused",
+	"unexpected eof",
+}
+
+func isTransientRegistryError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, transient := range transientRegistryErrors {
+		if strings.Contains(msg, transient) {
+			return true
+		}
+	}
+	return false
+}
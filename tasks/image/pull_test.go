@@ -0,0 +1,106 @@
+package image
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/docker/docker/api/types/registry"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestMirrorRepository(t *testing.T) {
+	var testcases = []struct {
+		mirror   string
+		repo     string
+		expected string
+	}{
+		{mirror: "mirror.gcr.io", repo: "alpine", expected: "mirror.gcr.io/library/alpine"},
+		{mirror: "https://mirror.gcr.io/", repo: "user/app", expected: "mirror.gcr.io/user/app"},
+		{
+			mirror:   "registry.internal:5000",
+			repo:     "docker.io/library/golang",
+			expected: "registry.internal:5000/library/golang",
+		},
+	}
+	for _, testcase := range testcases {
+		actual := mirrorRepository(testcase.mirror, testcase.repo)
+		assert.Check(t, is.Equal(actual, testcase.expected))
+	}
+}
+
+func TestIsTransientRegistryError(t *testing.T) {
+	assert.Check(t, isTransientRegistryError(errors.New(
+		"toomanyrequests: You have reached your pull rate limit")))
+	assert.Check(t, isTransientRegistryError(errors.New("net/http: TLS handshake timeout")))
+	assert.Check(t, !isTransientRegistryError(errors.New("manifest for alpine:nope not found")))
+}
+
+func TestPullImageFallsBackToMirror(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+
+	ctx := &context.ExecuteContext{
+		Client:      mockClient,
+		PullMirrors: []string{"mirror.one", "mirror.two"},
+	}
+	imageTask := &Task{config: &config.ImageConfig{Image: "alpine"}}
+
+	gomock.InOrder(
+		mockClient.EXPECT().PullImage(gomock.Any(), gomock.Any()).Return(
+			errors.New("toomanyrequests: rate limit")),
+		mockClient.EXPECT().PullImage(gomock.Any(), gomock.Any()).Return(
+			errors.New("503 Service Unavailable")),
+		mockClient.EXPECT().PullImage(gomock.Any(), gomock.Any()).Return(nil),
+		mockClient.EXPECT().TagImage("mirror.two/library/alpine:3.12", docker.TagImageOptions{
+			Repo:  "alpine",
+			Tag:   "3.12",
+			Force: true,
+		}).Return(nil),
+	)
+	assert.NilError(t, pullImage(ctx, imageTask, "alpine:3.12"))
+}
+
+func TestPullImageDoesNotUseMirrorForOtherErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+
+	ctx := &context.ExecuteContext{Client: mockClient, PullMirrors: []string{"mirror.one"}}
+	imageTask := &Task{config: &config.ImageConfig{Image: "alpine"}}
+
+	mockClient.EXPECT().PullImage(gomock.Any(), gomock.Any()).Return(
+		errors.New("manifest unknown"))
+	assert.ErrorContains(t, pullImage(ctx, imageTask, "alpine:nope"), "manifest unknown")
+}
+
+func TestRemoteChanged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+
+	ctx := &context.ExecuteContext{Client: mockClient}
+	imageTask := &Task{config: &config.ImageConfig{Image: "alpine", Tags: []string{"3.12"}}}
+	local := &docker.Image{ID: "abcdef", RepoDigests: []string{"alpine@sha256:aaaa"}}
+	remote := &registry.DistributionInspect{}
+	remote.Descriptor.Digest = "sha256:aaaa"
+	updated := &registry.DistributionInspect{}
+	updated.Descriptor.Digest = "sha256:bbbb"
+
+	mockClient.EXPECT().InspectImage("alpine:3.12").Return(local, nil).Times(3)
+	gomock.InOrder(
+		mockClient.EXPECT().InspectDistribution("alpine:3.12").Return(remote, nil),
+		mockClient.EXPECT().InspectDistribution("alpine:3.12").Return(updated, nil),
+		mockClient.EXPECT().InspectDistribution("alpine:3.12").Return(
+			nil, errors.New("unauthorized")),
+	)
+	assert.Check(t, !remoteChanged(ctx, imageTask))
+	assert.Check(t, remoteChanged(ctx, imageTask))
+	assert.Check(t, remoteChanged(ctx, imageTask))
+}
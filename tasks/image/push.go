@@ -2,32 +2,69 @@ package image
 
 import (
 	"io"
-	"os"
+	"time"
 
+	"github.com/dnephin/dobi/logging"
 	"github.com/dnephin/dobi/tasks/context"
 	docker "github.com/fsouza/go-dockerclient"
 )
 
 // RunPush pushes an image to the registry
 func RunPush(ctx *context.ExecuteContext, t *Task, _ bool) (bool, error) {
+	if err := t.runHooks(ctx, "pre-push", t.config.PrePush); err != nil {
+		return false, err
+	}
 	pushTag := func(tag string) error {
-		return pushImage(ctx, tag)
+		return pushImage(ctx, t, tag)
 	}
 	if err := t.ForEachRemoteTag(ctx, pushTag); err != nil {
 		return false, err
 	}
+	if t.config.TagByDigestOfInputs {
+		inputImage, err := inputImageName(ctx, t.config)
+		if err != nil {
+			return false, err
+		}
+		if err := pushImage(ctx, t, inputImage); err != nil {
+			return false, err
+		}
+	}
 	t.logger().Info("Pushed")
 	return true, nil
 }
 
-func pushImage(ctx *context.ExecuteContext, tag string) error {
+// sleep is used to wait between the retries of a push
+var sleep = time.Sleep
+
+// pushImage pushes the tag, and retries the push up to push-retries times
+// when it fails with a temporary error. The delay between retries starts at
+// push-retry-delay and is doubled after each retry.
+func pushImage(ctx *context.ExecuteContext, t *Task, tag string) error {
 	repo := parseAuthRepo(tag)
-	return Stream(os.Stdout, func(out io.Writer) error {
+	auth, err := t.getAuthConfig(ctx, repo)
+	if err != nil {
+		return err
+	}
+	delay := t.config.PushRetryDelayDuration()
+	for attempt := 1; ; attempt++ {
+		err = pushTag(ctx, tag, auth)
+		if err == nil || !isTransientRegistryError(err) || attempt > t.config.PushRetries {
+			return err
+		}
+		t.logger().Warnf("Failed to push %s: %s. Retrying in %s (retry %d of %d)",
+			tag, err, delay, attempt, t.config.PushRetries)
+		sleep(delay)
+		delay *= 2
+	}
+}
+
+func pushTag(ctx *context.ExecuteContext, tag string, auth docker.AuthConfiguration) error {
+	return Stream(logging.Log.Out, func(out io.Writer) error {
 		return ctx.Client.PushImage(docker.PushImageOptions{
 			Name:          tag,
 			OutputStream:  out,
 			RawJSONStream: true,
 			// TODO: timeout
-		}, ctx.GetAuthConfig(repo))
+		}, auth)
 	})
 }
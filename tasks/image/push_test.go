@@ -0,0 +1,69 @@
+package image
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func patchSleep(delays *[]time.Duration) func() {
+	original := sleep
+	sleep = func(delay time.Duration) {
+		*delays = append(*delays, delay)
+	}
+	return func() {
+		sleep = original
+	}
+}
+
+func TestPushImageRetriesTransientErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	delays := []time.Duration{}
+	defer patchSleep(&delays)()
+
+	ctx := &context.ExecuteContext{Client: mockClient}
+	imageTask := &Task{config: &config.ImageConfig{
+		Image:          "example.com/app",
+		PushRetries:    3,
+		PushRetryDelay: "2s",
+	}}
+
+	gomock.InOrder(
+		mockClient.EXPECT().PushImage(gomock.Any(), gomock.Any()).Return(
+			errors.New("received unexpected HTTP status: 503 Service Unavailable")),
+		mockClient.EXPECT().PushImage(gomock.Any(), gomock.Any()).Return(
+			errors.New("net/http: TLS handshake timeout")),
+		mockClient.EXPECT().PushImage(gomock.Any(), gomock.Any()).Return(nil),
+	)
+	assert.NilError(t, pushImage(ctx, imageTask, "example.com/app:1.0"))
+	assert.Check(t, is.DeepEqual(delays, []time.Duration{2 * time.Second, 4 * time.Second}))
+}
+
+func TestPushImageStopsRetrying(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	delays := []time.Duration{}
+	defer patchSleep(&delays)()
+
+	ctx := &context.ExecuteContext{Client: mockClient}
+	imageTask := &Task{config: &config.ImageConfig{Image: "example.com/app", PushRetries: 1}}
+
+	mockClient.EXPECT().PushImage(gomock.Any(), gomock.Any()).Return(
+		errors.New("502 Bad Gateway")).Times(2)
+	assert.ErrorContains(t, pushImage(ctx, imageTask, "example.com/app:1.0"), "502 Bad Gateway")
+	assert.Check(t, is.DeepEqual(delays, []time.Duration{time.Second}))
+
+	mockClient.EXPECT().PushImage(gomock.Any(), gomock.Any()).Return(
+		errors.New("denied: requested access to the resource is denied"))
+	assert.ErrorContains(t, pushImage(ctx, imageTask, "example.com/app:1.0"), "denied")
+}
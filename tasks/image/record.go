@@ -54,11 +54,13 @@ func getImageRecord(filepath string) (imageModifiedRecord, error) {
 }
 
 func recordPath(ctx *context.ExecuteContext, conf *config.ImageConfig) string {
-	return recordPathForTag(ctx.WorkingDir, GetImageName(ctx, conf))
+	return recordPathForTag(ctx.WorkingDir, ctx.Endpoint, GetImageName(ctx, conf))
 }
 
-func recordPathForTag(workdir string, tag string) string {
+// recordPathForTag returns the path to the image record. Records for a matrix
+// endpoint are stored separately because each endpoint has its own images.
+func recordPathForTag(workdir string, endpoint string, tag string) string {
 	imageName := strings.Replace(tag, "/", " ", all)
 	imageName = strings.Replace(imageName, ":", " ", all)
-	return filepath.Join(workdir, imageRecordDir, imageName)
+	return filepath.Join(workdir, imageRecordDir, endpoint, imageName)
 }
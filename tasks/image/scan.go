@@ -0,0 +1,179 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const scanRecordDir = ".dobi/scans"
+
+// dockerSocket is mounted into the scanner container so that the scanner can
+// read the image from the Docker host
+const dockerSocket = "/var/run/docker.sock"
+
+// RunScan scans the image for vulnerabilities, if the image was changed since
+// it last passed a scan
+func RunScan(ctx *context.ExecuteContext, t *Task, hasModifiedDeps bool) (bool, error) {
+	name := GetImageName(ctx, t.config)
+	image, err := GetImage(ctx, t.config)
+	if err != nil {
+		return false, fmt.Errorf("failed to get image %q: %s", name, err)
+	}
+	scanID, err := scanRecordID(ctx, t.config.Scan, image.ID)
+	if err != nil {
+		return false, err
+	}
+	record := scanRecordPath(ctx, t)
+	if !hasModifiedDeps && loadedImageID(record) == scanID {
+		logging.Skipped(t)
+		return false, nil
+	}
+
+	t.logger().Infof("Scanning with %s", t.config.Scan.ScannerImage())
+	if t.config.Scan.Baseline != "" {
+		err = runScanWithBaseline(ctx, t.config.Scan, name)
+	} else {
+		err = runScanner(ctx, t.config.Scan, name)
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := writeLoadedImageID(record, scanID); err != nil {
+		t.logger().Warnf("Failed to update scan record: %s", err)
+	}
+	t.logger().Info("Scanned")
+	return true, nil
+}
+
+// scanRecordID returns the value stored in the scan record. The value is the
+// ID of the image, and the digest of the baseline, so that the image is
+// scanned again when the baseline changes.
+func scanRecordID(
+	ctx *context.ExecuteContext,
+	scan config.ImageScan,
+	imageID string,
+) (string, error) {
+	if scan.Baseline == "" {
+		return imageID, nil
+	}
+	content, err := ioutil.ReadFile(absPath(scan.Baseline, ctx.WorkingDir))
+	if err != nil {
+		return "", fmt.Errorf("failed to read scan baseline: %s", err)
+	}
+	digest := sha256.Sum256(content)
+	return imageID + " " + hex.EncodeToString(digest[:]), nil
+}
+
+// runScanner runs the scanner in a container, and returns an error if the
+// scanner exits with a non-zero status
+func runScanner(ctx *context.ExecuteContext, scan config.ImageScan, image string) error {
+	status, err := runScannerContainer(ctx, scan, scan.Command(image), ctx.Settings.Output())
+	switch {
+	case err != nil:
+		return err
+	case status != 0:
+		return fmt.Errorf("scan of %s failed with exit code %d, the image may have "+
+			"vulnerabilities with a severity of %s or higher",
+			image, status, scanSeverity(scan))
+	}
+	return nil
+}
+
+// runScannerContainer runs the scanner in a container with the command, and
+// returns the exit status of the scanner
+func runScannerContainer(
+	ctx *context.ExecuteContext,
+	scan config.ImageScan,
+	cmd []string,
+	out io.Writer,
+) (int, error) {
+	scannerImage := scan.ScannerImage()
+	if err := ensureScannerImage(ctx, scannerImage); err != nil {
+		return 0, fmt.Errorf("failed to pull scanner image %q: %s", scannerImage, err)
+	}
+
+	container, err := ctx.Client.CreateContainer(docker.CreateContainerOptions{
+		Config: &docker.Config{
+			Image: scannerImage,
+			Cmd:   cmd,
+		},
+		HostConfig: &docker.HostConfig{
+			Binds: []string{dockerSocket + ":" + dockerSocket},
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create scanner container: %s", err)
+	}
+	defer ctx.Track(context.TrackedContainer, container.ID)()
+	defer removeScanner(ctx, container.ID)
+
+	closeWaiter, err := ctx.Client.AttachToContainerNonBlocking(docker.AttachToContainerOptions{
+		Container:    container.ID,
+		OutputStream: out,
+		ErrorStream:  os.Stderr,
+		Stream:       true,
+		Stdout:       true,
+		Stderr:       true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed attaching to scanner container: %s", err)
+	}
+	// Wait for all the output to be written before returning
+	defer closeWaiter.Wait() // nolint: errcheck
+
+	if err := ctx.Client.StartContainer(container.ID, nil); err != nil {
+		return 0, fmt.Errorf("failed starting scanner container: %s", err)
+	}
+	status, err := ctx.Client.WaitContainer(container.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed waiting for scanner container: %s", err)
+	}
+	return status, nil
+}
+
+// ensureScannerImage pulls the scanner image if it does not exist
+func ensureScannerImage(ctx *context.ExecuteContext, image string) error {
+	_, err := ctx.Client.InspectImage(image)
+	if err != docker.ErrNoSuchImage {
+		return err
+	}
+	repo, tag := docker.ParseRepositoryTag(image)
+	if tag == "" {
+		tag = "latest"
+	}
+	_, err = pullRepoTag(ctx, repo, tag, docker.AuthConfiguration{})
+	return err
+}
+
+func removeScanner(ctx *context.ExecuteContext, containerID string) {
+	if err := ctx.Client.RemoveContainer(docker.RemoveContainerOptions{
+		ID:            containerID,
+		RemoveVolumes: true,
+		Force:         true,
+	}); err != nil {
+		logging.Log.Warnf("Failed to remove scanner container %s: %s", containerID, err)
+	}
+}
+
+func scanSeverity(scan config.ImageScan) string {
+	if scan.Severity == "" {
+		return "high"
+	}
+	return scan.Severity
+}
+
+// scanRecordPath returns the path of the file which stores the ID of the
+// image which last passed a scan
+func scanRecordPath(ctx *context.ExecuteContext, t *Task) string {
+	return filepath.Join(ctx.WorkingDir, scanRecordDir, t.name.Resource())
+}
@@ -0,0 +1,64 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type fakeCloseWaiter struct{}
+
+func (fakeCloseWaiter) Close() error { return nil }
+
+func (fakeCloseWaiter) Wait() error { return nil }
+
+func expectScanner(mockClient *client.MockDockerClient, status int) {
+	mockClient.EXPECT().InspectImage("anchore/grype:latest").Return(nil, docker.ErrNoSuchImage)
+	mockClient.EXPECT().PullImage(gomock.Any(), gomock.Any()).Return(nil)
+	mockClient.EXPECT().CreateContainer(docker.CreateContainerOptions{
+		Config: &docker.Config{
+			Image: "anchore/grype:latest",
+			Cmd:   []string{"example.com/app:abcd", "--fail-on", "critical"},
+		},
+		HostConfig: &docker.HostConfig{
+			Binds: []string{"/var/run/docker.sock:/var/run/docker.sock"},
+		},
+	}).Return(&docker.Container{ID: "scanner-id"}, nil)
+	mockClient.EXPECT().AttachToContainerNonBlocking(gomock.Any()).Return(fakeCloseWaiter{}, nil)
+	mockClient.EXPECT().StartContainer("scanner-id", nil).Return(nil)
+	mockClient.EXPECT().WaitContainer("scanner-id").Return(status, nil)
+	mockClient.EXPECT().RemoveContainer(docker.RemoveContainerOptions{
+		ID:            "scanner-id",
+		RemoveVolumes: true,
+		Force:         true,
+	}).Return(nil)
+}
+
+func TestRunScanner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	ctx := &context.ExecuteContext{Client: mockClient}
+	scan := config.ImageScan{Scanner: config.ScannerGrype, Severity: "critical"}
+
+	expectScanner(mockClient, 0)
+	assert.NilError(t, runScanner(ctx, scan, "example.com/app:abcd"))
+}
+
+func TestRunScannerFindsVulnerabilities(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	ctx := &context.ExecuteContext{Client: mockClient}
+	scan := config.ImageScan{Scanner: config.ScannerGrype, Severity: "critical"}
+
+	expectScanner(mockClient, 1)
+	err := runScanner(ctx, scan, "example.com/app:abcd")
+	assert.Check(t, is.ErrorContains(err, "scan of example.com/app:abcd failed with exit code 1"))
+}
@@ -0,0 +1,250 @@
+package image
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// vulnerability found in an image by a scanner
+type vulnerability struct {
+	ID       string
+	Package  string
+	Severity string
+}
+
+func (v vulnerability) key() string {
+	return v.ID + " " + v.Package
+}
+
+// RunScanDiff scans the canonical tag of the image, and the diff-tag of the
+// image, and fails when the canonical tag has vulnerabilities which fail the
+// scan, and are not in the diff-tag
+func RunScanDiff(ctx *context.ExecuteContext, t *Task, _ bool) (bool, error) {
+	scan := t.config.Scan
+	name := GetImageName(ctx, t.config)
+	repo, _ := docker.ParseRepositoryTag(name)
+	base := repo + ":" + scan.GetDiffTag()
+
+	t.logger().Infof("Comparing to %s with %s", base, scan.ScannerImage())
+	current, err := scanVulnerabilities(ctx, scan, name)
+	if err != nil {
+		return false, err
+	}
+	previous, err := scanVulnerabilities(ctx, scan, base)
+	if err != nil {
+		return false, err
+	}
+
+	added, fixed := diffVulnerabilities(previous, current)
+	out := ctx.Settings.Output()
+	if err := writeVulnerabilities(out, "Fixed", fixed); err != nil {
+		return false, err
+	}
+	if err := writeVulnerabilities(out, "Added", added); err != nil {
+		return false, err
+	}
+	if failing := failingVulnerabilities(scan, added); len(failing) > 0 {
+		return false, fmt.Errorf("%s has %d vulnerabilities with a severity of %s or "+
+			"higher which are not in %s", name, len(failing), scanSeverity(scan), base)
+	}
+	t.logger().Info("Compared")
+	return true, nil
+}
+
+// runScanWithBaseline scans the image, and returns an error if there are
+// vulnerabilities which fail the scan, and are not in the baseline
+func runScanWithBaseline(
+	ctx *context.ExecuteContext,
+	scan config.ImageScan,
+	image string,
+) error {
+	baseline, err := readBaseline(absPath(scan.Baseline, ctx.WorkingDir))
+	if err != nil {
+		return fmt.Errorf("failed to read scan baseline: %s", err)
+	}
+	found, err := scanVulnerabilities(ctx, scan, image)
+	if err != nil {
+		return err
+	}
+
+	failing := []vulnerability{}
+	for _, vuln := range failingVulnerabilities(scan, found) {
+		if !baseline[vuln.ID] {
+			failing = append(failing, vuln)
+		}
+	}
+	if len(failing) == 0 {
+		return nil
+	}
+	out := ctx.Settings.Output()
+	if err := writeVulnerabilities(out, "Not in the baseline", failing); err != nil {
+		return err
+	}
+	return fmt.Errorf("scan of %s found %d vulnerabilities with a severity of %s or "+
+		"higher which are not in the baseline %s",
+		image, len(failing), scanSeverity(scan), scan.Baseline)
+}
+
+// readBaseline returns the vulnerability IDs in the baseline file
+func readBaseline(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() // nolint: errcheck
+
+	ids := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ids[strings.Fields(line)[0]] = true
+	}
+	return ids, scanner.Err()
+}
+
+// scanVulnerabilities runs the scanner, and returns every vulnerability found
+// in the image
+func scanVulnerabilities(
+	ctx *context.ExecuteContext,
+	scan config.ImageScan,
+	image string,
+) ([]vulnerability, error) {
+	out := new(bytes.Buffer)
+	status, err := runScannerContainer(ctx, scan, scan.ReportCommand(image), out)
+	switch {
+	case err != nil:
+		return nil, err
+	case status != 0:
+		return nil, fmt.Errorf("scan of %s failed with exit code %d", image, status)
+	}
+	vulns, err := parseScanReport(scan.Scanner, out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the scan of %s: %s", image, err)
+	}
+	return vulns, nil
+}
+
+// trivyReport is the JSON output of trivy
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID string
+			PkgName         string
+			Severity        string
+		}
+	}
+}
+
+// grypeReport is the JSON output of grype
+type grypeReport struct {
+	Matches []struct {
+		Vulnerability struct {
+			ID       string `json:"id"`
+			Severity string `json:"severity"`
+		} `json:"vulnerability"`
+		Artifact struct {
+			Name string `json:"name"`
+		} `json:"artifact"`
+	} `json:"matches"`
+}
+
+func parseScanReport(scanner string, report []byte) ([]vulnerability, error) {
+	vulns := []vulnerability{}
+	if scanner == config.ScannerGrype {
+		parsed := grypeReport{}
+		if err := json.Unmarshal(report, &parsed); err != nil {
+			return nil, err
+		}
+		for _, match := range parsed.Matches {
+			vulns = append(vulns, vulnerability{
+				ID:       match.Vulnerability.ID,
+				Package:  match.Artifact.Name,
+				Severity: match.Vulnerability.Severity,
+			})
+		}
+		return vulns, nil
+	}
+
+	parsed := trivyReport{}
+	if err := json.Unmarshal(report, &parsed); err != nil {
+		return nil, err
+	}
+	for _, result := range parsed.Results {
+		for _, vuln := range result.Vulnerabilities {
+			vulns = append(vulns, vulnerability{
+				ID:       vuln.VulnerabilityID,
+				Package:  vuln.PkgName,
+				Severity: vuln.Severity,
+			})
+		}
+	}
+	return vulns, nil
+}
+
+// failingVulnerabilities returns the vulnerabilities with a severity which
+// fails the scan
+func failingVulnerabilities(scan config.ImageScan, vulns []vulnerability) []vulnerability {
+	failing := []vulnerability{}
+	for _, vuln := range vulns {
+		if scan.FailsOn(vuln.Severity) {
+			failing = append(failing, vuln)
+		}
+	}
+	return failing
+}
+
+// diffVulnerabilities returns the vulnerabilities which are only in current,
+// and the vulnerabilities which are only in previous
+func diffVulnerabilities(
+	previous []vulnerability,
+	current []vulnerability,
+) ([]vulnerability, []vulnerability) {
+	return subtractVulnerabilities(current, previous), subtractVulnerabilities(previous, current)
+}
+
+func subtractVulnerabilities(from, remove []vulnerability) []vulnerability {
+	removed := make(map[string]bool)
+	for _, vuln := range remove {
+		removed[vuln.key()] = true
+	}
+	result := []vulnerability{}
+	seen := make(map[string]bool)
+	for _, vuln := range from {
+		if removed[vuln.key()] || seen[vuln.key()] {
+			continue
+		}
+		seen[vuln.key()] = true
+		result = append(result, vuln)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].key() < result[j].key()
+	})
+	return result
+}
+
+func writeVulnerabilities(out io.Writer, title string, vulns []vulnerability) error {
+	if len(vulns) == 0 {
+		return nil
+	}
+	fmt.Fprintf(out, "%s:\n", title)
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "  ID\tPACKAGE\tSEVERITY")
+	for _, vuln := range vulns {
+		fmt.Fprintf(writer, "  %s\t%s\t%s\n", vuln.ID, vuln.Package, vuln.Severity)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,103 @@
+package image
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+const trivyReportJSON = `{"Results": [{"Target": "app", "Vulnerabilities": [
+	{"VulnerabilityID": "CVE-2021-1", "PkgName": "openssl", "Severity": "CRITICAL"},
+	{"VulnerabilityID": "CVE-2021-2", "PkgName": "zlib", "Severity": "HIGH"},
+	{"VulnerabilityID": "CVE-2021-3", "PkgName": "curl", "Severity": "LOW"}
+]}]}`
+
+const grypeReportJSON = `{"matches": [
+	{
+		"vulnerability": {"id": "CVE-2021-1", "severity": "Critical"},
+		"artifact": {"name": "openssl"}
+	}
+]}`
+
+func TestParseScanReport(t *testing.T) {
+	vulns, err := parseScanReport(config.ScannerTrivy, []byte(trivyReportJSON))
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(vulns, 3))
+	assert.Check(t, is.DeepEqual(vulns[1],
+		vulnerability{ID: "CVE-2021-2", Package: "zlib", Severity: "HIGH"}))
+
+	vulns, err = parseScanReport(config.ScannerGrype, []byte(grypeReportJSON))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(vulns, []vulnerability{
+		{ID: "CVE-2021-1", Package: "openssl", Severity: "Critical"},
+	}))
+}
+
+func TestDiffVulnerabilities(t *testing.T) {
+	previous := []vulnerability{
+		{ID: "CVE-1", Package: "openssl", Severity: "HIGH"},
+		{ID: "CVE-2", Package: "zlib", Severity: "HIGH"},
+	}
+	current := []vulnerability{
+		{ID: "CVE-2", Package: "zlib", Severity: "HIGH"},
+		{ID: "CVE-3", Package: "curl", Severity: "CRITICAL"},
+	}
+	added, fixed := diffVulnerabilities(previous, current)
+	assert.Check(t, is.DeepEqual(added, current[1:]))
+	assert.Check(t, is.DeepEqual(fixed, previous[:1]))
+}
+
+func expectScanReport(mockClient *client.MockDockerClient, image string, report string) {
+	mockClient.EXPECT().InspectImage("aquasec/trivy:latest").Return(&docker.Image{}, nil)
+	mockClient.EXPECT().CreateContainer(docker.CreateContainerOptions{
+		Config: &docker.Config{
+			Image: "aquasec/trivy:latest",
+			Cmd:   []string{"image", "--no-progress", "--format", "json", image},
+		},
+		HostConfig: &docker.HostConfig{
+			Binds: []string{"/var/run/docker.sock:/var/run/docker.sock"},
+		},
+	}).Return(&docker.Container{ID: "scanner-id"}, nil)
+	mockClient.EXPECT().AttachToContainerNonBlocking(gomock.Any()).DoAndReturn(
+		func(opts docker.AttachToContainerOptions) (docker.CloseWaiter, error) {
+			_, err := opts.OutputStream.Write([]byte(report))
+			return fakeCloseWaiter{}, err
+		})
+	mockClient.EXPECT().StartContainer("scanner-id", nil).Return(nil)
+	mockClient.EXPECT().WaitContainer("scanner-id").Return(0, nil)
+	mockClient.EXPECT().RemoveContainer(gomock.Any()).Return(nil)
+}
+
+func TestRunScanWithBaseline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	dir := fs.NewDir(t, "scan-baseline",
+		fs.WithFile("baseline", "# known\nCVE-2021-1 openssl\n"))
+	defer dir.Remove()
+	out := new(bytes.Buffer)
+	ctx := &context.ExecuteContext{
+		Client:     mockClient,
+		WorkingDir: dir.Path(),
+		Settings:   context.Settings{JobOutput: out},
+	}
+	scan := config.ImageScan{Baseline: "baseline"}
+
+	expectScanReport(mockClient, "app:1.0", trivyReportJSON)
+	err := runScanWithBaseline(ctx, scan, "app:1.0")
+	assert.Check(t, is.ErrorContains(err, "found 1 vulnerabilities"))
+	assert.Check(t, is.Contains(out.String(), "CVE-2021-2"))
+	assert.Check(t, !bytes.Contains(out.Bytes(), []byte("CVE-2021-1")))
+
+	scan.Severity = "critical"
+	expectScanReport(mockClient, "app:1.0", trivyReportJSON)
+	assert.Check(t, runScanWithBaseline(ctx, scan, "app:1.0"))
+}
@@ -0,0 +1,266 @@
+package image
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dnephin/dobi/tasks/context"
+)
+
+// autoSourcePaths returns the paths of the Dockerfile and the files copied into
+// the image by COPY and ADD instructions, relative to the working directory.
+// It returns false when the sources can not be determined, and the whole
+// build context must be used instead.
+func autoSourcePaths(ctx *context.ExecuteContext, t *Task) ([]string, bool) {
+	var reader io.Reader
+	paths := []string{}
+	if t.config.Steps != "" {
+		reader = strings.NewReader(t.config.Steps)
+	} else {
+		dockerfile := filepath.Join(t.config.Context, t.config.Dockerfile)
+		file, err := os.Open(absPath(dockerfile, ctx.WorkingDir))
+		if err != nil {
+			t.logger().Warnf("Failed to read Dockerfile for auto-sources: %s", err)
+			return nil, false
+		}
+		defer file.Close() // nolint: errcheck
+		reader = file
+		paths = append(paths, dockerfile)
+	}
+
+	sources, err := dockerfileSources(reader, t.config.Target)
+	if err != nil {
+		t.logger().Debugf("Using the build context: %s", err)
+		return nil, false
+	}
+	contextDir := absPath(t.config.Context, ctx.WorkingDir)
+	for _, source := range sources {
+		matches, err := filepath.Glob(filepath.Join(contextDir, source))
+		if err != nil || len(matches) == 0 {
+			t.logger().Debugf("Using the build context: no files match %q", source)
+			return nil, false
+		}
+		for _, match := range matches {
+			relPath, err := filepath.Rel(ctx.WorkingDir, match)
+			if err != nil {
+				return nil, false
+			}
+			paths = append(paths, relPath)
+		}
+	}
+	return paths, true
+}
+
+// dockerfileSources returns the source paths of the COPY and ADD instructions
+// in a Dockerfile. Instructions which copy from another stage or image, and
+// ADD instructions with a URL, are ignored. When target is set, only the
+// instructions of the target stage and the stages it uses are included.
+func dockerfileSources(reader io.Reader, target string) ([]string, error) {
+	instructions, err := dockerfileInstructions(reader)
+	if err != nil {
+		return nil, err
+	}
+	if target != "" {
+		if instructions, err = targetInstructions(instructions, target); err != nil {
+			return nil, err
+		}
+	}
+	sources := []string{}
+	for _, instruction := range instructions {
+		fields := strings.Fields(instruction)
+		if len(fields) == 0 {
+			continue
+		}
+		cmd := strings.ToUpper(fields[0])
+		if cmd != "COPY" && cmd != "ADD" {
+			continue
+		}
+		flags, rest := splitFlags(instruction[len(fields[0]):])
+		if hasFromFlag(flags) {
+			continue
+		}
+		args, err := instructionArgs(rest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %s", instruction, err)
+		}
+		if len(args) < 2 {
+			continue
+		}
+		for _, source := range args[:len(args)-1] {
+			switch {
+			case cmd == "ADD" && isRemoteSource(source):
+				continue
+			case strings.HasPrefix(source, "<<"):
+				return nil, fmt.Errorf("heredocs are not supported: %q", instruction)
+			case strings.Contains(source, "$"):
+				return nil, fmt.Errorf("source %q uses a variable", source)
+			}
+			sources = append(sources, source)
+		}
+	}
+	return sources, nil
+}
+
+// dockerfileInstructions returns the instructions in a Dockerfile with line
+// continuations joined, and comments removed
+func dockerfileInstructions(reader io.Reader) ([]string, error) {
+	instructions := []string{}
+	current := ""
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			current += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		instructions = append(instructions, current+line)
+		current = ""
+	}
+	if current != "" {
+		instructions = append(instructions, current)
+	}
+	return instructions, scanner.Err()
+}
+
+// dockerfileStage is a build stage of a multi-stage Dockerfile
+type dockerfileStage struct {
+	name         string
+	instructions []string
+	// uses are the names or indexes of other stages used by the stage
+	uses []string
+}
+
+// targetInstructions returns the instructions of the target stage, the stages
+// it uses, and the instructions before the first stage
+func targetInstructions(instructions []string, target string) ([]string, error) {
+	global := []string{}
+	stages := []*dockerfileStage{}
+	for _, instruction := range instructions {
+		fields := strings.Fields(instruction)
+		cmd := strings.ToUpper(fields[0])
+		if cmd == "FROM" {
+			stages = append(stages, newDockerfileStage(fields[1:]))
+		}
+		if len(stages) == 0 {
+			global = append(global, instruction)
+			continue
+		}
+		stage := stages[len(stages)-1]
+		stage.instructions = append(stage.instructions, instruction)
+		if cmd == "COPY" {
+			flags, _ := splitFlags(instruction[len(fields[0]):])
+			for _, flag := range flags {
+				if strings.HasPrefix(flag, "--from=") {
+					stage.uses = append(stage.uses, strings.TrimPrefix(flag, "--from="))
+				}
+			}
+		}
+	}
+
+	lookup := func(ref string) int {
+		for i, stage := range stages {
+			if strings.EqualFold(stage.name, ref) || strconv.Itoa(i) == ref {
+				return i
+			}
+		}
+		return -1
+	}
+	index := lookup(target)
+	if index == -1 {
+		return nil, fmt.Errorf("target stage %q is not defined", target)
+	}
+	used := make(map[int]bool)
+	pending := []int{index}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		if used[current] {
+			continue
+		}
+		used[current] = true
+		for _, ref := range stages[current].uses {
+			// Other references are to images, not stages
+			if i := lookup(ref); i != -1 && i < current {
+				pending = append(pending, i)
+			}
+		}
+	}
+
+	result := global
+	for i, stage := range stages {
+		if used[i] {
+			result = append(result, stage.instructions...)
+		}
+	}
+	return result, nil
+}
+
+// newDockerfileStage returns a stage from the arguments of a FROM instruction
+func newDockerfileStage(args []string) *dockerfileStage {
+	_, rest := splitFlags(strings.Join(args, " "))
+	fields := strings.Fields(rest)
+	stage := &dockerfileStage{}
+	if len(fields) > 0 {
+		stage.uses = append(stage.uses, fields[0])
+	}
+	if len(fields) == 3 && strings.EqualFold(fields[1], "AS") {
+		stage.name = fields[2]
+	}
+	return stage
+}
+
+// instructionArgs splits the arguments of an instruction in either the JSON
+// or the whitespace separated form
+func instructionArgs(raw string) ([]string, error) {
+	if !strings.HasPrefix(raw, "[") {
+		return strings.Fields(raw), nil
+	}
+	args := []string{}
+	err := json.Unmarshal([]byte(raw), &args)
+	return args, err
+}
+
+// splitFlags splits the leading flags of an instruction from the rest of the
+// arguments
+func splitFlags(raw string) ([]string, string) {
+	flags := []string{}
+	rest := strings.TrimSpace(raw)
+	for strings.HasPrefix(rest, "--") {
+		parts := strings.SplitN(rest, " ", 2)
+		flags = append(flags, parts[0])
+		if len(parts) == 1 {
+			return flags, ""
+		}
+		rest = strings.TrimSpace(parts[1])
+	}
+	return flags, rest
+}
+
+// hasFromFlag returns true if the instruction copies from another stage or
+// image
+func hasFromFlag(flags []string) bool {
+	for _, flag := range flags {
+		if strings.HasPrefix(flag, "--from=") {
+			return true
+		}
+	}
+	return false
+}
+
+func isRemoteSource(source string) bool {
+	for _, prefix := range []string{"http://", "https://", "git@"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return false
+}
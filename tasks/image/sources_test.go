@@ -0,0 +1,66 @@
+package image
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestDockerfileSources(t *testing.T) {
+	dockerfile := `
+FROM golang:1.14 AS build
+# COPY ignored.go /go/
+COPY go.mod go.sum /go/src/app/
+copy --chown=1000:1000 cmd/ \
+    pkg/ /go/src/app/
+RUN go build ./...
+
+FROM alpine
+ADD https://example.com/ca.pem /etc/ssl/
+ADD ["config/app.yaml", "/etc/app.yaml"]
+COPY --from=build /go/bin/app /usr/bin/app
+`
+	sources, err := dockerfileSources(strings.NewReader(dockerfile), "")
+	assert.NilError(t, err)
+	expected := []string{"go.mod", "go.sum", "cmd/", "pkg/", "config/app.yaml"}
+	assert.Check(t, is.DeepEqual(sources, expected))
+}
+
+func TestDockerfileSourcesForTarget(t *testing.T) {
+	dockerfile := `
+ARG GO_VERSION=1.14
+FROM golang:${GO_VERSION} AS deps
+COPY go.mod go.sum /go/src/app/
+
+FROM deps AS build
+COPY cmd/ /go/src/app/cmd/
+RUN go build ./...
+
+FROM deps as test
+COPY testdata/ /go/src/app/testdata/
+
+FROM --platform=linux/amd64 alpine AS release
+COPY config/app.yaml /etc/app.yaml
+COPY --from=build /go/bin/app /usr/bin/app
+`
+	sources, err := dockerfileSources(strings.NewReader(dockerfile), "release")
+	assert.NilError(t, err)
+	expected := []string{"go.mod", "go.sum", "cmd/", "config/app.yaml"}
+	assert.Check(t, is.DeepEqual(sources, expected))
+
+	sources, err = dockerfileSources(strings.NewReader(dockerfile), "test")
+	assert.NilError(t, err)
+	expected = []string{"go.mod", "go.sum", "testdata/"}
+	assert.Check(t, is.DeepEqual(sources, expected))
+
+	_, err = dockerfileSources(strings.NewReader(dockerfile), "lint")
+	assert.Check(t, is.Error(err, `target stage "lint" is not defined`))
+}
+
+func TestDockerfileSourcesWithVariable(t *testing.T) {
+	dockerfile := "FROM alpine\nARG DIR\nCOPY ${DIR}/app /app\n"
+	_, err := dockerfileSources(strings.NewReader(dockerfile), "")
+	assert.Check(t, is.ErrorContains(err, `source "${DIR}/app" uses a variable`))
+}
@@ -10,6 +10,24 @@ import (
 	"github.com/dnephin/dobi/tasks/types"
 )
 
+func init() {
+	types.RegisterActions(
+		"job",
+		types.Action{Name: "run", Description: "Run the job"},
+		types.Action{
+			Name:        "start",
+			Description: "Start the job in the background, when it is not running",
+		},
+		types.Action{Name: "stop", Description: "Stop the job which was started in the background"},
+		types.Action{Name: "upload", Description: "Upload the artifact to meta.artifact-store"},
+		types.Action{
+			Name:        "download",
+			Description: "Download the artifact from meta.artifact-store",
+		},
+		types.Action{Name: "remove", Description: "Remove the artifact of the job"},
+	)
+}
+
 // GetTaskConfig returns a new task for the action
 func GetTaskConfig(name, action string, conf *config.JobConfig) (types.TaskConfig, error) {
 	switch action {
@@ -25,6 +43,41 @@ func GetTaskConfig(name, action string, conf *config.JobConfig) (types.TaskConfi
 			conf,
 			task.NoDependencies,
 			newRemoveTask), nil
+	case "start":
+		return types.NewTaskConfig(
+			task.NewName(name, action),
+			conf,
+			deps(conf),
+			newStartTask), nil
+	case "stop":
+		return types.NewTaskConfig(
+			task.NewName(name, action),
+			conf,
+			task.NoDependencies,
+			newStopTask), nil
+	case "upload":
+		return types.NewTaskConfig(
+			task.NewName(name, action),
+			conf,
+			func() []string { return []string{name + ":run"} },
+			newUploadTask), nil
+	case "download":
+		return types.NewTaskConfig(
+			task.NewName(name, action),
+			conf,
+			task.NoDependencies,
+			newDownloadTask), nil
+	}
+	if _, ok := conf.Actions[action]; ok {
+		actionConf, err := customActionConfig(conf, action)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewTaskConfig(
+			task.NewName(name, action),
+			actionConf,
+			deps(conf),
+			newRunTask), nil
 	}
 	if strings.HasPrefix(action, "capture") {
 		variable, err := parseCapture(action)
@@ -40,6 +93,20 @@ func GetTaskConfig(name, action string, conf *config.JobConfig) (types.TaskConfi
 	return nil, fmt.Errorf("invalid run action %q for task %q", action, name)
 }
 
+// customActionConfig returns a copy of the job config which runs the command
+// of the custom action. The copy has no artifact, so the action always runs.
+func customActionConfig(conf *config.JobConfig, action string) (*config.JobConfig, error) {
+	command, err := conf.ActionCommand(action)
+	if err != nil {
+		return nil, err
+	}
+	actionConf := *conf
+	actionConf.Command = command
+	actionConf.Artifact = config.PathGlobs{}
+	actionConf.RemoteCache = false
+	return &actionConf, nil
+}
+
 func deps(conf *config.JobConfig) func() []string {
 	return func() []string {
 		return conf.Dependencies()
@@ -1,8 +1,10 @@
 package job
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/dnephin/dobi/config"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 )
@@ -17,3 +19,23 @@ func TestParseCaptureInvalid(t *testing.T) {
 	_, err := parseCapture("capture")
 	assert.Check(t, is.ErrorContains(err, "invalid capture format"))
 }
+
+func TestGetTaskConfigCustomAction(t *testing.T) {
+	conf := &config.JobConfig{
+		Use:     "builder",
+		Actions: map[string]string{"coverage": "go test -cover ./..."},
+	}
+	assert.NilError(t, conf.Artifact.TransformConfig(reflect.ValueOf("dist/")))
+
+	taskConfig, err := GetTaskConfig("test", "coverage", conf)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(taskConfig.Name().Name(), "test:coverage"))
+	actionConf := taskConfig.Resource().(*config.JobConfig)
+	expected := []string{"go", "test", "-cover", "./..."}
+	assert.Check(t, is.DeepEqual(actionConf.Command.Value(), expected))
+	assert.Check(t, actionConf.Artifact.Empty())
+	assert.Check(t, !conf.Artifact.Empty())
+
+	_, err = GetTaskConfig("test", "lint", conf)
+	assert.Check(t, is.ErrorContains(err, `invalid run action "lint"`))
+}
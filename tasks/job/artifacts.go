@@ -0,0 +1,228 @@
+package job
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/pkg/errors"
+)
+
+// artifactTask uploads or downloads the artifact of a job to the artifact
+// store
+type artifactTask struct {
+	types.NoStop
+	name   task.Name
+	config *config.JobConfig
+	upload bool
+}
+
+func newUploadTask(name task.Name, conf config.Resource) types.Task {
+	return &artifactTask{name: name, config: conf.(*config.JobConfig), upload: true}
+}
+
+func newDownloadTask(name task.Name, conf config.Resource) types.Task {
+	return &artifactTask{name: name, config: conf.(*config.JobConfig)}
+}
+
+// Name returns the name of the task
+func (t *artifactTask) Name() task.Name {
+	return t.name
+}
+
+// Repr formats the task for logging
+func (t *artifactTask) Repr() string {
+	return fmt.Sprintf("%s %v", t.name.Format("job"), &t.config.Artifact)
+}
+
+// Run uploads or downloads the artifact
+func (t *artifactTask) Run(ctx *context.ExecuteContext, _ bool) (bool, error) {
+	logger := logging.ForTask(t)
+	if t.config.Artifact.Empty() {
+		return false, errors.Errorf("job %q has no artifact", t.name.Resource())
+	}
+	if ctx.ArtifactStore == "" {
+		return false, errors.New("meta.artifact-store is required to upload or download artifacts")
+	}
+	url, err := artifactURL(ctx, t.name.Resource())
+	if err != nil {
+		return false, err
+	}
+
+	if t.upload {
+		err = uploadArtifact(ctx.WorkingDir, t.config.Artifact.Globs(), url)
+	} else {
+		err = downloadArtifact(ctx.WorkingDir, url)
+	}
+	if err != nil {
+		return false, err
+	}
+	if t.upload {
+		logger.Infof("Uploaded to %s", url)
+		return false, nil
+	}
+	logger.Infof("Downloaded from %s", url)
+	return true, nil
+}
+
+// artifactURL returns the URL of a file in the artifact store
+func artifactURL(ctx *context.ExecuteContext, name string) (string, error) {
+	storeURL, err := ctx.Env.Resolve(ctx.ArtifactStore)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(storeURL, "/") + "/" + name + ".tar.gz", nil
+}
+
+func uploadArtifact(workingDir string, globs []string, url string) error {
+	paths, err := artifactPaths(workingDir, globs)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no files match the artifact, run the job first")
+	}
+	tarball, err := archive.TarWithOptions(workingDir, &archive.TarOptions{
+		IncludeFiles: paths,
+		Compression:  archive.Gzip,
+	})
+	if err != nil {
+		return err
+	}
+	defer tarball.Close() // nolint: errcheck
+
+	tmpFile, err := ioutil.TempFile("", "dobi-artifact-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name()) // nolint: errcheck
+	defer tmpFile.Close()           // nolint: errcheck
+	if _, err := io.Copy(tmpFile, tarball); err != nil {
+		return err
+	}
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return storeCopy(url, tmpFile, nil)
+}
+
+func downloadArtifact(workingDir string, url string) error {
+	tmpFile, err := ioutil.TempFile("", "dobi-artifact-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name()) // nolint: errcheck
+	defer tmpFile.Close()           // nolint: errcheck
+	if err := storeCopy(url, nil, tmpFile); err != nil {
+		return err
+	}
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return archive.Untar(tmpFile, workingDir, &archive.TarOptions{NoLchown: true})
+}
+
+// artifactPaths returns the paths matched by the artifact globs, relative to
+// the working directory
+func artifactPaths(workingDir string, globs []string) ([]string, error) {
+	paths := []string{}
+	for _, glob := range globs {
+		if !filepath.IsAbs(glob) {
+			glob = filepath.Join(workingDir, glob)
+		}
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			rel, err := filepath.Rel(workingDir, match)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				return nil, errors.Errorf(
+					"artifact %q is outside of the project directory", match)
+			}
+			paths = append(paths, rel)
+		}
+	}
+	return paths, nil
+}
+
+// storeCopy copies the file in src to url, or from url to dest. s3 and gs
+// URLs are copied with the aws and gsutil command line tools.
+func storeCopy(url string, src *os.File, dest *os.File) error {
+	scheme := strings.SplitN(url, "://", 2)[0]
+	switch scheme {
+	case "s3":
+		return commandCopy(url, src, dest, "aws", "s3", "cp")
+	case "gs":
+		return commandCopy(url, src, dest, "gsutil", "cp")
+	case "http", "https":
+		if src != nil {
+			return httpPut(url, src)
+		}
+		return httpGet(url, dest)
+	default:
+		return errors.Errorf("unsupported artifact store %q", url)
+	}
+}
+
+func commandCopy(url string, src *os.File, dest *os.File, command ...string) error {
+	args := command[1:]
+	if src != nil {
+		args = append(args, src.Name(), url)
+	} else {
+		args = append(args, url, dest.Name())
+	}
+	cmd := exec.Command(command[0], args...)
+	cmd.Stdout = logging.Log.Out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "failed to copy artifact with %s", command[0])
+	}
+	return nil
+}
+
+func httpPut(url string, src *os.File) error {
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, url, src)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("failed to upload artifact to %s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+func httpGet(url string, dest io.Writer) error {
+	resp, err := http.Get(url) // nolint: gosec
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to download artifact from %s: %s", url, resp.Status)
+	}
+	_, err = io.Copy(dest, resp.Body)
+	return err
+}
@@ -0,0 +1,66 @@
+package job
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+// newArtifactStore returns a server which stores the files uploaded with PUT
+func newArtifactStore(t *testing.T) *httptest.Server {
+	stored := map[string][]byte{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			body, err := ioutil.ReadAll(r.Body)
+			assert.Check(t, err)
+			stored[r.URL.Path] = body
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			body, ok := stored[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(body) // nolint: errcheck
+		}
+	}))
+}
+
+func TestUploadAndDownloadArtifactHTTP(t *testing.T) {
+	server := newArtifactStore(t)
+	defer server.Close()
+
+	source := fs.NewDir(t, "artifact-source",
+		fs.WithDir("dist", fs.WithFile("app", "binary")),
+		fs.WithFile("other", "not an artifact"))
+	defer source.Remove()
+
+	url := server.URL + "/builds/app.tar.gz"
+	assert.NilError(t, uploadArtifact(source.Path(), []string{"dist/*"}, url))
+
+	dest := fs.NewDir(t, "artifact-dest")
+	defer dest.Remove()
+	assert.NilError(t, downloadArtifact(dest.Path(), url))
+
+	expected := fs.Expected(t,
+		fs.MatchAnyFileMode,
+		fs.WithDir("dist", fs.MatchAnyFileMode, fs.WithFile("app", "binary", fs.MatchAnyFileMode)))
+	assert.Check(t, fs.Equal(dest.Path(), expected))
+
+	err := downloadArtifact(dest.Path(), server.URL+"/missing.tar.gz")
+	assert.Check(t, is.ErrorContains(err, "404 Not Found"))
+}
+
+func TestArtifactPathsOutsideProject(t *testing.T) {
+	dir := fs.NewDir(t, "artifact-paths")
+	defer dir.Remove()
+
+	_, err := artifactPaths(dir.Path(), []string{"/"})
+	assert.Check(t, is.ErrorContains(err, "outside of the project directory"))
+}
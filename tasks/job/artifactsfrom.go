@@ -0,0 +1,77 @@
+package job
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/mount"
+	log "github.com/sirupsen/logrus"
+)
+
+// artifactMounts returns a read-only mount for each file or directory which
+// matches the artifact of a job in artifacts-from. The files are mounted in
+// artifacts-path, at their path relative to the working directory.
+func artifactMounts(
+	logger *log.Entry,
+	ctx *context.ExecuteContext,
+	cfg *config.JobConfig,
+) []config.MountConfig {
+	mounts := []config.MountConfig{}
+	for _, name := range cfg.ArtifactsFrom {
+		job := ctx.Resources.Job(name)
+		if job == nil {
+			logger.Warnf("Job %s has not run, its artifact is not mounted", name)
+			continue
+		}
+		matched := false
+		for _, glob := range job.Artifact.Globs() {
+			if !filepath.IsAbs(glob) {
+				glob = filepath.Join(ctx.WorkingDir, glob)
+			}
+			// The glob is validated when the config is loaded
+			matches, _ := filepath.Glob(glob)
+			for _, match := range matches {
+				mounts = append(mounts, artifactMount(ctx.WorkingDir, cfg.ArtifactsPath, match))
+				matched = true
+			}
+		}
+		if !matched {
+			logger.Warnf("No files match the artifact of job %s", name)
+		}
+	}
+	return mounts
+}
+
+func artifactMount(workingDir string, artifactsPath string, match string) config.MountConfig {
+	bind, err := filepath.Rel(workingDir, match)
+	if err != nil || strings.HasPrefix(bind, "..") {
+		return config.MountConfig{
+			Bind:     match,
+			Path:     path.Join(artifactsPath, filepath.Base(match)),
+			ReadOnly: true,
+		}
+	}
+	return config.MountConfig{
+		Bind:     bind,
+		Path:     path.Join(artifactsPath, filepath.ToSlash(bind)),
+		ReadOnly: true,
+	}
+}
+
+// getArtifactBinds returns the bind mounts for the artifacts of the jobs in
+// artifacts-from
+func getArtifactBinds(
+	logger *log.Entry,
+	ctx *context.ExecuteContext,
+	cfg *config.JobConfig,
+) []string {
+	binds := []string{}
+	mounts := artifactMounts(logger, ctx, cfg)
+	for i := range mounts {
+		binds = append(binds, mount.AsBind(&mounts[i], ctx.WorkingDir))
+	}
+	return binds
+}
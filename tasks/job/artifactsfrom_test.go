@@ -0,0 +1,39 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestGetArtifactBinds(t *testing.T) {
+	dir := fs.NewDir(t, "artifacts-from",
+		fs.WithDir("dist", fs.WithFile("app", ""), fs.WithFile("app.sha256", "")),
+		fs.WithDir("docs"))
+	defer dir.Remove()
+
+	conf := config.NewConfig()
+	conf.WorkingDir = dir.Path()
+	ctx := context.NewExecuteContext(
+		conf, nil, execenv.NewExecEnv("exec", "project", dir.Path()), context.Settings{})
+	artifact := config.PathGlobs{}
+	globs := []interface{}{"dist/app*", "docs/"}
+	assert.NilError(t, artifact.TransformConfig(reflect.ValueOf(globs)))
+	ctx.Resources.Add("compile", &config.JobConfig{Artifact: artifact})
+
+	cfg := &config.JobConfig{ArtifactsFrom: []string{"compile"}, ArtifactsPath: "/artifacts"}
+	binds := getArtifactBinds(logging.Log.WithField("test", t.Name()), ctx, cfg)
+	expected := []string{
+		dir.Join("dist", "app") + ":/artifacts/dist/app:ro",
+		dir.Join("dist", "app.sha256") + ":/artifacts/dist/app.sha256:ro",
+		dir.Join("docs") + ":/artifacts/docs:ro",
+	}
+	assert.Check(t, is.DeepEqual(binds, expected))
+}
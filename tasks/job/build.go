@@ -16,6 +16,7 @@ import (
 	"github.com/dnephin/dobi/tasks/client"
 	"github.com/dnephin/dobi/tasks/context"
 	"github.com/dnephin/dobi/tasks/image"
+	"github.com/dnephin/dobi/utils/fs"
 	"github.com/docker/cli/cli/command/image/build"
 	"github.com/docker/docker/pkg/archive"
 	docker "github.com/fsouza/go-dockerclient"
@@ -28,12 +29,14 @@ func (t *Task) runWithBuildAndCopy(ctx *context.ExecuteContext) error {
 	imageName := fmt.Sprintf("%s:job-%s",
 		ctx.Resources.Image(t.config.Use).Image, name)
 
+	defer ctx.Track(context.TrackedImage, imageName)()
 	if err := t.buildImageWithMounts(ctx, imageName); err != nil {
 		return err
 	}
 	defer removeImage(t.logger(), ctx.Client, imageName)
 
-	defer removeContainerWithLogging(t.logger(), ctx.Client, name)
+	defer ctx.Track(context.TrackedContainer, name)()
+	defer removeContainerWithLogging(t.logger(), ctx.Client, name, t.config.StopGrace)
 	options := t.createOptions(ctx, name, imageName)
 	runErr := t.runContainer(ctx, options)
 	copyErr := copyFilesToHost(t.logger(), ctx, t.config, name)
@@ -45,14 +48,16 @@ func (t *Task) runWithBuildAndCopy(ctx *context.ExecuteContext) error {
 
 func (t *Task) buildImageWithMounts(ctx *context.ExecuteContext, imageName string) error {
 	baseImage := image.GetImageName(ctx, ctx.Resources.Image(t.config.Use))
-	mounts := getBindMounts(ctx, t.config)
+	mounts := append(
+		getCopyMounts(getBindMounts(ctx, t.config)),
+		artifactMounts(t.logger(), ctx, t.config)...)
 
 	dockerfile := buildDockerfileWithCopy(baseImage, mounts)
 	buildContext, dockerfileName, err := buildTarContext(dockerfile, mounts)
 	if err != nil {
 		return err
 	}
-	return image.Stream(os.Stdout, func(out io.Writer) error {
+	return image.Stream(logging.Log.Out, func(out io.Writer) error {
 		opts := buildImageOptions(ctx, out)
 		opts.InputStream = buildContext
 		opts.Name = imageName
@@ -72,6 +77,18 @@ func getBindMounts(ctx *context.ExecuteContext, cfg *config.JobConfig) []config.
 	return mounts
 }
 
+// getCopyMounts returns the bind mounts which are copied into the image. The
+// other bind mounts are replaced by volumes.
+func getCopyMounts(mounts []config.MountConfig) []config.MountConfig {
+	copyMounts := []config.MountConfig{}
+	for _, mount := range mounts {
+		if mount.Copy {
+			copyMounts = append(copyMounts, mount)
+		}
+	}
+	return copyMounts
+}
+
 func buildDockerfileWithCopy(baseImage string, mounts []config.MountConfig) *bytes.Buffer {
 	buf := bytes.NewBufferString("FROM " + baseImage + "\n")
 
@@ -130,6 +147,9 @@ func copyFilesToHost(
 	containerID string,
 ) error {
 	mounts := getBindMounts(ctx, cfg)
+	// a single unpacker is used for all the artifacts, so that paths which
+	// differ only by case are found across artifacts
+	unpacker := newUnpacker(logger, ctx.WorkingDir, fs.SymlinkPolicy(cfg.Symlinks))
 	for _, artifact := range cfg.Artifact.Globs() {
 		artifactPath, err := getArtifactPath(ctx.WorkingDir, artifact, mounts)
 		if err != nil {
@@ -145,7 +165,7 @@ func copyFilesToHost(
 		if err := ctx.Client.DownloadFromContainer(containerID, opts); err != nil {
 			return err
 		}
-		if err := unpack(buf, artifactPath); err != nil {
+		if err := unpacker.unpack(buf, artifactPath); err != nil {
 			return err
 		}
 	}
@@ -257,7 +277,26 @@ func rebasePath(path, oldPrefix, newPrefix string) string {
 	return filepathJoinPreserveDirectorySlash(newPrefix, relativePath)
 }
 
-func unpack(source io.Reader, path artifactPath) error {
+// unpacker creates files on the host from a tar archive of artifacts
+type unpacker struct {
+	logger   *log.Entry
+	root     string
+	symlinks fs.SymlinkPolicy
+	// seen maps the lower case form of each path which was unpacked to the
+	// path
+	seen map[string]string
+}
+
+func newUnpacker(logger *log.Entry, root string, symlinks fs.SymlinkPolicy) *unpacker {
+	return &unpacker{
+		logger:   logger,
+		root:     root,
+		symlinks: symlinks,
+		seen:     make(map[string]string),
+	}
+}
+
+func (u *unpacker) unpack(source io.Reader, path artifactPath) error {
 	tarReader := tar.NewReader(source)
 
 	for {
@@ -278,12 +317,34 @@ func unpack(source io.Reader, path artifactPath) error {
 			continue
 		}
 
+		if err := u.checkEntry(header, path); err != nil {
+			return err
+		}
 		if err := createFromTar(tarReader, header, path); err != nil {
 			return err
 		}
 	}
 }
 
+// checkEntry warns about paths that collide on case-insensitive filesystems,
+// and enforces the symlink policy
+func (u *unpacker) checkEntry(header *tar.Header, path artifactPath) error {
+	hostPath := path.hostPath(path.pathFromArchive(header.Name))
+	key := strings.ToLower(filepath.Clean(hostPath))
+	// overlapping artifacts may unpack the same path more than once
+	if previous, ok := u.seen[key]; ok && previous != filepath.Clean(hostPath) {
+		u.logger.Warnf(
+			"%s differs from another artifact only by case, "+
+				"this will break on case-insensitive filesystems", hostPath)
+	}
+	u.seen[key] = filepath.Clean(hostPath)
+
+	if header.Typeflag == tar.TypeSymlink && u.symlinks == fs.SymlinkForbidEscape {
+		return fs.CheckSymlinkEscape(u.root, hostPath, header.Linkname)
+	}
+	return nil
+}
+
 func fileMatchesGlob(path string, glob string) (bool, error) {
 	// Directory glob should match entire tree
 	if endsWithSlash(glob) && strings.HasPrefix(path, glob) {
@@ -1,11 +1,15 @@
 package job
 
 import (
+	"archive/tar"
 	"fmt"
 	"testing"
 
 	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/utils/fs"
 	"github.com/google/go-cmp/cmp"
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 )
@@ -29,6 +33,15 @@ COPY . /opt/var/foo
 	assert.Check(t, is.Equal(expected, buf.String()))
 }
 
+func TestGetCopyMounts(t *testing.T) {
+	mounts := []config.MountConfig{
+		{Bind: ".", Path: "/app", Copy: true},
+		{Bind: "./.cache", Path: "/root/.cache"},
+	}
+	expected := []config.MountConfig{{Bind: ".", Path: "/app", Copy: true}}
+	assert.Check(t, is.DeepEqual(expected, getCopyMounts(mounts)))
+}
+
 func TestGetArtifactPath(t *testing.T) {
 	workingDir := "/work"
 	mounts := []config.MountConfig{
@@ -216,3 +229,19 @@ func TestFileMatchesGlob(t *testing.T) {
 		}
 	}
 }
+
+func TestUnpackerWarnsAboutCaseCollisionsAcrossArtifacts(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+	unpacker := newUnpacker(log.NewEntry(logger), "/work", fs.SymlinkCopyAsLink)
+	bin := newArtifactPath("/work/dist/", "/go/dist/", "/work/dist/bin/")
+	all := newArtifactPath("/work/dist/", "/go/dist/", "/work/dist/")
+
+	assert.NilError(t, unpacker.checkEntry(&tar.Header{Name: "bin/dobi"}, bin))
+	// the same path from an overlapping artifact is not a collision
+	assert.NilError(t, unpacker.checkEntry(&tar.Header{Name: "dist/bin/dobi"}, all))
+	assert.Check(t, is.Len(hook.AllEntries(), 0))
+
+	assert.NilError(t, unpacker.checkEntry(&tar.Header{Name: "dist/bin/Dobi"}, all))
+	assert.Assert(t, is.Len(hook.AllEntries(), 1))
+	assert.Check(t, is.Contains(hook.LastEntry().Message, "/work/dist/bin/Dobi differs"))
+}
@@ -0,0 +1,141 @@
+package job
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/image"
+)
+
+// runWithRemoteCache downloads the artifact from the remote cache, or runs
+// the job and uploads the artifact to the remote cache
+func (t *Task) runWithRemoteCache(ctx *context.ExecuteContext) (bool, error) {
+	url, err := t.remoteCacheURL(ctx)
+	if err != nil {
+		return false, err
+	}
+	if t.downloadFromCache(ctx, url) {
+		return true, nil
+	}
+	if _, err := t.run(ctx); err != nil {
+		return false, err
+	}
+	t.uploadToCache(ctx, url)
+	return true, nil
+}
+
+// remoteCacheURL returns the URL of the artifact in the remote cache, which
+// is named with the digest of the inputs of the job
+func (t *Task) remoteCacheURL(ctx *context.ExecuteContext) (string, error) {
+	digest, err := t.inputDigest(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute the digest of the job inputs: %s", err)
+	}
+	return artifactURL(ctx, "cache/"+t.name.Resource()+"-"+digest[:16])
+}
+
+// downloadFromCache downloads the artifact from the remote cache. It returns
+// false if the artifact could not be downloaded, because the inputs of the
+// job have not been cached.
+func (t *Task) downloadFromCache(ctx *context.ExecuteContext, url string) bool {
+	if err := downloadArtifact(ctx.WorkingDir, url); err != nil {
+		t.logger().Debugf("Failed to download %s: %s", url, err)
+		return false
+	}
+	t.logger().Infof("Downloaded from %s", url)
+	return true
+}
+
+// uploadToCache uploads the artifact to the remote cache. A failed upload
+// does not fail the job.
+func (t *Task) uploadToCache(ctx *context.ExecuteContext, url string) {
+	if err := uploadArtifact(ctx.WorkingDir, t.config.Artifact.Globs(), url); err != nil {
+		t.logger().Warnf("Failed to upload to the remote cache: %s", err)
+		return
+	}
+	t.logger().Infof("Uploaded to %s", url)
+}
+
+// inputDigest returns a hex encoded sha256 of the image, the config of the
+// container, and the files in the sources, or in the mounts when the job has
+// no sources
+func (t *Task) inputDigest(ctx *context.ExecuteContext) (string, error) {
+	imageConfig := ctx.Resources.Image(t.config.Use)
+	taskImage, err := image.GetImage(ctx, imageConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to get image %q: %s", t.config.Use, err)
+	}
+
+	digest := sha256.New()
+	fmt.Fprintf(digest, "image:%s\ncommand:%s\nentrypoint:%s\nworking-dir:%s\nuser:%s\n",
+		taskImage.ID, &t.config.Command, &t.config.Entrypoint,
+		t.config.WorkingDir, t.config.User)
+	env := append([]string{}, t.config.Env...)
+	sort.Strings(env)
+	for _, variable := range env {
+		fmt.Fprintf(digest, "env:%s\n", variable)
+	}
+
+	paths := t.config.Sources.Paths()
+	if len(paths) == 0 {
+		ctx.Resources.EachMount(t.config.Mounts, func(_ string, mount *config.MountConfig) {
+			if !mount.Tmpfs {
+				paths = append(paths, mount.Bind)
+			}
+		})
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := hashPath(digest, ctx.WorkingDir, path); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// hashPath writes the path, mode, and content of each file in path to digest
+func hashPath(digest hash.Hash, workingDir string, path string) error {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+	// filepath.Walk visits files in lexical order, so the digest is stable
+	return filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(workingDir, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(digest, "file:%s:%s:%d\n", filepath.ToSlash(relPath), info.Mode(), info.Size())
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(digest, "link:%s\n", target)
+		case info.Mode().IsRegular():
+			return hashFile(digest, path)
+		}
+		return nil
+	})
+}
+
+func hashFile(digest io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close() // nolint: errcheck
+	_, err = io.Copy(digest, file)
+	return err
+}
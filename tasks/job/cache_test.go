@@ -0,0 +1,87 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func newRemoteCacheTask(
+	t *testing.T,
+	dir string,
+	mockClient client.DockerClient,
+	store string,
+) (*Task, *context.ExecuteContext) {
+	conf := config.NewConfig()
+	conf.WorkingDir = dir
+	ctx := context.NewExecuteContext(
+		conf, mockClient, execenv.NewExecEnv("exec", "project", dir),
+		context.NewSettings(true, true))
+	ctx.ArtifactStore = store
+	ctx.Resources.Add("builder", &config.ImageConfig{Image: "builder"})
+
+	job := &config.JobConfig{Use: "builder", RemoteCache: true}
+	assert.NilError(t, job.Artifact.TransformConfig(reflect.ValueOf("dist/")))
+	return &Task{name: task.NewDefaultName("app", ""), config: job}, ctx
+}
+
+func TestRunDownloadsFromRemoteCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	mockClient.EXPECT().InspectImage("builder:project-exec").
+		Return(&docker.Image{ID: "sha256:abcd"}, nil).AnyTimes()
+
+	server := newArtifactStore(t)
+	defer server.Close()
+
+	source := fs.NewDir(t, "cache-source", fs.WithDir("dist", fs.WithFile("app", "binary")))
+	defer source.Remove()
+	job, ctx := newRemoteCacheTask(t, source.Path(), mockClient, server.URL)
+	url, err := job.remoteCacheURL(ctx)
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(url, server.URL+"/cache/app-"))
+	assert.NilError(t, uploadArtifact(source.Path(), []string{"dist/"}, url))
+
+	// No container is created, because the artifact is downloaded
+	dest := fs.NewDir(t, "cache-dest")
+	defer dest.Remove()
+	job, ctx = newRemoteCacheTask(t, dest.Path(), mockClient, server.URL)
+	modified, err := job.Run(ctx, true)
+	assert.NilError(t, err)
+	assert.Check(t, modified)
+
+	expected := fs.Expected(t,
+		fs.MatchAnyFileMode,
+		fs.WithDir("dist", fs.MatchAnyFileMode, fs.WithFile("app", "binary", fs.MatchAnyFileMode)))
+	assert.Check(t, fs.Equal(dest.Path(), expected))
+}
+
+func TestInputDigestChangesWithTheCommand(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	mockClient.EXPECT().InspectImage("builder:project-exec").
+		Return(&docker.Image{ID: "sha256:abcd"}, nil).AnyTimes()
+
+	dir := fs.NewDir(t, "cache-digest")
+	defer dir.Remove()
+	job, ctx := newRemoteCacheTask(t, dir.Path(), mockClient, "http://store")
+	first, err := job.inputDigest(ctx)
+	assert.NilError(t, err)
+
+	assert.NilError(t, job.config.Command.TransformConfig(reflect.ValueOf("make all")))
+	second, err := job.inputDigest(ctx)
+	assert.NilError(t, err)
+	assert.Check(t, first != second)
+}
@@ -2,6 +2,9 @@ package job
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/dnephin/dobi/tasks/client"
 	"github.com/dnephin/dobi/tasks/context"
@@ -9,6 +12,70 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	labelProject = "dobi.project"
+	labelExecID  = "dobi.exec-id"
+	// labelOwner identifies the dobi process which created the container, as
+	// hostname:pid
+	labelOwner = "dobi.owner"
+)
+
+// owner returns the value of labelOwner for this process
+func owner() string {
+	hostname, _ := os.Hostname()
+	return hostname + ":" + strconv.Itoa(os.Getpid())
+}
+
+// ownerState is the state of the dobi process which created a container
+type ownerState int
+
+const (
+	// ownerUnknown is a process on another host, or a container without an
+	// owner label, so it is not known if the process is still running
+	ownerUnknown ownerState = iota
+	// ownerSelf is this process
+	ownerSelf
+	// ownerRunning is another process on this host which is still running
+	ownerRunning
+	// ownerExited is a process on this host which is no longer running
+	ownerExited
+)
+
+// getOwnerState returns the state of the process identified by the value of
+// the owner label
+func getOwnerState(owner string) ownerState {
+	hostname, _ := os.Hostname()
+	index := strings.LastIndex(owner, ":")
+	if index == -1 || owner[:index] != hostname {
+		return ownerUnknown
+	}
+	pid, err := strconv.Atoi(owner[index+1:])
+	switch {
+	case err != nil:
+		return ownerUnknown
+	case pid == os.Getpid():
+		return ownerSelf
+	case context.ProcessRunning(pid):
+		return ownerRunning
+	default:
+		return ownerExited
+	}
+}
+
+// containerLabels returns the labels from the config with the labels used to
+// identify containers created by this execution
+func containerLabels(ctx *context.ExecuteContext, labels map[string]string) map[string]string {
+	all := map[string]string{
+		labelProject: ctx.Env.Project,
+		labelExecID:  ctx.Env.ExecID,
+		labelOwner:   owner(),
+	}
+	for key, value := range labels {
+		all[key] = value
+	}
+	return all
+}
+
 // containerName returns the name of the container
 func containerName(ctx *context.ExecuteContext, name string) string {
 	return fmt.Sprintf("%s-%s", ctx.Env.Unique(), name)
@@ -0,0 +1,95 @@
+package job
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const diffDir = ".dobi/diff"
+
+// ignoredWritePaths are paths which are expected to be used for temporary
+// files, so writes to them are not reported
+var ignoredWritePaths = []string{"/tmp", "/var/tmp", "/run"}
+
+// checkWrites records the changes to the filesystem of the container, and
+// warns about any files which were written outside of a mount.
+func (t *Task) checkWrites(ctx *context.ExecuteContext, containerID string) error {
+	changes, err := ctx.Client.ContainerChanges(containerID)
+	if err != nil {
+		return fmt.Errorf("failed to get changes of container %q: %s", containerID, err)
+	}
+	diffFile := filepath.Join(ctx.WorkingDir, diffDir, t.name.Resource()+".txt")
+	if err := writeDiffFile(diffFile, changes); err != nil {
+		return fmt.Errorf("failed to write diff file: %s", err)
+	}
+
+	allowed := append(mountPaths(ctx, t.config), ignoredWritePaths...)
+	for _, change := range undeclaredWrites(changes, allowed) {
+		t.logger().Warnf("Wrote %q outside of a mount, the change will be lost", change.Path)
+	}
+	return nil
+}
+
+// mountPaths returns the paths in the container which are mounts or tmpfs
+func mountPaths(ctx *context.ExecuteContext, conf *config.JobConfig) []string {
+	paths := []string{}
+	for _, spec := range conf.Tmpfs {
+		tmpfsPath, _ := config.SplitTmpfs(spec)
+		paths = append(paths, tmpfsPath)
+	}
+	ctx.Resources.EachMount(conf.Mounts, func(_ string, mountConfig *config.MountConfig) {
+		paths = append(paths, mountConfig.Path)
+	})
+	return paths
+}
+
+func writeDiffFile(filename string, changes []docker.Change) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	for _, change := range changes {
+		buf.WriteString(change.String() + "\n")
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// undeclaredWrites returns the changes which are not inside one of the allowed
+// paths. Directories which contain an allowed path are modified when the
+// mount is created, so they are also ignored.
+func undeclaredWrites(changes []docker.Change, allowed []string) []docker.Change {
+	undeclared := []docker.Change{}
+	for _, change := range changes {
+		if !isAllowedWrite(change, allowed) {
+			undeclared = append(undeclared, change)
+		}
+	}
+	return undeclared
+}
+
+func isAllowedWrite(change docker.Change, allowed []string) bool {
+	changed := path.Clean(change.Path)
+	for _, allowedPath := range allowed {
+		allowedPath = path.Clean(allowedPath)
+		switch {
+		case isInside(changed, allowedPath):
+			return true
+		case change.Kind == docker.ChangeModify && isInside(allowedPath, changed):
+			return true
+		}
+	}
+	return false
+}
+
+func isInside(p, dir string) bool {
+	return p == dir || dir == "/" || strings.HasPrefix(p, dir+"/")
+}
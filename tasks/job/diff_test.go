@@ -0,0 +1,29 @@
+package job
+
+import (
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestUndeclaredWrites(t *testing.T) {
+	changes := []docker.Change{
+		{Path: "/go", Kind: docker.ChangeModify},
+		{Path: "/go/src", Kind: docker.ChangeModify},
+		{Path: "/go/src/app/bin", Kind: docker.ChangeAdd},
+		{Path: "/go/pkg", Kind: docker.ChangeAdd},
+		{Path: "/tmp/build.log", Kind: docker.ChangeAdd},
+		{Path: "/etc", Kind: docker.ChangeModify},
+		{Path: "/etc/passwd", Kind: docker.ChangeDelete},
+	}
+	allowed := []string{"/go/src/app", "/tmp"}
+
+	expected := []docker.Change{
+		{Path: "/go/pkg", Kind: docker.ChangeAdd},
+		{Path: "/etc", Kind: docker.ChangeModify},
+		{Path: "/etc/passwd", Kind: docker.ChangeDelete},
+	}
+	assert.Check(t, is.DeepEqual(undeclaredWrites(changes, allowed), expected))
+}
@@ -0,0 +1,46 @@
+package job
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/image"
+	"github.com/dnephin/dobi/tasks/task"
+)
+
+// imageVariables returns the environment variables with the tag and digest of
+// each image the job depends on, so that tools in the container can use the
+// images which were built earlier in the run
+func imageVariables(ctx *context.ExecuteContext, conf *config.JobConfig) ([]string, error) {
+	variables := []string{}
+	seen := map[string]bool{}
+	for _, dep := range conf.Dependencies() {
+		name := task.ParseName(dep).Resource()
+		imageConf := ctx.Resources.Image(name)
+		if imageConf == nil || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		img, err := image.GetImage(ctx, imageConf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect image %q: %s", name, err)
+		}
+		variables = append(variables,
+			imageVariable(name, "TAG")+"="+image.GetImageName(ctx, imageConf),
+			imageVariable(name, "DIGEST")+"="+img.ID)
+	}
+	return variables, nil
+}
+
+func imageVariable(name string, suffix string) string {
+	return envVariableName(fmt.Sprintf("DOBI_IMAGE_%s_%s", name, suffix))
+}
+
+// envVariableName replaces the characters which are not valid in the name of
+// an environment variable
+func envVariableName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_", "/", "_").Replace(name))
+}
@@ -0,0 +1,45 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestImageVariables(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+
+	conf := config.NewConfig()
+	ctx := context.NewExecuteContext(
+		conf, mockClient, execenv.NewExecEnv("exec", "project", "/dir"), context.Settings{})
+	ctx.Resources.Add("builder", &config.ImageConfig{Image: "example/builder"})
+	ctx.Resources.Add("api-image",
+		&config.ImageConfig{Image: "example/api", Tags: []string{"v1"}})
+	ctx.Resources.Add("source", &config.MountConfig{Bind: ".", Path: "/app"})
+
+	mockClient.EXPECT().InspectImage("example/builder:project-exec").Return(
+		&docker.Image{ID: "sha256:aaaa"}, nil)
+	mockClient.EXPECT().InspectImage("example/api:v1").Return(
+		&docker.Image{ID: "sha256:bbbb"}, nil)
+
+	job := &config.JobConfig{Use: "builder", Mounts: []string{"source"}}
+	job.Depends = []string{"api-image:tag"}
+	variables, err := imageVariables(ctx, job)
+	assert.NilError(t, err)
+	expected := []string{
+		"DOBI_IMAGE_BUILDER_TAG=example/builder:project-exec",
+		"DOBI_IMAGE_BUILDER_DIGEST=sha256:aaaa",
+		"DOBI_IMAGE_API_IMAGE_TAG=example/api:v1",
+		"DOBI_IMAGE_API_IMAGE_DIGEST=sha256:bbbb",
+	}
+	assert.Check(t, is.DeepEqual(variables, expected))
+}
@@ -0,0 +1,66 @@
+package job
+
+import (
+	"fmt"
+
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// networks returns the names of the Docker networks for the container. A
+// compose resource is replaced by the default network of the project.
+func (t *Task) networks(ctx *context.ExecuteContext) []string {
+	networks := []string{}
+	for _, network := range t.config.Networks {
+		if compose := ctx.Resources.Compose(network); compose != nil {
+			network = compose.DefaultNetwork()
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// withNetworks sets the first network as the network mode of the container,
+// unless net-mode is set. A container can only be created with a single
+// network, the others are connected by connectNetworks.
+func (t *Task) withNetworks(
+	ctx *context.ExecuteContext,
+	opts docker.CreateContainerOptions,
+) docker.CreateContainerOptions {
+	networks := t.networks(ctx)
+	if len(networks) == 0 || t.config.NetMode != "" {
+		return opts
+	}
+	opts.HostConfig.NetworkMode = networks[0]
+	opts.NetworkingConfig = &docker.NetworkingConfig{
+		EndpointsConfig: map[string]*docker.EndpointConfig{
+			networks[0]: {Aliases: t.config.NetworkAliases},
+		},
+	}
+	return opts
+}
+
+// connectNetworks connects the container to the networks which were not set
+// when the container was created
+func (t *Task) connectNetworks(
+	ctx *context.ExecuteContext,
+	containerID string,
+	opts docker.CreateContainerOptions,
+) error {
+	for _, network := range t.networks(ctx) {
+		if opts.NetworkingConfig != nil {
+			if _, ok := opts.NetworkingConfig.EndpointsConfig[network]; ok {
+				continue
+			}
+		}
+		err := ctx.Client.ConnectNetwork(network, docker.NetworkConnectionOptions{
+			Container:      containerID,
+			EndpointConfig: &docker.EndpointConfig{Aliases: t.config.NetworkAliases},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to connect to network %q: %s", network, err)
+		}
+		t.logger().Debugf("Connected to network %s", network)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestWithNetworksUsesComposeNetwork(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+
+	ctx := context.NewExecuteContext(
+		config.NewConfig(),
+		mockClient,
+		execenv.NewExecEnv("exec", "project", "/dir"),
+		context.Settings{})
+	ctx.Resources.Add("devenv", &config.ComposeConfig{Project: "Web-DevEnv"})
+	job := &Task{
+		name: task.NewName("test", "run"),
+		config: &config.JobConfig{
+			Networks:       []string{"devenv", "shared"},
+			NetworkAliases: []string{"tests"},
+		},
+	}
+
+	opts := job.withNetworks(ctx, docker.CreateContainerOptions{
+		HostConfig: &docker.HostConfig{},
+	})
+	assert.Check(t, is.Equal(opts.HostConfig.NetworkMode, "web-devenv_default"))
+	expected := &docker.NetworkingConfig{
+		EndpointsConfig: map[string]*docker.EndpointConfig{
+			"web-devenv_default": {Aliases: []string{"tests"}},
+		},
+	}
+	assert.Check(t, is.DeepEqual(opts.NetworkingConfig, expected))
+
+	mockClient.EXPECT().ConnectNetwork("shared", docker.NetworkConnectionOptions{
+		Container:      "id",
+		EndpointConfig: &docker.EndpointConfig{Aliases: []string{"tests"}},
+	}).Return(nil)
+	assert.NilError(t, job.connectNetworks(ctx, "id", opts))
+}
+
+func TestWithNetworksKeepsNetMode(t *testing.T) {
+	ctx := context.NewExecuteContext(
+		config.NewConfig(), nil, execenv.NewExecEnv("exec", "project", "/dir"), context.Settings{})
+	job := &Task{config: &config.JobConfig{NetMode: "bridge", Networks: []string{"shared"}}}
+
+	opts := job.withNetworks(ctx, docker.CreateContainerOptions{
+		HostConfig: &docker.HostConfig{NetworkMode: "bridge"},
+	})
+	assert.Check(t, is.Equal(opts.HostConfig.NetworkMode, "bridge"))
+	assert.Check(t, opts.NetworkingConfig == nil)
+}
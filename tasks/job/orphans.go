@@ -0,0 +1,83 @@
+package job
+
+import (
+	"fmt"
+
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+	log "github.com/sirupsen/logrus"
+)
+
+// RemoveOrphans finds job containers which were left behind by an earlier
+// execution with the same exec-id. If remove is true the containers are
+// removed, otherwise a warning is logged.
+//
+// The dobi process which created a container is read from the owner label. A
+// container is removed, even when it is still running, when the process is
+// this process, or a process on this host which is no longer running, like
+// a run which crashed. A container created by a process on another host, or
+// without an owner label, is only removed when it is not running, because the
+// run may still be in progress. Containers which belong to another dobi
+// process that is still running, or which were started as a service, are
+// never removed.
+func RemoveOrphans(ctx *context.ExecuteContext, remove bool) error {
+	containers, err := ctx.Client.ListContainers(docker.ListContainersOptions{
+		All: true,
+		Filters: map[string][]string{
+			"label": {
+				labelProject + "=" + ctx.Env.Project,
+				labelExecID + "=" + ctx.Env.ExecID,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %s", err)
+	}
+
+	for _, container := range containers {
+		if container.Labels[labelService] != "" {
+			continue
+		}
+		logger := logging.Log.WithFields(log.Fields{"container": containerDisplayName(container)})
+		running := container.State == "running"
+		switch getOwnerState(container.Labels[labelOwner]) {
+		case ownerRunning:
+			warnOtherRun(logger, ctx.Env.ExecID)
+			continue
+		case ownerUnknown:
+			if running {
+				warnOtherRun(logger, ctx.Env.ExecID)
+				continue
+			}
+		}
+		if !remove {
+			logger.Warn("Found container from an earlier run")
+			continue
+		}
+		logger.Info("Removing container from an earlier run")
+		err := ctx.Client.RemoveContainer(docker.RemoveContainerOptions{
+			ID:            container.ID,
+			RemoveVolumes: true,
+			Force:         running,
+		})
+		if err != nil {
+			logger.Warnf("Failed to remove container: %s", err)
+		}
+	}
+	return nil
+}
+
+func warnOtherRun(logger *log.Entry, execID string) {
+	logger.Warnf("Found container from another dobi run with exec-id %q. "+
+		"Runs with the same exec-id will replace each other's containers, "+
+		"use meta.unique-exec-id or $DOBI_EXEC_ID to avoid conflicts",
+		execID)
+}
+
+func containerDisplayName(container docker.APIContainers) string {
+	if len(container.Names) > 0 {
+		return container.Names[0]
+	}
+	return container.ID
+}
@@ -0,0 +1,99 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+)
+
+func TestRemoveOrphans(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+
+	ctx := &context.ExecuteContext{
+		Client: mockClient,
+		Env:    execenv.NewExecEnv("exec", "project", "/dir"),
+	}
+	mockClient.EXPECT().ListContainers(docker.ListContainersOptions{
+		All: true,
+		Filters: map[string][]string{
+			"label": {"dobi.project=project", "dobi.exec-id=exec"},
+		},
+	}).Return([]docker.APIContainers{{ID: "abcd", Names: []string{"/project-exec-test"}}}, nil)
+	mockClient.EXPECT().RemoveContainer(docker.RemoveContainerOptions{
+		ID:            "abcd",
+		RemoveVolumes: true,
+	})
+
+	assert.NilError(t, RemoveOrphans(ctx, true))
+}
+
+func TestRemoveOrphansSkipsContainersOfOtherRuns(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+
+	ctx := &context.ExecuteContext{
+		Client: mockClient,
+		Env:    execenv.NewExecEnv("exec", "project", "/dir"),
+	}
+	hostname, err := os.Hostname()
+	assert.NilError(t, err)
+	otherRun := fmt.Sprintf("%s:%d", hostname, os.Getppid())
+
+	mockClient.EXPECT().ListContainers(gomock.Any()).Return([]docker.APIContainers{
+		{ID: "abcd", Labels: map[string]string{labelOwner: otherRun}},
+		{ID: "efgh", Labels: map[string]string{labelOwner: owner()}},
+	}, nil)
+	mockClient.EXPECT().RemoveContainer(docker.RemoveContainerOptions{
+		ID:            "efgh",
+		RemoveVolumes: true,
+	})
+
+	assert.NilError(t, RemoveOrphans(ctx, true))
+}
+
+func TestRemoveOrphansRemovesRunningContainersOfCrashedRuns(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+
+	ctx := &context.ExecuteContext{
+		Client: mockClient,
+		Env:    execenv.NewExecEnv("exec", "project", "/dir"),
+	}
+	hostname, err := os.Hostname()
+	assert.NilError(t, err)
+	crashedRun := fmt.Sprintf("%s:%d", hostname, 99999999)
+
+	mockClient.EXPECT().ListContainers(gomock.Any()).Return([]docker.APIContainers{
+		{ID: "abcd", State: "running", Labels: map[string]string{labelOwner: crashedRun}},
+		{ID: "efgh", State: "running", Labels: map[string]string{labelOwner: "otherhost:1"}},
+		{ID: "ijkl", State: "exited", Labels: map[string]string{labelOwner: "otherhost:1"}},
+		{ID: "mnop", State: "running", Labels: map[string]string{labelOwner: owner()}},
+	}, nil)
+	mockClient.EXPECT().RemoveContainer(docker.RemoveContainerOptions{
+		ID:            "abcd",
+		RemoveVolumes: true,
+		Force:         true,
+	})
+	mockClient.EXPECT().RemoveContainer(docker.RemoveContainerOptions{
+		ID:            "ijkl",
+		RemoveVolumes: true,
+	})
+	mockClient.EXPECT().RemoveContainer(docker.RemoveContainerOptions{
+		ID:            "mnop",
+		RemoveVolumes: true,
+		Force:         true,
+	})
+
+	assert.NilError(t, RemoveOrphans(ctx, true))
+}
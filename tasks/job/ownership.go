@@ -0,0 +1,85 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/mount"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// ownershipPath is the container path where the artifacts are mounted to
+// change their ownership
+const ownershipPath = "/dobi-fix-ownership"
+
+// fixOwnership changes the owner of the artifact files to the user which runs
+// dobi, by running chown in a container from the image of the job
+func (t *Task) fixOwnership(ctx *context.ExecuteContext, imageName string) error {
+	options, ok := fixOwnershipOptions(ctx, t.config, imageName, os.Getuid(), os.Getgid())
+	if !ok {
+		return nil
+	}
+	t.logger().Debug("Fixing the ownership of the artifact")
+
+	container, err := ctx.Client.CreateContainer(options)
+	if err != nil {
+		return fmt.Errorf("failed to create container: %s", err)
+	}
+	defer ctx.Track(context.TrackedContainer, container.ID)()
+	defer removeContainer(t.logger(), ctx.Client, container.ID) // nolint: errcheck
+
+	if err := ctx.Client.StartContainer(container.ID, nil); err != nil {
+		return fmt.Errorf("failed to start container: %s", err)
+	}
+	status, err := ctx.Client.WaitContainer(container.ID)
+	switch {
+	case err != nil:
+		return fmt.Errorf("failed waiting for container: %s", err)
+	case status != 0:
+		return fmt.Errorf("chown exited with code %d", status)
+	}
+	return nil
+}
+
+// fixOwnershipOptions returns the options for a container which changes the
+// owner of the artifact files to uid and gid. It returns false if there are
+// no files to change, or if dobi is run as root, or on Windows.
+func fixOwnershipOptions(
+	ctx *context.ExecuteContext,
+	cfg *config.JobConfig,
+	imageName string,
+	uid int,
+	gid int,
+) (docker.CreateContainerOptions, bool) {
+	if uid <= 0 || gid < 0 {
+		return docker.CreateContainerOptions{}, false
+	}
+	binds := []string{}
+	paths := []string{}
+	for i, path := range cfg.Artifact.Paths() {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(ctx.WorkingDir, path)
+		}
+		target := ownershipPath + "/" + strconv.Itoa(i)
+		bind := &config.MountConfig{Bind: path, Path: target}
+		binds = append(binds, mount.AsBind(bind, ctx.WorkingDir))
+		paths = append(paths, target)
+	}
+	if len(paths) == 0 {
+		return docker.CreateContainerOptions{}, false
+	}
+
+	owner := strconv.Itoa(uid) + ":" + strconv.Itoa(gid)
+	return docker.CreateContainerOptions{
+		Config: &docker.Config{
+			Image:      imageName,
+			User:       "0:0",
+			Entrypoint: append([]string{"chown", "-R", owner}, paths...),
+		},
+		HostConfig: &docker.HostConfig{Binds: binds},
+	}, true
+}
@@ -0,0 +1,51 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestFixOwnershipOptions(t *testing.T) {
+	dir := fs.NewDir(t, "fix-ownership",
+		fs.WithDir("dist", fs.WithFile("app", "")),
+		fs.WithFile("coverage.out", ""))
+	defer dir.Remove()
+
+	artifact := config.PathGlobs{}
+	assert.NilError(t, artifact.TransformConfig(reflect.ValueOf([]interface{}{
+		dir.Join("dist"), dir.Join("coverage.out"), dir.Join("missing"),
+	})))
+	cfg := &config.JobConfig{Artifact: artifact}
+	ctx := &context.ExecuteContext{WorkingDir: dir.Path()}
+
+	options, ok := fixOwnershipOptions(ctx, cfg, "builder:abcd", 1000, 1001)
+	assert.Assert(t, ok)
+	expected := docker.CreateContainerOptions{
+		Config: &docker.Config{
+			Image: "builder:abcd",
+			User:  "0:0",
+			Entrypoint: []string{
+				"chown", "-R", "1000:1001",
+				"/dobi-fix-ownership/0", "/dobi-fix-ownership/1",
+			},
+		},
+		HostConfig: &docker.HostConfig{Binds: []string{
+			dir.Join("dist") + ":/dobi-fix-ownership/0:rw",
+			dir.Join("coverage.out") + ":/dobi-fix-ownership/1:rw",
+		}},
+	}
+	assert.Check(t, is.DeepEqual(options, expected))
+
+	_, ok = fixOwnershipOptions(ctx, cfg, "builder:abcd", 0, 0)
+	assert.Check(t, !ok, "root does not need to change the owner")
+
+	_, ok = fixOwnershipOptions(ctx, &config.JobConfig{}, "builder:abcd", 1000, 1000)
+	assert.Check(t, !ok, "no artifact")
+}
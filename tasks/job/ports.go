@@ -0,0 +1,91 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const portsDir = ".dobi/ports"
+
+// exportPorts writes the host ports published for the container to the
+// ports file, and sets them as environment variables.
+func (t *Task) exportPorts(ctx *context.ExecuteContext, containerID string) error {
+	container, err := ctx.Client.InspectContainer(containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container %q: %s", containerID, err)
+	}
+
+	ports := publishedPorts(container.NetworkSettings)
+	if err := writePortsFile(t.portsFile(ctx), ports); err != nil {
+		return fmt.Errorf("failed to write ports file: %s", err)
+	}
+	for _, port := range sortedPorts(ports) {
+		variable := portVariable(t.name.Resource(), port)
+		t.logger().Debugf("Setting %q to: %s", variable, ports[port])
+		if err := os.Setenv(variable, ports[port]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Task) portsFile(ctx *context.ExecuteContext) string {
+	path := t.config.PortsFile
+	if path == "" {
+		path = filepath.Join(portsDir, t.name.Resource()+".json")
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(ctx.WorkingDir, path)
+}
+
+// publishedPorts returns a mapping of container port to host port
+func publishedPorts(settings *docker.NetworkSettings) map[string]string {
+	ports := make(map[string]string)
+	if settings == nil {
+		return ports
+	}
+	for port, bindings := range settings.Ports {
+		if len(bindings) == 0 {
+			continue
+		}
+		ports[string(port)] = bindings[0].HostPort
+	}
+	return ports
+}
+
+func writePortsFile(path string, ports map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(ports, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, raw, 0644)
+}
+
+// portVariable returns the name of the environment variable for a container
+// port. The protocol is only included for non-tcp ports.
+func portVariable(job string, port string) string {
+	port = strings.TrimSuffix(port, "/tcp")
+	return envVariableName(fmt.Sprintf("DOBI_PORT_%s_%s", job, port))
+}
+
+func sortedPorts(ports map[string]string) []string {
+	keys := make([]string, 0, len(ports))
+	for port := range ports {
+		keys = append(keys, port)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,49 @@
+package job
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestExportPorts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	dir := fs.NewDir(t, "test-export-ports")
+	defer dir.Remove()
+
+	ctx := &context.ExecuteContext{Client: mockClient, WorkingDir: dir.Path()}
+	job := &Task{
+		name:   task.NewName("web-app", "run"),
+		config: &config.JobConfig{ExposePortsToHost: "auto"},
+	}
+	mockClient.EXPECT().InspectContainer("abcd").Return(&docker.Container{
+		NetworkSettings: &docker.NetworkSettings{
+			Ports: map[docker.Port][]docker.PortBinding{
+				"8080/tcp": {{HostIP: "0.0.0.0", HostPort: "32768"}},
+				"53/udp":   {{HostIP: "0.0.0.0", HostPort: "32769"}},
+				"9000/tcp": nil,
+			},
+		},
+	}, nil)
+	defer os.Unsetenv("DOBI_PORT_WEB_APP_8080")
+	defer os.Unsetenv("DOBI_PORT_WEB_APP_53_UDP")
+
+	assert.NilError(t, job.exportPorts(ctx, "abcd"))
+
+	raw, err := ioutil.ReadFile(dir.Join(".dobi", "ports", "web-app.json"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), "{\n  \"53/udp\": \"32769\",\n  \"8080/tcp\": \"32768\"\n}")
+	assert.Equal(t, os.Getenv("DOBI_PORT_WEB_APP_8080"), "32768")
+	assert.Equal(t, os.Getenv("DOBI_PORT_WEB_APP_53_UDP"), "32769")
+}
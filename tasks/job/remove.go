@@ -3,6 +3,7 @@ package job
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/dnephin/dobi/config"
 	"github.com/dnephin/dobi/logging"
@@ -40,6 +41,9 @@ func (t *RemoveTask) Run(ctx *context.ExecuteContext, _ bool) (bool, error) {
 	removeContainer(logger, ctx.Client, containerName(ctx, t.name.Resource())) // nolint: errcheck
 
 	for _, path := range t.config.Artifact.Paths() {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(ctx.WorkingDir, path)
+		}
 		if err := os.RemoveAll(path); err != nil {
 			logger.Warnf("failed to remove artifact %s: %s", t.config.Artifact, err)
 		}
@@ -7,6 +7,8 @@ import (
 	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"syscall"
 	"time"
@@ -22,6 +24,7 @@ import (
 	"github.com/dnephin/dobi/utils/fs"
 	"github.com/docker/docker/pkg/term"
 	"github.com/docker/go-connections/nat"
+	units "github.com/docker/go-units"
 	docker "github.com/fsouza/go-dockerclient"
 	log "github.com/sirupsen/logrus"
 )
@@ -37,9 +40,12 @@ func newRunTask(name task.Name, conf config.Resource) types.Task {
 // file or set of files.
 type Task struct {
 	types.NoStop
-	name      task.Name
-	config    *config.JobConfig
-	outStream io.Writer
+	name        task.Name
+	config      *config.JobConfig
+	outStream   io.Writer
+	warning     string
+	exitCode    *int
+	testResults *types.TestResults
 }
 
 // Name returns the name of the task
@@ -67,6 +73,20 @@ func (t *Task) Repr() string {
 	return fmt.Sprintf("%s%v", t.name.Format("job"), buff.String())
 }
 
+// Warning returns the warning when the container exited with a warn exit code
+func (t *Task) Warning() string {
+	return t.warning
+}
+
+// ExitCode returns the exit code of the container, and false if the container
+// did not exit
+func (t *Task) ExitCode() (int, bool) {
+	if t.exitCode == nil {
+		return 0, false
+	}
+	return *t.exitCode, true
+}
+
 // Run the job command in a container
 func (t *Task) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error) {
 	if !depsModified {
@@ -75,19 +95,28 @@ func (t *Task) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error)
 		case err != nil:
 			return false, err
 		case !stale:
-			t.logger().Info("is fresh")
+			logging.Skipped(t)
 			return false, nil
 		}
 	}
 	t.logger().Debug("is stale")
 
+	if t.config.RemoteCache {
+		return t.runWithRemoteCache(ctx)
+	}
+	return t.run(ctx)
+}
+
+func (t *Task) run(ctx *context.ExecuteContext) (bool, error) {
 	t.logger().Info("Start")
+	start := time.Now()
 	var err error
 	if ctx.Settings.BindMount {
 		err = t.runContainerWithBinds(ctx)
 	} else {
 		err = t.runWithBuildAndCopy(ctx)
 	}
+	t.readTestResults(ctx, start)
 	if err != nil {
 		return false, err
 	}
@@ -95,6 +124,12 @@ func (t *Task) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error)
 	return true, nil
 }
 
+// IsStale returns true if the job has no artifact, or the artifact is older
+// than the sources, mounts, or image of the job
+func (t *Task) IsStale(ctx *context.ExecuteContext) (bool, error) {
+	return t.isStale(ctx)
+}
+
 // nolint: gocyclo
 func (t *Task) isStale(ctx *context.ExecuteContext) (bool, error) {
 	if t.config.Artifact.Empty() {
@@ -112,10 +147,12 @@ func (t *Task) isStale(ctx *context.ExecuteContext) (bool, error) {
 		return true, nil
 	}
 
+	t.warnCaseCollisions(t.config.Sources.Paths())
 	if len(t.config.Sources.Paths()) != 0 {
 		sourcesLastModified, err := fs.LastModified(&fs.LastModifiedSearch{
-			Root:  ctx.WorkingDir,
-			Paths: t.config.Sources.Paths(),
+			Root:     ctx.WorkingDir,
+			Paths:    t.config.Sources.Paths(),
+			Symlinks: fs.SymlinkPolicy(t.config.Symlinks),
 		})
 		if err != nil {
 			return true, err
@@ -156,16 +193,36 @@ func (t *Task) artifactLastModified(workDir string) (time.Time, error) {
 	if len(paths) == 0 {
 		return time.Time{}, nil
 	}
-	return fs.LastModified(&fs.LastModifiedSearch{Root: workDir, Paths: paths})
+	t.warnCaseCollisions(paths)
+	return fs.LastModified(&fs.LastModifiedSearch{
+		Root:     workDir,
+		Paths:    paths,
+		Symlinks: fs.SymlinkPolicy(t.config.Symlinks),
+	})
+}
+
+func (t *Task) warnCaseCollisions(paths []string) {
+	for _, path := range fs.CaseCollisions(paths) {
+		t.logger().Warnf(
+			"%s differs from another path only by case, "+
+				"this will break on case-insensitive filesystems", path)
+	}
 }
 
 // TODO: support a .mountignore file used to ignore mtime of files
 func (t *Task) mountsLastModified(ctx *context.ExecuteContext) (time.Time, error) {
 	mountPaths := []string{}
 	ctx.Resources.EachMount(t.config.Mounts, func(name string, mount *config.MountConfig) {
+		if mount.Tmpfs {
+			return
+		}
 		mountPaths = append(mountPaths, mount.Bind)
 	})
-	return fs.LastModified(&fs.LastModifiedSearch{Root: ctx.WorkingDir, Paths: mountPaths})
+	return fs.LastModified(&fs.LastModifiedSearch{
+		Root:     ctx.WorkingDir,
+		Paths:    mountPaths,
+		Symlinks: fs.SymlinkPolicy(t.config.Symlinks),
+	})
 }
 
 func (t *Task) runContainerWithBinds(ctx *context.ExecuteContext) error {
@@ -173,22 +230,82 @@ func (t *Task) runContainerWithBinds(ctx *context.ExecuteContext) error {
 	imageName := image.GetImageName(ctx, ctx.Resources.Image(t.config.Use))
 	options := t.createOptions(ctx, name, imageName)
 
-	defer removeContainerWithLogging(t.logger(), ctx.Client, name)
-	return t.runContainer(ctx, options)
+	defer ctx.Track(context.TrackedContainer, name)()
+	defer removeContainerWithLogging(t.logger(), ctx.Client, name, t.config.StopGrace)
+	err := t.runContainer(ctx, options)
+	if t.config.FixOwnership {
+		if fixErr := t.fixOwnership(ctx, imageName); fixErr != nil {
+			t.logger().Warnf("Failed to fix the ownership of the artifact: %s", fixErr)
+		}
+	}
+	return err
 }
 
+// removeContainerWithLogging stops the container if it is still running, and
+// removes it. The container is given grace seconds to exit before it is
+// killed. It returns once the container no longer exists, so that the tasks
+// the job depends on, like a Compose project with the network used by the
+// container, are not stopped while the container is being removed.
 func removeContainerWithLogging(
 	logger *log.Entry,
 	client client.DockerClient,
 	containerID string,
+	grace int,
 ) {
+	stopRunningContainer(logger, client, containerID, grace)
 	removed, err := removeContainer(logger, client, containerID)
-	if !removed && err == nil {
+	switch {
+	case removed:
+		waitForRemoval(logger, client, containerID)
+	case err == nil:
 		logger.WithFields(log.Fields{"container": containerID}).Warn(
 			"Container does not exist")
 	}
 }
 
+func stopRunningContainer(
+	logger *log.Entry,
+	client client.DockerClient,
+	containerID string,
+	grace int,
+) {
+	container, err := client.InspectContainer(containerID)
+	if err != nil || !container.State.Running {
+		return
+	}
+	logger.Debugf("Stopping container, waiting up to %ds", grace)
+	switch err := client.StopContainer(containerID, uint(grace)); err.(type) {
+	case nil, *docker.ContainerNotRunning, *docker.NoSuchContainer:
+	default:
+		logger.WithFields(log.Fields{"container": containerID}).Warnf(
+			"Failed to stop container: %s", err)
+	}
+}
+
+var (
+	removalTimeout      = 10 * time.Second
+	removalPollInterval = 100 * time.Millisecond
+)
+
+// waitForRemoval waits until the container no longer exists. The remove API
+// can return before the container is gone when a removal is already in
+// progress.
+func waitForRemoval(logger *log.Entry, client client.DockerClient, containerID string) {
+	deadline := time.Now().Add(removalTimeout)
+	for {
+		_, err := client.InspectContainer(containerID)
+		if _, ok := err.(*docker.NoSuchContainer); ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			logger.WithFields(log.Fields{"container": containerID}).Warnf(
+				"Container still exists %s after it was removed", removalTimeout)
+			return
+		}
+		time.Sleep(removalPollInterval)
+	}
+}
+
 func (t *Task) runContainer(
 	ctx *context.ExecuteContext,
 	options docker.CreateContainerOptions,
@@ -199,16 +316,38 @@ func (t *Task) runContainer(
 		return fmt.Errorf("failed creating container %q: %s", name, err)
 	}
 
+	if err := t.connectNetworks(ctx, container.ID, options); err != nil {
+		return err
+	}
+
 	chanSig := t.forwardSignals(ctx.Client, container.ID)
 	defer signal.Stop(chanSig)
 
+	stdout, stderr := t.outputStreams(ctx)
+	logStdout, logStderr, closeLog, err := t.teeToLog(ctx, stdout, stderr)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+	defer flushOutput(t.logger(), stdout, stderr)
+	// Secrets are masked before the output is prefixed and written to the log
+	maskedStdout := logging.NewMaskWriter(logStdout)
+	maskedStderr := logging.NewMaskWriter(logStderr)
+	defer flushOutput(t.logger(), maskedStdout, maskedStderr)
+
+	stdin, err := t.inputStream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stdin.Close() // nolint: errcheck
+
 	closeWaiter, err := ctx.Client.AttachToContainerNonBlocking(docker.AttachToContainerOptions{
 		Container:    container.ID,
-		OutputStream: t.output(),
-		ErrorStream:  os.Stderr,
-		InputStream:  ioutil.NopCloser(os.Stdin),
+		OutputStream: t.output(maskedStdout),
+		ErrorStream:  maskedStderr,
+		InputStream:  stdin,
 		Stream:       true,
-		Stdin:        t.config.Interactive,
+		Stdin:        t.attachStdin(),
 		RawTerminal:  t.config.Interactive,
 		Stdout:       true,
 		Stderr:       true,
@@ -234,16 +373,103 @@ func (t *Task) runContainer(
 	if err := ctx.Client.StartContainer(container.ID, nil); err != nil {
 		return fmt.Errorf("failed starting container %q: %s", name, err)
 	}
+	if t.config.ExposePortsToHost == "auto" {
+		if err := t.exportPorts(ctx, container.ID); err != nil {
+			return err
+		}
+	}
 
 	initWindow(chanSig)
-	return t.wait(ctx.Client, container.ID)
+	if err := t.wait(ctx, container.ID); err != nil {
+		return err
+	}
+	if t.config.CheckWrites {
+		return t.checkWrites(ctx, container.ID)
+	}
+	return nil
+}
+
+// attachStdin returns true if the stdin of the container is attached to an
+// input stream
+func (t *Task) attachStdin() bool {
+	return t.config.Interactive || t.config.Stdin != ""
+}
+
+// inputStream returns the stream sent to the stdin of the container. The
+// stream is the stdin of dobi unless a file is set in the config.
+func (t *Task) inputStream(ctx *context.ExecuteContext) (io.ReadCloser, error) {
+	switch t.config.Stdin {
+	case "", "-":
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	path := t.config.Stdin
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ctx.WorkingDir, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin: %s", err)
+	}
+	return file, nil
+}
+
+// outputStreams returns the writers for the stdout and stderr of the
+// container. Interactive jobs use a raw terminal, so the output is never
+// prefixed. The prefix is the name of the task, so that the output of
+// different actions of the same job can be told apart.
+func (t *Task) outputStreams(ctx *context.ExecuteContext) (io.Writer, io.Writer) {
+	if !ctx.Settings.PrefixOutput || t.config.Interactive {
+		return ctx.Settings.Output(), os.Stderr
+	}
+	// The name of the default action ends with a ':'
+	name := strings.TrimSuffix(t.name.Name(), ":")
+	return logging.NewPrefixWriter(ctx.Settings.Output(), name, ctx.Settings.Color),
+		logging.NewPrefixWriter(os.Stderr, name, ctx.Settings.Color)
 }
 
-func (t *Task) output() io.Writer {
+// teeToLog writes the output of the container to the log file of the task, as
+// well as to stdout and stderr, when a log directory is set. The returned
+// function closes the log file.
+func (t *Task) teeToLog(
+	ctx *context.ExecuteContext,
+	stdout io.Writer,
+	stderr io.Writer,
+) (io.Writer, io.Writer, func(), error) {
+	if ctx.Settings.LogDir == "" {
+		return stdout, stderr, func() {}, nil
+	}
+	file, err := logging.OpenTaskLog(ctx.Settings.LogDir, t.name.Name())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create log file: %s", err)
+	}
+	closeLog := func() {
+		if err := file.Close(); err != nil {
+			t.logger().Warnf("Failed to close log file %s: %s", file.Name(), err)
+		}
+	}
+	return io.MultiWriter(stdout, file), io.MultiWriter(stderr, file), closeLog, nil
+}
+
+// flusher is a writer which buffers part of the output
+type flusher interface {
+	Flush() error
+}
+
+func flushOutput(logger *log.Entry, writers ...io.Writer) {
+	for _, writer := range writers {
+		if flusher, ok := writer.(flusher); ok {
+			if err := flusher.Flush(); err != nil {
+				logger.Warnf("Failed to write output: %s", err)
+			}
+		}
+	}
+}
+
+func (t *Task) output(stdout io.Writer) io.Writer {
 	if t.outStream == nil {
-		return os.Stdout
+		return stdout
 	}
-	return io.MultiWriter(t.outStream, os.Stdout)
+	return io.MultiWriter(t.outStream, stdout)
 }
 
 func (t *Task) createOptions(
@@ -254,7 +480,15 @@ func (t *Task) createOptions(
 	t.logger().Debugf("Image name %q", imageName)
 
 	interactive := t.config.Interactive
+	attachStdin := t.attachStdin()
 	portBinds, exposedPorts := asPortBindings(t.config.Ports)
+	// ShmSize is validated when the config is loaded
+	shmSize, _ := t.config.ShmSizeBytes()
+	memory, nanoCpus := t.resourceLimits(ctx)
+	binds := getMountsForHostConfig(ctx, t.config.Mounts)
+	if ctx.Settings.BindMount {
+		binds = append(binds, getArtifactBinds(t.logger(), ctx, t.config)...)
+	}
 	// TODO: only set Tty if running in a tty
 	opts := docker.CreateContainerOptions{
 		Name: name,
@@ -262,36 +496,102 @@ func (t *Task) createOptions(
 			Cmd:          t.config.Command.Value(),
 			Image:        imageName,
 			User:         t.config.User,
-			OpenStdin:    interactive,
+			OpenStdin:    attachStdin,
 			Tty:          interactive,
-			AttachStdin:  interactive,
-			StdinOnce:    interactive,
-			Labels:       t.config.Labels,
+			AttachStdin:  attachStdin,
+			StdinOnce:    attachStdin,
+			Labels:       containerLabels(ctx, t.config.Labels),
 			AttachStderr: true,
 			AttachStdout: true,
-			Env:          t.config.Env,
+			Env:          t.env(ctx),
 			Entrypoint:   t.config.Entrypoint.Value(),
 			WorkingDir:   t.config.WorkingDir,
 			ExposedPorts: exposedPorts,
 		},
 		HostConfig: &docker.HostConfig{
-			Binds:        getMountsForHostConfig(ctx, t.config.Mounts),
-			Privileged:   t.config.Privileged,
-			NetworkMode:  t.config.NetMode,
-			PortBindings: portBinds,
-			Devices:      getDevices(t.config.Devices),
+			Binds:           binds,
+			Tmpfs:           getTmpfsForHostConfig(ctx, t.config.Mounts, t.config.Tmpfs),
+			Privileged:      t.config.Privileged,
+			NetworkMode:     t.config.NetMode,
+			UsernsMode:      t.config.UsernsMode,
+			PortBindings:    portBinds,
+			PublishAllPorts: t.config.ExposePortsToHost == "auto",
+			Devices:         getDevices(t.config.Devices),
+			DeviceRequests:  t.config.GpuRequests(),
+			ShmSize:         shmSize,
+			Ulimits:         getUlimits(t.config.Ulimits),
+			Memory:          memory,
+			NanoCPUs:        nanoCpus,
+			CapAdd:          t.config.CapAdd,
+			CapDrop:         t.config.CapDrop,
 		},
 	}
+	opts = t.withNetworks(ctx, opts)
 	if t.config.ProvideDocker {
 		opts = provideDocker(opts)
 	}
+	if t.config.ProvideSSH {
+		opts = t.provideSSH(opts)
+	}
 	return opts
 }
 
+// env returns the environment variables for the container. The variables
+// from the config are last, so they can override the image variables.
+func (t *Task) env(ctx *context.ExecuteContext) []string {
+	variables, err := imageVariables(ctx, t.config)
+	if err != nil {
+		t.logger().Warnf("Failed to set image variables: %s", err)
+	}
+	return append(variables, t.config.Env...)
+}
+
+// resourceLimits returns the memory and CPU limits for the container. The
+// host profile provides the limits when they are not set on the job, and
+// --default-memory provides the memory limit when neither sets it.
+func (t *Task) resourceLimits(ctx *context.ExecuteContext) (int64, int64) {
+	memory, nanoCpus := t.config.MemoryBytes(), t.config.NanoCpus()
+	if ctx.HostProfile != nil {
+		if memory == 0 {
+			memory = ctx.HostProfile.JobMemoryBytes()
+		}
+		if nanoCpus == 0 {
+			nanoCpus = ctx.HostProfile.JobNanoCpus()
+		}
+	}
+	if memory == 0 {
+		memory = ctx.Settings.DefaultMemory
+	}
+	return memory, nanoCpus
+}
+
+func getUlimits(specs []string) []docker.ULimit {
+	ulimits := []docker.ULimit{}
+	for _, spec := range specs {
+		// Ulimits are validated when the config is loaded
+		ulimit, err := units.ParseUlimit(spec)
+		if err != nil {
+			continue
+		}
+		ulimits = append(ulimits, docker.ULimit{
+			Name: ulimit.Name,
+			Soft: ulimit.Soft,
+			Hard: ulimit.Hard,
+		})
+	}
+	return ulimits
+}
+
 func getMountsForHostConfig(ctx *context.ExecuteContext, mounts []string) []string {
 	binds := []string{}
 	ctx.Resources.EachMount(mounts, func(name string, mountConfig *config.MountConfig) {
-		if !ctx.Settings.BindMount && mountConfig.IsBind() {
+		switch {
+		case mountConfig.Tmpfs:
+			return
+		case !ctx.Settings.BindMount && mountConfig.IsBind() && !mountConfig.Copy:
+			binds = append(binds, mount.AsVolume(mountConfig, volumeName(ctx, name)))
+			return
+		case !ctx.Settings.BindMount && mountConfig.IsBind():
 			return
 		}
 		binds = append(binds, mount.AsBind(mountConfig, ctx.WorkingDir))
@@ -299,6 +599,32 @@ func getMountsForHostConfig(ctx *context.ExecuteContext, mounts []string) []stri
 	return binds
 }
 
+// volumeName returns the name of the volume used in place of a bind mount
+// when bind mounts are disabled
+func volumeName(ctx *context.ExecuteContext, mountName string) string {
+	return fmt.Sprintf("%s-%s", ctx.Env.Unique(), mountName)
+}
+
+func getTmpfsForHostConfig(
+	ctx *context.ExecuteContext,
+	mounts []string,
+	specs []string,
+) map[string]string {
+	tmpfs := make(map[string]string)
+	for _, spec := range specs {
+		path, opts := config.SplitTmpfs(spec)
+		tmpfs[path] = opts
+	}
+	ctx.Resources.EachMount(mounts, func(name string, mountConfig *config.MountConfig) {
+		if !mountConfig.Tmpfs {
+			return
+		}
+		path, opts := mount.AsTmpfs(mountConfig)
+		tmpfs[path] = opts
+	})
+	return tmpfs
+}
+
 func getDevices(devices []config.Device) []docker.Device {
 	var dockerdevices []docker.Device
 	for _, dev := range devices {
@@ -344,15 +670,51 @@ func provideDocker(opts docker.CreateContainerOptions) docker.CreateContainerOpt
 	return opts
 }
 
-func (t *Task) wait(client client.DockerClient, containerID string) error {
-	status, err := client.WaitContainer(containerID)
+const (
+	// containerSSHAuthSock is the path of the ssh-agent socket in the container
+	containerSSHAuthSock = "/run/ssh-agent.sock"
+	// dockerDesktopSSHAuthSock is the ssh-agent socket provided by Docker
+	// Desktop, because sockets on the host can not be bind mounted
+	dockerDesktopSSHAuthSock = "/run/host-services/ssh-auth.sock"
+)
+
+func (t *Task) provideSSH(opts docker.CreateContainerOptions) docker.CreateContainerOptions {
+	path := hostSSHAuthSock(runtime.GOOS)
+	if path == "" {
+		t.logger().Warn("provide-ssh is set but $SSH_AUTH_SOCK is not set")
+		return opts
+	}
+	opts.HostConfig.Binds = append(opts.HostConfig.Binds, path+":"+containerSSHAuthSock)
+	opts.Config.Env = append(opts.Config.Env, "SSH_AUTH_SOCK="+containerSSHAuthSock)
+	return opts
+}
+
+func hostSSHAuthSock(goos string) string {
+	if goos == "darwin" {
+		return dockerDesktopSSHAuthSock
+	}
+	return os.Getenv("SSH_AUTH_SOCK")
+}
+
+func (t *Task) wait(ctx *context.ExecuteContext, containerID string) error {
+	status, err := ctx.Client.WaitContainer(containerID)
 	if err != nil {
 		return fmt.Errorf("failed to wait on container exit: %s", err)
 	}
-	if status != 0 {
+	t.exitCode = &status
+	switch t.config.ExitCodes.Classify(status) {
+	case config.OutcomeSuccess:
+		return nil
+	case config.OutcomeWarn:
+		if ctx.Settings.Strict {
+			return fmt.Errorf("exited with warning status code %d (--strict)", status)
+		}
+		t.warning = fmt.Sprintf("exited with warning status code %d", status)
+		t.logger().Warn(t.warning)
+		return nil
+	default:
 		return fmt.Errorf("exited with non-zero status code %d", status)
 	}
-	return nil
 }
 
 func (t *Task) forwardSignals(
@@ -379,6 +741,7 @@ func (t *Task) forwardSignals(
 				handleWinSizeChangeSignal(logger, client, containerID)
 			default:
 				handleShutdownSignals(logger, client, containerID, sysSignal)
+				killAfterGrace(logger, client, containerID, t.config.StopGrace)
 			}
 		}
 	}()
@@ -404,6 +767,30 @@ func handleWinSizeChangeSignal(
 	}
 }
 
+// killAfterGrace kills the container if it is still running after the grace
+// period. The container has already received the signal from
+// handleShutdownSignals.
+func killAfterGrace(
+	logger log.FieldLogger,
+	client client.DockerClient,
+	containerID string,
+	grace int,
+) {
+	time.AfterFunc(time.Duration(grace)*time.Second, func() {
+		container, err := client.InspectContainer(containerID)
+		if err != nil || !container.State.Running {
+			return
+		}
+		logger.Warnf("Container did not exit after %ds, killing it", grace)
+		if err := client.KillContainer(docker.KillContainerOptions{
+			ID:     containerID,
+			Signal: docker.SIGKILL,
+		}); err != nil {
+			logger.WithError(err).Warn("Failed to kill container")
+		}
+	})
+}
+
 func handleShutdownSignals(
 	logger log.FieldLogger,
 	client client.DockerClient,
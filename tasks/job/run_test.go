@@ -0,0 +1,159 @@
+package job
+
+import (
+	"bytes"
+	"io/ioutil"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestProvideSSH(t *testing.T) {
+	defer env.Patch(t, "SSH_AUTH_SOCK", "/tmp/agent.sock")()
+	job := &Task{name: task.NewName("job", "run"), config: &config.JobConfig{ProvideSSH: true}}
+	opts := docker.CreateContainerOptions{
+		Config:     &docker.Config{Env: []string{"A=b"}},
+		HostConfig: &docker.HostConfig{},
+	}
+
+	opts = job.provideSSH(opts)
+	expected := []string{hostSSHAuthSock(runtime.GOOS) + ":/run/ssh-agent.sock"}
+	assert.Check(t, is.DeepEqual(opts.HostConfig.Binds, expected))
+	expected = []string{"A=b", "SSH_AUTH_SOCK=/run/ssh-agent.sock"}
+	assert.Check(t, is.DeepEqual(opts.Config.Env, expected))
+}
+
+func TestHostSSHAuthSock(t *testing.T) {
+	defer env.Patch(t, "SSH_AUTH_SOCK", "/tmp/agent.sock")()
+	assert.Check(t, is.Equal(hostSSHAuthSock("linux"), "/tmp/agent.sock"))
+	assert.Check(t, is.Equal(hostSSHAuthSock("darwin"), "/run/host-services/ssh-auth.sock"))
+}
+
+func TestWaitWithWarnExitCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	mockClient.EXPECT().WaitContainer("id").Return(2, nil).Times(2)
+
+	ctx := &context.ExecuteContext{Client: mockClient}
+	job := &Task{
+		name:   task.NewDefaultName("lint", ""),
+		config: &config.JobConfig{ExitCodes: config.ExitCodes{Warn: []int{2}}},
+	}
+	assert.NilError(t, job.wait(ctx, "id"))
+	assert.Check(t, is.Equal(job.Warning(), "exited with warning status code 2"))
+
+	ctx.Settings.Strict = true
+	err := job.wait(ctx, "id")
+	assert.Check(t, is.Error(err, "exited with warning status code 2 (--strict)"))
+}
+
+func TestInputStreamFromFile(t *testing.T) {
+	dir := fs.NewDir(t, "job-stdin", fs.WithFile("dump.sql", "CREATE TABLE a;"))
+	defer dir.Remove()
+
+	ctx := &context.ExecuteContext{WorkingDir: dir.Path()}
+	job := &Task{name: task.NewName("load", "run"), config: &config.JobConfig{Stdin: "dump.sql"}}
+	assert.Check(t, job.attachStdin())
+
+	stdin, err := job.inputStream(ctx)
+	assert.NilError(t, err)
+	defer stdin.Close() // nolint: errcheck
+	content, err := ioutil.ReadAll(stdin)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(content), "CREATE TABLE a;"))
+
+	job.config.Stdin = "missing.sql"
+	_, err = job.inputStream(ctx)
+	assert.Check(t, is.ErrorContains(err, "failed to open stdin"))
+}
+
+func TestRemoveContainerWithLoggingStopsRunningContainer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+
+	running := &docker.Container{State: docker.State{Running: true}}
+	gomock.InOrder(
+		mockClient.EXPECT().InspectContainer("id").Return(running, nil),
+		mockClient.EXPECT().StopContainer("id", uint(7)).Return(nil),
+		mockClient.EXPECT().RemoveContainer(docker.RemoveContainerOptions{
+			ID:            "id",
+			RemoveVolumes: true,
+			Force:         true,
+		}).Return(nil),
+		mockClient.EXPECT().InspectContainer("id").Return(&docker.Container{}, nil),
+		mockClient.EXPECT().InspectContainer("id").Return(nil, &docker.NoSuchContainer{ID: "id"}),
+	)
+
+	defer patchRemovalPollInterval(time.Millisecond)()
+	logger := logging.ForTask(&Task{name: task.NewName("job", "run")})
+	removeContainerWithLogging(logger, mockClient, "id", 7)
+}
+
+func patchRemovalPollInterval(interval time.Duration) func() {
+	original := removalPollInterval
+	removalPollInterval = interval
+	return func() {
+		removalPollInterval = original
+	}
+}
+
+func TestResourceLimits(t *testing.T) {
+	gig := int64(1024 * 1024 * 1024)
+	ctx := &context.ExecuteContext{Settings: context.Settings{DefaultMemory: gig}}
+	job := &Task{config: &config.JobConfig{Cpus: "2"}}
+
+	memory, nanoCpus := job.resourceLimits(ctx)
+	assert.Check(t, is.Equal(memory, gig))
+	assert.Check(t, is.Equal(nanoCpus, int64(2e9)))
+
+	ctx.HostProfile = &config.HostProfile{JobMemory: "512m"}
+	memory, _ = job.resourceLimits(ctx)
+	assert.Check(t, is.Equal(memory, int64(512*1024*1024)))
+
+	job.config.Memory = "4g"
+	memory, _ = job.resourceLimits(ctx)
+	assert.Check(t, is.Equal(memory, 4*gig))
+}
+
+func TestCheckoutIsCopiedWithoutBindMounts(t *testing.T) {
+	ctx := context.NewExecuteContext(
+		config.NewConfig(), nil, execenv.NewExecEnv("exec", "project", "/dir"),
+		context.NewSettings(false, false))
+	ctx.Resources.Add("source", &config.CheckoutConfig{Repo: "git@host:repo", Path: "/src"})
+	job := &config.JobConfig{Mounts: []string{"source"}}
+
+	assert.Check(t, is.Len(getMountsForHostConfig(ctx, job.Mounts), 0))
+	mounts := getCopyMounts(getBindMounts(ctx, job))
+	assert.Assert(t, is.Len(mounts, 1))
+	assert.Check(t, is.Equal(mounts[0].Bind, ".dobi/checkouts/source"))
+	assert.Check(t, is.Equal(mounts[0].Path, "/src"))
+}
+
+func TestOutputStreamsArePrefixedWithTheTaskName(t *testing.T) {
+	out := new(bytes.Buffer)
+	ctx := &context.ExecuteContext{
+		Settings: context.Settings{PrefixOutput: true, JobOutput: out},
+	}
+	for _, name := range []task.Name{task.NewName("job", "test"), task.NewName("job", "")} {
+		job := &Task{name: name, config: &config.JobConfig{}}
+		stdout, _ := job.outputStreams(ctx)
+		_, err := stdout.Write([]byte("ok\n"))
+		assert.NilError(t, err)
+	}
+	assert.Check(t, is.Equal(out.String(), "[job:test] ok\n[job] ok\n"))
+}
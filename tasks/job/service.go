@@ -0,0 +1,112 @@
+package job
+
+import (
+	"fmt"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/image"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+	docker "github.com/fsouza/go-dockerclient"
+	log "github.com/sirupsen/logrus"
+)
+
+// labelService identifies containers started by the start action, which are
+// left running when dobi exits
+const labelService = "dobi.service"
+
+// serviceTask starts the container of a job in the background, or stops it
+type serviceTask struct {
+	types.NoStop
+	name   task.Name
+	config *config.JobConfig
+	start  bool
+}
+
+func newStartTask(name task.Name, conf config.Resource) types.Task {
+	return &serviceTask{name: name, config: conf.(*config.JobConfig), start: true}
+}
+
+func newStopTask(name task.Name, conf config.Resource) types.Task {
+	return &serviceTask{name: name, config: conf.(*config.JobConfig)}
+}
+
+// Name returns the name of the task
+func (t *serviceTask) Name() task.Name {
+	return t.name
+}
+
+func (t *serviceTask) logger() *log.Entry {
+	return logging.ForTask(t)
+}
+
+// Repr formats the task for logging
+func (t *serviceTask) Repr() string {
+	return fmt.Sprintf("%s %v", t.name.Format("job"), t.config.Command.String())
+}
+
+// Run starts or stops the container
+func (t *serviceTask) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error) {
+	name := containerName(ctx, t.name.Resource())
+	if t.start {
+		return t.startContainer(ctx, name, depsModified)
+	}
+	return t.stopContainer(ctx, name)
+}
+
+func (t *serviceTask) startContainer(
+	ctx *context.ExecuteContext,
+	name string,
+	depsModified bool,
+) (bool, error) {
+	container, err := ctx.Client.InspectContainer(name)
+	switch err.(type) {
+	case nil:
+		if container.State.Running && !depsModified {
+			t.logger().Info("is running")
+			return false, t.waitForReady(ctx, container.ID)
+		}
+		removeContainer(t.logger(), ctx.Client, name) // nolint: errcheck
+	case *docker.NoSuchContainer:
+	default:
+		return false, fmt.Errorf("failed to inspect container %q: %s", name, err)
+	}
+
+	if !ctx.Settings.BindMount {
+		return false, fmt.Errorf("a job can not be started as a service with --no-bind-mount")
+	}
+	job := &Task{name: t.name, config: t.config}
+	imageName := image.GetImageName(ctx, ctx.Resources.Image(t.config.Use))
+	options := job.createOptions(ctx, name, imageName)
+	options.Config.Labels[labelService] = "true"
+	options.Config.AttachStdout, options.Config.AttachStderr = false, false
+
+	container, err = ctx.Client.CreateContainer(options)
+	if err != nil {
+		return false, fmt.Errorf("failed creating container %q: %s", name, err)
+	}
+	if err := ctx.Client.StartContainer(container.ID, nil); err != nil {
+		return false, fmt.Errorf("failed starting container %q: %s", name, err)
+	}
+	t.logger().Info("Started")
+	return true, t.waitForReady(ctx, container.ID)
+}
+
+func (t *serviceTask) stopContainer(ctx *context.ExecuteContext, name string) (bool, error) {
+	err := ctx.Client.StopContainer(name, uint(t.config.StopGrace))
+	switch err.(type) {
+	case nil, *docker.ContainerNotRunning:
+	case *docker.NoSuchContainer:
+		t.logger().Info("is not running")
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to stop container %q: %s", name, err)
+	}
+	if _, err := removeContainer(t.logger(), ctx.Client, name); err != nil {
+		return false, err
+	}
+	t.logger().Info("Stopped")
+	return true, nil
+}
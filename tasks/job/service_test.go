@@ -0,0 +1,117 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestStartTaskSkipsRunningContainer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+
+	ctx := &context.ExecuteContext{
+		Client: mockClient,
+		Env:    execenv.NewExecEnv("exec", "project", "/dir"),
+	}
+	mockClient.EXPECT().InspectContainer("project-exec-api").Return(
+		&docker.Container{State: docker.State{Running: true}}, nil)
+
+	startTask := newStartTask(task.NewName("api", "start"), &config.JobConfig{})
+	modified, err := startTask.Run(ctx, false)
+	assert.NilError(t, err)
+	assert.Check(t, !modified)
+}
+
+func TestStopTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+
+	ctx := &context.ExecuteContext{
+		Client: mockClient,
+		Env:    execenv.NewExecEnv("exec", "project", "/dir"),
+	}
+	mockClient.EXPECT().StopContainer("project-exec-api", uint(5)).Return(nil)
+	mockClient.EXPECT().RemoveContainer(docker.RemoveContainerOptions{
+		ID:            "project-exec-api",
+		RemoveVolumes: true,
+		Force:         true,
+	})
+
+	stopTask := newStopTask(task.NewName("api", "stop"), &config.JobConfig{StopGrace: 5})
+	modified, err := stopTask.Run(ctx, false)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(modified, true))
+}
+
+func TestStartTaskWaitsForHealthcheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	defer patchWaitForInterval(time.Millisecond)()
+
+	ctx := &context.ExecuteContext{
+		Client: mockClient,
+		Env:    execenv.NewExecEnv("exec", "project", "/dir"),
+	}
+	running := func(health string) *docker.Container {
+		return &docker.Container{
+			ID:    "id",
+			State: docker.State{Running: true, Health: docker.Health{Status: health}},
+		}
+	}
+	gomock.InOrder(
+		mockClient.EXPECT().InspectContainer("project-exec-api").Return(running("starting"), nil),
+		mockClient.EXPECT().InspectContainer("id").Return(running("starting"), nil),
+		mockClient.EXPECT().InspectContainer("id").Return(running("healthy"), nil),
+	)
+
+	conf := &config.JobConfig{WaitFor: config.WaitFor{Healthcheck: true}}
+	startTask := newStartTask(task.NewName("api", "start"), conf)
+	modified, err := startTask.Run(ctx, false)
+	assert.NilError(t, err)
+	assert.Check(t, !modified)
+}
+
+func TestStartTaskFailsWhenContainerExits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	defer patchWaitForInterval(time.Millisecond)()
+
+	ctx := &context.ExecuteContext{
+		Client: mockClient,
+		Env:    execenv.NewExecEnv("exec", "project", "/dir"),
+	}
+	gomock.InOrder(
+		mockClient.EXPECT().InspectContainer("project-exec-api").Return(
+			&docker.Container{ID: "id", State: docker.State{Running: true}}, nil),
+		mockClient.EXPECT().InspectContainer("id").Return(
+			&docker.Container{ID: "id", State: docker.State{ExitCode: 3}}, nil),
+	)
+
+	conf := &config.JobConfig{WaitFor: config.WaitFor{Port: 8080}}
+	startTask := newStartTask(task.NewName("api", "start"), conf)
+	_, err := startTask.Run(ctx, false)
+	assert.Check(t, is.Error(err,
+		`container "id" is not ready: the container exited with code 3`))
+}
+
+func patchWaitForInterval(interval time.Duration) func() {
+	original := waitForInterval
+	waitForInterval = interval
+	return func() {
+		waitForInterval = original
+	}
+}
@@ -0,0 +1,158 @@
+package job
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+// TestResults returns the results parsed from the test-results file after the
+// last run, or nil
+func (t *Task) TestResults() *types.TestResults {
+	return t.testResults
+}
+
+// readTestResults parses the test-results file written by the job. A file
+// which is missing, or was not written since the job started, is ignored with
+// a warning, so that the results of a previous run are never reported.
+func (t *Task) readTestResults(ctx *context.ExecuteContext, start time.Time) {
+	if t.config.TestResults == "" {
+		return
+	}
+	path := t.config.TestResults
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ctx.WorkingDir, path)
+	}
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		t.logger().Warnf("Test results %s were not written by the job", t.config.TestResults)
+		return
+	case err != nil:
+		t.logger().Warnf("Failed to read test results: %s", err)
+		return
+	case info.ModTime().Before(start.Truncate(time.Second)):
+		t.logger().Warnf("Test results %s are from a previous run", t.config.TestResults)
+		return
+	}
+
+	results, err := parseTestResultsFile(path)
+	if err != nil {
+		t.logger().Warnf("Failed to parse test results %s: %s", t.config.TestResults, err)
+		return
+	}
+	t.testResults = results
+	if results.Failed > 0 {
+		t.logger().Warnf("%d of %d tests failed", results.Failed, results.Total)
+	}
+}
+
+func parseTestResultsFile(path string) (*types.TestResults, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() // nolint: errcheck
+
+	if strings.ToLower(filepath.Ext(path)) == ".xml" {
+		return parseJUnit(file)
+	}
+	return parseGoTestJSON(file)
+}
+
+type junitSuite struct {
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []junitCase  `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string    `xml:"name,attr"`
+	Classname string    `xml:"classname,attr"`
+	Failure   *struct{} `xml:"failure"`
+	Error     *struct{} `xml:"error"`
+	Skipped   *struct{} `xml:"skipped"`
+}
+
+// parseJUnit parses a JUnit XML file. The root element may be either a
+// testsuites or a testsuite element.
+func parseJUnit(reader io.Reader) (*types.TestResults, error) {
+	root := junitSuite{}
+	if err := xml.NewDecoder(reader).Decode(&root); err != nil {
+		return nil, err
+	}
+	results := &types.TestResults{}
+	addJUnitSuite(results, root)
+	return results, nil
+}
+
+func addJUnitSuite(results *types.TestResults, suite junitSuite) {
+	for _, testCase := range suite.Cases {
+		results.Total++
+		switch {
+		case testCase.Failure != nil || testCase.Error != nil:
+			results.Failed++
+			results.Failures = append(results.Failures,
+				qualifiedTestName(testCase.Classname, testCase.Name))
+		case testCase.Skipped != nil:
+			results.Skipped++
+		}
+	}
+	for _, child := range suite.Suites {
+		addJUnitSuite(results, child)
+	}
+}
+
+// goTestEvent is a line of the output from go test -json
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+}
+
+func parseGoTestJSON(reader io.Reader) (*types.TestResults, error) {
+	results := &types.TestResults{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+		event := goTestEvent{}
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		if event.Test == "" {
+			continue
+		}
+		switch event.Action {
+		case "pass":
+			results.Total++
+		case "skip":
+			results.Total++
+			results.Skipped++
+		case "fail":
+			results.Total++
+			results.Failed++
+			results.Failures = append(results.Failures,
+				qualifiedTestName(event.Package, event.Test))
+		}
+	}
+	return results, scanner.Err()
+}
+
+func qualifiedTestName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,60 @@
+package job
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dnephin/dobi/tasks/types"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestParseJUnit(t *testing.T) {
+	raw := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="unit">
+    <testcase classname="app.Parser" name="testEmpty"></testcase>
+    <testcase classname="app.Parser" name="testInvalid">
+      <failure message="expected error">stack</failure>
+    </testcase>
+    <testcase classname="app.Parser" name="testSlow"><skipped/></testcase>
+  </testsuite>
+  <testsuite name="integration">
+    <testcase name="testConnect"><error message="timeout"/></testcase>
+  </testsuite>
+</testsuites>`
+	results, err := parseJUnit(strings.NewReader(raw))
+	assert.NilError(t, err)
+	expected := &types.TestResults{
+		Total:    4,
+		Failed:   2,
+		Skipped:  1,
+		Failures: []string{"app.Parser.testInvalid", "testConnect"},
+	}
+	assert.Check(t, is.DeepEqual(results, expected))
+}
+
+func TestParseGoTestJSON(t *testing.T) {
+	raw := `{"Action":"run","Package":"example.com/app","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/app","Test":"TestOne"}
+{"Action":"run","Package":"example.com/app","Test":"TestTwo"}
+{"Action":"output","Package":"example.com/app","Test":"TestTwo","Output":"oops\n"}
+{"Action":"fail","Package":"example.com/app","Test":"TestTwo"}
+{"Action":"skip","Package":"example.com/app","Test":"TestThree"}
+{"Action":"fail","Package":"example.com/app"}
+`
+	results, err := parseGoTestJSON(strings.NewReader(raw))
+	assert.NilError(t, err)
+	expected := &types.TestResults{
+		Total:    3,
+		Failed:   1,
+		Skipped:  1,
+		Failures: []string{"example.com/app.TestTwo"},
+	}
+	assert.Check(t, is.DeepEqual(results, expected))
+}
+
+func TestParseGoTestJSONInvalid(t *testing.T) {
+	_, err := parseGoTestJSON(strings.NewReader("{}\nnot json\n"))
+	assert.Check(t, is.ErrorContains(err, "line 2:"))
+}
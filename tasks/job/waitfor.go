@@ -0,0 +1,114 @@
+package job
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// waitForInterval is the time between the checks of wait-for
+var waitForInterval = time.Second
+
+// notReady is returned by a check which failed, and may pass later
+type notReady struct {
+	reason string
+}
+
+func (e *notReady) Error() string {
+	return e.reason
+}
+
+// waitForReady waits for the checks in wait-for to pass. It returns an error
+// if the checks don't pass before the timeout, or if a check can never pass.
+func (t *serviceTask) waitForReady(ctx *context.ExecuteContext, containerID string) error {
+	waitFor := t.config.WaitFor
+	if waitFor.IsZero() {
+		return nil
+	}
+	timeout := waitFor.TimeoutDuration()
+	deadline := time.Now().Add(timeout)
+	t.logger().Infof("Waiting up to %s for the container to be ready", timeout)
+	for {
+		err := checkReady(ctx, waitFor, containerID)
+		if err == nil {
+			t.logger().Info("Ready")
+			return nil
+		}
+		if _, ok := err.(*notReady); !ok {
+			return fmt.Errorf("container %q is not ready: %s", containerID, err)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("container %q was not ready after %s: %s",
+				containerID, timeout, err)
+		}
+		t.logger().Debugf("Not ready: %s", err)
+		time.Sleep(waitForInterval)
+	}
+}
+
+func checkReady(
+	ctx *context.ExecuteContext,
+	waitFor config.WaitFor,
+	containerID string,
+) error {
+	container, err := ctx.Client.InspectContainer(containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container: %s", err)
+	}
+	if !container.State.Running {
+		return fmt.Errorf("the container exited with code %d", container.State.ExitCode)
+	}
+
+	if waitFor.Healthcheck {
+		switch status := container.State.Health.Status; status {
+		case "healthy":
+		case "":
+			return fmt.Errorf("the container does not have a healthcheck")
+		default:
+			return &notReady{reason: "the healthcheck status is " + status}
+		}
+	}
+	if waitFor.Port != 0 {
+		if err := checkPort(ctx, container, waitFor.Port); err != nil {
+			return err
+		}
+	}
+	if waitFor.URL != "" {
+		if err := checkURL(waitFor.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkPort(ctx *context.ExecuteContext, container *docker.Container, port int) error {
+	hostPort, ok := publishedPorts(container.NetworkSettings)[strconv.Itoa(port)+"/tcp"]
+	if !ok {
+		return fmt.Errorf("port %d is not published to the host", port)
+	}
+	address := net.JoinHostPort(ctx.DockerHostname(), hostPort)
+	conn, err := net.DialTimeout("tcp", address, waitForInterval)
+	if err != nil {
+		return &notReady{reason: fmt.Sprintf("port %d is not accepting connections", port)}
+	}
+	return conn.Close()
+}
+
+func checkURL(target string) error {
+	client := http.Client{Timeout: 5 * waitForInterval}
+	resp, err := client.Get(target)
+	if err != nil {
+		return &notReady{reason: fmt.Sprintf("request to %s failed: %s", target, err)}
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode >= 400 {
+		return &notReady{reason: fmt.Sprintf("%s returned %s", target, resp.Status)}
+	}
+	return nil
+}
@@ -9,6 +9,14 @@ import (
 	"github.com/dnephin/dobi/tasks/types"
 )
 
+func init() {
+	types.RegisterActions(
+		"mount",
+		types.Action{Name: "create", Description: "Create the bind mount directory or volume"},
+		types.Action{Name: "remove", Description: "Remove the volume"},
+	)
+}
+
 // GetTaskConfig returns a new task for the action
 func GetTaskConfig(name, action string, conf *config.MountConfig) (types.TaskConfig, error) {
 
@@ -0,0 +1,138 @@
+package mount
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// hostOS is the operating system of the host. It is a variable so that it can
+// be changed by tests.
+var hostOS = runtime.GOOS
+
+// defaultSharedDirectories are the directories shared by Docker Desktop for
+// Mac when the settings file does not list them
+var defaultSharedDirectories = []string{
+	"/Users", "/Volumes", "/private", "/tmp", "/var/folders",
+}
+
+var (
+	windowsDrivePath = regexp.MustCompile(`^([a-zA-Z]):[\\/]`)
+	wslDrivePath     = regexp.MustCompile(`^/mnt/([a-zA-Z])(/|$)`)
+)
+
+// dockerPath returns the path of a bind mount in the form expected by the
+// Docker host. Windows paths like C:\src are changed to /c/src, and so are
+// WSL paths like /mnt/c/src when the Docker host is not in the WSL
+// distribution.
+func dockerPath(path string) string {
+	switch {
+	case hostOS == "windows" && windowsDrivePath.MatchString(path):
+		drive := strings.ToLower(path[:1])
+		return "/" + drive + strings.Replace(path[2:], `\`, "/", -1)
+	case isWSLWithRemoteHost() && wslDrivePath.MatchString(path):
+		return strings.TrimPrefix(path, "/mnt")
+	default:
+		return path
+	}
+}
+
+// isWSLWithRemoteHost returns true when dobi is running in WSL and the Docker
+// host is reached over tcp, so it does not share the WSL filesystem
+func isWSLWithRemoteHost() bool {
+	return os.Getenv("WSL_DISTRO_NAME") != "" &&
+		strings.HasPrefix(os.Getenv("DOCKER_HOST"), "tcp://")
+}
+
+// checkShared returns an error if the path of a bind mount is not shared with
+// the Docker host, because the mount would be empty in the container
+func checkShared(ctx *context.ExecuteContext, path string) error {
+	switch {
+	case hostOS == "darwin":
+		info, err := ctx.Client.Info()
+		if err != nil || !isDockerDesktop(info) {
+			return nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		shared, err := sharedDirectories(desktopSettingsPath(home))
+		if err != nil {
+			return err
+		}
+		return checkDesktopShared(path, shared)
+	case isWSLWithRemoteHost() && !wslDrivePath.MatchString(path):
+		return fmt.Errorf(
+			"bind mount %s is in the WSL filesystem, which is not shared with the "+
+				"Docker host at DOCKER_HOST, so the mount would be empty. Move the "+
+				"files to a path under /mnt/<drive>, or enable the WSL integration "+
+				"in Docker Desktop and unset DOCKER_HOST", path)
+	default:
+		return nil
+	}
+}
+
+func isDockerDesktop(info *docker.DockerInfo) bool {
+	return info.OperatingSystem == "Docker Desktop"
+}
+
+func desktopSettingsPath(home string) string {
+	return filepath.Join(home, "Library", "Group Containers", "group.com.docker", "settings.json")
+}
+
+// sharedDirectories returns the file sharing directories from the Docker
+// Desktop settings file
+func sharedDirectories(settingsPath string) ([]string, error) {
+	raw, err := ioutil.ReadFile(settingsPath)
+	switch {
+	case os.IsNotExist(err):
+		return defaultSharedDirectories, nil
+	case err != nil:
+		return nil, err
+	}
+	settings := struct {
+		FilesharingDirectories []string `json:"filesharingDirectories"`
+	}{}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return nil, fmt.Errorf("failed to read Docker Desktop settings %s: %s", settingsPath, err)
+	}
+	if len(settings.FilesharingDirectories) == 0 {
+		return defaultSharedDirectories, nil
+	}
+	return settings.FilesharingDirectories, nil
+}
+
+// checkDesktopShared returns an error if path is not in one of the shared
+// directories
+func checkDesktopShared(path string, shared []string) error {
+	paths := []string{path}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil && resolved != path {
+		paths = append(paths, resolved)
+	}
+	for _, dir := range shared {
+		for _, candidate := range paths {
+			if isSubPath(candidate, dir) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf(
+		"bind mount %s is not in a directory shared by Docker Desktop, so the "+
+			"mount would be empty. Add the directory in Docker Desktop "+
+			"Settings > Resources > File sharing. Shared directories: %s",
+		path, strings.Join(shared, ", "))
+}
+
+func isSubPath(path, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
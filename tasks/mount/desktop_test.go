@@ -0,0 +1,68 @@
+package mount
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func patchHostOS(goos string) func() {
+	original := hostOS
+	hostOS = goos
+	return func() { hostOS = original }
+}
+
+func TestDockerPathWindows(t *testing.T) {
+	defer patchHostOS("windows")()
+	assert.Check(t, is.Equal(dockerPath(`C:\Users\dev\src`), "/c/Users/dev/src"))
+	assert.Check(t, is.Equal(dockerPath("/already/unix"), "/already/unix"))
+}
+
+func TestDockerPathWSL(t *testing.T) {
+	defer patchHostOS("linux")()
+	defer env.PatchAll(t, map[string]string{
+		"WSL_DISTRO_NAME": "Ubuntu",
+		"DOCKER_HOST":     "tcp://localhost:2375",
+	})()
+
+	assert.Check(t, is.Equal(dockerPath("/mnt/c/src/app"), "/c/src/app"))
+	assert.Check(t, is.Equal(dockerPath("/home/dev/app"), "/home/dev/app"))
+
+	err := checkShared(nil, "/home/dev/app")
+	assert.Check(t, is.ErrorContains(err, "bind mount /home/dev/app is in the WSL filesystem"))
+	assert.Check(t, checkShared(nil, "/mnt/c/src/app"))
+}
+
+func TestDockerPathWSLIntegration(t *testing.T) {
+	defer patchHostOS("linux")()
+	defer env.PatchAll(t, map[string]string{"WSL_DISTRO_NAME": "Ubuntu", "DOCKER_HOST": ""})()
+
+	assert.Check(t, is.Equal(dockerPath("/mnt/c/src/app"), "/mnt/c/src/app"))
+}
+
+func TestSharedDirectories(t *testing.T) {
+	dir := fs.NewDir(t, "test-shared-dirs",
+		fs.WithFile("settings.json", `{"filesharingDirectories": ["/Users", "/opt/src"]}`))
+	defer dir.Remove()
+
+	shared, err := sharedDirectories(dir.Join("settings.json"))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(shared, []string{"/Users", "/opt/src"}))
+
+	shared, err = sharedDirectories(dir.Join("missing.json"))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(shared, defaultSharedDirectories))
+}
+
+func TestCheckDesktopShared(t *testing.T) {
+	shared := []string{"/Users", "/opt/src"}
+	assert.Check(t, checkDesktopShared("/Users/dev/app", shared))
+	assert.Check(t, checkDesktopShared("/opt/src", shared))
+
+	err := checkDesktopShared(filepath.FromSlash("/opt/srcs/app"), shared)
+	assert.Check(t, is.ErrorContains(err, "is not in a directory shared by Docker Desktop"))
+}
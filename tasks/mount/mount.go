@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/dnephin/dobi/config"
 	"github.com/dnephin/dobi/logging"
@@ -54,6 +55,17 @@ func runCreate(task *Task, ctx *context.ExecuteContext) (bool, error) {
 func (t *createAction) run(ctx *context.ExecuteContext) (bool, error) {
 	logger := logging.ForTask(t.task)
 
+	if t.task.config.Tmpfs {
+		logger.Debug("tmpfs is created with the container")
+		return false, nil
+	}
+
+	if t.task.config.IsBind() {
+		if err := checkShared(ctx, AbsBindPath(t.task.config, ctx.WorkingDir)); err != nil {
+			return false, err
+		}
+	}
+
 	if t.exists(ctx) {
 		logger.Debug("is fresh")
 		return false, nil
@@ -79,7 +91,12 @@ func (t *createAction) createBind(ctx *context.ExecuteContext) error {
 
 	switch t.task.config.File {
 	case true:
-		return ioutil.WriteFile(path, []byte{}, mode)
+		if t.task.config.Content != "" {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+		}
+		return ioutil.WriteFile(path, []byte(t.task.config.Content), mode)
 	default:
 		return os.MkdirAll(path, mode)
 	}
@@ -98,6 +115,9 @@ func (t *createAction) exists(ctx *context.ExecuteContext) bool {
 }
 
 func remove(task *Task, ctx *context.ExecuteContext) (bool, error) {
+	if task.config.Tmpfs {
+		return false, nil
+	}
 	if task.config.Name == "" {
 		logging.ForTask(task).Warn("Bind mounts are not removable")
 		return false, nil
@@ -1,6 +1,7 @@
 package mount
 
 import (
+	"io/ioutil"
 	"testing"
 
 	"github.com/dnephin/dobi/config"
@@ -45,6 +46,33 @@ func TestTaskRun(t *testing.T) {
 	assert.Assert(t, !modified)
 }
 
+func TestTaskRunWithContent(t *testing.T) {
+	dir := fs.NewDir(t, "test-mount-task-content")
+	defer dir.Remove()
+
+	ctx := defaultExecContext(dir.Path())
+	task := &Task{
+		name: task.NewName("resource", "action"),
+		config: &config.MountConfig{
+			Bind:     ".dobi/mounts/abcd-app.ini",
+			Path:     "/etc/app.ini",
+			Content:  "debug = true\n",
+			File:     true,
+			ReadOnly: true,
+			Mode:     0644,
+		},
+		run: runCreate,
+	}
+
+	modified, err := task.Run(ctx, false)
+	assert.NilError(t, err)
+	assert.Assert(t, modified)
+
+	content, err := ioutil.ReadFile(dir.Join(".dobi", "mounts", "abcd-app.ini"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "debug = true\n")
+}
+
 func TestAsBind(t *testing.T) {
 	workDir := "/working"
 	mountConf := &config.MountConfig{
@@ -54,3 +82,38 @@ func TestAsBind(t *testing.T) {
 	expected := "/working/a/b/c:/target:rw"
 	assert.Equal(t, AsBind(mountConf, workDir), expected)
 }
+
+func TestAsTmpfs(t *testing.T) {
+	mountConf := &config.MountConfig{
+		Path:     "/scratch",
+		Tmpfs:    true,
+		ReadOnly: true,
+		Mode:     0700,
+	}
+	path, opts := AsTmpfs(mountConf)
+	assert.Equal(t, path, "/scratch")
+	assert.Equal(t, opts, "ro,mode=700")
+}
+
+func TestAsBindReadOnlyWithConsistency(t *testing.T) {
+	mountConf := &config.MountConfig{
+		Path:        "/target",
+		Bind:        "/source",
+		ReadOnly:    true,
+		Consistency: "cached",
+	}
+	expected := "/source:/target:ro,cached"
+	assert.Equal(t, AsBind(mountConf, "/working"), expected)
+}
+
+func TestAsVolume(t *testing.T) {
+	mountConf := &config.MountConfig{
+		Path:        "/root/.cache",
+		Bind:        "./.cache",
+		ReadOnly:    true,
+		Consistency: "delegated",
+	}
+	expected := "proj-cache:/root/.cache:ro"
+	assert.Equal(t, AsVolume(mountConf, "proj-cache"), expected)
+	assert.Equal(t, mountConf.Bind, "./.cache")
+}
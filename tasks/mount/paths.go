@@ -3,6 +3,7 @@ package mount
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/dnephin/dobi/config"
 )
@@ -15,7 +16,32 @@ func AsBind(c *config.MountConfig, workingDir string) string {
 	} else {
 		mode = "rw"
 	}
-	return fmt.Sprintf("%s:%s:%s", AbsBindPath(c, workingDir), c.Path, mode)
+	if c.Consistency != "" {
+		mode += "," + c.Consistency
+	}
+	return fmt.Sprintf("%s:%s:%s", dockerPath(AbsBindPath(c, workingDir)), c.Path, mode)
+}
+
+// AsVolume returns a bind mount string which mounts the named volume at the
+// path of a MountConfig. It is used in place of a host bind mount when bind
+// mounts are disabled.
+func AsVolume(c *config.MountConfig, volume string) string {
+	volumeConfig := *c
+	volumeConfig.Bind, volumeConfig.Name, volumeConfig.Consistency = "", volume, ""
+	return AsBind(&volumeConfig, "")
+}
+
+// AsTmpfs returns the path and options used to mount a MountConfig as a
+// tmpfs
+func AsTmpfs(c *config.MountConfig) (string, string) {
+	opts := []string{}
+	if c.ReadOnly {
+		opts = append(opts, "ro")
+	}
+	if c.Mode != 0 {
+		opts = append(opts, fmt.Sprintf("mode=%o", c.Mode))
+	}
+	return c.Path, strings.Join(opts, ",")
 }
 
 // AbsBindPath returns the MountConfig.Bind as an absolute path
@@ -0,0 +1,119 @@
+package tasks
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+)
+
+// defaultNotifyTemplate renders a Slack-compatible message
+const defaultNotifyTemplate = `{"text": {{ json .Text }}}`
+
+// notifyTimeout is the longest time to wait for the webhook to respond
+var notifyTimeout = 10 * time.Second
+
+// notification is the data used to render the notify template
+type notification struct {
+	Project  string
+	Endpoint string
+	Status   string
+	Error    string
+	Tasks    []TaskResult
+	Summary  string
+	Text     string
+}
+
+// sendNotification posts the notification for the run to meta.notify.url. A
+// notification is only sent if meta.notify.on matches the result of the run.
+func sendNotification(
+	notify config.Notify,
+	execEnv *execenv.ExecEnv,
+	endpoint string,
+	report *Report,
+	runErr error,
+) error {
+	if notify.URL == "" || !notify.ShouldSend(runErr != nil) {
+		return nil
+	}
+	url, err := execEnv.Resolve(notify.URL)
+	if err != nil {
+		return fmt.Errorf("failed to resolve notify url: %s", err)
+	}
+	body, err := renderNotification(notify, newNotification(execEnv, endpoint, report, runErr))
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: notifyTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notify url returned %s", resp.Status)
+	}
+	return nil
+}
+
+func newNotification(
+	execEnv *execenv.ExecEnv,
+	endpoint string,
+	report *Report,
+	runErr error,
+) notification {
+	data := notification{
+		Project:  execEnv.Project,
+		Endpoint: endpoint,
+		Status:   "success",
+		Tasks:    report.Tasks,
+	}
+	if runErr != nil {
+		data.Status = "failure"
+		data.Error = runErr.Error()
+	}
+
+	summary := new(bytes.Buffer)
+	// WriteSummary only fails when the writer fails
+	report.WriteSummary(summary) // nolint: errcheck
+	data.Summary = strings.TrimSpace(summary.String())
+
+	project := data.Project
+	if endpoint != "" {
+		project += " (" + endpoint + ")"
+	}
+	text := new(bytes.Buffer)
+	switch runErr {
+	case nil:
+		fmt.Fprintf(text, "dobi run of %s succeeded", project)
+	default:
+		fmt.Fprintf(text, "dobi run of %s failed: %s", project, data.Error)
+	}
+	if len(report.Tasks) > 0 {
+		fmt.Fprintf(text, "\n```\n%s\n```", data.Summary)
+	}
+	data.Text = text.String()
+	return data
+}
+
+func renderNotification(notify config.Notify, data notification) ([]byte, error) {
+	source := notify.Template
+	if source == "" {
+		source = defaultNotifyTemplate
+	}
+	tmpl, err := template.New("notify").Funcs(config.NotifyTemplateFuncs).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notify template: %s", err)
+	}
+	body := new(bytes.Buffer)
+	if err := tmpl.Execute(body, data); err != nil {
+		return nil, fmt.Errorf("failed to render notify template: %s", err)
+	}
+	return body.Bytes(), nil
+}
@@ -0,0 +1,84 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func newTestNotifyServer(t *testing.T, bodies *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Check(t, err)
+		assert.Check(t, is.Equal(r.Header.Get("Content-Type"), "application/json"))
+		*bodies = append(*bodies, string(body))
+	}))
+}
+
+func TestSendNotificationDefaultTemplate(t *testing.T) {
+	bodies := []string{}
+	server := newTestNotifyServer(t, &bodies)
+	defer server.Close()
+
+	report := &Report{Tasks: []TaskResult{
+		{Name: "builder:build", Status: StatusRun, Duration: time.Second},
+		{Name: "test:run", Status: StatusFailed, Error: "exit code 1"},
+	}}
+	notify := config.Notify{URL: server.URL}
+	execEnv := execenv.NewExecEnv("exec", "project", "/dir")
+	err := sendNotification(notify, execEnv, "", report, fmt.Errorf("test:run failed"))
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(bodies, 1))
+
+	message := map[string]string{}
+	assert.NilError(t, json.Unmarshal([]byte(bodies[0]), &message))
+	assert.Check(t, is.Contains(message["text"], "dobi run of project failed: test:run failed"))
+	assert.Check(t, is.Contains(message["text"], "builder:build"))
+}
+
+func TestSendNotificationCustomTemplate(t *testing.T) {
+	bodies := []string{}
+	server := newTestNotifyServer(t, &bodies)
+	defer server.Close()
+
+	report := &Report{Tasks: []TaskResult{{Name: "test:run", Status: StatusRun}}}
+	notify := config.Notify{
+		URL:      server.URL,
+		Template: `{"status": {{ json .Status }}, "tasks": {{ len .Tasks }}}`,
+	}
+	execEnv := execenv.NewExecEnv("exec", "project", "/dir")
+	assert.NilError(t, sendNotification(notify, execEnv, "", report, nil))
+	assert.Check(t, is.DeepEqual(bodies, []string{`{"status": "success", "tasks": 1}`}))
+}
+
+func TestSendNotificationOnFailure(t *testing.T) {
+	bodies := []string{}
+	server := newTestNotifyServer(t, &bodies)
+	defer server.Close()
+
+	notify := config.Notify{URL: server.URL, On: config.NotifyFailure}
+	execEnv := execenv.NewExecEnv("exec", "project", "/dir")
+	assert.NilError(t, sendNotification(notify, execEnv, "", &Report{}, nil))
+	assert.Check(t, is.Len(bodies, 0))
+}
+
+func TestSendNotificationErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	notify := config.Notify{URL: server.URL}
+	execEnv := execenv.NewExecEnv("exec", "project", "/dir")
+	err := sendNotification(notify, execEnv, "", &Report{}, nil)
+	assert.Check(t, is.ErrorContains(err, "notify url returned 404 Not Found"))
+}
@@ -0,0 +1,164 @@
+package tasks
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/task"
+)
+
+// filterByPaths returns the tasks for resources which use files matching one
+// of the patterns, the tasks which depend on them, and the dependencies of
+// those tasks. The dependencies of an alias or a pipeline are not required,
+// so that an alias only runs the tasks which are affected.
+func filterByPaths(
+	conf *config.Config,
+	tasks *TaskCollection,
+	patterns []string,
+) *TaskCollection {
+	all := tasks.All()
+
+	// tasks are in dependency order, so every dependency is checked first
+	affected := make(map[string]bool)
+	for _, taskConfig := range all {
+		if dependsOnAny(affected, taskConfig.Dependencies()) ||
+			matchesAnyPath(resourcePaths(conf, taskConfig.Resource()), patterns) {
+			addName(affected, taskConfig.Name())
+		}
+	}
+
+	required := make(map[string]bool)
+	keep := make([]bool, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		name := all[i].Name()
+		if !affected[name.MapKey()] && !required[name.MapKey()] && !required[name.Name()] {
+			logging.Log.Debugf("Skipping %s, no files match --only-paths", name)
+			continue
+		}
+		keep[i] = true
+		switch all[i].Resource().(type) {
+		case *config.AliasConfig, *config.PipelineConfig:
+			continue
+		}
+		for _, dep := range all[i].Dependencies() {
+			required[task.ParseName(dep).MapKey()] = true
+		}
+	}
+
+	filtered := newTaskCollection()
+	for i, taskConfig := range all {
+		if keep[i] {
+			filtered.add(taskConfig)
+		}
+	}
+	return filtered
+}
+
+func addName(names map[string]bool, name task.Name) {
+	// Add both the key and the string name so that it matches against
+	// dependencies specified with or without an action
+	names[name.MapKey()] = true
+	names[name.Name()] = true
+}
+
+func dependsOnAny(names map[string]bool, deps []string) bool {
+	for _, dep := range deps {
+		if names[task.ParseName(dep).MapKey()] {
+			return true
+		}
+	}
+	return false
+}
+
+// resourcePaths returns the paths used by a resource, relative to the
+// directory of the config
+func resourcePaths(conf *config.Config, resource config.Resource) []string {
+	var paths, mountPaths []string
+	switch res := resource.(type) {
+	case *config.JobConfig:
+		paths = append([]string(nil), res.Sources.Globs()...)
+		for _, name := range res.Mounts {
+			switch mount := conf.Resources[name].(type) {
+			case *config.MountConfig:
+				mountPaths = append(mountPaths, resourcePaths(conf, mount)...)
+			case *config.CheckoutConfig:
+				mountPaths = append(mountPaths, resourcePaths(conf, mount.Mount(name))...)
+			}
+		}
+	case *config.ImageConfig:
+		paths = []string{res.Context}
+	case *config.MountConfig:
+		paths = []string{res.Bind}
+	case *config.TemplateConfig:
+		paths = []string{res.Source}
+	case *config.ComposeConfig:
+		paths = append([]string(nil), res.Files...)
+	}
+
+	if res, ok := resource.(dirResource); ok {
+		dir := res.ResourceDir(conf.WorkingDir)
+		for i, path := range paths {
+			paths[i] = relativePath(conf.WorkingDir, dir, path)
+		}
+	}
+	return append(paths, mountPaths...)
+}
+
+func relativePath(projectDir, dir, path string) string {
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	rel, err := filepath.Rel(projectDir, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+// matchesAnyPath returns true if any of the paths overlaps with the directory
+// of any pattern. A path overlaps when it is inside the directory, or the
+// directory is inside the path.
+func matchesAnyPath(paths []string, patterns []string) bool {
+	for _, pattern := range patterns {
+		base := patternBase(pattern)
+		for _, path := range paths {
+			if path == "" {
+				continue
+			}
+			path = patternBase(path)
+			if isSubPath(base, path) || isSubPath(path, base) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// patternBase returns the leading part of a path pattern which does not
+// contain any glob characters
+func patternBase(pattern string) string {
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	for i, part := range parts {
+		if strings.ContainsAny(part, "*?[") {
+			parts = parts[:i]
+			break
+		}
+	}
+	if len(parts) == 0 {
+		return "."
+	}
+	return filepath.FromSlash(strings.Join(parts, "/"))
+}
+
+// isSubPath returns true if path is dir or is inside of dir
+func isSubPath(path, dir string) bool {
+	if dir == "." || path == dir {
+		return true
+	}
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
@@ -0,0 +1,82 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestFilterByPaths(t *testing.T) {
+	conf := config.NewConfig()
+	conf.WorkingDir = "/project"
+	conf.Resources = map[string]config.Resource{
+		"api-src": &config.MountConfig{Bind: "services/api", Path: "/app"},
+		"api-image": &config.ImageConfig{
+			Image:      "api",
+			Context:    "dockerfiles/api",
+			Dockerfile: "Dockerfile",
+		},
+		"api-test": &config.JobConfig{Use: "api-image", Mounts: []string{"api-src"}},
+		"web-image": &config.ImageConfig{
+			Image:      "web",
+			Context:    "services/web",
+			Dockerfile: "Dockerfile",
+		},
+		"sub-image": &config.ImageConfig{
+			Image:      "sub",
+			Context:    "api",
+			Dockerfile: "Dockerfile",
+			Annotations: config.Annotations{
+				Annotations: config.AnnotationFields{WorkingDir: "services"},
+			},
+		},
+		"all": aliasWithDeps([]string{"api-test", "web-image", "sub-image"}),
+		"web": aliasWithDeps([]string{"web-image"}),
+	}
+	tasks, err := collectTasks(RunOptions{Config: conf, Tasks: []string{"all", "web"}})
+	assert.NilError(t, err)
+
+	filtered := filterByPaths(conf, tasks, []string{"services/api/**"})
+	names := []string{}
+	for _, taskConfig := range filtered.All() {
+		names = append(names, taskConfig.Name().Resource())
+	}
+	expected := []string{"api-image", "api-src", "api-test", "sub-image", "all"}
+	assert.Check(t, is.DeepEqual(names, expected))
+}
+
+func TestMatchesAnyPath(t *testing.T) {
+	var testcases = []struct {
+		path     string
+		pattern  string
+		expected bool
+	}{
+		{path: "services/api/main.go", pattern: "services/api/**", expected: true},
+		{path: ".", pattern: "services/api/**", expected: true},
+		{path: "services", pattern: "services/api/*.go", expected: true},
+		{path: "services/apiv2", pattern: "services/api/**", expected: false},
+		{path: "services/web/**/*.js", pattern: "services/api/**", expected: false},
+		{path: "", pattern: "**", expected: false},
+	}
+	for _, tc := range testcases {
+		actual := matchesAnyPath([]string{tc.path}, []string{tc.pattern})
+		assert.Check(t, is.Equal(actual, tc.expected), "%s %s", tc.path, tc.pattern)
+	}
+}
+
+func TestResourcePathsDoesNotModifyConfig(t *testing.T) {
+	conf := config.NewConfig()
+	conf.WorkingDir = "/project"
+	compose := &config.ComposeConfig{
+		Files: []string{"compose.yml"},
+		Annotations: config.Annotations{
+			Annotations: config.AnnotationFields{WorkingDir: "services"},
+		},
+	}
+
+	paths := resourcePaths(conf, compose)
+	assert.Check(t, is.DeepEqual(paths, []string{"services/compose.yml"}))
+	assert.Check(t, is.DeepEqual(compose.Files, []string{"compose.yml"}))
+}
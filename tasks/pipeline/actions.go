@@ -0,0 +1,78 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/alias"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+func init() {
+	types.RegisterActions(
+		"pipeline",
+		types.Action{Name: "run", Description: "Run the stages of the pipeline"},
+		types.Action{
+			Name:        "remove",
+			Description: "Run the remove action of the tasks in the pipeline",
+		},
+	)
+}
+
+// GetTaskConfig returns a new TaskConfig for the action
+func GetTaskConfig(name, act string, conf *config.PipelineConfig) (types.TaskConfig, error) {
+	switch act {
+	case "", "run":
+		return types.NewTaskConfig(
+			task.NewDefaultName(name, "run"), conf, runDeps(name, conf), NewTask), nil
+	case "remove", "rm":
+		return types.NewTaskConfig(
+			task.NewName(name, "rm"), conf, alias.RemoveDeps(conf), NewTask), nil
+	}
+	if index, ok := conf.Stage(act); ok {
+		return types.NewTaskConfig(
+			task.NewName(name, act), conf, stageDeps(name, conf, index), NewTask), nil
+	}
+	return nil, fmt.Errorf("invalid pipeline action %q for task %q", act, name)
+}
+
+// NewTask creates a new Task object
+func NewTask(name task.Name, conf config.Resource) types.Task {
+	return &Task{name: name, config: conf.(*config.PipelineConfig)}
+}
+
+// stageTask returns the task which runs the stage at index. A stage with a
+// single task runs that task, and a stage with more than one task runs the
+// stage action of the pipeline.
+func stageTask(name string, conf *config.PipelineConfig, index int) string {
+	stage := conf.Stages[index]
+	if len(stage.Tasks) == 1 {
+		return stage.Tasks[0]
+	}
+	return name + ":" + config.StageAction(index)
+}
+
+// runDeps returns the dependencies for the run action, which are the tasks of
+// each stage in order
+func runDeps(name string, conf *config.PipelineConfig) func() []string {
+	return func() []string {
+		deps := []string{}
+		for index := range conf.Stages {
+			deps = append(deps, stageTask(name, conf, index))
+		}
+		return deps
+	}
+}
+
+// stageDeps returns the dependencies for a stage action, which are the
+// previous stage, and the tasks of the stage
+func stageDeps(name string, conf *config.PipelineConfig, index int) func() []string {
+	return func() []string {
+		deps := []string{}
+		if index > 0 {
+			deps = append(deps, stageTask(name, conf, index-1))
+		}
+		return append(deps, conf.Stages[index].Tasks...)
+	}
+}
@@ -0,0 +1,37 @@
+package pipeline
+
+import (
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+// Task is a pipeline task
+type Task struct {
+	types.NoStop
+	name   task.Name
+	config *config.PipelineConfig
+}
+
+// Name returns the name of the task
+func (t *Task) Name() task.Name {
+	return t.name
+}
+
+// Repr formats the task for logging
+func (t *Task) Repr() string {
+	return t.name.Format("pipeline")
+}
+
+// Run does nothing. The stages were already run as dependencies.
+func (t *Task) Run(ctx *context.ExecuteContext, depsModified bool) (bool, error) {
+	logging.ForTask(t).Info("Done")
+	return depsModified, nil
+}
+
+// IsStale returns false, a pipeline is only stale when a stage is modified
+func (t *Task) IsStale(_ *context.ExecuteContext) (bool, error) {
+	return false, nil
+}
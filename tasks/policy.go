@@ -0,0 +1,249 @@
+package tasks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/logging"
+)
+
+// Plan is the plan of tasks sent to the policy commands as JSON
+type Plan struct {
+	Tasks  []string              `json:"tasks"`
+	Images map[string]*PlanImage `json:"images"`
+	Jobs   map[string]*PlanJob   `json:"jobs"`
+	Mounts map[string]*PlanMount `json:"mounts"`
+}
+
+// PlanImage is an image in the plan
+type PlanImage struct {
+	Image     string            `json:"image"`
+	Tags      []string          `json:"tags"`
+	Buildable bool              `json:"buildable"`
+	Args      map[string]string `json:"args"`
+}
+
+// PlanJob is a job in the plan
+type PlanJob struct {
+	Use           string   `json:"use"`
+	Command       []string `json:"command"`
+	Mounts        []string `json:"mounts"`
+	Privileged    bool     `json:"privileged"`
+	User          string   `json:"user"`
+	NetMode       string   `json:"net-mode"`
+	CapAdd        []string `json:"cap-add"`
+	ProvideDocker bool     `json:"provide-docker"`
+	ProvideSSH    bool     `json:"provide-ssh"`
+	Devices       []string `json:"devices"`
+}
+
+// PlanMount is a mount in the plan
+type PlanMount struct {
+	Bind     string `json:"bind"`
+	Path     string `json:"path"`
+	Name     string `json:"name"`
+	ReadOnly bool   `json:"read-only"`
+}
+
+// newPlan returns the plan for the tasks, with the variables in the config
+// resolved. An error is returned if a resource can not be resolved, so that
+// the policy never checks a value which is different from the value used by
+// the task.
+func newPlan(conf *config.Config, tasks *TaskCollection, env *execenv.ExecEnv) (*Plan, error) {
+	plan := &Plan{
+		Images: make(map[string]*PlanImage),
+		Jobs:   make(map[string]*PlanJob),
+		Mounts: make(map[string]*PlanMount),
+	}
+	for _, taskConfig := range tasks.All() {
+		plan.Tasks = append(plan.Tasks, taskConfig.Name().Name())
+		if err := plan.add(conf, taskConfig.Name().Resource(), env); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+func (p *Plan) add(conf *config.Config, name string, env *execenv.ExecEnv) error {
+	resource, err := resolveForPlan(conf.Resources[name], env)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %q for the policy check: %s", name, err)
+	}
+	switch resource := resource.(type) {
+	case *config.ImageConfig:
+		p.Images[name] = &PlanImage{
+			Image:     resource.Image,
+			Tags:      resource.Tags,
+			Buildable: resource.IsBuildable(),
+			Args:      resource.Args,
+		}
+	case *config.JobConfig:
+		job := &PlanJob{
+			Use:           resource.Use,
+			Command:       resource.Command.Value(),
+			Mounts:        resource.Mounts,
+			Privileged:    resource.Privileged,
+			User:          resource.User,
+			NetMode:       resource.NetMode,
+			CapAdd:        resource.CapAdd,
+			ProvideDocker: resource.ProvideDocker,
+			ProvideSSH:    resource.ProvideSSH,
+		}
+		for _, device := range resource.Devices {
+			job.Devices = append(job.Devices, device.Host)
+		}
+		p.Jobs[name] = job
+		if err := p.add(conf, resource.Use, env); err != nil {
+			return err
+		}
+		for _, mount := range resource.Mounts {
+			if err := p.add(conf, mount, env); err != nil {
+				return err
+			}
+		}
+	case *config.MountConfig:
+		p.Mounts[name] = &PlanMount{
+			Bind:     resource.Bind,
+			Path:     resource.Path,
+			Name:     resource.Name,
+			ReadOnly: resource.ReadOnly,
+		}
+	}
+	return nil
+}
+
+// resolveForPlan resolves the resource with the same variables used when the
+// task runs
+func resolveForPlan(resource config.Resource, env *execenv.ExecEnv) (config.Resource, error) {
+	if resource == nil {
+		return nil, nil
+	}
+	var resolver config.Resolver = env
+	if res, ok := resource.(variablesResource); ok {
+		resolver = env.WithVariables(res.ResourceVariables())
+	}
+	return resource.Resolve(resolver)
+}
+
+// checkPolicy runs each policy command with the plan. The config is changed
+// to match any changes made to the plan by a policy command.
+func checkPolicy(conf *config.Config, tasks *TaskCollection, env *execenv.ExecEnv) error {
+	if len(conf.Meta.Policy) == 0 {
+		return nil
+	}
+	plan, err := newPlan(conf, tasks, env)
+	if err != nil {
+		return err
+	}
+	for _, command := range conf.Meta.Policy {
+		logging.Log.Debugf("Running policy: %s", command)
+		changed, err := runPolicy(conf.WorkingDir, command, plan)
+		if err != nil {
+			return err
+		}
+		if changed == nil {
+			continue
+		}
+		applyPlan(conf, plan, changed)
+		if plan, err = newPlan(conf, tasks, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPolicy runs the policy command, and returns the changed plan if the
+// command printed one
+func runPolicy(workingDir, command string, plan *Plan) (*Plan, error) {
+	input, err := json.Marshal(plan)
+	if err != nil {
+		return nil, err
+	}
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = workingDir
+	cmd.Env = os.Environ()
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, fmt.Errorf("failed to run policy %q: %s", command, err)
+		}
+		reason := strings.TrimSpace(stderr.String() + "\n" + stdout.String())
+		return nil, fmt.Errorf("rejected by policy %q: %s", command, reason)
+	}
+
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return nil, nil
+	}
+	changed := &Plan{}
+	if err := json.Unmarshal(stdout.Bytes(), changed); err != nil {
+		return nil, fmt.Errorf("policy %q printed an invalid plan: %s", command, err)
+	}
+	return changed, nil
+}
+
+// applyPlan sets the fields of the config which were changed in the plan
+func applyPlan(conf *config.Config, plan, changed *Plan) {
+	for name, image := range changed.Images {
+		before, ok := plan.Images[name]
+		if ok && image.Image != before.Image {
+			logPlanChange(name, "image", before.Image, image.Image)
+			conf.Resources[name].(*config.ImageConfig).Image = image.Image
+		}
+	}
+	for name, job := range changed.Jobs {
+		before, ok := plan.Jobs[name]
+		if !ok {
+			continue
+		}
+		resource := conf.Resources[name].(*config.JobConfig)
+		if job.Privileged != before.Privileged {
+			logPlanChange(name, "privileged", before.Privileged, job.Privileged)
+			resource.Privileged = job.Privileged
+		}
+		if job.User != before.User {
+			logPlanChange(name, "user", before.User, job.User)
+			resource.User = job.User
+		}
+		if job.NetMode != before.NetMode {
+			logPlanChange(name, "net-mode", before.NetMode, job.NetMode)
+			resource.NetMode = job.NetMode
+		}
+		if !equalStrings(job.CapAdd, before.CapAdd) {
+			logPlanChange(name, "cap-add", before.CapAdd, job.CapAdd)
+			resource.CapAdd = job.CapAdd
+		}
+	}
+	for name, mount := range changed.Mounts {
+		before, ok := plan.Mounts[name]
+		if ok && mount.ReadOnly != before.ReadOnly {
+			logPlanChange(name, "read-only", before.ReadOnly, mount.ReadOnly)
+			conf.Resources[name].(*config.MountConfig).ReadOnly = mount.ReadOnly
+		}
+	}
+}
+
+func logPlanChange(name, field string, before, after interface{}) {
+	logging.Log.Infof("Policy changed %s of %q from %v to %v", field, name, before, after)
+}
+
+func equalStrings(first, second []string) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/env"
+)
+
+func policyConfig(policy ...string) *config.Config {
+	conf := config.NewConfig()
+	conf.WorkingDir = "."
+	conf.Meta.Policy = policy
+	conf.Resources["builder"] = &config.ImageConfig{
+		Image:      "{env.REGISTRY}/builder",
+		Context:    ".",
+		Dockerfile: "Dockerfile",
+	}
+	conf.Resources["source"] = &config.MountConfig{Bind: ".", Path: "/app"}
+	conf.Resources["test"] = &config.JobConfig{
+		Use:        "builder",
+		Mounts:     []string{"source"},
+		Privileged: true,
+	}
+	return conf
+}
+
+func collectPolicyTasks(t *testing.T, conf *config.Config) *TaskCollection {
+	tasks, err := collectTasks(RunOptions{Config: conf, Tasks: []string{"test"}})
+	assert.NilError(t, err)
+	return tasks
+}
+
+func TestNewPlan(t *testing.T) {
+	defer env.Patch(t, "REGISTRY", "registry.example")()
+	conf := policyConfig()
+	tasks := collectPolicyTasks(t, conf)
+
+	plan, err := newPlan(conf, tasks, execenv.NewExecEnv("exec", "project", "."))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(plan.Tasks, []string{"builder:build", "source:", "test:"}))
+	assert.Check(t, is.Equal(plan.Images["builder"].Image, "registry.example/builder"))
+	assert.Check(t, plan.Images["builder"].Buildable)
+	assert.Check(t, plan.Jobs["test"].Privileged)
+	assert.Check(t, is.Equal(plan.Mounts["source"].Path, "/app"))
+}
+
+func TestCheckPolicyFailsWhenResourceIsNotResolved(t *testing.T) {
+	defer env.Patch(t, "REGISTRY", "")()
+	conf := policyConfig("cat > /dev/null")
+	tasks := collectPolicyTasks(t, conf)
+
+	err := checkPolicy(conf, tasks, execenv.NewExecEnv("exec", "project", "."))
+	assert.Check(t, is.ErrorContains(err, `failed to resolve "builder" for the policy check`))
+}
+
+func TestCheckPolicyRejects(t *testing.T) {
+	defer env.Patch(t, "REGISTRY", "registry.example")()
+	conf := policyConfig(`echo "privileged jobs are not allowed" >&2; exit 3`)
+	tasks := collectPolicyTasks(t, conf)
+
+	err := checkPolicy(conf, tasks, execenv.NewExecEnv("exec", "project", "."))
+	assert.Check(t, is.ErrorContains(err, "privileged jobs are not allowed"))
+}
+
+func TestCheckPolicyNoChange(t *testing.T) {
+	defer env.Patch(t, "REGISTRY", "registry.example")()
+	conf := policyConfig("cat > /dev/null")
+	tasks := collectPolicyTasks(t, conf)
+
+	err := checkPolicy(conf, tasks, execenv.NewExecEnv("exec", "project", "."))
+	assert.NilError(t, err)
+	assert.Check(t, conf.Resources["test"].(*config.JobConfig).Privileged)
+}
+
+func TestCheckPolicyChangesPlan(t *testing.T) {
+	defer env.Patch(t, "REGISTRY", "registry.example")()
+	conf := policyConfig(`sed -e 's/"privileged":true/"privileged":false/' ` +
+		`-e 's|registry.example/builder|registry.internal/builder|'`)
+	tasks := collectPolicyTasks(t, conf)
+
+	err := checkPolicy(conf, tasks, execenv.NewExecEnv("exec", "project", "."))
+	assert.NilError(t, err)
+	assert.Check(t, !conf.Resources["test"].(*config.JobConfig).Privileged)
+	assert.Check(t, is.Equal(conf.Resources["builder"].(*config.ImageConfig).Image,
+		"registry.internal/builder"))
+}
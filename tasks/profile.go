@@ -0,0 +1,105 @@
+package tasks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	units "github.com/docker/go-units"
+)
+
+// parseDefaultMemory parses the value of --default-memory as a number of bytes
+func parseDefaultMemory(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	size, err := units.RAMInBytes(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --default-memory %q: %s", value, err)
+	}
+	return size, nil
+}
+
+// applyHostProfile sets the small host profile on the context. The mode is
+// one of:
+//  * auto (or empty) - use the profile when the Docker host is small
+//  * small - always use the profile
+//  * default - never use the profile
+func applyHostProfile(
+	ctx *context.ExecuteContext,
+	profile config.HostProfile,
+	mode string,
+) error {
+	switch mode {
+	case "", "auto":
+		if profile.IsZero() {
+			return nil
+		}
+	case "small":
+	case "default":
+		return nil
+	default:
+		return fmt.Errorf("invalid host profile %q, must be one of: auto, small, default", mode)
+	}
+
+	info, err := ctx.Client.Info()
+	if err != nil {
+		return fmt.Errorf("failed to get docker host info: %s", err)
+	}
+	if mode != "small" && !profile.IsSmall(info.MemTotal, info.NCPU) {
+		return nil
+	}
+	logging.Log.Infof("Using small host profile (memory: %d bytes, cpus: %d)",
+		info.MemTotal, info.NCPU)
+	ctx.HostProfile = &profile
+	ctx.Parallelism = parallelLimit(info.NCPU, hostLoad(), profile.MaxParallel)
+	return nil
+}
+
+// parallelLimit returns the number of tasks which can run at the same time on
+// a host with the number of CPUs and the load. The limit is the number of
+// CPUs which are not busy, and never more than max, unless max is zero.
+func parallelLimit(cpus int, load float64, max int) int {
+	limit := cpus - int(load+0.5)
+	if max > 0 && limit > max {
+		limit = max
+	}
+	if limit < 1 {
+		return 1
+	}
+	return limit
+}
+
+// loadAvgPath is the file which contains the load average of the host
+var loadAvgPath = "/proc/loadavg"
+
+// hostLoad returns the load average of the last minute. The load is only
+// known when the Docker host is the local host, and it is zero when it can
+// not be read.
+func hostLoad() float64 {
+	if !isLocalDockerHost(os.Getenv("DOCKER_HOST")) {
+		return 0
+	}
+	raw, err := ioutil.ReadFile(loadAvgPath)
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(raw))
+	if len(fields) == 0 {
+		return 0
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return load
+}
+
+func isLocalDockerHost(host string) bool {
+	return host == "" || strings.HasPrefix(host, "unix://")
+}
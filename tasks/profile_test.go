@@ -0,0 +1,115 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestApplyHostProfile(t *testing.T) {
+	profile := config.HostProfile{MemoryBelow: "8g", JobMemory: "2g"}
+	gig := int64(1024 * 1024 * 1024)
+
+	var testcases = []struct {
+		doc      string
+		mode     string
+		memory   int64
+		expected bool
+	}{
+		{doc: "auto on small host", mode: "auto", memory: 4 * gig, expected: true},
+		{doc: "auto on large host", mode: "", memory: 16 * gig, expected: false},
+		{doc: "forced small", mode: "small", memory: 16 * gig, expected: true},
+		{doc: "forced default", mode: "default", expected: false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.doc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockClient := client.NewMockDockerClient(ctrl)
+			if tc.memory > 0 {
+				mockClient.EXPECT().Info().Return(&docker.DockerInfo{MemTotal: tc.memory, NCPU: 8}, nil)
+			}
+
+			ctx := &context.ExecuteContext{Client: mockClient}
+			assert.NilError(t, applyHostProfile(ctx, profile, tc.mode))
+			assert.Check(t, is.Equal(ctx.HostProfile != nil, tc.expected))
+			assert.Check(t, is.Equal(ctx.Parallelism > 0, tc.expected))
+		})
+	}
+}
+
+func TestApplyHostProfileLimitsParallelism(t *testing.T) {
+	dir := fs.NewDir(t, "loadavg", fs.WithFile("loadavg", "2.60 1.50 1.00 3/400 1234\n"))
+	defer dir.Remove()
+	defer patchLoadAvgPath(dir.Join("loadavg"))()
+	defer env.Patch(t, "DOCKER_HOST", "")()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := client.NewMockDockerClient(ctrl)
+	mockClient.EXPECT().Info().Return(&docker.DockerInfo{NCPU: 8}, nil)
+
+	ctx := &context.ExecuteContext{Client: mockClient}
+	profile := config.HostProfile{CpusBelow: 16, MaxParallel: 6}
+	assert.NilError(t, applyHostProfile(ctx, profile, "auto"))
+	assert.Check(t, is.Equal(ctx.Parallelism, 5))
+}
+
+func patchLoadAvgPath(path string) func() {
+	orig := loadAvgPath
+	loadAvgPath = path
+	return func() { loadAvgPath = orig }
+}
+
+func TestParallelLimit(t *testing.T) {
+	var testcases = []struct {
+		doc      string
+		cpus     int
+		load     float64
+		max      int
+		expected int
+	}{
+		{doc: "idle host", cpus: 4, expected: 4},
+		{doc: "busy host", cpus: 4, load: 2.4, expected: 2},
+		{doc: "overloaded host", cpus: 4, load: 9, expected: 1},
+		{doc: "max is lower", cpus: 16, load: 1, max: 4, expected: 4},
+		{doc: "max is higher", cpus: 4, max: 8, expected: 4},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.doc, func(t *testing.T) {
+			assert.Check(t, is.Equal(parallelLimit(tc.cpus, tc.load, tc.max), tc.expected))
+		})
+	}
+}
+
+func TestHostLoadOfRemoteDockerHost(t *testing.T) {
+	defer env.Patch(t, "DOCKER_HOST", "tcp://build1:2376")()
+	assert.Check(t, is.Equal(hostLoad(), float64(0)))
+}
+
+func TestApplyHostProfileInvalidMode(t *testing.T) {
+	ctx := &context.ExecuteContext{}
+	err := applyHostProfile(ctx, config.HostProfile{}, "tiny")
+	assert.Check(t, is.ErrorContains(err, `invalid host profile "tiny"`))
+}
+
+func TestParseDefaultMemory(t *testing.T) {
+	size, err := parseDefaultMemory("")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(size, int64(0)))
+
+	size, err = parseDefaultMemory("2g")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(size, int64(2*1024*1024*1024)))
+
+	_, err = parseDefaultMemory("lots")
+	assert.Check(t, is.ErrorContains(err, `invalid --default-memory "lots"`))
+}
@@ -0,0 +1,28 @@
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+)
+
+// setVariableProviders adds the secret store providers to the exec env, using
+// the settings from meta.variable-providers
+func setVariableProviders(execEnv *execenv.ExecEnv, conf config.VariableProviders) error {
+	settings, err := execEnv.ResolveSlice([]string{
+		conf.Vault.Address,
+		conf.Vault.Token,
+		conf.Vault.Namespace,
+		conf.SSM.Region,
+		conf.SSM.Profile,
+		conf.GCP.Project,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resolve variable-providers: %s", err)
+	}
+	execEnv.SetProvider("vault", execenv.NewVaultProvider(settings[0], settings[1], settings[2]))
+	execEnv.SetProvider("ssm", &execenv.SSMProvider{Region: settings[3], Profile: settings[4]})
+	execEnv.SetProvider("gcp", &execenv.GCPProvider{Project: settings[5]})
+	return nil
+}
@@ -0,0 +1,261 @@
+package tasks
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/image"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+// Task statuses used in a Report
+const (
+	StatusRun     = "run"
+	StatusSkipped = "skipped"
+	StatusFailed  = "failed"
+	StatusWarning = "warning"
+	StatusNotRun  = "not-run"
+	// StatusAllowedFailure is the status of a task which failed, for a
+	// resource with allow-failure
+	StatusAllowedFailure = "allowed-failure"
+)
+
+// TaskResult is the result of running a single task
+type TaskResult struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"-"`
+	Output   string        `json:"output,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Warning  string        `json:"warning,omitempty"`
+	// Tests are the results parsed from the test-results file of a job
+	Tests *types.TestResults `json:"tests,omitempty"`
+	// Start and ExitCode are only used by the trace
+	Start    time.Time `json:"-"`
+	ExitCode *int      `json:"-"`
+}
+
+// MarshalJSON includes the duration in seconds
+func (r TaskResult) MarshalJSON() ([]byte, error) {
+	type result TaskResult
+	return json.Marshal(struct {
+		result
+		Duration float64 `json:"duration"`
+	}{result: result(r), Duration: r.Duration.Seconds()})
+}
+
+// Report is the result of every task run by executeTasks, in the order they
+// were run
+type Report struct {
+	Tasks []TaskResult `json:"tasks"`
+	// mu guards Tasks, because tasks in a parallel alias are run concurrently
+	mu sync.Mutex
+	// events receives each result, when meta.events-url is set
+	events *eventStream
+}
+
+// record calls f with the report locked, so that f can add a result and
+// update the last result. The last result is then sent to the event stream.
+func (r *Report) record(f func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f()
+	if len(r.Tasks) > 0 {
+		r.events.taskResult(r.Tasks[len(r.Tasks)-1])
+	}
+}
+
+func (r *Report) add(
+	ctx *context.ExecuteContext,
+	name string,
+	resource config.Resource,
+	start time.Time,
+	modified bool,
+	err error,
+) {
+	result := TaskResult{
+		Name:     name,
+		Status:   StatusSkipped,
+		Start:    start,
+		Duration: time.Since(start),
+		Output:   resourceOutput(ctx, resource),
+	}
+	switch {
+	case err != nil:
+		result.Status = StatusFailed
+		result.Error = logging.Mask(err.Error())
+	case modified:
+		result.Status = StatusRun
+	}
+	r.Tasks = append(r.Tasks, result)
+}
+
+// warn sets the status of the last task to a warning
+func (r *Report) warn(warning string) {
+	if warning == "" || len(r.Tasks) == 0 {
+		return
+	}
+	last := &r.Tasks[len(r.Tasks)-1]
+	last.Status = StatusWarning
+	last.Warning = warning
+}
+
+// allowFailure sets the status of the last task, which failed, to an allowed
+// failure
+func (r *Report) allowFailure() {
+	if len(r.Tasks) == 0 {
+		return
+	}
+	r.Tasks[len(r.Tasks)-1].Status = StatusAllowedFailure
+}
+
+// setExitCode sets the exit code of the last task
+func (r *Report) setExitCode(code int) {
+	if len(r.Tasks) == 0 {
+		return
+	}
+	r.Tasks[len(r.Tasks)-1].ExitCode = &code
+}
+
+// setTestResults sets the test results of the last task
+func (r *Report) setTestResults(results *types.TestResults) {
+	if results == nil || len(r.Tasks) == 0 {
+		return
+	}
+	r.Tasks[len(r.Tasks)-1].Tests = results
+}
+
+// resourceOutput returns the image or artifact produced by a resource
+func resourceOutput(ctx *context.ExecuteContext, resource config.Resource) string {
+	switch conf := resource.(type) {
+	case *config.ImageConfig:
+		return image.GetImageName(ctx, conf)
+	case *config.JobConfig:
+		if conf.Artifact.Empty() {
+			return ""
+		}
+		return conf.Artifact.String()
+	case *config.TemplateConfig:
+		return conf.Output
+	default:
+		return ""
+	}
+}
+
+// WriteSummary writes a table of task results to out, followed by the names
+// of any failed tests
+func (r *Report) WriteSummary(out io.Writer) error {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "TASK\tSTATUS\tDURATION\tOUTPUT")
+	for _, result := range r.Tasks {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n",
+			result.Name, result.Status, result.Duration.Round(time.Millisecond), result.Output)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	for _, result := range r.Tasks {
+		if result.Tests == nil || result.Tests.Failed == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "\n%s: %d of %d tests failed\n",
+			result.Name, result.Tests.Failed, result.Tests.Total)
+		for _, name := range result.Tests.Failures {
+			fmt.Fprintf(out, "  %s\n", name)
+		}
+	}
+	return nil
+}
+
+// WriteFile writes the report to a file. Files with a .xml extension are
+// written in JUnit format, all other files are written as JSON.
+func (r *Report) WriteFile(path string) error {
+	var raw []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		raw, err = r.junit()
+	default:
+		raw, err = json.MarshalIndent(r, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, raw, 0644)
+}
+
+type junitTestSuite struct {
+	XMLName  xml.Name        `xml:"testsuite"`
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name    string        `xml:"name,attr"`
+	Time    string        `xml:"time,attr"`
+	Failure *junitMessage `xml:"failure,omitempty"`
+	Skipped *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+func (r *Report) junit() ([]byte, error) {
+	suite := junitTestSuite{Name: "dobi", Tests: len(r.Tasks)}
+	var total time.Duration
+	for _, result := range r.Tasks {
+		total += result.Duration
+		testCase := junitTestCase{Name: result.Name, Time: seconds(result.Duration)}
+		switch result.Status {
+		case StatusFailed:
+			suite.Failures++
+			testCase.Failure = &junitMessage{Message: result.Error}
+		case StatusSkipped:
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Message: "up-to-date"}
+		case StatusAllowedFailure:
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Message: "allowed to fail: " + result.Error}
+		case StatusNotRun:
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Message: "a dependency failed"}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Time = seconds(total)
+
+	raw, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), raw...), nil
+}
+
+func seconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
+
+// reportPath returns the path of the report file for a matrix endpoint
+func reportPath(path string, endpoint string) string {
+	if endpoint == "" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + endpoint + ext
+}
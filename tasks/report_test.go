@@ -0,0 +1,115 @@
+package tasks
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/types"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func newTestReport() *Report {
+	return &Report{Tasks: []TaskResult{
+		{
+			Name:     "builder:build",
+			Status:   StatusSkipped,
+			Duration: 20 * time.Millisecond,
+			Output:   "builder:latest",
+		},
+		{Name: "test:run", Status: StatusRun, Duration: 1500 * time.Millisecond},
+		{Name: "lint:run", Status: StatusFailed, Duration: time.Second, Error: "exit 1"},
+	}}
+}
+
+func TestReportWriteSummary(t *testing.T) {
+	buf := new(bytes.Buffer)
+	assert.NilError(t, newTestReport().WriteSummary(buf))
+	expected := `TASK           STATUS   DURATION  OUTPUT
+builder:build  skipped  20ms      builder:latest
+test:run       run      1.5s      
+lint:run       failed   1s        
+`
+	assert.Equal(t, buf.String(), expected)
+}
+
+func TestReportWriteSummaryWithFailedTests(t *testing.T) {
+	report := newTestReport()
+	report.Tasks = report.Tasks[1:2]
+	report.setTestResults(&types.TestResults{
+		Total:    12,
+		Failed:   2,
+		Failures: []string{"app.TestOne", "app.TestTwo"},
+	})
+
+	buf := new(bytes.Buffer)
+	assert.NilError(t, report.WriteSummary(buf))
+	expected := `TASK      STATUS  DURATION  OUTPUT
+test:run  run     1.5s      
+
+test:run: 2 of 12 tests failed
+  app.TestOne
+  app.TestTwo
+`
+	assert.Equal(t, buf.String(), expected)
+}
+
+func TestReportWarn(t *testing.T) {
+	report := newTestReport()
+	report.warn("")
+	assert.Check(t, is.Equal(report.Tasks[2].Status, StatusFailed))
+
+	report.Tasks = report.Tasks[:2]
+	report.warn("exited with warning status code 2")
+	expected := TaskResult{
+		Name:     "test:run",
+		Status:   StatusWarning,
+		Duration: 1500 * time.Millisecond,
+		Warning:  "exited with warning status code 2",
+	}
+	assert.Check(t, is.DeepEqual(report.Tasks[1], expected))
+}
+
+func TestReportWriteFileJSON(t *testing.T) {
+	dir := fs.NewDir(t, "test-report")
+	defer dir.Remove()
+
+	path := dir.Join("report.json")
+	assert.NilError(t, newTestReport().WriteFile(path))
+	raw, err := ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(string(raw), `"name": "test:run"`))
+	assert.Check(t, is.Contains(string(raw), `"duration": 1.5`))
+	assert.Check(t, is.Contains(string(raw), `"error": "exit 1"`))
+}
+
+func TestReportWriteFileJUnit(t *testing.T) {
+	dir := fs.NewDir(t, "test-report")
+	defer dir.Remove()
+
+	path := dir.Join("report.xml")
+	assert.NilError(t, newTestReport().WriteFile(path))
+	raw, err := ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(string(raw),
+		`<testsuite name="dobi" tests="3" failures="1" skipped="1" time="2.520">`))
+	assert.Check(t, is.Contains(string(raw), `<failure message="exit 1"></failure>`))
+}
+
+func TestReportPath(t *testing.T) {
+	assert.Check(t, is.Equal(reportPath("out/report.xml", ""), "out/report.xml"))
+	assert.Check(t, is.Equal(reportPath("out/report.xml", "arm64"), "out/report-arm64.xml"))
+}
+
+func TestReportAddMasksSecretsInErrors(t *testing.T) {
+	logging.AddSecret("s3cret-token")
+	report := &Report{}
+	report.add(nil, "deploy:run", nil, time.Now(), false,
+		fmt.Errorf("login failed with s3cret-token"))
+	assert.Check(t, is.Equal(report.Tasks[0].Error, "login failed with ******"))
+}
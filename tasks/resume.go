@@ -0,0 +1,96 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runStateFile is the path of the file which stores the state of the last
+// failed run, relative to the working directory
+const runStateFile = ".dobi/last-run.json"
+
+// runState is the state of a failed run, which is used by --continue
+type runState struct {
+	// Tasks are the task names given to the run
+	Tasks  []string          `json:"tasks"`
+	Params map[string]string `json:"params,omitempty"`
+	// Completed are the tasks which completed successfully. The value is true
+	// if the task was modified.
+	Completed map[string]bool `json:"completed"`
+}
+
+func runStatePath(options RunOptions) string {
+	path := filepath.Join(options.Config.WorkingDir, runStateFile)
+	return reportPath(path, options.Endpoint)
+}
+
+// saveRunState writes the state of a failed run, or removes the state file
+// when the run succeeded, so that only a failed run can be continued. The
+// tasks in previous are from the run which was continued, and keep their
+// modified state.
+func saveRunState(
+	options RunOptions,
+	tasks []string,
+	report *Report,
+	previous map[string]bool,
+	runErr error,
+) error {
+	path := runStatePath(options)
+	if runErr == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	state := runState{
+		Tasks:     tasks,
+		Params:    options.Params,
+		Completed: make(map[string]bool),
+	}
+	for _, result := range report.Tasks {
+		switch result.Status {
+		case StatusRun, StatusSkipped, StatusWarning:
+			state.Completed[result.Name] = result.Status != StatusSkipped || previous[result.Name]
+		}
+	}
+	raw, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, raw, 0644)
+}
+
+// loadRunState reads the state of the last failed run. The tasks and params
+// of the failed run are used when options has none, otherwise they must
+// match the failed run.
+func loadRunState(options *RunOptions) (map[string]bool, error) {
+	raw, err := ioutil.ReadFile(runStatePath(*options))
+	switch {
+	case os.IsNotExist(err):
+		return nil, fmt.Errorf("there is no failed run to continue")
+	case err != nil:
+		return nil, err
+	}
+	state := runState{}
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, fmt.Errorf("failed to read the state of the last run: %s", err)
+	}
+
+	if len(options.Tasks) == 0 && len(options.Params) == 0 {
+		options.Tasks, options.Params = state.Tasks, state.Params
+		return state.Completed, nil
+	}
+	if strings.Join(options.Tasks, " ") != strings.Join(state.Tasks, " ") {
+		return nil, fmt.Errorf("can not continue, the failed run was for tasks: %s",
+			strings.Join(state.Tasks, " "))
+	}
+	return state.Completed, nil
+}
@@ -0,0 +1,54 @@
+package tasks
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestSaveAndLoadRunState(t *testing.T) {
+	dir := fs.NewDir(t, "resume")
+	defer dir.Remove()
+
+	options := RunOptions{
+		Config: &config.Config{WorkingDir: dir.Path()},
+		Params: map[string]string{"version": "1.0"},
+	}
+	report := &Report{Tasks: []TaskResult{
+		{Name: "builder:build", Status: StatusRun},
+		{Name: "source:create", Status: StatusSkipped},
+		{Name: "deps:run", Status: StatusSkipped},
+		{Name: "test:run", Status: StatusFailed},
+		{Name: "dist:run", Status: StatusNotRun},
+	}}
+	previous := map[string]bool{"deps:run": true}
+	err := saveRunState(options, []string{"release"}, report, previous, fmt.Errorf("failed"))
+	assert.NilError(t, err)
+
+	continued := RunOptions{Config: options.Config}
+	completed, err := loadRunState(&continued)
+	assert.NilError(t, err)
+	expected := map[string]bool{
+		"builder:build": true,
+		"source:create": false,
+		"deps:run":      true,
+	}
+	assert.Check(t, is.DeepEqual(completed, expected))
+	assert.Check(t, is.DeepEqual(continued.Tasks, []string{"release"}))
+	assert.Check(t, is.DeepEqual(continued.Params, options.Params))
+
+	continued = RunOptions{Config: options.Config, Tasks: []string{"test"}}
+	_, err = loadRunState(&continued)
+	assert.Check(t, is.Error(err, "can not continue, the failed run was for tasks: release"))
+
+	assert.NilError(t, saveRunState(options, []string{"release"}, report, nil, nil))
+	_, err = os.Stat(runStatePath(options))
+	assert.Check(t, os.IsNotExist(err))
+	_, err = loadRunState(&continued)
+	assert.Check(t, is.Error(err, "there is no failed run to continue"))
+}
@@ -0,0 +1,405 @@
+package tasks
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+// step is either a single task, or the branches of a parallel alias or a
+// stage of a pipeline. The tasks in a branch are run in order, and the
+// branches are run at the same time.
+type step struct {
+	task     types.TaskConfig
+	branches [][]types.TaskConfig
+}
+
+// planSteps returns the steps used to run the tasks. The tasks are run in
+// order, except for the tasks of a parallel alias, or of a stage of a
+// pipeline.
+func planSteps(tasks *TaskCollection) []step {
+	steps := []step{}
+	for _, taskConfig := range tasks.All() {
+		steps = append(steps, step{task: taskConfig})
+	}
+	for _, taskConfig := range tasks.All() {
+		if members := parallelMembers(taskConfig); len(members) > 1 {
+			steps = parallelSteps(tasks, steps, taskConfig, members)
+		}
+	}
+	return steps
+}
+
+// parallelMembers returns the tasks which are run at the same time by a
+// parallel alias, or by the action which runs a stage of a pipeline
+func parallelMembers(taskConfig types.TaskConfig) []string {
+	switch conf := taskConfig.Resource().(type) {
+	case *config.AliasConfig:
+		if conf.Parallel {
+			return conf.Tasks
+		}
+	case *config.PipelineConfig:
+		if index, ok := conf.Stage(taskConfig.Name().Action()); ok {
+			return conf.Stages[index].Tasks
+		}
+	}
+	return nil
+}
+
+// parallelSteps replaces the tasks of each member with a single parallel
+// step. Each branch of the step is a member, and the dependencies of the
+// member which are not shared with another member. The step replaces the
+// tasks at the position of the anchor, which is the alias or the stage, and
+// the tasks before the anchor which depend on a task in the step are moved
+// after it. The other dependencies of the anchor, like the previous stage of
+// a pipeline, stay before the step.
+func parallelSteps(
+	tasks *TaskCollection,
+	steps []step,
+	anchor types.TaskConfig,
+	members []string,
+) []step {
+	single := make(map[string]bool)
+	for _, step := range steps {
+		if step.task != nil {
+			single[step.task.Name().Name()] = true
+		}
+	}
+
+	isMember := make(map[string]bool)
+	for _, member := range members {
+		isMember[task.ParseName(member).MapKey()] = true
+	}
+	before := make(map[string]bool)
+	for _, dep := range orderedDependencies(anchor) {
+		depConfig := tasks.Get(task.ParseName(dep))
+		if depConfig == nil || isMember[task.ParseName(dep).MapKey()] {
+			continue
+		}
+		before[depConfig.Name().Name()] = true
+		dependencies(tasks, depConfig.Name(), before)
+	}
+
+	closures := []map[string]bool{}
+	counts := make(map[string]int)
+	for _, member := range members {
+		memberConfig := tasks.Get(task.ParseName(member))
+		if memberConfig == nil {
+			continue
+		}
+		closure := map[string]bool{memberConfig.Name().Name(): true}
+		dependencies(tasks, memberConfig.Name(), closure)
+		for name := range closure {
+			if before[name] {
+				delete(closure, name)
+				continue
+			}
+			counts[name]++
+		}
+		closures = append(closures, closure)
+	}
+
+	branchOf := make(map[string]int)
+	for i, closure := range closures {
+		for name := range closure {
+			if counts[name] == 1 && single[name] {
+				branchOf[name] = i
+			}
+		}
+	}
+	if len(closures) < 2 {
+		return steps
+	}
+
+	branches := make([][]types.TaskConfig, len(closures))
+	result := []step{}
+	deferred := []step{}
+	seenAlias := false
+	for _, current := range steps {
+		switch {
+		case seenAlias:
+			result = append(result, current)
+		case current.task != nil && current.task.Name().Name() == anchor.Name().Name():
+			seenAlias = true
+			result = append(result, step{branches: nonEmptyBranches(branches)})
+			result = append(result, deferred...)
+			result = append(result, current)
+		case current.task != nil && isBranchTask(branchOf, current.task):
+			index := branchOf[current.task.Name().Name()]
+			branches[index] = append(branches[index], current.task)
+		case stepDependsOn(tasks, current, branchOf):
+			deferred = append(deferred, current)
+		default:
+			result = append(result, current)
+		}
+	}
+	return result
+}
+
+func isBranchTask(branchOf map[string]int, taskConfig types.TaskConfig) bool {
+	_, ok := branchOf[taskConfig.Name().Name()]
+	return ok
+}
+
+func nonEmptyBranches(branches [][]types.TaskConfig) [][]types.TaskConfig {
+	result := [][]types.TaskConfig{}
+	for _, branch := range branches {
+		if len(branch) > 0 {
+			result = append(result, branch)
+		}
+	}
+	return result
+}
+
+// stepDependsOn returns true if any task in the step depends on one of the
+// named tasks
+func stepDependsOn(tasks *TaskCollection, current step, names map[string]int) bool {
+	taskConfigs := []types.TaskConfig{}
+	if current.task != nil {
+		taskConfigs = append(taskConfigs, current.task)
+	}
+	for _, branch := range current.branches {
+		taskConfigs = append(taskConfigs, branch...)
+	}
+	for _, taskConfig := range taskConfigs {
+		deps := make(map[string]bool)
+		dependencies(tasks, taskConfig.Name(), deps)
+		for name := range deps {
+			if _, ok := names[name]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkParallelArtifacts returns an error if jobs in different branches of a
+// step write to overlapping artifact paths. The branches run at the same
+// time, so the jobs would overwrite each other's artifacts.
+func checkParallelArtifacts(steps []step) error {
+	for _, current := range steps {
+		seen := []branchArtifact{}
+		for i, branch := range current.branches {
+			for _, taskConfig := range branch {
+				job, ok := taskConfig.Resource().(*config.JobConfig)
+				if !ok {
+					continue
+				}
+				for _, glob := range job.Artifact.Globs() {
+					artifact := branchArtifact{
+						branch: i,
+						task:   taskConfig.Name().Name(),
+						glob:   glob,
+					}
+					for _, other := range seen {
+						if other.branch != i && artifactsOverlap(other.glob, glob) {
+							return fmt.Errorf(
+								"%q and %q can not run in parallel, "+
+									"because the artifacts %q and %q overlap",
+								other.task, artifact.task, other.glob, artifact.glob)
+						}
+					}
+					seen = append(seen, artifact)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// branchArtifact is an artifact of a job in a branch of a parallel step
+type branchArtifact struct {
+	branch int
+	task   string
+	glob   string
+}
+
+// artifactsOverlap returns true if a path matched by one glob could be the
+// same as, or inside, a path matched by the other glob
+func artifactsOverlap(one, two string) bool {
+	oneElements, twoElements := splitPath(one), splitPath(two)
+	for i := 0; i < len(oneElements) && i < len(twoElements); i++ {
+		if !elementsMayMatch(oneElements[i], twoElements[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func elementsMayMatch(one, two string) bool {
+	switch {
+	case hasGlobMeta(one) && hasGlobMeta(two):
+		return true
+	case hasGlobMeta(one):
+		matched, _ := filepath.Match(one, two)
+		return matched
+	case hasGlobMeta(two):
+		matched, _ := filepath.Match(two, one)
+		return matched
+	}
+	return one == two
+}
+
+func splitPath(path string) []string {
+	path = filepath.ToSlash(filepath.Clean(path))
+	if path == "." {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// continueOnErrorTasks returns the names of the tasks which are run by a
+// continue-on-error alias, including the dependencies of the tasks in the
+// alias
+func continueOnErrorTasks(tasks *TaskCollection) map[string]bool {
+	names := make(map[string]bool)
+	for _, taskConfig := range tasks.All() {
+		alias, ok := taskConfig.Resource().(*config.AliasConfig)
+		if !ok || !alias.ContinueOnError {
+			continue
+		}
+		for _, member := range alias.Tasks {
+			memberConfig := tasks.Get(task.ParseName(member))
+			if memberConfig == nil {
+				continue
+			}
+			names[memberConfig.Name().Name()] = true
+			dependencies(tasks, memberConfig.Name(), names)
+		}
+	}
+	return names
+}
+
+// executor runs the steps, and records the tasks which were started or
+// failed
+type executor struct {
+	ctx       *context.ExecuteContext
+	report    *Report
+	keepGoing bool
+	// continueOnError are the tasks which may fail without stopping the
+	// execution, because they are run by a continue-on-error alias
+	continueOnError map[string]bool
+	failed          *failedTasks
+	// slots limits the number of tasks of a parallel step which run at the
+	// same time. It is nil when there is no limit.
+	slots chan struct{}
+
+	mu      sync.Mutex
+	started []types.Task
+}
+
+func newExecutor(
+	ctx *context.ExecuteContext,
+	tasks *TaskCollection,
+	report *Report,
+	keepGoing bool,
+) *executor {
+	exec := &executor{
+		ctx:             ctx,
+		report:          report,
+		keepGoing:       keepGoing,
+		continueOnError: continueOnErrorTasks(tasks),
+		failed:          newFailedTasks(),
+	}
+	if ctx.Parallelism > 0 {
+		exec.slots = make(chan struct{}, ctx.Parallelism)
+	}
+	return exec
+}
+
+func (e *executor) startedTasks() []types.Task {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.started
+}
+
+func (e *executor) addStarted(started types.Task) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.started = append(e.started, started)
+}
+
+func (e *executor) runStep(current step) error {
+	if current.task != nil {
+		return e.run(current.task)
+	}
+
+	errs := make([]error, len(current.branches))
+	wg := sync.WaitGroup{}
+	for i, branch := range current.branches {
+		wg.Add(1)
+		go func(i int, branch []types.TaskConfig) {
+			defer wg.Done()
+			for _, taskConfig := range branch {
+				if err := e.runInSlot(taskConfig); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}(i, branch)
+	}
+	wg.Wait()
+
+	if err := interruptedErr(e.ctx); err != nil {
+		return err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runInSlot runs a task of a parallel step once fewer than ctx.Parallelism
+// tasks are running
+func (e *executor) runInSlot(taskConfig types.TaskConfig) error {
+	if e.slots == nil {
+		return e.run(taskConfig)
+	}
+	e.slots <- struct{}{}
+	defer func() { <-e.slots }()
+	return e.run(taskConfig)
+}
+
+// run a task. An error is returned when the execution should stop.
+func (e *executor) run(taskConfig types.TaskConfig) error {
+	if err := interruptedErr(e.ctx); err != nil {
+		return err
+	}
+	name := taskConfig.Name()
+	if dep, ok := e.failed.dependsOnFailed(taskConfig.Dependencies()); ok {
+		logging.Log.Warnf("Not running %q because %q failed", name, dep)
+		e.failed.add(name, nil)
+		e.report.record(func() {
+			e.report.Tasks = append(e.report.Tasks,
+				TaskResult{Name: name.Name(), Status: StatusNotRun})
+		})
+		if e.keepGoing || e.continueOnError[name.Name()] {
+			return nil
+		}
+		return e.failed.err()
+	}
+
+	err := executeTask(e.ctx, taskConfig, e.report, e.addStarted)
+	if err == nil {
+		return nil
+	}
+	if interrupted := interruptedErr(e.ctx); interrupted != nil {
+		logging.Log.Error(err)
+		return interrupted
+	}
+	if !e.keepGoing && !e.continueOnError[name.Name()] {
+		return err
+	}
+	logging.Log.Error(err)
+	e.failed.add(name, err)
+	return nil
+}
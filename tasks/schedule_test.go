@@ -0,0 +1,353 @@
+package tasks
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/env"
+)
+
+// scheduleTask records the tasks which ran. Tasks in a parallel step run
+// concurrently, so the names are recorded with a lock.
+type scheduleTask struct {
+	types.NoStop
+	name task.Name
+	err  error
+	ran  *stoppedTasks
+	run  func()
+}
+
+func (t *scheduleTask) Name() task.Name {
+	return t.name
+}
+
+func (t *scheduleTask) Repr() string {
+	return t.name.Name()
+}
+
+func (t *scheduleTask) Run(_ *context.ExecuteContext, _ bool) (bool, error) {
+	t.ran.add(t.name.Name())
+	if t.run != nil {
+		t.run()
+	}
+	return true, t.err
+}
+
+type scheduleTasks struct {
+	tasks *TaskCollection
+	ran   *stoppedTasks
+}
+
+func newScheduleTasks() *scheduleTasks {
+	return &scheduleTasks{tasks: newTaskCollection(), ran: &stoppedTasks{}}
+}
+
+func (s *scheduleTasks) add(name string, err error, run func(), deps ...string) {
+	s.tasks.add(types.NewTaskConfig(
+		task.NewName(name, "run"),
+		&config.EnvConfig{},
+		func() []string { return deps },
+		func(name task.Name, _ config.Resource) types.Task {
+			return &scheduleTask{name: name, err: err, ran: s.ran, run: run}
+		}))
+}
+
+func (s *scheduleTasks) addAlias(name string, alias *config.AliasConfig) {
+	s.tasks.add(types.NewTaskConfig(
+		task.NewName(name, "run"),
+		alias,
+		func() []string { return alias.Tasks },
+		func(name task.Name, _ config.Resource) types.Task {
+			return &scheduleTask{name: name, ran: s.ran}
+		}))
+}
+
+func stepNames(steps []step) []interface{} {
+	names := []interface{}{}
+	for _, current := range steps {
+		if current.task != nil {
+			names = append(names, current.task.Name().Name())
+			continue
+		}
+		branches := [][]string{}
+		for _, branch := range current.branches {
+			branchNames := []string{}
+			for _, taskConfig := range branch {
+				branchNames = append(branchNames, taskConfig.Name().Name())
+			}
+			branches = append(branches, branchNames)
+		}
+		names = append(names, branches)
+	}
+	return names
+}
+
+func TestPlanStepsParallelAlias(t *testing.T) {
+	s := newScheduleTasks()
+	s.add("shared", nil, nil)
+	s.add("gen", nil, nil)
+	s.add("vet", nil, nil, "shared:run", "gen:run")
+	s.add("lint", nil, nil, "shared:run")
+	s.addAlias("check", &config.AliasConfig{
+		Tasks:    []string{"vet:run", "lint:run"},
+		Parallel: true,
+	})
+	s.add("publish", nil, nil, "check:run")
+
+	expected := []interface{}{
+		"shared:run",
+		[][]string{{"gen:run", "vet:run"}, {"lint:run"}},
+		"check:run",
+		"publish:run",
+	}
+	assert.Check(t, is.DeepEqual(stepNames(planSteps(s.tasks)), expected))
+}
+
+func TestPlanStepsMovesDependentsAfterParallelStep(t *testing.T) {
+	s := newScheduleTasks()
+	s.add("one", nil, nil)
+	s.add("report", nil, nil, "one:run")
+	s.add("two", nil, nil)
+	s.addAlias("all", &config.AliasConfig{Tasks: []string{"one:run", "two:run"}, Parallel: true})
+
+	expected := []interface{}{
+		[][]string{{"one:run"}, {"two:run"}},
+		"report:run",
+		"all:run",
+	}
+	assert.Check(t, is.DeepEqual(stepNames(planSteps(s.tasks)), expected))
+}
+
+func TestPlanStepsPipelineStages(t *testing.T) {
+	runOptions := RunOptions{
+		Config: &config.Config{
+			Resources: map[string]config.Resource{
+				"build":   aliasWithDeps([]string{}),
+				"test":    aliasWithDeps([]string{"build"}),
+				"lint":    aliasWithDeps([]string{}),
+				"publish": aliasWithDeps([]string{}),
+				"release": &config.PipelineConfig{Stages: []config.PipelineStage{
+					{Tasks: []string{"build"}},
+					{Tasks: []string{"test", "lint"}},
+					{Tasks: []string{"publish"}},
+				}},
+			},
+		},
+		Tasks: []string{"release"},
+	}
+	tasks, err := collectTasks(runOptions)
+	assert.NilError(t, err)
+
+	expected := []interface{}{
+		"build:run",
+		[][]string{{"test:run"}, {"lint:run"}},
+		"release:stage-2",
+		"publish:run",
+		"release:run",
+	}
+	assert.Check(t, is.DeepEqual(stepNames(planSteps(tasks)), expected))
+}
+
+func TestExecuteTasksParallelAlias(t *testing.T) {
+	started := make(chan string, 2)
+	// each task waits until the other has started, so the test only passes
+	// when the tasks run at the same time
+	waitForOther := func(name string) func() {
+		return func() {
+			started <- name
+			select {
+			case <-time.After(5 * time.Second):
+			case other := <-started:
+				started <- other
+			}
+		}
+	}
+
+	s := newScheduleTasks()
+	s.add("one", nil, waitForOther("one"))
+	s.add("two", nil, waitForOther("two"))
+	s.addAlias("all", &config.AliasConfig{Tasks: []string{"one:run", "two:run"}, Parallel: true})
+
+	ctx := context.NewExecuteContext(
+		config.NewConfig(), nil, execenv.NewExecEnv("exec", "project", "/dir"), context.Settings{})
+	report := &Report{}
+	timeout := time.AfterFunc(10*time.Second, func() { panic("tasks did not run in parallel") })
+	defer timeout.Stop()
+	assert.NilError(t, executeTasks(ctx, s.tasks, report, false))
+
+	assert.Check(t, is.Len(report.Tasks, 3))
+	assert.Check(t, is.Equal(report.Tasks[2].Name, "all:run"))
+}
+
+func TestExecuteTasksParallelAliasWithParallelismLimit(t *testing.T) {
+	mu := sync.Mutex{}
+	running, most := 0, 0
+	track := func() {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}
+
+	s := newScheduleTasks()
+	s.add("one", nil, track)
+	s.add("two", nil, track)
+	s.add("three", nil, track)
+	s.addAlias("all", &config.AliasConfig{
+		Tasks:    []string{"one:run", "two:run", "three:run"},
+		Parallel: true,
+	})
+
+	ctx := context.NewExecuteContext(
+		config.NewConfig(), nil, execenv.NewExecEnv("exec", "project", "/dir"), context.Settings{})
+	ctx.Parallelism = 1
+	assert.NilError(t, executeTasks(ctx, s.tasks, &Report{}, false))
+	assert.Check(t, is.Equal(most, 1))
+	assert.Check(t, is.Len(s.ran.names, 4))
+}
+
+// staticProvider is a secret provider with fixed values
+type staticProvider map[string]string
+
+func (p staticProvider) Lookup(ref string) (string, error) {
+	return p[ref], nil
+}
+
+func TestExecuteTasksParallelResolvesVariables(t *testing.T) {
+	defer env.Patch(t, "DOBI_TEST_REGISTRY", "registry.example")()
+	execEnv := execenv.NewExecEnv("exec", "project", "/dir")
+	execEnv.SetProvider("secret", staticProvider{"token": "abcd"})
+
+	// both branches resolve the same variables after the other branch has
+	// started, so that the cached values are read and written at the same
+	// time. The values are checked after the run, because the assertions
+	// synchronize the branches.
+	started := make(chan struct{})
+	var once sync.Once
+	ready := &sync.WaitGroup{}
+	ready.Add(2)
+	newResolve := func(values *[]string) func() {
+		return func() {
+			ready.Done()
+			once.Do(func() {
+				go func() {
+					ready.Wait()
+					close(started)
+				}()
+			})
+			<-started
+			for i := 0; i < 50; i++ {
+				value, err := execEnv.Resolve(
+					fmt.Sprintf("{env.DOBI_TEST_REGISTRY}/app-%d:{secret:token}", i))
+				if err != nil {
+					value = err.Error()
+				}
+				*values = append(*values, value)
+			}
+		}
+	}
+	var one, two []string
+	s := newScheduleTasks()
+	s.add("one", nil, newResolve(&one))
+	s.add("two", nil, newResolve(&two))
+	s.addAlias("all", &config.AliasConfig{Tasks: []string{"one:run", "two:run"}, Parallel: true})
+
+	ctx := context.NewExecuteContext(config.NewConfig(), nil, execEnv, context.Settings{})
+	assert.NilError(t, executeTasks(ctx, s.tasks, &Report{}, false))
+
+	expected := []string{}
+	for i := 0; i < 50; i++ {
+		expected = append(expected, fmt.Sprintf("registry.example/app-%d:abcd", i))
+	}
+	assert.Check(t, is.DeepEqual(one, expected))
+	assert.Check(t, is.DeepEqual(two, expected))
+}
+
+func TestExecuteTasksContinueOnError(t *testing.T) {
+	s := newScheduleTasks()
+	s.add("lint", fmt.Errorf("lint failed"), nil)
+	s.add("vet", nil, nil)
+	s.add("fmt", fmt.Errorf("fmt failed"), nil)
+	s.addAlias("check", &config.AliasConfig{
+		Tasks:           []string{"lint:run", "vet:run", "fmt:run"},
+		ContinueOnError: true,
+	})
+	s.add("publish", nil, nil, "check:run")
+
+	ctx := context.NewExecuteContext(
+		config.NewConfig(), nil, execenv.NewExecEnv("exec", "project", "/dir"), context.Settings{})
+	report := &Report{}
+	err := executeTasks(ctx, s.tasks, report, false)
+	assert.Check(t, is.ErrorContains(err, "2 tasks failed"))
+	assert.Check(t, is.ErrorContains(err, `"lint:run": lint failed`))
+	assert.Check(t, is.ErrorContains(err, `"fmt:run": fmt failed`))
+	assert.Check(t, is.DeepEqual(s.ran.names, []string{"lint:run", "vet:run", "fmt:run"}))
+
+	statuses := []string{}
+	for _, result := range report.Tasks {
+		statuses = append(statuses, result.Status)
+	}
+	expected := []string{StatusFailed, StatusRun, StatusFailed, StatusNotRun}
+	assert.Check(t, is.DeepEqual(statuses, expected))
+}
+
+func (s *scheduleTasks) addJob(name string, artifacts ...interface{}) {
+	job := &config.JobConfig{}
+	if err := job.Artifact.TransformConfig(reflect.ValueOf(artifacts)); err != nil {
+		panic(err)
+	}
+	s.tasks.add(types.NewTaskConfig(
+		task.NewName(name, "run"),
+		job,
+		func() []string { return nil },
+		func(name task.Name, _ config.Resource) types.Task {
+			return &scheduleTask{name: name, ran: s.ran}
+		}))
+}
+
+func TestCheckParallelArtifacts(t *testing.T) {
+	var testcases = []struct {
+		one, two string
+		overlap  bool
+	}{
+		{one: "dist/", two: "dist", overlap: true},
+		{one: "dist/app", two: "dist/", overlap: true},
+		{one: "dist/*.tar.gz", two: "dist/app.tar.gz", overlap: true},
+		{one: "*", two: "dist/app", overlap: true},
+		{one: "dist/", two: "reports/", overlap: false},
+		{one: "*.log", two: "reports/", overlap: false},
+		{one: "dist/*.tar.gz", two: "dist/app.zip", overlap: false},
+	}
+	for _, testcase := range testcases {
+		s := newScheduleTasks()
+		s.addJob("one", testcase.one)
+		s.addJob("two", testcase.two)
+		s.addAlias("all", &config.AliasConfig{
+			Tasks:    []string{"one:run", "two:run"},
+			Parallel: true,
+		})
+
+		err := checkParallelArtifacts(planSteps(s.tasks))
+		if !testcase.overlap {
+			assert.Check(t, err, testcase)
+			continue
+		}
+		assert.Check(t, is.ErrorContains(err, "can not run in parallel"), testcase)
+	}
+}
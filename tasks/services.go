@@ -0,0 +1,61 @@
+package tasks
+
+import (
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/task"
+)
+
+// serviceActions returns the actions which start and stop a resource, and
+// true if the resource is annotated as a service
+func serviceActions(resource config.Resource) (string, string, bool) {
+	switch conf := resource.(type) {
+	case *config.JobConfig:
+		return "start", "stop", conf.IsService()
+	case *config.ComposeConfig:
+		return "detach", "down", conf.IsService()
+	default:
+		return "", "", false
+	}
+}
+
+// ServiceNames returns the names of the resources which are annotated as a
+// service, in sorted order
+func ServiceNames(conf *config.Config) []string {
+	names := []string{}
+	for _, name := range conf.Sorted() {
+		if _, _, ok := serviceActions(conf.Resources[name]); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// startTaskName returns the task which starts the resource when it is a
+// service, and the task uses the default action
+func startTaskName(name task.Name, resource config.Resource) task.Name {
+	start, _, ok := serviceActions(resource)
+	if !ok || name.Action() != "" {
+		return name
+	}
+	return task.NewName(name.Resource(), start)
+}
+
+// stopTaskNames returns the tasks which stop the services, in the reverse of
+// the order they are started
+func stopTaskNames(options RunOptions) ([]string, error) {
+	options.Up, options.Down = true, false
+	tasks, err := collectTasks(options)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	all := tasks.All()
+	for i := len(all) - 1; i >= 0; i-- {
+		name := all[i].Name()
+		start, stop, ok := serviceActions(options.Config.Resources[name.Resource()])
+		if ok && name.Action() == start {
+			names = append(names, task.NewName(name.Resource(), stop).Name())
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,54 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func serviceJob(use string, depends ...string) *config.JobConfig {
+	job := &config.JobConfig{Use: use}
+	job.Depends = depends
+	job.Annotations.Annotations.Service = true
+	return job
+}
+
+func servicesConfig() *config.Config {
+	db := &config.ComposeConfig{}
+	db.Annotations.Annotations.Service = true
+	return &config.Config{
+		Resources: map[string]config.Resource{
+			"builder": &config.ImageConfig{Context: ".", Dockerfile: "Dockerfile"},
+			"api":     serviceJob("builder", "db"),
+			"web":     serviceJob("builder", "api"),
+			"db":      db,
+			"test":    &config.JobConfig{Use: "builder"},
+		},
+	}
+}
+
+func TestServiceNames(t *testing.T) {
+	assert.Check(t, is.DeepEqual(ServiceNames(servicesConfig()), []string{"api", "db", "web"}))
+}
+
+func TestCollectTasksUp(t *testing.T) {
+	conf := servicesConfig()
+	tasks, err := collectTasks(RunOptions{Config: conf, Tasks: ServiceNames(conf), Up: true})
+	assert.NilError(t, err)
+
+	names := []string{}
+	for _, taskConfig := range tasks.All() {
+		names = append(names, taskConfig.Name().Name())
+	}
+	expected := []string{"builder:build", "db:detach", "api:start", "web:start"}
+	assert.Check(t, is.DeepEqual(names, expected))
+}
+
+func TestStopTaskNames(t *testing.T) {
+	conf := servicesConfig()
+	names, err := stopTaskNames(RunOptions{Config: conf, Tasks: ServiceNames(conf), Down: true})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(names, []string{"web:stop", "api:stop", "db:down"}))
+}
@@ -0,0 +1,59 @@
+package tasks
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+)
+
+// InterruptedError is returned when the tasks are stopped by a signal
+type InterruptedError struct {
+	Signal os.Signal
+}
+
+func (e InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted by %s", e.Signal)
+}
+
+// ExitCode returns the exit code used by shells for a process which was
+// stopped by the signal
+func (e InterruptedError) ExitCode() int {
+	if sig, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+	return 128 + int(syscall.SIGINT)
+}
+
+// handleSignals marks the execution as interrupted on SIGINT or SIGTERM, so
+// that no more tasks are started and the started tasks are stopped. A second
+// signal exits immediately. The returned function stops handling signals.
+func handleSignals(ctx *context.ExecuteContext) func() {
+	chanSig := make(chan os.Signal, 2)
+	signal.Notify(chanSig, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		for sig := range chanSig {
+			if ctx.Interrupted() != nil {
+				logging.Log.Warnf("Received %s again, exiting without stopping tasks", sig)
+				os.Exit(InterruptedError{Signal: sig}.ExitCode())
+			}
+			logging.Log.Warnf("Received %s, stopping tasks", sig)
+			ctx.Interrupt(sig)
+		}
+	}()
+	return func() {
+		signal.Stop(chanSig)
+		close(chanSig)
+	}
+}
+
+func interruptedErr(ctx *context.ExecuteContext) error {
+	if sig := ctx.Interrupted(); sig != nil {
+		return InterruptedError{Signal: sig}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package tasks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/image"
+	"github.com/dnephin/dobi/tasks/task"
+)
+
+// parseSkip returns the set of resource and task names from --skip
+func parseSkip(conf *config.Config, items []string) (map[string]bool, error) {
+	return parseTaskSelectors(conf, "skip", items)
+}
+
+// parseForce returns the set of resource and task names from --force
+func parseForce(conf *config.Config, items []string) (map[string]bool, error) {
+	return parseTaskSelectors(conf, "force", items)
+}
+
+// parseTaskSelectors returns the set of names selected by the items of a flag.
+// Each item is the name of a resource, type=name, or the name of a task in the
+// form resource:action, which selects only that task.
+func parseTaskSelectors(
+	conf *config.Config,
+	flag string,
+	items []string,
+) (map[string]bool, error) {
+	selected := make(map[string]bool, len(items))
+	for _, item := range items {
+		name, resType := item, ""
+		if parts := strings.SplitN(item, "=", 2); len(parts) == 2 {
+			resType, name = parts[0], parts[1]
+		}
+		resource, ok := conf.Resources[task.ParseName(name).Resource()]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("can not %s %q, the resource does not exist", flag, item)
+		case resType != "" && !config.IsResourceType(resource, resType):
+			return nil, fmt.Errorf("can not %s %q, %s is a %s resource",
+				flag, item, name, config.ResourceType(resource))
+		}
+		selected[name] = true
+	}
+	return selected, nil
+}
+
+// isSelected returns true if the task, or the resource of the task, is in the
+// set of names from parseTaskSelectors
+func isSelected(selected map[string]bool, name task.Name) bool {
+	return selected[name.Resource()] || selected[name.Name()]
+}
+
+// checkSkipAndForce returns an error if a task is selected by both --skip and
+// --force
+func checkSkipAndForce(skip, force map[string]bool) error {
+	for name := range force {
+		if isSelected(skip, task.ParseName(name)) {
+			return fmt.Errorf("can not both skip and force %q", name)
+		}
+	}
+	return nil
+}
+
+// checkSkipped returns an error if the image or artifact of a skipped
+// resource does not exist, because the tasks which use it would fail
+func checkSkipped(ctx *context.ExecuteContext, name string, resource config.Resource) error {
+	switch conf := resource.(type) {
+	case *config.ImageConfig:
+		if _, err := image.GetImage(ctx, conf); err != nil {
+			return fmt.Errorf("can not skip %q, image %s does not exist: %s",
+				name, image.GetImageName(ctx, conf), err)
+		}
+	case *config.JobConfig:
+		if !conf.Artifact.Empty() && len(conf.Artifact.Paths()) == 0 {
+			return fmt.Errorf("can not skip %q, artifact %s does not exist",
+				name, &conf.Artifact)
+		}
+	}
+	return nil
+}
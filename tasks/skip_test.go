@@ -0,0 +1,70 @@
+package tasks
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+)
+
+func TestParseSkip(t *testing.T) {
+	conf := &config.Config{
+		Resources: map[string]config.Resource{
+			"builder": &config.ImageConfig{},
+			"test":    &config.JobConfig{},
+		},
+	}
+	skip, err := parseSkip(conf, []string{"image=builder", "test"})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(skip, map[string]bool{"builder": true, "test": true}))
+
+	_, err = parseSkip(conf, []string{"job=builder"})
+	assert.Check(t, is.ErrorContains(err,
+		`can not skip "job=builder", builder is a image resource`))
+
+	_, err = parseSkip(conf, []string{"missing"})
+	assert.Check(t, is.ErrorContains(err, `can not skip "missing", the resource does not exist`))
+}
+
+func TestParseForceTaskNames(t *testing.T) {
+	conf := &config.Config{
+		Resources: map[string]config.Resource{
+			"builder": &config.ImageConfig{},
+			"test":    &config.JobConfig{},
+		},
+	}
+	force, err := parseForce(conf, []string{"builder:build", "test"})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(force, map[string]bool{"builder:build": true, "test": true}))
+	assert.Check(t, isSelected(force, task.NewName("builder", "build")))
+	assert.Check(t, !isSelected(force, task.NewName("builder", "push")))
+	assert.Check(t, isSelected(force, task.NewName("test", "run")))
+
+	_, err = parseForce(conf, []string{"missing:run"})
+	assert.Check(t, is.ErrorContains(err,
+		`can not force "missing:run", the resource does not exist`))
+
+	skip := map[string]bool{"builder": true}
+	err = checkSkipAndForce(skip, force)
+	assert.Check(t, is.Error(err, `can not both skip and force "builder:build"`))
+}
+
+func TestCheckSkippedJobArtifact(t *testing.T) {
+	dir := fs.NewDir(t, "skip", fs.WithDir("dist", fs.WithFile("app", "")))
+	defer dir.Remove()
+
+	job := &config.JobConfig{}
+	assert.NilError(t, job.Artifact.TransformConfig(reflect.ValueOf(dir.Join("dist/app"))))
+	ctx := &context.ExecuteContext{WorkingDir: dir.Path()}
+	assert.Check(t, checkSkipped(ctx, "build", job))
+
+	job = &config.JobConfig{}
+	assert.NilError(t, job.Artifact.TransformConfig(reflect.ValueOf(dir.Join("dist/other"))))
+	assert.Check(t, is.ErrorContains(checkSkipped(ctx, "build", job),
+		`can not skip "build", artifact`))
+}
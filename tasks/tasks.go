@@ -1,14 +1,21 @@
 package tasks
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dnephin/dobi/config"
 	"github.com/dnephin/dobi/execenv"
 	"github.com/dnephin/dobi/logging"
 	"github.com/dnephin/dobi/tasks/alias"
+	"github.com/dnephin/dobi/tasks/checkout"
 	"github.com/dnephin/dobi/tasks/client"
 	"github.com/dnephin/dobi/tasks/compose"
 	"github.com/dnephin/dobi/tasks/context"
@@ -16,7 +23,9 @@ import (
 	"github.com/dnephin/dobi/tasks/image"
 	"github.com/dnephin/dobi/tasks/job"
 	"github.com/dnephin/dobi/tasks/mount"
+	"github.com/dnephin/dobi/tasks/pipeline"
 	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/template"
 	"github.com/dnephin/dobi/tasks/types"
 	log "github.com/sirupsen/logrus"
 )
@@ -51,14 +60,18 @@ func newTaskCollection() *TaskCollection {
 
 func collectTasks(options RunOptions) (*TaskCollection, error) {
 	return collect(options, &collectionState{
-		newTaskCollection(),
-		task.NewStack(),
+		tasks:     newTaskCollection(),
+		taskStack: task.NewStack(),
+		visited:   make(map[string]bool),
 	})
 }
 
 type collectionState struct {
 	tasks     *TaskCollection
 	taskStack *task.Stack
+	// visited is the set of resource:action names which have been collected,
+	// so that a task shared by multiple resources is only run once
+	visited map[string]bool
 }
 
 func collect(options RunOptions, state *collectionState) (*TaskCollection, error) {
@@ -69,28 +82,47 @@ func collect(options RunOptions, state *collectionState) (*TaskCollection, error
 		if !ok {
 			return nil, fmt.Errorf("resource %q does not exist", resourceName)
 		}
+		if options.Up {
+			taskname = startTaskName(taskname, resource)
+		}
 
 		taskConfig, err := buildTaskConfig(resourceName, taskname.Action(), resource)
 		if err != nil {
 			return nil, err
 		}
 
+		if state.visited[taskConfig.Name().Name()] {
+			continue
+		}
 		if state.taskStack.Contains(taskConfig.Name()) {
 			return nil, fmt.Errorf(
 				"Invalid dependency cycle: %s", strings.Join(state.taskStack.Names(), ", "))
 		}
 		state.taskStack.Push(taskConfig.Name())
 
-		options.Tasks = taskConfig.Dependencies()
+		options.Tasks = orderedDependencies(taskConfig)
 		if _, err := collect(options, state); err != nil {
 			return nil, err
 		}
 		state.tasks.add(taskConfig)
+		state.visited[taskConfig.Name().Name()] = true
 		state.taskStack.Pop() // nolint: errcheck
 	}
 	return state.tasks, nil
 }
 
+// orderedDependencies returns the tasks which must run before the task. These
+// are the dependencies of the task, and the tasks from the after field of the
+// resource. Removing a resource does not need the tasks from after.
+func orderedDependencies(taskConfig types.TaskConfig) []string {
+	deps := append([]string{}, taskConfig.Dependencies()...)
+	switch taskConfig.Name().Action() {
+	case "rm", "remove", "down":
+		return deps
+	}
+	return append(deps, config.RunAfter(taskConfig.Resource())...)
+}
+
 // TODO: some way to make this a registry
 func buildTaskConfig(name, action string, resource config.Resource) (types.TaskConfig, error) {
 	switch conf := resource.(type) {
@@ -102,10 +134,16 @@ func buildTaskConfig(name, action string, resource config.Resource) (types.TaskC
 		return mount.GetTaskConfig(name, action, conf)
 	case *config.AliasConfig:
 		return alias.GetTaskConfig(name, action, conf)
+	case *config.PipelineConfig:
+		return pipeline.GetTaskConfig(name, action, conf)
 	case *config.EnvConfig:
 		return env.GetTaskConfig(name, action, conf)
 	case *config.ComposeConfig:
 		return compose.GetTaskConfig(name, action, conf)
+	case *config.TemplateConfig:
+		return template.GetTaskConfig(name, action, conf)
+	case *config.CheckoutConfig:
+		return checkout.GetTaskConfig(name, action, conf)
 	default:
 		panic(fmt.Sprintf("Unexpected config type %T", conf))
 	}
@@ -119,47 +157,237 @@ func reversed(tasks []types.Task) []types.Task {
 	return reversed
 }
 
-func executeTasks(ctx *context.ExecuteContext, tasks *TaskCollection) error {
-	startedTasks := []types.Task{}
+func executeTasks(
+	ctx *context.ExecuteContext,
+	tasks *TaskCollection,
+	report *Report,
+	keepGoing bool,
+) (err error) {
+	exec := newExecutor(ctx, tasks, report, keepGoing)
 
 	defer func() {
-		logging.Log.Debug("stopping tasks")
-		for _, startedTask := range reversed(startedTasks) {
-			if err := startedTask.Stop(ctx); err != nil {
-				logging.Log.Warnf("Failed to stop task %q: %s", startedTask.Name(), err)
-			}
+		if stopErr := stopTasks(ctx, tasks, exec.startedTasks()); err == nil {
+			err = stopErr
 		}
 	}()
 
 	logging.Log.Debug("executing tasks")
-	for _, taskConfig := range tasks.All() {
-		resource, err := taskConfig.Resource().Resolve(ctx.Env)
-		if err != nil {
+	steps := planSteps(tasks)
+	if err := checkParallelArtifacts(steps); err != nil {
+		return err
+	}
+	for _, step := range steps {
+		if err := exec.runStep(step); err != nil {
 			return err
 		}
-		ctx.Resources.Add(taskConfig.Name().Resource(), resource)
+	}
+	if err := interruptedErr(ctx); err != nil {
+		return err
+	}
+	return exec.failed.err()
+}
 
-		currentTask := taskConfig.Task(resource)
-		startedTasks = append(startedTasks, currentTask)
-		start := time.Now()
-		logging.Log.WithFields(log.Fields{"time": start, "task": currentTask}).Debug("Start")
+func executeTask(
+	ctx *context.ExecuteContext,
+	taskConfig types.TaskConfig,
+	report *Report,
+	started func(types.Task),
+) error {
+	resolver := resolverForResource(ctx, taskConfig.Resource())
+	resource, err := taskConfig.Resource().Resolve(resolver)
+	if err != nil {
+		return err
+	}
+	ctx.Resources.Add(taskConfig.Name().Resource(), resource)
+
+	taskCtx, err := contextForResource(ctx, resource)
+	if err != nil {
+		return err
+	}
 
-		depsModified := hasModifiedDeps(ctx, taskConfig.Dependencies())
-		modified, err := currentTask.Run(ctx, depsModified)
+	if isSelected(ctx.Settings.Skip, taskConfig.Name()) {
+		start := time.Now()
+		err := checkSkipped(taskCtx, taskConfig.Name().Resource(), resource)
+		report.record(func() {
+			report.add(ctx, taskConfig.Name().Name(), resource, start, false, err)
+		})
 		if err != nil {
-			return fmt.Errorf("failed to execute task %q: %s", currentTask.Name(), err)
+			return err
 		}
+		logging.Log.WithFields(log.Fields{"task": taskConfig.Name()}).Info("Skipped by --skip")
+		return nil
+	}
+
+	if modified, ok := ctx.Settings.Completed[taskConfig.Name().Name()]; ok {
+		report.record(func() {
+			report.add(ctx, taskConfig.Name().Name(), resource, time.Now(), false, nil)
+		})
 		if modified {
-			ctx.SetModified(currentTask.Name())
+			ctx.SetModified(taskConfig.Name())
+		}
+		logging.Log.WithFields(log.Fields{"task": taskConfig.Name()}).Info("Skipped by --continue")
+		return nil
+	}
+
+	currentTask := taskConfig.Task(resource)
+	started(currentTask)
+	report.events.taskStarted(currentTask.Name().Name())
+	start := time.Now()
+	logging.Log.WithFields(log.Fields{"time": start, "task": currentTask}).Debug("Start")
+
+	depsModified := hasModifiedDeps(ctx, taskConfig.Dependencies())
+	if isSelected(ctx.Settings.Force, taskConfig.Name()) {
+		logging.Log.WithFields(log.Fields{"task": currentTask}).Debug("Forced by --force")
+		depsModified = true
+	}
+	modified, err := currentTask.Run(taskCtx, depsModified)
+	report.record(func() {
+		report.add(ctx, currentTask.Name().Name(), resource, start, modified, err)
+		if warner, ok := currentTask.(types.Warner); ok && err == nil {
+			report.warn(warner.Warning())
+		}
+		if exiter, ok := currentTask.(types.ExitCoder); ok {
+			if code, exited := exiter.ExitCode(); exited {
+				report.setExitCode(code)
+			}
 		}
-		logging.Log.WithFields(log.Fields{
-			"elapsed": time.Since(start),
-			"task":    currentTask,
-		}).Debug("Complete")
+		if reporter, ok := currentTask.(types.TestReporter); ok {
+			report.setTestResults(reporter.TestResults())
+		}
+		if err != nil && allowsFailure(resource) {
+			report.allowFailure()
+		}
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to execute task %q: %s", currentTask.Name(), err)
+		if !allowsFailure(resource) {
+			return err
+		}
+		logging.Log.WithFields(log.Fields{"task": currentTask}).Warnf(
+			"%s, continuing because the resource sets allow-failure", err)
+		return nil
 	}
+	if modified {
+		ctx.SetModified(currentTask.Name())
+	}
+	logging.Log.WithFields(log.Fields{
+		"elapsed": time.Since(start),
+		"task":    currentTask,
+	}).Debug("Complete")
 	return nil
 }
 
+// failedTasks tracks the tasks which failed, or were not run because a
+// dependency failed, when tasks are run with keep-going or continue-on-error
+type failedTasks struct {
+	mu     sync.Mutex
+	names  map[string]bool
+	errors []error
+}
+
+func newFailedTasks() *failedTasks {
+	return &failedTasks{names: make(map[string]bool)}
+}
+
+// add a task which failed with err. A nil err marks a task which was not run.
+func (f *failedTasks) add(name task.Name, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Add both the key and the string name so that it matches against
+	// dependencies specified with or without an action
+	f.names[name.MapKey()] = true
+	f.names[name.Name()] = true
+	if err != nil {
+		f.errors = append(f.errors, err)
+	}
+}
+
+func (f *failedTasks) dependsOnFailed(deps []string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, dep := range deps {
+		if f.names[task.ParseName(dep).MapKey()] {
+			return dep, true
+		}
+	}
+	return "", false
+}
+
+func (f *failedTasks) err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch len(f.errors) {
+	case 0:
+		return nil
+	case 1:
+		return f.errors[0]
+	}
+	msgs := []string{}
+	for _, err := range f.errors {
+		msgs = append(msgs, "  "+err.Error())
+	}
+	return fmt.Errorf("%d tasks failed:\n%s", len(f.errors), strings.Join(msgs, "\n"))
+}
+
+type hostedResource interface {
+	Host() string
+}
+
+type builderResource interface {
+	BuilderName() string
+}
+
+type dirResource interface {
+	ResourceDir(projectDir string) string
+}
+
+type variablesResource interface {
+	ResourceVariables() map[string]string
+}
+
+type failureResource interface {
+	AllowsFailure() bool
+}
+
+// allowsFailure returns true if a failure of a task of the resource does not
+// stop the run
+func allowsFailure(resource config.Resource) bool {
+	res, ok := resource.(failureResource)
+	return ok && res.AllowsFailure()
+}
+
+// resolverForResource returns the resolver used to resolve the variables in
+// a resource, which includes the variables set by the resource
+func resolverForResource(
+	ctx *context.ExecuteContext,
+	resource config.Resource,
+) config.Resolver {
+	if res, ok := resource.(variablesResource); ok {
+		return ctx.Env.WithVariables(res.ResourceVariables())
+	}
+	return ctx.Env
+}
+
+// contextForResource returns the context used to run the task for a resource.
+// Resources which set a Docker host or builder use a client for that host, and
+// resources which set a working directory use that directory.
+func contextForResource(
+	ctx *context.ExecuteContext,
+	resource config.Resource,
+) (*context.ExecuteContext, error) {
+	if res, ok := resource.(dirResource); ok {
+		ctx = ctx.ForWorkingDir(res.ResourceDir(ctx.WorkingDir))
+	}
+	if res, ok := resource.(builderResource); ok && res.BuilderName() != "" {
+		return ctx.ForBuilder(res.BuilderName(), resource.String())
+	}
+	hosted, ok := resource.(hostedResource)
+	if !ok {
+		return ctx, nil
+	}
+	return ctx.ForHost(hosted.Host())
+}
+
 func hasModifiedDeps(ctx *context.ExecuteContext, deps []string) bool {
 	for _, dep := range deps {
 		taskName := task.ParseName(dep)
@@ -170,13 +398,111 @@ func hasModifiedDeps(ctx *context.ExecuteContext, deps []string) bool {
 	return false
 }
 
+// resourceParams returns the parameters defined by an alias or a pipeline
+func resourceParams(resource config.Resource) map[string]string {
+	switch conf := resource.(type) {
+	case *config.AliasConfig:
+		return conf.Params
+	case *config.PipelineConfig:
+		return conf.Params
+	default:
+		return nil
+	}
+}
+
+// setParams sets the value of every parameter defined by an alias or a
+// pipeline in the collection. Values from params override the defaults from
+// the config.
+func setParams(
+	execEnv *execenv.ExecEnv,
+	tasks *TaskCollection,
+	params map[string]string,
+) error {
+	used := make(map[string]bool)
+	for _, taskConfig := range tasks.All() {
+		for name, value := range resourceParams(taskConfig.Resource()) {
+			if override, ok := params[name]; ok {
+				value = override
+				used[name] = true
+			}
+			if value == "" {
+				return fmt.Errorf("a value is required for parameter %q of %q",
+					name, taskConfig.Name().Resource())
+			}
+			execEnv.SetParam(name, value)
+		}
+	}
+	for name := range params {
+		if !used[name] {
+			return fmt.Errorf("parameter %q is not defined by any alias or pipeline", name)
+		}
+	}
+	return nil
+}
+
 // RunOptions are the options supported by Run
 type RunOptions struct {
 	Client    client.DockerClient
+	NewClient client.Factory
 	Config    *config.Config
 	Tasks     []string
+	Params    map[string]string
+	// Variables are set with --var, and override all other variables used
+	// by {var.<name>}
+	Variables map[string]string
 	Quiet     bool
 	BindMount bool
+	// PrefixOutput prefixes each line of job output with the name of the task
+	PrefixOutput bool
+	// Color enables color in the prefix of job output
+	Color bool
+	// JobOutput receives the stdout of jobs. Defaults to os.Stdout.
+	JobOutput io.Writer
+	// LogDir is the directory where the output of each job is also written to
+	// a file named after the task
+	LogDir string
+	// OnlyPaths restricts the tasks to those for resources which use files
+	// matching one of the path patterns, and the tasks that depend on them
+	OnlyPaths []string
+	// Skip is the list of resources which are treated as up-to-date, so their
+	// tasks are not run. Each item is a resource name, type=name, or a task
+	// name.
+	Skip []string
+	// Force is the list of resources which are treated as stale, so their
+	// tasks are always run. Each item is a resource name, type=name, or a
+	// task name.
+	Force []string
+	// Strict fails jobs which exit with one of their warn exit codes
+	Strict bool
+	// KeepGoing continues to run tasks which don't depend on a failed task
+	KeepGoing bool
+	// Summary prints a table of task results after the tasks are run
+	Summary bool
+	// ReportFile is the path of a file where the task results are written
+	ReportFile string
+	// TraceFile is the path of a file where a trace of the tasks is written
+	// in the Chrome trace event format
+	TraceFile string
+	// Report receives the task results when it is set
+	Report *Report
+	// HostProfile is one of auto, small, or default. See applyHostProfile.
+	HostProfile string
+	// DefaultMemory is the memory limit for jobs which don't set memory, as
+	// a size with a unit suffix
+	DefaultMemory string
+	// Up runs the start action of resources which are annotated as a service,
+	// instead of their default action
+	Up bool
+	// Down replaces the tasks with the stop action of the services they
+	// start, in the reverse of the order they are started
+	Down bool
+	// Endpoint is the name of the matrix endpoint, set by RunMatrix
+	Endpoint string
+	// SaveRunState records the tasks which completed when the run fails, so
+	// that the run can be continued
+	SaveRunState bool
+	// Continue skips the tasks which completed in the last failed run
+	Continue bool
 }
 
 func getNames(options RunOptions) []string {
@@ -193,10 +519,18 @@ func getNames(options RunOptions) []string {
 
 // Run one or more tasks
 func Run(options RunOptions) error {
+	var completed map[string]bool
+	if options.Continue {
+		var err error
+		if completed, err = loadRunState(&options); err != nil {
+			return err
+		}
+	}
 	options.Tasks = getNames(options)
 	if len(options.Tasks) == 0 {
 		return fmt.Errorf("no task to run, and no default task defined")
 	}
+	taskNames := options.Tasks
 
 	execEnv, err := execenv.NewExecEnvFromConfig(
 		options.Config.Meta.ExecID,
@@ -206,16 +540,207 @@ func Run(options RunOptions) error {
 	if err != nil {
 		return err
 	}
+	if err := setVariableProviders(execEnv, options.Config.Meta.VariableProviders); err != nil {
+		return err
+	}
+	execEnv.SetVariables(options.Config.Meta.Variables)
+	execEnv.SetCommandLineVariables(options.Variables)
+	if options.Endpoint != "" {
+		execEnv.ExecID += "-" + options.Endpoint
+	}
+	if options.Config.Meta.UniqueExecID {
+		if options.Up || options.Down {
+			return fmt.Errorf("services can not be started or stopped with meta.unique-exec-id")
+		}
+		suffix, err := randomSuffix()
+		if err != nil {
+			return err
+		}
+		execEnv.ExecID += "-" + suffix
+	}
 
+	if options.Down {
+		if options.Tasks, err = stopTaskNames(options); err != nil {
+			return err
+		}
+	}
 	tasks, err := collectTasks(options)
 	if err != nil {
 		return err
 	}
+	if len(options.OnlyPaths) > 0 {
+		tasks = filterByPaths(options.Config, tasks, options.OnlyPaths)
+		if len(tasks.All()) == 0 {
+			logging.Log.Info("No tasks use files matching --only-paths")
+			return nil
+		}
+	}
+
+	if err := setParams(execEnv, tasks, options.Params); err != nil {
+		return err
+	}
+	if err := checkPolicy(options.Config, tasks, execEnv); err != nil {
+		return err
+	}
+
+	settings := context.NewSettings(options.Quiet, options.BindMount)
+	settings.PrefixOutput = options.PrefixOutput
+	settings.Color = options.Color
+	settings.JobOutput = options.JobOutput
+	settings.LogDir = options.LogDir
+	if options.LogDir != "" && options.Endpoint != "" {
+		settings.LogDir = filepath.Join(options.LogDir, options.Endpoint)
+	}
+	settings.Strict = options.Strict
+	settings.Skip, err = parseSkip(options.Config, options.Skip)
+	if err != nil {
+		return err
+	}
+	settings.Force, err = parseForce(options.Config, options.Force)
+	if err != nil {
+		return err
+	}
+	if err := checkSkipAndForce(settings.Skip, settings.Force); err != nil {
+		return err
+	}
+	settings.DefaultMemory, err = parseDefaultMemory(options.DefaultMemory)
+	if err != nil {
+		return err
+	}
+	settings.Completed = completed
+	ctx := context.NewExecuteContext(options.Config, options.Client, execEnv, settings)
+	ctx.Clients = client.NewPool(options.Client, options.NewClient)
+	ctx.Endpoint = options.Endpoint
+	ctx.Tracker = context.NewTracker(options.Config.WorkingDir, execEnv.ExecID)
+	context.RemoveAbandoned(options.Config.WorkingDir, ctx.Clients)
+	defer cleanupTracked(ctx)
+
+	err = applyHostProfile(ctx, options.Config.Meta.SmallHost, options.HostProfile)
+	if err != nil {
+		return err
+	}
+	if err := job.RemoveOrphans(ctx, options.Config.Meta.Orphans != "warn"); err != nil {
+		return err
+	}
+	report := options.Report
+	if report == nil {
+		report = &Report{}
+	}
+	report.events, err = newEventStream(options.Config.Meta, execEnv, options.Endpoint)
+	if err != nil {
+		return err
+	}
+	report.events.runStarted(tasks)
+	stopSignals := handleSignals(ctx)
+	err = executeTasks(ctx, tasks, report, options.KeepGoing)
+	stopSignals()
+	report.events.runFinished(err)
+	report.events.close()
+	notifyErr := sendNotification(
+		options.Config.Meta.Notify, execEnv, options.Endpoint, report, err)
+	if notifyErr != nil {
+		logging.Log.Warnf("Failed to send notification: %s", notifyErr)
+	}
+	if options.Config.Meta.UniqueExecID {
+		// The exec-id is not used again, so nothing else would remove the
+		// containers
+		if removeErr := job.RemoveOrphans(ctx, true); removeErr != nil {
+			logging.Log.Warnf("Failed to remove containers: %s", removeErr)
+		}
+	}
+	if reportErr := writeReport(options, report); reportErr != nil {
+		logging.Log.Warnf("Failed to write report: %s", reportErr)
+	}
+	if options.SaveRunState {
+		if stateErr := saveRunState(options, taskNames, report, completed, err); stateErr != nil {
+			logging.Log.Warnf("Failed to save the state of the run: %s", stateErr)
+		}
+	}
+	return err
+}
+
+// cleanupTracked removes the temporary resources which were not removed by
+// the tasks that created them, because a task failed or panicked
+func cleanupTracked(ctx *context.ExecuteContext) {
+	if err := ctx.Tracker.Cleanup(ctx.Clients); err != nil {
+		logging.Log.Warnf("%s, they will be removed by the next run", err)
+	}
+}
 
-	ctx := context.NewExecuteContext(
-		options.Config,
-		options.Client,
-		execEnv,
-		context.NewSettings(options.Quiet, options.BindMount))
-	return executeTasks(ctx, tasks)
+func randomSuffix() (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to create a unique exec-id: %s", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func writeReport(options RunOptions, report *Report) error {
+	if options.Summary {
+		if err := report.WriteSummary(logging.Log.Out); err != nil {
+			return err
+		}
+	}
+	if options.TraceFile != "" {
+		if err := report.WriteTrace(reportPath(options.TraceFile, options.Endpoint)); err != nil {
+			return err
+		}
+	}
+	if options.ReportFile == "" {
+		return nil
+	}
+	return report.WriteFile(reportPath(options.ReportFile, options.Endpoint))
+}
+
+// RunMatrix runs the tasks once for each Docker host in the matrix defined
+// in the config. A failure on one host does not prevent the tasks from running
+// on the other hosts. The result for each host is logged after all runs are
+// complete.
+func RunMatrix(options RunOptions) error {
+	matrix := options.Config.Meta.Matrix
+	if len(matrix) == 0 {
+		return fmt.Errorf("no matrix defined in meta")
+	}
+	if options.NewClient == nil {
+		return fmt.Errorf("a client factory is required to run a matrix")
+	}
+
+	pool := client.NewPool(options.Client, options.NewClient)
+	endpoints := sortedKeys(matrix)
+	results := make(map[string]error)
+	for _, endpoint := range endpoints {
+		logging.Log.Infof("Running on matrix endpoint %q", endpoint)
+		endpointClient, err := pool.Get(matrix[endpoint])
+		if err != nil {
+			results[endpoint] = err
+			continue
+		}
+		endpointOptions := options
+		endpointOptions.Client = endpointClient
+		endpointOptions.Endpoint = endpoint
+		results[endpoint] = Run(endpointOptions)
+	}
+
+	failed := []string{}
+	for _, endpoint := range endpoints {
+		if err := results[endpoint]; err != nil {
+			logging.Log.Errorf("%s: failed: %s", endpoint, err)
+			failed = append(failed, endpoint)
+			continue
+		}
+		logging.Log.Infof("%s: ok", endpoint)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("tasks failed on: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func sortedKeys(mapping map[string]string) []string {
+	keys := []string{}
+	for key := range mapping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
@@ -1,9 +1,16 @@
 package tasks
 
 import (
+	"fmt"
+	"syscall"
 	"testing"
 
 	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/client"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 )
@@ -43,5 +50,275 @@ func TestCollectTasksDoesNotErrorOnDuplicateTask(t *testing.T) {
 	}
 	tasks, err := collectTasks(runOptions)
 	assert.Check(t, is.Nil(err))
-	assert.Check(t, is.Len(tasks.All(), 3))
+	assert.Check(t, is.Len(tasks.All(), 2))
+}
+
+func TestCollectTasksDeduplicatesSharedDependencies(t *testing.T) {
+	runOptions := RunOptions{
+		Config: &config.Config{
+			Resources: map[string]config.Resource{
+				"base":  &config.ImageConfig{Context: ".", Dockerfile: "Dockerfile"},
+				"one":   aliasWithDeps([]string{"base", "base:build"}),
+				"two":   aliasWithDeps([]string{"base:build"}),
+				"three": aliasWithDeps([]string{"one", "two", "base:rm"}),
+			},
+		},
+		Tasks: []string{"three"},
+	}
+	tasks, err := collectTasks(runOptions)
+	assert.NilError(t, err)
+
+	names := []string{}
+	for _, taskConfig := range tasks.All() {
+		names = append(names, taskConfig.Name().Name())
+	}
+	expected := []string{"base:build", "one:run", "two:run", "base:rm", "three:run"}
+	assert.Check(t, is.DeepEqual(names, expected))
+}
+
+func afterConfig() *config.Config {
+	return &config.Config{
+		Resources: map[string]config.Resource{
+			"lint": aliasWithDeps([]string{}),
+			"base": &config.ImageConfig{Context: ".", Dockerfile: "Dockerfile"},
+			"app": &config.JobConfig{
+				Use:       "base",
+				Dependent: config.Dependent{After: []string{"lint"}},
+			},
+		},
+	}
+}
+
+func collectedNames(t *testing.T, options RunOptions) []string {
+	tasks, err := collectTasks(options)
+	assert.NilError(t, err)
+
+	names := []string{}
+	for _, taskConfig := range tasks.All() {
+		names = append(names, taskConfig.Name().Name())
+	}
+	return names
+}
+
+func TestCollectTasksRunsAfterTasksFirst(t *testing.T) {
+	runOptions := RunOptions{Config: afterConfig(), Tasks: []string{"app", "app:rm"}}
+	// The job was named without an action, so the task has the default action
+	expected := []string{"base:build", "lint:run", "app:", "app:rm"}
+	assert.Check(t, is.DeepEqual(collectedNames(t, runOptions), expected))
+}
+
+func TestCollectTasksSkipsAfterTasksForRemove(t *testing.T) {
+	runOptions := RunOptions{Config: afterConfig(), Tasks: []string{"app:rm"}}
+	expected := []string{"app:rm"}
+	assert.Check(t, is.DeepEqual(collectedNames(t, runOptions), expected))
+}
+
+func TestSetParams(t *testing.T) {
+	runOptions := RunOptions{
+		Config: &config.Config{
+			Resources: map[string]config.Resource{
+				"one": &config.ImageConfig{},
+				"deploy": &config.AliasConfig{
+					Tasks:  []string{"one"},
+					Params: map[string]string{"stage": "staging", "region": "us"},
+				},
+			},
+		},
+		Tasks: []string{"deploy"},
+	}
+	tasks, err := collectTasks(runOptions)
+	assert.NilError(t, err)
+
+	execEnv := execenv.NewExecEnv("exec", "project", "cwd")
+	err = setParams(execEnv, tasks, map[string]string{"stage": "production"})
+	assert.NilError(t, err)
+
+	value, err := execEnv.Resolve("{param.stage}-{param.region}")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("production-us", value))
+
+	err = setParams(execEnv, tasks, map[string]string{"bogus": "value"})
+	assert.Check(t, is.ErrorContains(err, `parameter "bogus" is not defined`))
+}
+
+func TestSetParamsMissingRequired(t *testing.T) {
+	runOptions := RunOptions{
+		Config: &config.Config{
+			Resources: map[string]config.Resource{
+				"deploy": &config.AliasConfig{Params: map[string]string{"stage": ""}},
+			},
+		},
+		Tasks: []string{"deploy"},
+	}
+	tasks, err := collectTasks(runOptions)
+	assert.NilError(t, err)
+
+	execEnv := execenv.NewExecEnv("exec", "project", "cwd")
+	err = setParams(execEnv, tasks, nil)
+	assert.Check(t, is.ErrorContains(err,
+		`a value is required for parameter "stage" of "deploy"`))
+}
+
+func TestResolverForResourceUsesResourceVariables(t *testing.T) {
+	execEnv := execenv.NewExecEnv("exec", "project", "/dir")
+	execEnv.SetVariables(map[string]string{"VERSION": "1.2.3"})
+	ctx := &context.ExecuteContext{Env: execEnv}
+
+	image := &config.ImageConfig{Image: "example/app", Tags: []string{"{var.VERSION}"}}
+	resolved, err := image.Resolve(resolverForResource(ctx, image))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(resolved.(*config.ImageConfig).Tags, []string{"1.2.3"}))
+
+	image.Variables = map[string]string{"VERSION": "0.9.0"}
+	resolved, err = image.Resolve(resolverForResource(ctx, image))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(resolved.(*config.ImageConfig).Tags, []string{"0.9.0"}))
+}
+
+func TestRunMatrixReportsFailedEndpoints(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Meta.Matrix = map[string]string{
+		"arm64": "tcp://arm64:2376",
+		"amd64": "tcp://amd64:2376",
+	}
+	err := RunMatrix(RunOptions{
+		Config: conf,
+		NewClient: func(host string) (client.DockerClient, error) {
+			return nil, fmt.Errorf("host %s is unavailable", host)
+		},
+	})
+	assert.Check(t, is.ErrorContains(err, "tasks failed on: amd64, arm64"))
+}
+
+type fakeTask struct {
+	types.NoStop
+	name task.Name
+	err  error
+	ran  *[]string
+	run  func(ctx *context.ExecuteContext)
+}
+
+func (t *fakeTask) Name() task.Name {
+	return t.name
+}
+
+func (t *fakeTask) Repr() string {
+	return t.name.Name()
+}
+
+func (t *fakeTask) Run(ctx *context.ExecuteContext, _ bool) (bool, error) {
+	*t.ran = append(*t.ran, t.name.Name())
+	if t.run != nil {
+		t.run(ctx)
+	}
+	return true, t.err
+}
+
+func TestExecuteTasksKeepGoing(t *testing.T) {
+	ran := []string{}
+	newTaskConfig := func(name string, err error, deps ...string) types.TaskConfig {
+		return types.NewTaskConfig(
+			task.NewName(name, "run"),
+			&config.EnvConfig{},
+			func() []string { return deps },
+			func(name task.Name, _ config.Resource) types.Task {
+				return &fakeTask{name: name, err: err, ran: &ran}
+			})
+	}
+	tasks := newTaskCollection()
+	tasks.add(newTaskConfig("lint", fmt.Errorf("lint failed")))
+	tasks.add(newTaskConfig("build", nil))
+	tasks.add(newTaskConfig("publish", nil, "lint:run"))
+	tasks.add(newTaskConfig("test", fmt.Errorf("test failed"), "build:run"))
+
+	ctx := context.NewExecuteContext(
+		config.NewConfig(), nil, execenv.NewExecEnv("exec", "project", "/dir"), context.Settings{})
+	report := &Report{}
+	err := executeTasks(ctx, tasks, report, true)
+	assert.Check(t, is.ErrorContains(err, "2 tasks failed"))
+	assert.Check(t, is.ErrorContains(err, `"lint:run": lint failed`))
+	assert.Check(t, is.ErrorContains(err, `"test:run": test failed`))
+	assert.Check(t, is.DeepEqual(ran, []string{"lint:run", "build:run", "test:run"}))
+
+	statuses := []string{}
+	for _, result := range report.Tasks {
+		statuses = append(statuses, result.Status)
+	}
+	expected := []string{StatusFailed, StatusRun, StatusNotRun, StatusFailed}
+	assert.Check(t, is.DeepEqual(statuses, expected))
+}
+
+func TestExecuteTasksStopsOnFailure(t *testing.T) {
+	ran := []string{}
+	tasks := newTaskCollection()
+	for _, name := range []string{"one", "two"} {
+		tasks.add(types.NewTaskConfig(
+			task.NewName(name, "run"),
+			&config.EnvConfig{},
+			func() []string { return nil },
+			func(name task.Name, _ config.Resource) types.Task {
+				return &fakeTask{name: name, err: fmt.Errorf("failed"), ran: &ran}
+			}))
+	}
+
+	ctx := context.NewExecuteContext(
+		config.NewConfig(), nil, execenv.NewExecEnv("exec", "project", "/dir"), context.Settings{})
+	err := executeTasks(ctx, tasks, &Report{}, false)
+	assert.Check(t, is.ErrorContains(err, `failed to execute task "one:run": failed`))
+	assert.Check(t, is.DeepEqual(ran, []string{"one:run"}))
+}
+
+func TestExecuteTasksAllowFailure(t *testing.T) {
+	ran := []string{}
+	newTaskConfig := func(name string, resource config.Resource, err error) types.TaskConfig {
+		return types.NewTaskConfig(
+			task.NewName(name, "run"),
+			resource,
+			func() []string { return nil },
+			func(name task.Name, _ config.Resource) types.Task {
+				return &fakeTask{name: name, err: err, ran: &ran}
+			})
+	}
+	optional := &config.EnvConfig{Annotations: config.Annotations{AllowFailure: true}}
+	tasks := newTaskCollection()
+	tasks.add(newTaskConfig("lint", optional, fmt.Errorf("lint failed")))
+	tasks.add(newTaskConfig("test", &config.EnvConfig{}, nil))
+
+	ctx := context.NewExecuteContext(
+		config.NewConfig(), nil, execenv.NewExecEnv("exec", "project", "/dir"), context.Settings{})
+	report := &Report{}
+	assert.NilError(t, executeTasks(ctx, tasks, report, false))
+	assert.Check(t, is.DeepEqual(ran, []string{"lint:run", "test:run"}))
+
+	assert.Assert(t, is.Len(report.Tasks, 2))
+	assert.Check(t, is.Equal(report.Tasks[0].Status, StatusAllowedFailure))
+	assert.Check(t, is.Contains(report.Tasks[0].Error, "lint failed"))
+	assert.Check(t, is.Equal(report.Tasks[1].Status, StatusRun))
+}
+
+func TestExecuteTasksStopsWhenInterrupted(t *testing.T) {
+	ran := []string{}
+	tasks := newTaskCollection()
+	for _, name := range []string{"one", "two"} {
+		tasks.add(types.NewTaskConfig(
+			task.NewName(name, "run"),
+			&config.EnvConfig{},
+			func() []string { return nil },
+			func(name task.Name, _ config.Resource) types.Task {
+				return &fakeTask{
+					name: name,
+					ran:  &ran,
+					run: func(ctx *context.ExecuteContext) {
+						ctx.Interrupt(syscall.SIGTERM)
+					},
+				}
+			}))
+	}
+
+	ctx := context.NewExecuteContext(
+		config.NewConfig(), nil, execenv.NewExecEnv("exec", "project", "/dir"), context.Settings{})
+	err := executeTasks(ctx, tasks, &Report{}, true)
+	assert.Check(t, is.DeepEqual(err, InterruptedError{Signal: syscall.SIGTERM}))
+	assert.Check(t, is.Equal(err.(InterruptedError).ExitCode(), 143))
+	assert.Check(t, is.DeepEqual(ran, []string{"one:run"}))
 }
@@ -0,0 +1,128 @@
+package tasks
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+// stopTasks stops the tasks which were started. A task is stopped only after
+// the started tasks which depend on it have stopped. An error is returned
+// only when teardown on-error is fail.
+func stopTasks(
+	ctx *context.ExecuteContext,
+	tasks *TaskCollection,
+	started []types.Task,
+) error {
+	logging.Log.Debug("stopping tasks")
+	failed := newFailedStops()
+	stop := func(startedTask types.Task) {
+		if err := startedTask.Stop(ctx); err != nil {
+			logging.Log.Warnf("Failed to stop task %q: %s", startedTask.Name(), err)
+			failed.add(startedTask.Name(), err)
+		}
+	}
+
+	switch ctx.Teardown.Order {
+	case config.TeardownParallel:
+		stopParallel(tasks, started, stop)
+	default:
+		for _, startedTask := range reversed(started) {
+			stop(startedTask)
+		}
+	}
+
+	if ctx.Teardown.OnError != config.TeardownFail {
+		return nil
+	}
+	return failed.err()
+}
+
+// stopParallel stops each task as soon as the tasks which depend on it have
+// stopped
+func stopParallel(tasks *TaskCollection, started []types.Task, stop func(types.Task)) {
+	done := make([]chan struct{}, len(started))
+	for i := range started {
+		done[i] = make(chan struct{})
+	}
+
+	deps := make([]map[string]bool, len(started))
+	for i, startedTask := range started {
+		deps[i] = make(map[string]bool)
+		dependencies(tasks, startedTask.Name(), deps[i])
+	}
+
+	wg := sync.WaitGroup{}
+	for i, startedTask := range started {
+		dependents := []chan struct{}{}
+		for j := range started {
+			if j != i && deps[j][startedTask.Name().Name()] {
+				dependents = append(dependents, done[j])
+			}
+		}
+
+		wg.Add(1)
+		go func(startedTask types.Task, done chan struct{}) {
+			defer wg.Done()
+			defer close(done)
+			for _, dependent := range dependents {
+				<-dependent
+			}
+			stop(startedTask)
+		}(startedTask, done[i])
+	}
+	wg.Wait()
+}
+
+// dependencies adds the names of the tasks which the named task depends on,
+// directly or through other tasks, to deps
+func dependencies(tasks *TaskCollection, name task.Name, deps map[string]bool) {
+	taskConfig := tasks.Get(name)
+	if taskConfig == nil {
+		return
+	}
+	for _, dep := range orderedDependencies(taskConfig) {
+		depConfig := tasks.Get(task.ParseName(dep))
+		if depConfig == nil || deps[depConfig.Name().Name()] {
+			continue
+		}
+		deps[depConfig.Name().Name()] = true
+		dependencies(tasks, depConfig.Name(), deps)
+	}
+}
+
+// failedStops records the tasks which failed to stop. Tasks may be stopped
+// concurrently, so the errors are guarded by a lock.
+type failedStops struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func newFailedStops() *failedStops {
+	return &failedStops{}
+}
+
+func (f *failedStops) add(name task.Name, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = append(f.errors, fmt.Sprintf("%q: %s", name, err))
+}
+
+func (f *failedStops) err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch len(f.errors) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("failed to stop task %s", f.errors[0])
+	}
+	return fmt.Errorf("failed to stop %d tasks:\n  %s",
+		len(f.errors), strings.Join(f.errors, "\n  "))
+}
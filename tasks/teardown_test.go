@@ -0,0 +1,108 @@
+package tasks
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type stoppedTasks struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (s *stoppedTasks) add(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.names = append(s.names, name)
+}
+
+type fakeStopTask struct {
+	fakeTask
+	stopped *stoppedTasks
+	stopErr error
+}
+
+func (t *fakeStopTask) Stop(_ *context.ExecuteContext) error {
+	t.stopped.add(t.name.Name())
+	return t.stopErr
+}
+
+// newTeardownTasks returns tasks where a job and a test both depend on a db,
+// through an alias
+func newTeardownTasks(stopped *stoppedTasks, stopErr error) (*TaskCollection, []types.Task) {
+	tasks := newTaskCollection()
+	started := []types.Task{}
+	add := func(name string, deps ...string) {
+		taskName := task.NewName(name, "run")
+		tasks.add(types.NewTaskConfig(
+			taskName,
+			&config.EnvConfig{},
+			func() []string { return deps },
+			func(name task.Name, _ config.Resource) types.Task { return nil }))
+		started = append(started, &fakeStopTask{
+			fakeTask: fakeTask{name: taskName},
+			stopped:  stopped,
+			stopErr:  stopErr,
+		})
+	}
+	add("db")
+	add("deps", "db:run")
+	add("job", "deps:run")
+	add("test", "db:run")
+	return tasks, started
+}
+
+func TestStopTasksReverse(t *testing.T) {
+	stopped := &stoppedTasks{}
+	tasks, started := newTeardownTasks(stopped, nil)
+
+	ctx := &context.ExecuteContext{}
+	assert.NilError(t, stopTasks(ctx, tasks, started))
+	expected := []string{"test:run", "job:run", "deps:run", "db:run"}
+	assert.Check(t, is.DeepEqual(stopped.names, expected))
+}
+
+func TestStopTasksParallelStopsDependentsFirst(t *testing.T) {
+	stopped := &stoppedTasks{}
+	tasks, started := newTeardownTasks(stopped, nil)
+
+	ctx := &context.ExecuteContext{Teardown: config.Teardown{Order: config.TeardownParallel}}
+	assert.NilError(t, stopTasks(ctx, tasks, started))
+	assert.Assert(t, is.Len(stopped.names, 4))
+	assert.Check(t, is.Equal(stopped.names[3], "db:run"))
+
+	index := map[string]int{}
+	for i, name := range stopped.names {
+		index[name] = i
+	}
+	assert.Check(t, index["job:run"] < index["deps:run"])
+}
+
+func TestStopTasksOnError(t *testing.T) {
+	stopped := &stoppedTasks{}
+	tasks, started := newTeardownTasks(stopped, fmt.Errorf("timeout"))
+
+	ctx := &context.ExecuteContext{}
+	assert.NilError(t, stopTasks(ctx, tasks, started))
+	assert.Check(t, is.Len(stopped.names, 4))
+
+	ctx.Teardown.OnError = config.TeardownFail
+	err := stopTasks(ctx, tasks, started)
+	assert.Check(t, is.ErrorContains(err, "failed to stop 4 tasks"))
+	assert.Check(t, is.ErrorContains(err, `"db:run": timeout`))
+}
+
+func TestDependencies(t *testing.T) {
+	tasks, _ := newTeardownTasks(&stoppedTasks{}, nil)
+	deps := map[string]bool{}
+	dependencies(tasks, task.NewName("job", "run"), deps)
+	assert.Check(t, is.DeepEqual(deps, map[string]bool{"deps:run": true, "db:run": true}))
+}
@@ -0,0 +1,149 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	gotemplate "text/template"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/logging"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"github.com/dnephin/dobi/tasks/types"
+)
+
+func init() {
+	types.RegisterActions(
+		"template",
+		types.Action{Name: "render", Description: "Render the template"},
+		types.Action{Name: "remove", Description: "Remove the rendered file"},
+	)
+}
+
+// GetTaskConfig returns a new task for the action
+func GetTaskConfig(
+	name, action string,
+	conf *config.TemplateConfig,
+) (types.TaskConfig, error) {
+	switch action {
+	case "", "render":
+		return types.NewTaskConfig(
+			task.NewDefaultName(name, "render"), conf, deps(conf), newTask), nil
+	case "remove", "rm":
+		return types.NewTaskConfig(
+			task.NewName(name, "rm"), conf, task.NoDependencies, newRemoveTask), nil
+	default:
+		return nil, fmt.Errorf("invalid template action %q for task %q", action, name)
+	}
+}
+
+func deps(conf *config.TemplateConfig) func() []string {
+	return func() []string {
+		return conf.Dependencies()
+	}
+}
+
+// Task renders a template to a file
+type Task struct {
+	types.NoStop
+	name   task.Name
+	config *config.TemplateConfig
+}
+
+func newTask(name task.Name, conf config.Resource) types.Task {
+	return &Task{name: name, config: conf.(*config.TemplateConfig)}
+}
+
+// Name returns the name of the task
+func (t *Task) Name() task.Name {
+	return t.name
+}
+
+// Repr formats the task for logging
+func (t *Task) Repr() string {
+	return fmt.Sprintf("%s %s", t.name.Format("template"), t.config.Output)
+}
+
+// Run renders the template. The output file is only written if the content
+// has changed.
+func (t *Task) Run(ctx *context.ExecuteContext, _ bool) (bool, error) {
+	source := absPath(ctx.WorkingDir, t.config.Source)
+	tmpl, err := gotemplate.New(filepath.Base(source)).
+		Funcs(templateFuncs(ctx)).
+		Option("missingkey=error").
+		ParseFiles(source)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse template: %s", err)
+	}
+
+	buf := new(bytes.Buffer)
+	data := struct{ Values map[string]string }{Values: t.config.Values}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return false, fmt.Errorf("failed to render template: %s", err)
+	}
+
+	output := absPath(ctx.WorkingDir, t.config.Output)
+	if current, err := ioutil.ReadFile(output); err == nil && bytes.Equal(current, buf.Bytes()) {
+		logging.Skipped(t)
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return false, err
+	}
+	if err := ioutil.WriteFile(output, buf.Bytes(), os.FileMode(t.config.Mode)); err != nil {
+		return false, err
+	}
+	logging.ForTask(t).Info("Rendered")
+	return true, nil
+}
+
+func templateFuncs(ctx *context.ExecuteContext) gotemplate.FuncMap {
+	return gotemplate.FuncMap{
+		"env": os.Getenv,
+		"variable": func(name string) (string, error) {
+			return ctx.Env.Resolve("{" + name + "}")
+		},
+	}
+}
+
+func absPath(workingDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(workingDir, path)
+}
+
+// RemoveTask removes the rendered file
+type RemoveTask struct {
+	types.NoStop
+	name   task.Name
+	config *config.TemplateConfig
+}
+
+func newRemoveTask(name task.Name, conf config.Resource) types.Task {
+	return &RemoveTask{name: name, config: conf.(*config.TemplateConfig)}
+}
+
+// Name returns the name of the task
+func (t *RemoveTask) Name() task.Name {
+	return t.name
+}
+
+// Repr formats the task for logging
+func (t *RemoveTask) Repr() string {
+	return fmt.Sprintf("%s %s", t.name.Format("template"), t.config.Output)
+}
+
+// Run removes the rendered file
+func (t *RemoveTask) Run(ctx *context.ExecuteContext, _ bool) (bool, error) {
+	output := absPath(ctx.WorkingDir, t.config.Output)
+	if err := os.Remove(output); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	logging.ForTask(t).Info("Removed")
+	return true, nil
+}
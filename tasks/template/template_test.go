@@ -0,0 +1,44 @@
+package template
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/dnephin/dobi/config"
+	"github.com/dnephin/dobi/execenv"
+	"github.com/dnephin/dobi/tasks/context"
+	"github.com/dnephin/dobi/tasks/task"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestTaskRun(t *testing.T) {
+	dir := fs.NewDir(t, "test-template-task",
+		fs.WithFile("app.tmpl",
+			"replicas: {{ .Values.replicas }}\nproject: {{ variable \"project\" }}\n"))
+	defer dir.Remove()
+
+	ctx := &context.ExecuteContext{
+		WorkingDir: dir.Path(),
+		Env:        execenv.NewExecEnv("exec", "theproject", dir.Path()),
+	}
+	tmplTask := newTask(task.NewName("manifest", "render"), &config.TemplateConfig{
+		Source: "app.tmpl",
+		Output: "dist/app.yaml",
+		Values: map[string]string{"replicas": "3"},
+		Mode:   0644,
+	})
+
+	modified, err := tmplTask.Run(ctx, false)
+	assert.NilError(t, err)
+	assert.Assert(t, modified)
+
+	content, err := ioutil.ReadFile(dir.Join("dist", "app.yaml"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "replicas: 3\nproject: theproject\n")
+
+	// Next run is a no-op
+	modified, err = tmplTask.Run(ctx, false)
+	assert.NilError(t, err)
+	assert.Assert(t, !modified)
+}
@@ -0,0 +1,83 @@
+package tasks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+)
+
+// traceEvent is a complete event in the Chrome trace event format, which can
+// be viewed with chrome://tracing or https://ui.perfetto.dev
+type traceEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat"`
+	Phase     string                 `json:"ph"`
+	Timestamp int64                  `json:"ts"`
+	Duration  int64                  `json:"dur"`
+	PID       int                    `json:"pid"`
+	TID       int                    `json:"tid"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+type trace struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// WriteTrace writes a span for each task which was run, or checked, to a file
+// in the Chrome trace event format
+func (r *Report) WriteTrace(path string) error {
+	raw, err := json.MarshalIndent(r.trace(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, raw, 0644)
+}
+
+func (r *Report) trace() trace {
+	events := []traceEvent{}
+	for _, result := range r.Tasks {
+		// tasks which were not run have no span
+		if result.Start.IsZero() {
+			continue
+		}
+		events = append(events, traceEvent{
+			Name:      result.Name,
+			Category:  "task",
+			Phase:     "X",
+			Timestamp: result.Start.UnixNano() / int64(time.Microsecond),
+			Duration:  microseconds(result.Duration),
+			PID:       1,
+			TID:       1,
+			Args:      traceArgs(result),
+		})
+	}
+	return trace{TraceEvents: events, DisplayTimeUnit: "ms"}
+}
+
+func traceArgs(result TaskResult) map[string]interface{} {
+	args := map[string]interface{}{"status": result.Status}
+	switch result.Status {
+	case StatusSkipped:
+		args["cache"] = "hit"
+	case StatusRun, StatusWarning:
+		args["cache"] = "miss"
+	}
+	if result.Output != "" {
+		args["output"] = result.Output
+	}
+	if result.ExitCode != nil {
+		args["exit-code"] = *result.ExitCode
+	}
+	if result.Error != "" {
+		args["error"] = result.Error
+	}
+	if result.Warning != "" {
+		args["warning"] = result.Warning
+	}
+	return args
+}
+
+func microseconds(duration time.Duration) int64 {
+	return int64(duration / time.Microsecond)
+}
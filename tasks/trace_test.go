@@ -0,0 +1,67 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestReportTrace(t *testing.T) {
+	start := time.Unix(100, 0)
+	exitCode := 2
+	report := &Report{Tasks: []TaskResult{
+		{
+			Name:     "builder:build",
+			Status:   StatusSkipped,
+			Start:    start,
+			Duration: 20 * time.Millisecond,
+			Output:   "builder:latest",
+		},
+		{
+			Name:     "lint:run",
+			Status:   StatusFailed,
+			Start:    start.Add(20 * time.Millisecond),
+			Duration: time.Second,
+			Error:    "exited with non-zero status code 2",
+			ExitCode: &exitCode,
+		},
+		{Name: "test:run", Status: StatusNotRun},
+	}}
+
+	expected := trace{
+		DisplayTimeUnit: "ms",
+		TraceEvents: []traceEvent{
+			{
+				Name:      "builder:build",
+				Category:  "task",
+				Phase:     "X",
+				Timestamp: 100000000,
+				Duration:  20000,
+				PID:       1,
+				TID:       1,
+				Args: map[string]interface{}{
+					"status": StatusSkipped,
+					"cache":  "hit",
+					"output": "builder:latest",
+				},
+			},
+			{
+				Name:      "lint:run",
+				Category:  "task",
+				Phase:     "X",
+				Timestamp: 100020000,
+				Duration:  1000000,
+				PID:       1,
+				TID:       1,
+				Args: map[string]interface{}{
+					"status":    StatusFailed,
+					"exit-code": 2,
+					"error":     "exited with non-zero status code 2",
+				},
+			},
+		},
+	}
+	assert.Check(t, is.DeepEqual(report.trace(), expected))
+}
@@ -0,0 +1,20 @@
+package types
+
+// Action describes an action supported by a type of resource
+type Action struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+var actionRegistry = map[string][]Action{}
+
+// RegisterActions sets the actions supported by a type of resource. The first
+// action is the default.
+func RegisterActions(resType string, actions ...Action) {
+	actionRegistry[resType] = actions
+}
+
+// Actions returns the actions supported by a type of resource
+func Actions(resType string) []Action {
+	return actionRegistry[resType]
+}
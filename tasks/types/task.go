@@ -15,6 +15,42 @@ type Task interface {
 	Stop(*context.ExecuteContext) error
 }
 
+// Warner is implemented by tasks which can complete with a warning
+type Warner interface {
+	// Warning returns the warning from the last run, or an empty string
+	Warning() string
+}
+
+// ExitCoder is implemented by tasks which run a container
+type ExitCoder interface {
+	// ExitCode returns the exit code of the container, and false if the
+	// container did not exit
+	ExitCode() (int, bool)
+}
+
+// TestReporter is implemented by tasks which parse the results of the tests
+// run by the task
+type TestReporter interface {
+	// TestResults returns the test results from the last run, or nil
+	TestResults() *TestResults
+}
+
+// StaleChecker is implemented by tasks which can check if they are stale
+// without running
+type StaleChecker interface {
+	// IsStale returns true if the task would run when none of its dependencies
+	// were modified
+	IsStale(*context.ExecuteContext) (bool, error)
+}
+
+// TestResults is a summary of the tests run by a task
+type TestResults struct {
+	Total    int      `json:"total"`
+	Failed   int      `json:"failed"`
+	Skipped  int      `json:"skipped"`
+	Failures []string `json:"failures,omitempty"`
+}
+
 // RunFunc is a function which performs the task. It received a context and a
 // bool indicating if any dependencies were modified. It should return true if
 // the resource was modified, otherwise false.
@@ -18,6 +18,9 @@ type LastModifiedSearch struct {
 	Root     string
 	Excludes []string
 	Paths    []string
+	// Symlinks is the policy used for symlinks found in Paths. The default
+	// policy is SymlinkCopyAsLink.
+	Symlinks SymlinkPolicy
 }
 
 // LastModified returns the latest modified time for all the files and
@@ -65,6 +68,10 @@ func LastModified(search *LastModifiedSearch) (time.Time, error) {
 			return nil
 		}
 
+		info, err = statWithPolicy(search.Root, filePath, info, search.Symlinks)
+		if err != nil {
+			return err
+		}
 		if info.ModTime().After(latest) {
 			latest = info.ModTime()
 		}
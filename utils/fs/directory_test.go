@@ -128,3 +128,35 @@ func touch(name string, mtime time.Time) error {
 
 	return os.Chtimes(name, mtime, mtime)
 }
+
+func TestLastModifiedSymlinkPolicy(t *testing.T) {
+	outside := fs.NewDir(t, "test-directory-last-modified-symlink-outside",
+		fs.WithFile("target", ""))
+	defer outside.Remove()
+	tmpdir := fs.NewDir(t, "test-directory-last-modified-symlink", fs.WithDir("a"))
+	defer tmpdir.Remove()
+	assert.NilError(t, os.Symlink(outside.Join("target"), tmpdir.Join("a", "link")))
+
+	mtime := time.Now().AddDate(0, 0, 10)
+	assert.NilError(t, touch(outside.Join("target"), mtime))
+
+	actual, err := LastModified(&LastModifiedSearch{
+		Root:     tmpdir.Path(),
+		Paths:    []string{"a"},
+		Symlinks: SymlinkFollow,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, actual, mtime)
+
+	_, err = LastModified(&LastModifiedSearch{
+		Root:     tmpdir.Path(),
+		Paths:    []string{"a"},
+		Symlinks: SymlinkForbidEscape,
+	})
+	assert.Assert(t, cmp.ErrorContains(err, "resolves outside of"))
+}
+
+func TestCaseCollisions(t *testing.T) {
+	paths := []string{"a/File", "a/other", "a/file", "b/file"}
+	assert.DeepEqual(t, CaseCollisions(paths), []string{"a/file"})
+}
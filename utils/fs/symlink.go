@@ -0,0 +1,77 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SymlinkPolicy controls how symlinks are handled when walking or copying files
+type SymlinkPolicy string
+
+const (
+	// SymlinkCopyAsLink treats a symlink as a file. The modified time of the
+	// link is used, and the link is copied as a link.
+	SymlinkCopyAsLink SymlinkPolicy = "copy-as-link"
+	// SymlinkFollow uses the modified time of the file the symlink points to
+	SymlinkFollow SymlinkPolicy = "follow"
+	// SymlinkForbidEscape returns an error if a symlink resolves to a path
+	// outside of the root directory
+	SymlinkForbidEscape SymlinkPolicy = "forbid-escape"
+)
+
+// CheckSymlinkEscape returns an error if the symlink at path resolves to a
+// location outside of root. linkname is the target of the link.
+func CheckSymlinkEscape(root, path, linkname string) error {
+	target := linkname
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	rel, err := filepath.Rel(root, filepath.Clean(target))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("symlink %s resolves outside of %s", path, root)
+	}
+	return nil
+}
+
+// statWithPolicy returns the FileInfo of a symlink found while walking a
+// directory, using policy to decide if the link should be followed
+func statWithPolicy(
+	root, path string,
+	info os.FileInfo,
+	policy SymlinkPolicy,
+) (os.FileInfo, error) {
+	if info.Mode()&os.ModeSymlink == 0 {
+		return info, nil
+	}
+	switch policy {
+	case SymlinkFollow:
+		return os.Stat(path)
+	case SymlinkForbidEscape:
+		linkname, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		return info, CheckSymlinkEscape(root, path, linkname)
+	default:
+		return info, nil
+	}
+}
+
+// CaseCollisions returns all the paths which are equal to an earlier path in
+// the list when compared without case. Paths like this can not be created on
+// case-insensitive filesystems like the default on macOS and Windows.
+func CaseCollisions(paths []string) []string {
+	seen := make(map[string]bool)
+	collisions := []string{}
+	for _, path := range paths {
+		key := strings.ToLower(path)
+		if seen[key] {
+			collisions = append(collisions, path)
+			continue
+		}
+		seen[key] = true
+	}
+	return collisions
+}